@@ -0,0 +1,91 @@
+// Package amqplog logs the deliveries consumed by AMQP subscribers with the
+// godin logger.
+package amqplog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-godin/log"
+	"github.com/go-godin/log/correlation"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Handler processes a delivery.
+type Handler func(ctx context.Context, d amqp.Delivery) error
+
+// Middleware logs the start of every delivery as debug and its finish with
+// the exchange, routing key, delivery attempt and duration in seconds, as
+// info or as error if the handler failed. The handler receives a Log in its
+// context, see log.FromContext, carrying these fields, the trace of the
+// message headers (b3 or traceparent) and its correlation ID. Messages
+// without correlation ID get a new one.
+func Middleware(logger log.Log) func(Handler) Handler {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, d amqp.Delivery) error {
+			begin := time.Now()
+
+			h := header(d.Headers)
+			if tp, ok := log.TraceFromHeader(h); ok {
+				ctx = log.ContextWithTrace(ctx, tp)
+			}
+			id := d.CorrelationId
+			if id == "" {
+				id = h.Get(correlation.Header)
+			}
+			if id == "" {
+				id = correlation.Generate()
+			}
+			ctx = correlation.NewContext(ctx, id)
+
+			keyvals := []interface{}{"exchange", d.Exchange, "routing_key", d.RoutingKey, "attempt", attempt(d)}
+			if d.MessageId != "" {
+				keyvals = append(keyvals, "message_id", d.MessageId)
+			}
+			l := logger.WithTrace(ctx).With(keyvals...)
+			l.Debug("consume started")
+
+			err := next(log.NewContext(ctx, l), d)
+			if err != nil {
+				l.Error("consume failed", "duration", time.Since(begin).Seconds(), "err", err)
+				return err
+			}
+			l.Info("consume finished", "duration", time.Since(begin).Seconds())
+			return nil
+		}
+	}
+}
+
+// attempt returns the delivery attempt, counted by quorum queues in the
+// x-delivery-count header. Other queues only tell whether it's redelivered.
+func attempt(d amqp.Delivery) int64 {
+	switch n := d.Headers["x-delivery-count"].(type) {
+	case int32:
+		return int64(n) + 1
+	case int64:
+		return n + 1
+	}
+	if d.Redelivered {
+		return 2
+	}
+	return 1
+}
+
+// header converts the string values of the message headers to an
+// http.Header.
+func header(t amqp.Table) http.Header {
+	h := make(http.Header, len(t))
+	for key, value := range t {
+		switch v := value.(type) {
+		case string:
+			h.Set(key, v)
+		case []byte:
+			h.Set(key, string(v))
+		case fmt.Stringer:
+			h.Set(key, v.String())
+		}
+	}
+	return h
+}
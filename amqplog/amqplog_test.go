@@ -0,0 +1,121 @@
+package amqplog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-godin/log"
+	"github.com/go-godin/log/correlation"
+	kitlog "github.com/go-kit/kit/log"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestMiddleware(t *testing.T) {
+	failure := errors.New("rejected")
+	tests := []struct {
+		name            string
+		delivery        amqp.Delivery
+		err             error
+		wantMessage     string
+		wantLevel       string
+		wantAttempt     float64
+		wantCorrelation string // empty for a generated one
+		wantTrace       string
+	}{
+		{
+			name:        "finished",
+			delivery:    amqp.Delivery{Exchange: "orders", RoutingKey: "created"},
+			wantMessage: "consume finished",
+			wantLevel:   "info",
+			wantAttempt: 1,
+		},
+		{
+			name:        "failed",
+			delivery:    amqp.Delivery{Exchange: "orders", RoutingKey: "created"},
+			err:         failure,
+			wantMessage: "consume failed",
+			wantLevel:   "error",
+			wantAttempt: 1,
+		},
+		{
+			name:        "redelivered",
+			delivery:    amqp.Delivery{Exchange: "orders", RoutingKey: "created", Redelivered: true},
+			wantMessage: "consume finished",
+			wantLevel:   "info",
+			wantAttempt: 2,
+		},
+		{
+			name:        "quorum queue delivery count",
+			delivery:    amqp.Delivery{Exchange: "orders", RoutingKey: "created", Redelivered: true, Headers: amqp.Table{"x-delivery-count": int64(3)}},
+			wantMessage: "consume finished",
+			wantLevel:   "info",
+			wantAttempt: 4,
+		},
+		{
+			name:            "correlation ID property",
+			delivery:        amqp.Delivery{CorrelationId: "c1", Headers: amqp.Table{correlation.Header: "c2"}},
+			wantMessage:     "consume finished",
+			wantLevel:       "info",
+			wantAttempt:     1,
+			wantCorrelation: "c1",
+		},
+		{
+			name:            "correlation ID header",
+			delivery:        amqp.Delivery{Headers: amqp.Table{correlation.Header: []byte("c2")}},
+			wantMessage:     "consume finished",
+			wantLevel:       "info",
+			wantAttempt:     1,
+			wantCorrelation: "c2",
+		},
+		{
+			name:        "traceparent header",
+			delivery:    amqp.Delivery{Headers: amqp.Table{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}},
+			wantMessage: "consume finished",
+			wantLevel:   "info",
+			wantAttempt: 1,
+			wantTrace:   "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := log.NewLogger(log.LevelInfo, log.WithSink(kitlog.NewJSONLogger(&buf)))
+			var handlerCorrelation string
+			h := Middleware(logger)(func(ctx context.Context, d amqp.Delivery) error {
+				handlerCorrelation, _ = correlation.FromContext(ctx)
+				return tt.err
+			})
+			if err := h(context.Background(), tt.delivery); err != tt.err {
+				t.Fatalf("handler returned %v, want %v", err, tt.err)
+			}
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != 1 {
+				t.Fatalf("logged %d entries, want 1: %s", len(lines), buf.String())
+			}
+			var entry map[string]interface{}
+			if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+				t.Fatalf("decoding %q: %v", lines[0], err)
+			}
+			if entry[log.MessageKey] != tt.wantMessage || entry["severity"] != tt.wantLevel {
+				t.Errorf("message, severity = %v, %v, want %s, %s", entry[log.MessageKey], entry["severity"], tt.wantMessage, tt.wantLevel)
+			}
+			if entry["attempt"] != tt.wantAttempt {
+				t.Errorf("attempt = %v, want %v", entry["attempt"], tt.wantAttempt)
+			}
+			if entry["exchange"] != tt.delivery.Exchange || entry["routing_key"] != tt.delivery.RoutingKey {
+				t.Errorf("exchange, routing_key = %v, %v", entry["exchange"], entry["routing_key"])
+			}
+			if handlerCorrelation == "" || (tt.wantCorrelation != "" && handlerCorrelation != tt.wantCorrelation) {
+				t.Errorf("correlation ID = %q, want %q", handlerCorrelation, tt.wantCorrelation)
+			}
+			if tt.wantTrace != "" && entry[log.TraceIDKey] != tt.wantTrace {
+				t.Errorf("trace_id = %v, want %s", entry[log.TraceIDKey], tt.wantTrace)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+module github.com/go-godin/log/amqplog
+
+go 1.22.0
+
+require (
+	github.com/go-godin/log v0.0.0-20261015011011-9795f6140b6d
+	github.com/go-kit/kit v0.9.0
+	github.com/rabbitmq/amqp091-go v1.15.0
+)
+
+require (
+	github.com/fsnotify/fsnotify v1.9.0 // indirect
+	github.com/go-logfmt/logfmt v0.4.0 // indirect
+	github.com/kr/logfmt v0.0.0-20140226030751-b84e30acd515 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	go.uber.org/atomic v1.4.0 // indirect
+	go.uber.org/multierr v1.2.0 // indirect
+	go.uber.org/zap v1.10.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// The replace builds against the root module of this checkout. Dependents
+// ignore it and get the version required above.
+replace github.com/go-godin/log => ..
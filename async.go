@@ -0,0 +1,309 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-godin/log/level"
+	"github.com/go-kit/kit/log"
+)
+
+// WithAsync decouples logging from writing: entries are enqueued onto a
+// queue of size entries and filtered and written by a background worker, so
+// slow sinks don't add to the latency of the caller. Logging blocks while
+// the queue is full, see WithOverflowPolicy. Panic entries are written
+// before Panic panics, Fatal drains the queue before exiting. Call Flush or
+// Close before the process exits otherwise:
+//
+//	logger := log.NewLogger("info", log.WithAsync(10000))
+//	defer logger.Close()
+func WithAsync(size int) Option {
+	return func(o *options) { o.async = size }
+}
+
+// OverflowPolicy is the behavior of WithAsync once the queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the caller until the worker made room.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest drops the oldest queued entry for the new one.
+	OverflowDropOldest
+	// OverflowDropNewest drops the new entry.
+	OverflowDropNewest
+)
+
+// WithOverflowPolicy sets the behavior of WithAsync once the queue is full.
+// Fatal and panic entries are never dropped. Defaults to OverflowBlock.
+func WithOverflowPolicy(policy OverflowPolicy) Option {
+	return func(o *options) { o.overflow = policy }
+}
+
+// WithOnDrop sets the function called with every entry dropped by
+// WithOverflowPolicy, e.g. to count them in a metric. It's called by the
+// goroutine logging the new entry and must not log through the Log.
+func WithOnDrop(onDrop func(keyvals []interface{})) Option {
+	return func(o *options) { o.onDrop = onDrop }
+}
+
+// asyncSink hands entries to next in a background worker.
+type asyncSink struct {
+	next     log.Logger
+	queue    asyncQueue
+	done     chan struct{}
+	overflow OverflowPolicy
+	onDrop   func(keyvals []interface{})
+	dropped  atomic.Uint64
+
+	mtx    sync.RWMutex
+	closed bool
+
+	// acks holds the Flush markers dequeued by producers dropping the
+	// oldest entry, acknowledged by the worker once the entry it's writing
+	// is written
+	acksMtx sync.Mutex
+	acks    []chan struct{}
+	pending int32
+}
+
+type asyncEntry struct {
+	keyvals []interface{}
+	ack     chan struct{}
+}
+
+// asyncQueue is the queue between the callers and the worker of an
+// asyncSink.
+type asyncQueue interface {
+	// push enqueues the entry, blocking while the queue is full.
+	push(e asyncEntry)
+	// tryPush enqueues the entry unless the queue is full.
+	tryPush(e asyncEntry) bool
+	// tryPop dequeues the oldest entry unless the queue is empty.
+	tryPop() (asyncEntry, bool)
+	// pop dequeues the oldest entry, blocking while the queue is empty. It
+	// returns false once the queue is closed and drained.
+	pop() (asyncEntry, bool)
+	// close makes pop return false once the queue is drained.
+	close()
+}
+
+// chanQueue is the asyncQueue used by default.
+type chanQueue chan asyncEntry
+
+func (q chanQueue) push(e asyncEntry) { q <- e }
+
+func (q chanQueue) tryPush(e asyncEntry) bool {
+	select {
+	case q <- e:
+		return true
+	default:
+		return false
+	}
+}
+
+func (q chanQueue) tryPop() (asyncEntry, bool) {
+	select {
+	case e := <-q:
+		return e, true
+	default:
+		return asyncEntry{}, false
+	}
+}
+
+func (q chanQueue) pop() (asyncEntry, bool) {
+	e, ok := <-q
+	return e, ok
+}
+
+func (q chanQueue) close() { close(q) }
+
+func newAsyncSink(next log.Logger, queue asyncQueue, overflow OverflowPolicy, onDrop func([]interface{})) *asyncSink {
+	a := &asyncSink{
+		next:     next,
+		queue:    queue,
+		done:     make(chan struct{}),
+		overflow: overflow,
+		onDrop:   onDrop,
+	}
+	go a.run()
+	return a
+}
+
+// Log enqueues the entry. Entries logged after Close are written directly.
+func (a *asyncSink) Log(keyvals ...interface{}) error {
+	// the caller may reuse the slice once Log returns
+	e := asyncEntry{keyvals: append([]interface{}(nil), keyvals...)}
+	lvl, _ := level.FromKeyvals(keyvals)
+	critical := lvl != nil && (lvl.String() == LevelFatal || lvl.String() == LevelPanic)
+
+	a.mtx.RLock()
+	if a.closed {
+		a.mtx.RUnlock()
+		return a.next.Log(e.keyvals...)
+	}
+	if critical {
+		a.queue.push(e)
+	} else {
+		a.enqueue(e)
+	}
+	a.mtx.RUnlock()
+
+	if critical && lvl.String() == LevelPanic {
+		return a.Flush()
+	}
+	return nil
+}
+
+// enqueue adds the entry to the queue according to the overflow policy.
+func (a *asyncSink) enqueue(e asyncEntry) {
+	switch a.overflow {
+	case OverflowDropNewest:
+		if !a.queue.tryPush(e) {
+			a.drop(e)
+		}
+	case OverflowDropOldest:
+		for !a.queue.tryPush(e) {
+			old, ok := a.queue.tryPop()
+			if !ok {
+				continue
+			}
+			if old.ack != nil {
+				// the worker may still be writing the entry preceding the
+				// Flush
+				a.handOver(old.ack)
+				continue
+			}
+			a.drop(old)
+		}
+	default:
+		a.queue.push(e)
+	}
+}
+
+// handOver leaves the acknowledgement of a dequeued Flush marker to the
+// worker.
+func (a *asyncSink) handOver(ack chan struct{}) {
+	a.acksMtx.Lock()
+	a.acks = append(a.acks, ack)
+	atomic.StoreInt32(&a.pending, 1)
+	a.acksMtx.Unlock()
+}
+
+// ackHandedOver acknowledges the Flush markers handed over by producers.
+// It's called by the worker between entries, so all entries preceding the
+// markers are written.
+func (a *asyncSink) ackHandedOver() {
+	if atomic.LoadInt32(&a.pending) == 0 {
+		return
+	}
+	a.acksMtx.Lock()
+	for _, ack := range a.acks {
+		close(ack)
+	}
+	a.acks = nil
+	atomic.StoreInt32(&a.pending, 0)
+	a.acksMtx.Unlock()
+}
+
+func (a *asyncSink) drop(e asyncEntry) {
+	a.dropped.Add(1)
+	if a.onDrop != nil {
+		a.onDrop(expandBound(e.keyvals))
+	}
+}
+
+// Flush blocks until the entries enqueued so far are written.
+func (a *asyncSink) Flush() error {
+	a.mtx.RLock()
+	if a.closed {
+		a.mtx.RUnlock()
+		return nil
+	}
+	ack := make(chan struct{})
+	a.queue.push(asyncEntry{ack: ack})
+	a.mtx.RUnlock()
+
+	<-ack
+	return nil
+}
+
+// Close writes the queued entries and stops the worker.
+func (a *asyncSink) Close() error {
+	a.mtx.Lock()
+	if a.closed {
+		a.mtx.Unlock()
+		return nil
+	}
+	a.closed = true
+	a.queue.close()
+	a.mtx.Unlock()
+
+	<-a.done
+	return nil
+}
+
+func (a *asyncSink) run() {
+	defer close(a.done)
+	defer a.ackHandedOver()
+	for {
+		e, ok := a.queue.pop()
+		if !ok {
+			return
+		}
+		a.ackHandedOver()
+		if e.ack != nil {
+			close(e.ack)
+			continue
+		}
+		_ = a.next.Log(e.keyvals...)
+	}
+}
+
+// Dropped returns the amount of entries dropped by WithOverflowPolicy,
+// summed over the queues of WithAsync the Log writes through.
+func (l Log) Dropped() uint64 {
+	var dropped uint64
+	for _, sink := range l.sinks {
+		if a, ok := sink.(*asyncSink); ok {
+			dropped += a.dropped.Load()
+		}
+	}
+	return dropped
+}
+
+// Flush blocks until the entries enqueued by WithAsync are written and
+// flushes the sinks providing a Flush method.
+func (l Log) Flush() error {
+	return flushSinks(l.allSinks(), false)
+}
+
+// Close writes the entries enqueued by WithAsync and stops its worker,
+// entries logged afterwards are written synchronously. The sinks providing
+// a Flush method are flushed. Close is shared by all Logs derived from the
+// same NewLogger call.
+func (l Log) Close() error {
+	return flushSinks(l.allSinks(), true)
+}
+
+// flushSinks flushes the sinks, closing the async sink first if closeAsync
+// is set. It returns the first error.
+func flushSinks(sinks []log.Logger, closeAsync bool) error {
+	var first error
+	for _, sink := range sinks {
+		var err error
+		switch s := sink.(type) {
+		case *asyncSink:
+			if closeAsync {
+				err = s.Close()
+			} else {
+				err = s.Flush()
+			}
+		case interface{ Flush() error }:
+			err = s.Flush()
+		}
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
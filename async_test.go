@@ -0,0 +1,298 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// countingSink counts the entries written to it.
+type countingSink struct {
+	n int64
+}
+
+func (s *countingSink) Log(keyvals ...interface{}) error {
+	atomic.AddInt64(&s.n, 1)
+	return nil
+}
+
+func (s *countingSink) count() int64 {
+	return atomic.LoadInt64(&s.n)
+}
+
+// blockingSink blocks every write until release is closed. started is
+// closed once the first write blocks.
+type blockingSink struct {
+	countingSink
+	once    sync.Once
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingSink() *blockingSink {
+	return &blockingSink{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (s *blockingSink) Log(keyvals ...interface{}) error {
+	s.once.Do(func() { close(s.started) })
+	<-s.release
+	return s.countingSink.Log(keyvals...)
+}
+
+// queues are the queues of WithAsync, exercised by the tests and benchmarks.
+var queues = []struct {
+	name string
+	opts []Option
+}{
+	{"channel", nil},
+	{"ring", []Option{WithRingBuffer()}},
+}
+
+// queued returns the amount of entries in the queue of a.
+func queued(a *asyncSink) int {
+	switch q := a.queue.(type) {
+	case chanQueue:
+		return len(q)
+	case *ringQueue:
+		return int(q.tail.Load() - q.head.Load())
+	}
+	panic("unknown queue")
+}
+
+func TestAsyncOverflow(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		overflow OverflowPolicy
+	}{
+		{"block", OverflowBlock},
+		{"drop oldest", OverflowDropOldest},
+		{"drop newest", OverflowDropNewest},
+	} {
+		for _, q := range queues {
+			t.Run(q.name+"/"+tt.name, func(t *testing.T) {
+				const producers, perProducer = 8, 2000
+				sink := &countingSink{}
+				logger := NewLogger("info", append([]Option{WithSink(sink), WithAsync(4), WithOverflowPolicy(tt.overflow)}, q.opts...)...)
+
+				var wg sync.WaitGroup
+				for p := 0; p < producers; p++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for n := 0; n < perProducer; n++ {
+							logger.Info("entry", "n", n)
+							if n%500 == 0 {
+								_ = logger.Flush()
+							}
+						}
+					}()
+				}
+				wg.Wait()
+				if err := logger.Close(); err != nil {
+					t.Fatal(err)
+				}
+
+				written, dropped := sink.count(), int64(logger.Dropped())
+				if written+dropped != producers*perProducer {
+					t.Fatalf("%d written + %d dropped, want %d", written, dropped, producers*perProducer)
+				}
+				if tt.overflow == OverflowBlock && dropped != 0 {
+					t.Fatalf("%d entries dropped while blocking", dropped)
+				}
+			})
+		}
+	}
+}
+
+func TestAsyncFullQueue(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		overflow    OverflowPolicy
+		wantDropped uint64
+	}{
+		{"drop newest", OverflowDropNewest, 3},
+		{"drop oldest", OverflowDropOldest, 3},
+	} {
+		for _, q := range queues {
+			t.Run(q.name+"/"+tt.name, func(t *testing.T) {
+				sink := newBlockingSink()
+				var onDrop []interface{}
+				logger := NewLogger("info", append([]Option{WithSink(sink), WithAsync(2), WithOverflowPolicy(tt.overflow),
+					WithOnDrop(func(keyvals []interface{}) { onDrop = append(onDrop, keyvals...) })}, q.opts...)...)
+
+				// the worker blocks on the first entry, the next two fill the queue
+				logger.Info("first")
+				<-sink.started
+				for i := 0; i < 5; i++ {
+					logger.Info("entry", "i", i)
+				}
+				if got := logger.Dropped(); got != tt.wantDropped {
+					t.Fatalf("Dropped() = %d, want %d", got, tt.wantDropped)
+				}
+				if len(onDrop) == 0 {
+					t.Fatal("onDrop wasn't called")
+				}
+
+				close(sink.release)
+				if err := logger.Close(); err != nil {
+					t.Fatal(err)
+				}
+				if got := sink.count(); got != 3 {
+					t.Fatalf("%d entries written, want 3", got)
+				}
+			})
+		}
+	}
+}
+
+func TestAsyncFlushDropOldest(t *testing.T) {
+	for _, q := range queues {
+		t.Run(q.name, func(t *testing.T) {
+			sink := newBlockingSink()
+			logger := NewLogger("info", append([]Option{WithSink(sink), WithAsync(2), WithOverflowPolicy(OverflowDropOldest)}, q.opts...)...)
+			async := logger.sinks[0].(*asyncSink)
+
+			// the worker blocks on the first entry, a and the Flush marker
+			// fill the queue
+			logger.Info("first")
+			<-sink.started
+			logger.Info("a")
+			flushed := make(chan struct{})
+			go func() {
+				_ = logger.Flush()
+				close(flushed)
+			}()
+			for queued(async) < 2 {
+				time.Sleep(time.Millisecond)
+			}
+
+			// b drops a, c dequeues the marker to make room
+			logger.Info("b")
+			logger.Info("c")
+			select {
+			case <-flushed:
+				t.Fatal("Flush returned while the entry preceding it was being written")
+			case <-time.After(20 * time.Millisecond):
+			}
+
+			close(sink.release)
+			<-flushed
+			if got := sink.count(); got < 1 {
+				t.Fatalf("%d entries written when Flush returned, want the first", got)
+			}
+			if err := logger.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if got := sink.count(); got != 3 {
+				t.Fatalf("%d entries written, want 3", got)
+			}
+		})
+	}
+}
+
+func TestDropped(t *testing.T) {
+	a, b := &asyncSink{}, &asyncSink{}
+	a.dropped.Store(2)
+	b.dropped.Store(3)
+	l := Log{sinks: []log.Logger{a, &countingSink{}, b}}
+	if got := l.Dropped(); got != 5 {
+		t.Errorf("Dropped() = %d, want 5", got)
+	}
+	if got := (Log{}).Dropped(); got != 0 {
+		t.Errorf("Dropped() without WithAsync = %d, want 0", got)
+	}
+}
+
+func TestAsyncCloseDrains(t *testing.T) {
+	sink := &countingSink{}
+	logger := NewLogger("info", WithSink(sink), WithAsync(1024))
+	for i := 0; i < 1000; i++ {
+		logger.Info("entry", "i", i)
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := sink.count(); got != 1000 {
+		t.Fatalf("%d entries written after Close, want 1000", got)
+	}
+
+	// entries logged after Close are written synchronously
+	logger.Info("after close")
+	if got := sink.count(); got != 1001 {
+		t.Fatalf("%d entries written, want 1001", got)
+	}
+}
+
+// BenchmarkAsync logs from parallel producers. With OverflowBlock the
+// producers wait for the worker, which measures the throughput of the
+// queue, with OverflowDropNewest they never wait, which measures the cost of
+// enqueuing for the caller.
+func BenchmarkAsync(b *testing.B) {
+	for _, bb := range []struct {
+		name     string
+		overflow OverflowPolicy
+	}{
+		{"block", OverflowBlock},
+		{"drop newest", OverflowDropNewest},
+	} {
+		for _, q := range queues {
+			b.Run(q.name+"/"+bb.name, func(b *testing.B) {
+				logger := NewLogger("info", append([]Option{WithSink(&countingSink{}), WithAsync(1 << 14), WithOverflowPolicy(bb.overflow)}, q.opts...)...)
+				defer logger.Close()
+
+				b.ReportAllocs()
+				b.SetParallelism(4)
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						logger.Info("request served", "status", 200)
+					}
+				})
+				b.StopTimer()
+				_ = logger.Flush()
+			})
+		}
+	}
+}
+
+// BenchmarkAsyncQueue measures the queues alone, with parallel producers
+// and the worker only dequeuing, so the cost of handing entries over isn't
+// hidden by building and writing them.
+func BenchmarkAsyncQueue(b *testing.B) {
+	for _, bb := range []struct {
+		name  string
+		queue func() asyncQueue
+	}{
+		{"channel", func() asyncQueue { return make(chanQueue, 1<<14) }},
+		{"ring", func() asyncQueue { return newRingQueue(1 << 14) }},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			q := bb.queue()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for {
+					if _, ok := q.pop(); !ok {
+						return
+					}
+				}
+			}()
+
+			b.ReportAllocs()
+			b.SetParallelism(4)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				e := asyncEntry{keyvals: []interface{}{"message", "m"}}
+				for pb.Next() {
+					q.push(e)
+				}
+			})
+			b.StopTimer()
+			q.close()
+			<-done
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package log
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-godin/log/level"
+	"github.com/go-kit/kit/log"
+)
+
+// AtomicLevel is the level of a Log, which can be changed at runtime. It's
+// shared by the Log and all Logs derived from it with With, Named or
+// WithTrace, so a change applies to all of them immediately. It's safe for
+// concurrent use.
+type AtomicLevel struct {
+	// mtx serializes changes, Log only reads state
+	mtx     sync.Mutex
+	next    log.Logger
+	closers []io.Closer
+	named   map[string]string
+	state   atomic.Pointer[levelState]
+
+	// sinks are the sinks built from a config file, replaced on reloads
+	sinks []log.Logger
+
+	// goKitCompat is set if sinks created on reloads need to be wrapped
+	goKitCompat bool
+	// floor is the least severe level accepted by sinks with their own level
+	floor level.Value
+
+	// sampledDebug is set by WithTraceSampledDebug
+	sampledDebug bool
+	// datadogFields is set by WithDatadogTraceFields
+	datadogFields bool
+	// spanTags restricts the keyvals tagged onto spans
+	spanTags spanTagPolicy
+	// caller and callerSkip are set by WithCaller
+	caller     bool
+	callerSkip int
+	// unfiltered is set if stages in front of the filter need all entries,
+	// e.g. recorders, so Log methods can't drop entries early
+	unfiltered bool
+
+	// window is the level to revert to after a temporary level, see EnableDebugFor
+	window *levelWindow
+}
+
+type levelWindow struct {
+	previous string
+	until    time.Time
+	timer    *time.Timer
+}
+
+type levelState struct {
+	name   string
+	filter log.Logger
+	// min and named hold the least severe levels passing the filter
+	min   level.Value
+	named map[string]level.Value
+}
+
+// newAtomicLevel creates the level filter in front of next for a level
+// specification like "info,storage=debug".
+func newAtomicLevel(next log.Logger, spec string) (*AtomicLevel, error) {
+	s := &AtomicLevel{next: next}
+	return s, s.setSpec(spec)
+}
+
+// set replaces the default level. The levels of named loggers are kept.
+func (s *AtomicLevel) set(logLevel string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.cancelWindow()
+	return s.apply(logLevel, s.named)
+}
+
+// setSpec replaces the default level and the levels of named loggers.
+func (s *AtomicLevel) setSpec(spec string) error {
+	def, named := parseLevelSpec(spec)
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.cancelWindow()
+	return s.apply(def, named)
+}
+
+// setFor replaces the default level for the duration d, after which revert
+// is called with the level to revert to. Setting a level again while a
+// temporary one is active extends the window, and keeps the level reverted to.
+func (s *AtomicLevel) setFor(logLevel string, d time.Duration, revert func(previous string)) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	window := &levelWindow{previous: s.state.Load().name, until: time.Now().Add(d)}
+	if s.window != nil {
+		window.previous = s.window.previous
+		s.cancelWindow()
+	}
+	if err := s.apply(logLevel, s.named); err != nil {
+		return err
+	}
+	window.timer = time.AfterFunc(d, func() {
+		s.mtx.Lock()
+		if s.window != window {
+			// the window was cancelled or replaced
+			s.mtx.Unlock()
+			return
+		}
+		s.window = nil
+		_ = s.apply(window.previous, s.named)
+		s.mtx.Unlock()
+		revert(window.previous)
+	})
+	s.window = window
+	return nil
+}
+
+// until returns when the temporary level ends, or the zero time.
+func (s *AtomicLevel) until() time.Time {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.window == nil {
+		return time.Time{}
+	}
+	return s.window.until
+}
+
+func (s *AtomicLevel) cancelWindow() {
+	if s.window != nil {
+		s.window.timer.Stop()
+		s.window = nil
+	}
+}
+
+// configSinks returns the sinks built from a config file.
+func (s *AtomicLevel) configSinks() []log.Logger {
+	if s == nil {
+		return nil
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.sinks
+}
+
+// sink returns the sink entries passing the filter are handed to, resolving
+// Lazy values as the filter does.
+func (s *AtomicLevel) sink() log.Logger {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return lazyResolver{next: s.next}
+}
+
+// setNext replaces the sink entries are handed to, built from sinks, and
+// closes the closers of the previous one once it doesn't receive entries
+// anymore.
+func (s *AtomicLevel) setNext(next log.Logger, sinks []log.Logger, closers []io.Closer) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	previous := s.closers
+	s.next, s.sinks, s.closers = next, sinks, closers
+	_ = s.apply(s.state.Load().name, s.named)
+	for _, c := range previous {
+		_ = c.Close()
+	}
+}
+
+func (s *AtomicLevel) apply(logLevel string, named map[string]string) error {
+	levelOpt, err := evaluateLogLevel(logLevel)
+	// lazy values are resolved once entries passed the filter
+	next := lazyResolver{next: s.next}
+	// forced entries only bypass this filter, not those of WithMinLevelSink
+	filter := level.NewFilter(next, levelOpt, level.AllowForced())
+	if len(named) > 0 {
+		var namedErr error
+		filter, namedErr = newNamedFilter(next, filter, named)
+		if err == nil {
+			err = namedErr
+		}
+	}
+	state := &levelState{name: levelName(logLevel), filter: filter}
+	state.min, _ = level.Parse(state.name)
+	for name, lvl := range named {
+		if v, parseErr := ParseLevel(lvl); parseErr == nil {
+			if state.named == nil {
+				state.named = make(map[string]level.Value)
+			}
+			state.named[name], _ = level.Parse(string(v))
+		}
+	}
+	s.named = named
+	s.state.Store(state)
+	return err
+}
+
+// name returns the name of the default level.
+func (s *AtomicLevel) name() string {
+	if s == nil {
+		return ""
+	}
+	return s.state.Load().name
+}
+
+// Level returns the name of the current level, e.g. "info".
+func (s *AtomicLevel) Level() string {
+	return s.name()
+}
+
+// SetLevel changes the level, accepting aliases as ParseLevel does. The
+// levels of named loggers are kept. It fails for unknown levels, leaving the
+// level unchanged. Unlike Log.SetLevel it doesn't emit the change.
+func (s *AtomicLevel) SetLevel(logLevel string) error {
+	lvl, err := ParseLevel(logLevel)
+	if err != nil {
+		return err
+	}
+	return s.set(string(lvl))
+}
+
+// Enabled reports whether entries of the level pass the filter, or are
+// accepted by a sink with its own level, given the name of the Named logger,
+// which is empty for unnamed ones.
+func (s *AtomicLevel) Enabled(lvl Level, name string) bool {
+	if s == nil {
+		return false
+	}
+	v, ok := level.Parse(string(lvl))
+	if !ok {
+		return false
+	}
+	return s.accepts(v, name)
+}
+
+// accepts reports whether entries of the level pass the filter, or are
+// accepted by a sink with its own level.
+func (s *AtomicLevel) accepts(v level.Value, name string) bool {
+	if s.floor != nil && v.Severity() >= s.floor.Severity() {
+		return true
+	}
+	return s.passes(v, name)
+}
+
+// drops reports whether entries of the level logged by the Named logger
+// name are certainly dropped, so the Log can skip building them.
+func (s *AtomicLevel) drops(v level.Value, name string) bool {
+	if s == nil || s.unfiltered {
+		return false
+	}
+	if s.floor != nil && v.Severity() >= s.floor.Severity() {
+		return false
+	}
+	return !s.passes(v, name)
+}
+
+// passes reports whether entries of the level pass the filter.
+func (s *AtomicLevel) passes(v level.Value, name string) bool {
+	if level.IsForced(v) {
+		return true
+	}
+	state := s.state.Load()
+	min := state.min
+	if named, ok := lookupNamed(state.named, name); ok {
+		min = named
+	}
+	return v.Severity() >= min.Severity()
+}
+
+func (s *AtomicLevel) Log(keyvals ...interface{}) error {
+	return s.state.Load().filter.Log(keyvals...)
+}
+
+// bumpLevels are the levels bumpLevel steps through.
+var bumpLevels = []string{LevelDebug, LevelInfo, LevelWarning, LevelError}
+
+// bumpLevel makes the default level one step more verbose, or less verbose,
+// and emits the change.
+func (l Log) bumpLevel(verbose bool, source string) {
+	before := l.levels.name()
+	current, ok := level.Parse(before)
+	if !ok {
+		return
+	}
+
+	after := before
+	for i := range bumpLevels {
+		name := bumpLevels[i]
+		if !verbose {
+			name = bumpLevels[len(bumpLevels)-1-i]
+		}
+		v, _ := level.Parse(name)
+		if verbose && v.Severity() < current.Severity() || !verbose && v.Severity() > current.Severity() {
+			after = name
+		}
+	}
+	if after != before {
+		_ = l.changeLevel(after, source)
+	}
+}
+
+// changeLevel replaces the default level and emits the change. It fails for
+// unknown levels.
+func (l Log) changeLevel(name, source string) error {
+	before := l.levels.name()
+	if err := l.levels.SetLevel(name); err != nil {
+		return err
+	}
+	name = l.levels.name()
+	l.ConfigChanged(ConfigChange{
+		Setting: "level",
+		Before:  before,
+		After:   name,
+		Source:  source,
+	})
+	return nil
+}
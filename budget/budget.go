@@ -0,0 +1,271 @@
+// Package budget limits the daily log volume of a service. Once a configured
+// share of the daily budget is used up, the Limiter progressively degrades:
+// Debug entries are dropped first, then Info entries get sampled and finally
+// only Warning and Error entries pass until the next day begins.
+package budget
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/level"
+	"github.com/go-kit/kit/log"
+)
+
+// Stage describes how far the Limiter has degraded today.
+type Stage int
+
+const (
+	// StageNormal passes all entries.
+	StageNormal Stage = iota
+	// StageDropDebug drops Debug entries.
+	StageDropDebug
+	// StageSampleInfo drops Debug entries and samples Info entries.
+	StageSampleInfo
+	// StageExhausted drops all Debug and Info entries.
+	StageExhausted
+)
+
+func (s Stage) String() string {
+	switch s {
+	case StageDropDebug:
+		return "drop_debug"
+	case StageSampleInfo:
+		return "sample_info"
+	case StageExhausted:
+		return "exhausted"
+	default:
+		return "normal"
+	}
+}
+
+// Usage reports the volume consumed during the current day.
+type Usage struct {
+	Day     time.Time
+	Bytes   int64
+	Entries int64
+	Dropped int64
+	Stage   Stage
+}
+
+// Limiter is a go-kit logger enforcing the daily budget before handing
+// entries to the next logger.
+type Limiter struct {
+	next         log.Logger
+	maxBytes     int64
+	maxEntries   int64
+	dropDebugAt  float64
+	sampleInfoAt float64
+	sampleInfo   int64
+	dropAll      bool
+	location     *time.Location
+	now          func() time.Time
+	mtx          sync.Mutex
+	usage        Usage
+	infoSeen     int64
+}
+
+// Option sets a parameter for the Limiter.
+type Option func(*Limiter)
+
+// Bytes sets the daily budget of encoded JSON bytes. Zero disables the limit.
+func Bytes(max int64) Option {
+	return func(l *Limiter) { l.maxBytes = max }
+}
+
+// Entries sets the daily budget of entries. Zero disables the limit.
+func Entries(max int64) Option {
+	return func(l *Limiter) { l.maxEntries = max }
+}
+
+// DropDebugAt sets the used share of the budget (0..1) after which Debug
+// entries are dropped. Defaults to 0.5.
+func DropDebugAt(share float64) Option {
+	return func(l *Limiter) { l.dropDebugAt = share }
+}
+
+// SampleInfoAt sets the used share of the budget (0..1) after which only one
+// in every n Info entries passes. Defaults to 0.8 and 1 in 10.
+func SampleInfoAt(share float64, n int64) Option {
+	return func(l *Limiter) {
+		l.sampleInfoAt = share
+		l.sampleInfo = n
+	}
+}
+
+// DropAllWhenExhausted drops Warning and Error entries as well once the
+// budget is exhausted. By default they still pass.
+func DropAllWhenExhausted() Option {
+	return func(l *Limiter) { l.dropAll = true }
+}
+
+// Location sets the time zone in which days begin. Defaults to UTC.
+func Location(loc *time.Location) Option {
+	return func(l *Limiter) { l.location = loc }
+}
+
+// NewLimiter wraps next and enforces the configured daily budget.
+func NewLimiter(next log.Logger, options ...Option) *Limiter {
+	l := &Limiter{
+		next:         next,
+		dropDebugAt:  0.5,
+		sampleInfoAt: 0.8,
+		sampleInfo:   10,
+		location:     time.UTC,
+		now:          time.Now,
+	}
+	for _, option := range options {
+		option(l)
+	}
+	l.usage.Day = l.today()
+	return l
+}
+
+// Usage returns the volume consumed during the current day.
+func (l *Limiter) Usage() Usage {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.rollover()
+	return l.usage
+}
+
+// Log hands the entry to the next logger unless the budget forbids it.
+func (l *Limiter) Log(keyvals ...interface{}) error {
+	var size int64
+	if l.maxBytes > 0 {
+		size = estimate(keyvals)
+	}
+
+	lvl, _ := level.FromKeyvals(keyvals)
+
+	l.mtx.Lock()
+	l.rollover()
+	if !l.allow(lvl) {
+		l.usage.Dropped++
+		l.mtx.Unlock()
+		return nil
+	}
+	before := l.usage.Stage
+	l.usage.Bytes += size
+	l.usage.Entries++
+	l.usage.Stage = l.stage()
+	usage := l.usage
+	l.mtx.Unlock()
+
+	err := l.next.Log(keyvals...)
+	if usage.Stage != before {
+		l.announce(usage)
+	}
+	return err
+}
+
+// estimate returns the size of the entry encoded as a JSON line. Values the
+// JSON encoding fails on are estimated by their string form instead, so an
+// entry is never dropped for the estimate.
+func estimate(keyvals []interface{}) int64 {
+	if data, err := json.Marshal(kv.Map(keyvals)); err == nil {
+		return int64(len(data)) + 1 // trailing newline
+	}
+
+	size := int64(2) // braces and trailing newline, less the last comma
+	for i := 0; i < len(keyvals); i += 2 {
+		var v interface{} = log.ErrMissingValue
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		// quoted key, colon, quoted value and comma
+		size += int64(len(kv.Key(keyvals[i])) + len(kv.String(v)) + 6)
+	}
+	return size
+}
+
+// allow reports whether an entry of the given level may pass at the current
+// stage. Levels are compared by severity, so forced debug entries and custom
+// levels below info are reduced as well. Entries without level always pass
+// unless the budget is exhausted and DropAll is set.
+func (l *Limiter) allow(lvl level.Value) bool {
+	switch l.usage.Stage {
+	case StageNormal:
+		return true
+	case StageExhausted:
+		if l.dropAll {
+			return false
+		}
+		return lvl == nil || lvl.Severity() > level.InfoValue().Severity()
+	}
+
+	switch {
+	case lvl == nil:
+		return true
+	case lvl.Severity() <= level.DebugValue().Severity():
+		return false
+	case lvl.Severity() <= level.InfoValue().Severity():
+		if l.usage.Stage != StageSampleInfo {
+			return true
+		}
+		l.infoSeen++
+		return l.sampleInfo > 0 && (l.infoSeen-1)%l.sampleInfo == 0
+	}
+	return true
+}
+
+// stage computes the stage from the share of the budget used so far.
+func (l *Limiter) stage() Stage {
+	used := 0.0
+	if l.maxBytes > 0 {
+		used = float64(l.usage.Bytes) / float64(l.maxBytes)
+	}
+	if l.maxEntries > 0 {
+		if e := float64(l.usage.Entries) / float64(l.maxEntries); e > used {
+			used = e
+		}
+	}
+
+	switch {
+	case used >= 1:
+		return StageExhausted
+	case used >= l.sampleInfoAt:
+		return StageSampleInfo
+	case used >= l.dropDebugAt:
+		return StageDropDebug
+	default:
+		return StageNormal
+	}
+}
+
+// announce logs a warning about the new stage. It bypasses the budget, so the
+// notice is emitted even if all other entries are dropped.
+func (l *Limiter) announce(usage Usage) {
+	message := "log budget degraded to stage " + usage.Stage.String()
+	if usage.Stage == StageExhausted {
+		message = "DAILY LOG BUDGET EXHAUSTED: dropping debug and info entries until the end of the day"
+		if l.dropAll {
+			message = "DAILY LOG BUDGET EXHAUSTED: dropping all entries until the end of the day"
+		}
+	}
+	_ = l.next.Log(
+		level.Key(), level.WarnValue(),
+		kv.MessageKey, message,
+		"budget_stage", usage.Stage.String(),
+		"budget_bytes", l.maxBytes,
+		"budget_entries", l.maxEntries,
+		"used_bytes", usage.Bytes,
+		"used_entries", usage.Entries,
+		"budget_resets_at", usage.Day.AddDate(0, 0, 1).Format(time.RFC3339),
+	)
+}
+
+// rollover resets the usage once a new day started.
+func (l *Limiter) rollover() {
+	if today := l.today(); !today.Equal(l.usage.Day) {
+		l.usage = Usage{Day: today}
+		l.infoSeen = 0
+	}
+}
+
+func (l *Limiter) today() time.Time {
+	y, m, d := l.now().In(l.location).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, l.location)
+}
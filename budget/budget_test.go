@@ -0,0 +1,122 @@
+package budget
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/go-godin/log/level"
+)
+
+type recordingLogger struct {
+	entries [][]interface{}
+}
+
+func (r *recordingLogger) Log(keyvals ...interface{}) error {
+	r.entries = append(r.entries, keyvals)
+	return nil
+}
+
+func TestLimiterEstimate(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		keyvals []interface{}
+	}{
+		{"plain", []interface{}{"message", "m", "n", 1}},
+		{"channel", []interface{}{"message", "m", "ch", make(chan int)}},
+		{"function", []interface{}{"message", "m", "f", func() {}}},
+		{"infinity", []interface{}{"message", "m", "v", math.Inf(1)}},
+		{"missing value", []interface{}{"message", "m", "dangling"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &recordingLogger{}
+			l := NewLimiter(next, Bytes(1<<20))
+			if err := l.Log(tt.keyvals...); err != nil {
+				t.Fatalf("Log() = %v", err)
+			}
+			if len(next.entries) != 1 {
+				t.Fatalf("passed %d entries, want 1", len(next.entries))
+			}
+			if usage := l.Usage(); usage.Bytes <= 0 || usage.Entries != 1 {
+				t.Errorf("usage = %+v, want the entry counted", usage)
+			}
+		})
+	}
+}
+
+func TestLimiterStages(t *testing.T) {
+	debug := []interface{}{level.Key(), level.DebugValue(), "message", "m"}
+	info := []interface{}{level.Key(), level.InfoValue(), "message", "m"}
+	warn := []interface{}{level.Key(), level.WarnValue(), "message", "m"}
+	forced := []interface{}{level.Key(), level.Force(level.DebugValue()), "message", "m"}
+	unleveled := []interface{}{"message", "m"}
+
+	for _, tt := range []struct {
+		name    string
+		options []Option
+		logged  int // entries logged before the one under test
+		entry   []interface{}
+		stage   Stage
+		passes  bool
+	}{
+		{"debug in normal", nil, 4, debug, StageNormal, true},
+		{"debug in drop debug", nil, 5, debug, StageDropDebug, false},
+		{"info in drop debug", nil, 5, info, StageDropDebug, true},
+		{"info in sample info", nil, 8, info, StageSampleInfo, true},
+		{"debug exhausted", nil, 10, debug, StageExhausted, false},
+		{"info exhausted", nil, 10, info, StageExhausted, false},
+		{"warn exhausted", nil, 10, warn, StageExhausted, true},
+		{"warn exhausted dropping all", []Option{DropAllWhenExhausted()}, 10, warn, StageExhausted, false},
+		{"forced debug in drop debug", nil, 5, forced, StageDropDebug, false},
+		{"forced debug exhausted", nil, 10, forced, StageExhausted, false},
+		{"no level in drop debug", nil, 5, unleveled, StageDropDebug, true},
+		{"no level exhausted", nil, 10, unleveled, StageExhausted, true},
+		{"no level exhausted dropping all", []Option{DropAllWhenExhausted()}, 10, unleveled, StageExhausted, false},
+		{"custom drop debug share", []Option{DropDebugAt(0.2)}, 2, debug, StageDropDebug, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &recordingLogger{}
+			l := NewLimiter(next, append([]Option{Entries(10)}, tt.options...)...)
+			for i := 0; i < tt.logged; i++ {
+				_ = l.Log(warn...)
+			}
+			if stage := l.Usage().Stage; stage != tt.stage {
+				t.Fatalf("stage = %v, want %v", stage, tt.stage)
+			}
+			before := len(next.entries)
+			_ = l.Log(tt.entry...)
+			if passed := len(next.entries) > before; passed != tt.passes {
+				t.Errorf("passed = %v, want %v", passed, tt.passes)
+			}
+		})
+	}
+}
+
+func TestLimiterSampleInfo(t *testing.T) {
+	next := &recordingLogger{}
+	l := NewLimiter(next, Entries(1000), SampleInfoAt(0, 3))
+	for i := 0; i < 9; i++ {
+		_ = l.Log(level.Key(), level.InfoValue(), "message", "m")
+	}
+	// the first entry passes in the normal stage and announces the sampling
+	if got := len(next.entries); got != 1+1+3 {
+		t.Errorf("passed %d entries, want 5", got)
+	}
+}
+
+func TestLimiterRollover(t *testing.T) {
+	now := time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC)
+	next := &recordingLogger{}
+	l := NewLimiter(next, Entries(1))
+	l.now = func() time.Time { return now }
+	l.usage.Day = l.today()
+
+	_ = l.Log(level.Key(), level.InfoValue(), "message", "m")
+	if stage := l.Usage().Stage; stage != StageExhausted {
+		t.Fatalf("stage = %v, want %v", stage, StageExhausted)
+	}
+	now = now.Add(2 * time.Hour)
+	if usage := l.Usage(); usage.Stage != StageNormal || usage.Entries != 0 {
+		t.Errorf("usage after midnight = %+v, want it reset", usage)
+	}
+}
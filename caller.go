@@ -0,0 +1,165 @@
+package log
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// CallerKey is the key of the call site added by WithCaller.
+const CallerKey = "caller"
+
+// WithCaller adds the call site of every entry as CallerKey field, shortened
+// to the directory and file, e.g. "service/handler.go:42". Frames of this
+// module, including the adapters, and the standard library's log package are
+// skipped, skip sets the amount of further frames to skip, so entries logged
+// by wrapper helpers point at the caller of the helper:
+//
+//	// logError is called by the handlers, which should be reported
+//	func logError(logger log.Log, err error) { logger.Error("request failed", "err", err) }
+//
+//	logger := log.NewLogger("info", log.WithCaller(1))
+//
+// Entries already holding a CallerKey field keep it.
+func WithCaller(skip int) Option {
+	return func(o *options) {
+		o.caller = true
+		o.callerSkip = skip
+	}
+}
+
+// Caller is a hint setting the call site of a single entry, which can be
+// passed anywhere between the keyvals like To. Adapters use it to report the
+// code calling into the library they connect instead of the library itself.
+// The hint is ignored unless WithCaller is set.
+type Caller struct {
+	pc       uintptr
+	prefixes []string
+}
+
+// CallerPC returns a hint setting the call site to the program counter, as
+// returned by runtime.Callers or held by a slog.Record. A zero pc omits the
+// call site.
+func CallerPC(pc uintptr) Caller {
+	return Caller{pc: pc}
+}
+
+// CallerOutside returns a hint setting the call site to the first frame
+// outside this module and the packages whose functions have one of the
+// prefixes, e.g. "github.com/sirupsen/logrus.". The skip of WithCaller
+// applies to the remaining frames.
+func CallerOutside(prefixes ...string) Caller {
+	return Caller{prefixes: prefixes}
+}
+
+// packagePrefix is the prefix of the names of the functions of this package.
+var packagePrefix = func() string {
+	pc, _, _, _ := runtime.Caller(0)
+	name := runtime.FuncForPC(pc).Name()
+	pkg := strings.LastIndexByte(name, '/') + 1
+	return name[:pkg+strings.IndexByte(name[pkg:], '.')+1]
+}()
+
+// modulePrefix is the prefix of the names of the functions of the
+// subpackages of this package.
+var modulePrefix = strings.TrimSuffix(packagePrefix, ".") + "/"
+
+// caller returns the call site of the entry being logged, if WithCaller is
+// set, and keyvals without Caller hints.
+func (l Log) caller(keyvals []interface{}) (string, bool, []interface{}) {
+	hint, hinted, keyvals := extractCaller(keyvals)
+	if l.levels == nil || !l.levels.caller {
+		return "", false, keyvals
+	}
+	if _, ok := fieldValue(keyvals, CallerKey); ok {
+		return "", false, keyvals
+	}
+	if hinted && hint.prefixes == nil {
+		if hint.pc == 0 {
+			return "", false, keyvals
+		}
+		frame, _ := runtime.CallersFrames([]uintptr{hint.pc}).Next()
+		return shortCaller(frame.File, frame.Line), true, keyvals
+	}
+
+	var pcs [64]uintptr
+	// skips runtime.Callers and caller
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs[:])])
+	skip := l.levels.callerSkip
+	for {
+		frame, more := frames.Next()
+		if !skipsFrame(frame.Function, hint.prefixes) {
+			if skip == 0 {
+				return shortCaller(frame.File, frame.Line), true, keyvals
+			}
+			skip--
+		}
+		if !more {
+			return "", false, keyvals
+		}
+	}
+}
+
+// skipsFrame reports whether the function belongs to this module, except
+// its test packages, the
+// standard library's log package, which calls the writer of StdLogger, or a
+// package with one of the prefixes.
+func skipsFrame(function string, prefixes []string) bool {
+	if strings.HasPrefix(function, packagePrefix) || strings.HasPrefix(function, "log.") {
+		return true
+	}
+	if strings.HasPrefix(function, modulePrefix) {
+		// the external tests of the adapters log like their users
+		pkg := strings.LastIndexByte(function, '/') + 1
+		if !strings.HasSuffix(function[:pkg+strings.IndexByte(function[pkg:], '.')], "_test") {
+			return true
+		}
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractCaller removes the Caller hints in key position from keyvals and
+// returns the last one.
+func extractCaller(keyvals []interface{}) (Caller, bool, []interface{}) {
+	found, last := 0, -1
+	for i, v := range keyvals {
+		if _, ok := v.(Caller); ok {
+			found++
+			last = i
+		}
+	}
+	switch {
+	case found == 0:
+		return Caller{}, false, keyvals
+	case found == 1 && last == len(keyvals)-1 && last%2 == 0:
+		// the hint appended by the adapters
+		return keyvals[last].(Caller), true, keyvals[:last]
+	}
+
+	var hint Caller
+	list := make([]interface{}, 0, len(keyvals))
+	for _, v := range keyvals {
+		if c, ok := v.(Caller); ok && len(list)%2 == 0 {
+			hint = c
+			continue
+		}
+		list = append(list, v)
+	}
+	return hint, true, list
+}
+
+// shortCaller returns file:line with file shortened to its directory and
+// name.
+func shortCaller(file string, line int) string {
+	if i := strings.LastIndexByte(file, '/'); i > 0 {
+		if j := strings.LastIndexByte(file[:i], '/'); j >= 0 {
+			file = file[j+1:]
+		}
+	}
+	return file + ":" + strconv.Itoa(line)
+}
@@ -0,0 +1,143 @@
+// The frames of package log are skipped by WithCaller, so the call sites are
+// tested from outside the package.
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-godin/log"
+	kitlog "github.com/go-kit/kit/log"
+)
+
+// here returns the call site of its caller as reported by WithCaller.
+func here() string {
+	_, file, line, _ := runtime.Caller(1)
+	return filepath.Join(filepath.Base(filepath.Dir(file)), filepath.Base(file)) + ":" + strconv.Itoa(line)
+}
+
+// herePC returns the program counter and call site of its caller.
+func herePC() (uintptr, string) {
+	var pcs [1]uintptr
+	runtime.Callers(2, pcs[:])
+	_, file, line, _ := runtime.Caller(1)
+	return pcs[0], filepath.Join(filepath.Base(filepath.Dir(file)), filepath.Base(file)) + ":" + strconv.Itoa(line)
+}
+
+// logFailure is a wrapper helper, the entries of which report its caller
+// with WithCaller(1).
+func logFailure(l log.Log, want string) {
+	l.Error("failed", "want", want)
+}
+
+// logOutside logs an entry reporting the caller of the functions starting
+// with its name.
+func logOutside(l log.Log, want string) {
+	l.Info("test", "want", want, log.CallerOutside("github.com/go-godin/log_test.logOutside"))
+}
+
+func TestCaller(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []log.Option
+		log  func(l log.Log)
+	}{
+		{
+			name: "leveled method",
+			opts: []log.Option{log.WithCaller(0)},
+			log:  func(l log.Log) { l.Info("test", "want", here()) },
+		},
+		{
+			name: "ctx method",
+			opts: []log.Option{log.WithCaller(0)},
+			log:  func(l log.Log) { l.InfoCtx(context.Background(), "test", "want", here()) },
+		},
+		{
+			name: "log method",
+			opts: []log.Option{log.WithCaller(0)},
+			log:  func(l log.Log) { l.Log("message", "test", "want", here()) },
+		},
+		{
+			name: "named",
+			opts: []log.Option{log.WithCaller(0)},
+			log:  func(l log.Log) { l.Named("db").Warning("test", "want", here()) },
+		},
+		{
+			name: "skipped helper",
+			opts: []log.Option{log.WithCaller(1)},
+			log:  func(l log.Log) { logFailure(l, here()) },
+		},
+		{
+			name: "pc hint",
+			opts: []log.Option{log.WithCaller(0)},
+			log: func(l log.Log) {
+				pc, want := herePC()
+				l.Info("test", "want", want, log.CallerPC(pc))
+			},
+		},
+		{
+			name: "pc hint between keyvals",
+			opts: []log.Option{log.WithCaller(0)},
+			log: func(l log.Log) {
+				pc, want := herePC()
+				l.Info("test", "a", 1, log.CallerPC(pc), "want", want)
+			},
+		},
+		{
+			name: "zero pc hint",
+			opts: []log.Option{log.WithCaller(0)},
+			log:  func(l log.Log) { l.Info("test", log.CallerPC(0)) },
+		},
+		{
+			name: "outside hint",
+			opts: []log.Option{log.WithCaller(0)},
+			log:  func(l log.Log) { logOutside(l, here()) },
+		},
+		{
+			name: "explicit caller",
+			opts: []log.Option{log.WithCaller(0)},
+			log:  func(l log.Log) { l.Info("test", log.CallerKey, "elsewhere.go:1", "want", "elsewhere.go:1") },
+		},
+		{
+			name: "without caller",
+			log: func(l log.Log) {
+				pc, _ := herePC()
+				l.Info("test", log.CallerPC(pc))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l := log.NewLogger(log.LevelDebug, append([]log.Option{log.WithSink(kitlog.NewJSONLogger(&buf))}, tt.opts...)...)
+			tt.log(l)
+
+			var entry map[string]interface{}
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if err := json.Unmarshal([]byte(lines[len(lines)-1]), &entry); err != nil {
+				t.Fatal(err)
+			}
+			caller, ok := entry[log.CallerKey]
+			if want, wanted := entry["want"]; !wanted {
+				if ok {
+					t.Errorf("caller = %v, want none", caller)
+				}
+			} else if caller != want {
+				t.Errorf("caller = %v, want %v", caller, want)
+			}
+			for key := range entry {
+				switch key {
+				case log.MessageKey, "severity", log.LoggerKey, log.CallerKey, "a", "want":
+				default:
+					t.Errorf("unexpected field %s in %v", key, entry)
+				}
+			}
+		})
+	}
+}
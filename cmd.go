@@ -0,0 +1,72 @@
+package log
+
+import (
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-godin/log/level"
+)
+
+// CmdKey is the key of the command field of captured output.
+const CmdKey = "cmd"
+
+// CaptureCmd logs the output of cmd line by line, stdout at the level
+// stdout, stderr at the level stderr. Each line is the message of an entry
+// with the cmd field set to the base name of the command and the stream
+// field to "stdout" or "stderr". Unknown levels fall back to info. It must
+// be called before the command is started; the returned function logs the
+// final lines not terminated by a newline and must be called after Wait:
+//
+//	cmd := exec.Command("pg_dump", "app")
+//	flush := logger.CaptureCmd(cmd, log.LevelInfo, log.LevelWarning)
+//	err := cmd.Run()
+//	flush()
+func (l Log) CaptureCmd(cmd *exec.Cmd, stdout, stderr Level) (flush func()) {
+	l = l.With(CmdKey, filepath.Base(cmd.Path))
+	out := &lineWriter{logger: l.With("stream", "stdout"), level: levelValue(stdout)}
+	errOut := &lineWriter{logger: l.With("stream", "stderr"), level: levelValue(stderr)}
+	cmd.Stdout = out
+	cmd.Stderr = errOut
+	return func() {
+		out.flush()
+		errOut.flush()
+	}
+}
+
+// lineWriter logs every line written to it, buffering incomplete ones.
+type lineWriter struct {
+	mtx    sync.Mutex
+	logger Log
+	level  level.Value
+	buf    []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.log(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) flush() {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if len(w.buf) > 0 {
+		w.log(w.buf)
+		w.buf = nil
+	}
+}
+
+func (w *lineWriter) log(line []byte) {
+	w.logger.At(w.level, string(bytes.TrimRight(line, "\r")))
+}
@@ -0,0 +1,124 @@
+// Package coerce normalizes the types of selected fields before entries reach
+// the sink. Services logging the same key once as string and once as number
+// cause mapping conflicts in backends like Elasticsearch; coercing the values
+// of such keys keeps their type consistent.
+package coerce
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-kit/kit/log"
+)
+
+type kind int
+
+const (
+	kindNumber kind = iota + 1
+	kindBool
+	kindString
+)
+
+type coercer struct {
+	next  log.Logger
+	rules map[string]kind
+}
+
+// Option sets a parameter for the coercer.
+type Option func(*coercer)
+
+// Numbers converts numeric string values of the given keys to int64 or
+// float64. Values which aren't numeric are passed unmodified.
+func Numbers(keys ...string) Option {
+	return rule(kindNumber, keys)
+}
+
+// Booleans converts the string values "true" and "false" (case insensitive)
+// of the given keys to booleans. Other values are passed unmodified.
+func Booleans(keys ...string) Option {
+	return rule(kindBool, keys)
+}
+
+// Strings converts all values of the given keys to their string representation.
+func Strings(keys ...string) Option {
+	return rule(kindString, keys)
+}
+
+func rule(k kind, keys []string) Option {
+	return func(c *coercer) {
+		for _, key := range keys {
+			c.rules[key] = k
+		}
+	}
+}
+
+// New wraps next and coerces the values of the configured keys.
+func New(next log.Logger, options ...Option) log.Logger {
+	c := &coercer{
+		next:  next,
+		rules: make(map[string]kind),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+func (c *coercer) Log(keyvals ...interface{}) error {
+	var coerced []interface{}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		k, ok := c.rules[kv.Key(keyvals[i])]
+		if !ok {
+			continue
+		}
+		v, changed := coerce(k, keyvals[i+1])
+		if !changed {
+			continue
+		}
+		if coerced == nil {
+			// copy on first change, the caller's keyvals must not be modified
+			coerced = append([]interface{}(nil), keyvals...)
+		}
+		coerced[i+1] = v
+	}
+	if coerced == nil {
+		return c.next.Log(keyvals...)
+	}
+	return c.next.Log(coerced...)
+}
+
+func coerce(k kind, v interface{}) (interface{}, bool) {
+	if k == kindString {
+		if _, ok := v.(string); ok || v == nil {
+			return v, false
+		}
+		return kv.String(v), true
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return v, false
+	}
+	s = strings.TrimSpace(s)
+
+	switch k {
+	case kindNumber:
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, true
+		}
+		// NaN and infinity can't be represented in JSON
+		if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f, true
+		}
+	case kindBool:
+		switch strings.ToLower(s) {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		}
+	}
+	return v, false
+}
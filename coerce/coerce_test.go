@@ -0,0 +1,54 @@
+package coerce
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type recordingLogger struct {
+	keyvals []interface{}
+}
+
+func (r *recordingLogger) Log(keyvals ...interface{}) error {
+	r.keyvals = keyvals
+	return nil
+}
+
+func TestCoerce(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		value   interface{}
+		want    interface{}
+	}{
+		{"integer", []Option{Numbers("v")}, "42", int64(42)},
+		{"negative integer", []Option{Numbers("v")}, " -7 ", int64(-7)},
+		{"float", []Option{Numbers("v")}, "1.5", 1.5},
+		{"not a number", []Option{Numbers("v")}, "42ms", "42ms"},
+		{"NaN", []Option{Numbers("v")}, "NaN", "NaN"},
+		{"infinity", []Option{Numbers("v")}, "+Inf", "+Inf"},
+		{"number kept", []Option{Numbers("v")}, 3, 3},
+		{"true", []Option{Booleans("v")}, "TRUE", true},
+		{"false", []Option{Booleans("v")}, "false", false},
+		{"not a boolean", []Option{Booleans("v")}, "yes", "yes"},
+		{"number to string", []Option{Strings("v")}, 42, "42"},
+		{"error to string", []Option{Strings("v")}, errors.New("boom"), "boom"},
+		{"nil kept", []Option{Strings("v")}, nil, nil},
+		{"other key", []Option{Numbers("other")}, "42", "42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &recordingLogger{}
+			keyvals := []interface{}{"message", "m", "v", tt.value}
+			_ = New(next, tt.options...).Log(keyvals...)
+
+			if got := next.keyvals[3]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("value = %#v, want %#v", got, tt.want)
+			}
+			if !reflect.DeepEqual(keyvals[3], tt.value) {
+				t.Errorf("the caller's keyvals were modified to %#v", keyvals[3])
+			}
+		})
+	}
+}
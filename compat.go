@@ -0,0 +1,50 @@
+package log
+
+import (
+	"github.com/go-godin/log/level"
+	"github.com/go-kit/kit/log"
+)
+
+// goKitLevels maps the names of the levels to the values emitted by go-kit's level package.
+var goKitLevels = map[string]string{
+	LevelDebug:   "debug",
+	LevelInfo:    "info",
+	LevelWarning: "warn",
+	LevelError:   "error",
+	LevelFatal:   "fatal",
+	LevelPanic:   "panic",
+}
+
+// WithGoKitCompat makes the output follow plain go-kit conventions, i.e.
+// "level":"warn" instead of "severity":"warning" and "msg" instead of
+// "message", so existing dashboards and saved queries keep working.
+// It applies to the sink and all outputs.
+func WithGoKitCompat() Option {
+	return func(o *options) { o.goKitCompat = true }
+}
+
+// goKitCompat translates the level and message keys before handing entries to next.
+type goKitCompat struct {
+	next log.Logger
+}
+
+func (c goKitCompat) Log(keyvals ...interface{}) error {
+	translated := make([]interface{}, len(keyvals))
+	copy(translated, keyvals)
+	for i := 0; i < len(translated)-1; i += 2 {
+		switch {
+		case translated[i] == level.Key():
+			if v, ok := translated[i+1].(level.Value); ok {
+				translated[i] = "level"
+				if name, ok := goKitLevels[v.String()]; ok {
+					translated[i+1] = name
+				} else {
+					translated[i+1] = v.String()
+				}
+			}
+		case translated[i] == MessageKey:
+			translated[i] = "msg"
+		}
+	}
+	return c.next.Log(translated...)
+}
@@ -0,0 +1,39 @@
+package log
+
+// ConfigChangedMessage is the message of the entry emitted on every change of
+// the logger configuration at runtime.
+const ConfigChangedMessage = "logger.config_changed"
+
+// Sources of configuration changes.
+const (
+	SourceAPI    = "api"
+	SourceHTTP   = "http"
+	SourceSignal = "signal"
+	SourceFile   = "file"
+	SourceRemote = "remote"
+)
+
+// ConfigChange describes a change of the logger configuration, e.g. a new
+// level or an added sink.
+type ConfigChange struct {
+	// Setting names what changed, e.g. "level" or "sink".
+	Setting string
+	// Before and After hold the previous and the new value.
+	Before interface{}
+	After  interface{}
+	// Source tells what triggered the change, e.g. SourceHTTP.
+	Source string
+}
+
+// ConfigChanged emits a standardized entry for the change, so changes of the
+// observability setup can be audited. The entry carries no level and thus
+// passes any level filter.
+func (l Log) ConfigChanged(change ConfigChange) {
+	_ = l.kitLogger.Log(
+		MessageKey, ConfigChangedMessage,
+		"setting", change.Setting,
+		"before", change.Before,
+		"after", change.After,
+		"source", change.Source,
+	)
+}
@@ -0,0 +1,340 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-kit/kit/log"
+	"gopkg.in/yaml.v3"
+)
+
+// FileConfig is the logger configuration kept in a YAML or JSON file:
+//
+//	level: info,storage=debug
+//	format: logfmt
+//	sinks:
+//	  - type: stdout
+//	  - type: file
+//	    path: /var/log/service/errors.log
+//	    levels: [error, fatal]
+type FileConfig struct {
+	// Level is the level specification as passed to NewLogger.
+	Level string `json:"level" yaml:"level"`
+	// Format is the encoding of the stdout, stderr and file sinks, "json"
+	// (default) or "logfmt".
+	Format string `json:"format,omitempty" yaml:"format,omitempty"`
+	// Sinks defaults to stdout.
+	Sinks []SinkConfig `json:"sinks,omitempty" yaml:"sinks,omitempty"`
+}
+
+// SinkConfig configures a sink of a FileConfig.
+type SinkConfig struct {
+	// Type is "stdout", "stderr", "file" or a type added with RegisterSinkType.
+	Type string `json:"type" yaml:"type"`
+	// Path is the file the "file" sink appends to.
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// Levels restricts the sink to entries of these levels, as WithLevelSink
+	// does, and accepts the aliases of ParseLevel. Sinks without levels
+	// receive the entries of all other levels.
+	Levels []string `json:"levels,omitempty" yaml:"levels,omitempty"`
+	// Options holds the parameters of registered sink types.
+	Options map[string]string `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// SinkFactory creates a sink from its configuration. The sink is closed
+// when it's replaced by a reload, if it implements io.Closer.
+type SinkFactory func(config SinkConfig) (log.Logger, error)
+
+var (
+	sinkTypesMtx sync.RWMutex
+	sinkTypes    = map[string]SinkFactory{}
+)
+
+// RegisterSinkType makes sinks of the given type available to FileConfig,
+// e.g. RegisterSinkType("gelf", ...) for a sink shipping to Graylog.
+func RegisterSinkType(name string, factory SinkFactory) {
+	sinkTypesMtx.Lock()
+	defer sinkTypesMtx.Unlock()
+	sinkTypes[name] = factory
+}
+
+// LoadConfig reads a FileConfig from path. Files ending with .json are
+// decoded as JSON, all others as YAML.
+func LoadConfig(path string) (FileConfig, error) {
+	var cfg FileConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &cfg)
+	} else {
+		err = yaml.Unmarshal(data, &cfg)
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("decoding %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// NewLoggerFromFile creates a new Log configured by the file at path. The
+// sinks of the file replace the sink set by WithSink and WithLevelSink, all
+// other options apply. Use WatchConfig to apply changes of the file live.
+func NewLoggerFromFile(path string, opts ...Option) (Log, error) {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return Log{}, err
+	}
+
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	sink, sinks, closers, err := buildSinks(cfg, o.goKitCompat)
+	if err != nil {
+		return Log{}, err
+	}
+
+	// the sinks are already wrapped for go-kit compatibility and bound fields
+	l := NewLogger(cfg.Level, append(opts, WithSink(sink), func(o *options) {
+		o.outputs = nil
+		o.goKitCompat = false
+		o.fileSinks = true
+	})...)
+	l.levels.sinks, l.levels.closers = sinks, closers
+	l.levels.goKitCompat = o.goKitCompat
+	return l, nil
+}
+
+// WatchConfig watches the file at path and applies changes of the level and
+// the sinks live, e.g. when Kubernetes updates a mounted ConfigMap. Every
+// change is emitted with ConfigChanged, a file which can't be loaded is
+// reported as Error and otherwise ignored. Changes are applied until stop is
+// called.
+func (l Log) WatchConfig(path string) (stop func() error, err error) {
+	path = filepath.Clean(path)
+	// resolved along with loading the file, so a swap right afterwards is noticed
+	realPath, _ := filepath.EvalSymlinks(path)
+	current, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// the directory is watched, since editors and Kubernetes replace the file
+	// instead of writing it
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		_ = watcher.Close()
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// ConfigMaps are updated by swapping a symlink, which changes the real path
+				newRealPath, _ := filepath.EvalSymlinks(path)
+				if filepath.Clean(event.Name) != path && newRealPath == realPath {
+					continue
+				}
+				if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && newRealPath == realPath {
+					continue
+				}
+				realPath = newRealPath
+				current = l.reloadConfig(path, current)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				l.Error("watching log config failed", "path", path, "err", err)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() error {
+		var err error
+		once.Do(func() {
+			err = watcher.Close()
+			<-done
+		})
+		return err
+	}, nil
+}
+
+// reloadConfig applies the changes of the file compared to current, and
+// returns the applied configuration.
+func (l Log) reloadConfig(path string, current FileConfig) FileConfig {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		// the file may be in the middle of being replaced
+		if !errors.Is(err, os.ErrNotExist) {
+			l.Error("reloading log config failed", "path", path, "err", err)
+		}
+		return current
+	}
+
+	if cfg.Format != current.Format || !reflect.DeepEqual(cfg.Sinks, current.Sinks) {
+		sink, sinks, closers, err := buildSinks(cfg, l.levels.goKitCompat)
+		if err != nil {
+			l.Error("reloading log config failed", "path", path, "err", err)
+			return current
+		}
+		l.levels.setNext(sink, sinks, closers)
+		l.ConfigChanged(ConfigChange{
+			Setting: "sinks",
+			Before:  sinkTypeNames(current.Sinks),
+			After:   sinkTypeNames(cfg.Sinks),
+			Source:  SourceFile,
+		})
+	}
+
+	if cfg.Level != current.Level {
+		before := l.levels.name()
+		if err := l.levels.setSpec(cfg.Level); err != nil {
+			l.Warning("", "err", err)
+		}
+		l.ConfigChanged(ConfigChange{
+			Setting: "level",
+			Before:  before,
+			After:   l.levels.name(),
+			Source:  SourceFile,
+		})
+	}
+	return cfg
+}
+
+// buildSinks creates the sinks of cfg and combines them into a single one.
+// The sinks are returned unwrapped as well, to flush them.
+func buildSinks(cfg FileConfig, compat bool) (log.Logger, []log.Logger, []io.Closer, error) {
+	var encode func(w io.Writer) log.Logger
+	switch cfg.Format {
+	case "", "json":
+		encode = func(w io.Writer) log.Logger { return newJSONSink(w) }
+	case "logfmt":
+		encode = func(w io.Writer) log.Logger { return log.NewLogfmtLogger(log.NewSyncWriter(w)) }
+	default:
+		return nil, nil, nil, fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+
+	sinks := cfg.Sinks
+	if len(sinks) == 0 {
+		sinks = []SinkConfig{{Type: "stdout"}}
+	}
+
+	var (
+		built    []log.Logger
+		closers  []io.Closer
+		fallback fanout
+		outputs  = make(map[string]fanout)
+	)
+	closeAll := func() {
+		for _, c := range closers {
+			_ = c.Close()
+		}
+	}
+	for _, sc := range sinks {
+		var sink log.Logger
+		switch sc.Type {
+		case "stdout":
+			sink = encode(os.Stdout)
+		case "stderr":
+			sink = encode(os.Stderr)
+		case "file":
+			f, err := os.OpenFile(sc.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+			if err != nil {
+				closeAll()
+				return nil, nil, nil, err
+			}
+			closers = append(closers, f)
+			sink = encode(f)
+		default:
+			sinkTypesMtx.RLock()
+			factory, ok := sinkTypes[sc.Type]
+			sinkTypesMtx.RUnlock()
+			if !ok {
+				closeAll()
+				return nil, nil, nil, fmt.Errorf("unknown sink type %q", sc.Type)
+			}
+			var err error
+			if sink, err = factory(sc); err != nil {
+				closeAll()
+				return nil, nil, nil, fmt.Errorf("creating %s sink: %v", sc.Type, err)
+			}
+			if c, ok := sink.(io.Closer); ok {
+				closers = append(closers, c)
+			}
+		}
+
+		built = append(built, sink)
+		sink = bindable(sink)
+		if compat {
+			sink = goKitCompat{next: sink}
+		}
+		if len(sc.Levels) == 0 {
+			fallback = append(fallback, sink)
+		}
+		for _, name := range sc.Levels {
+			lvl, err := ParseLevel(name)
+			if err != nil {
+				closeAll()
+				return nil, nil, nil, fmt.Errorf("%s sink: %v", sc.Type, err)
+			}
+			outputs[string(lvl)] = append(outputs[string(lvl)], sink)
+		}
+	}
+
+	var sink log.Logger = fallback
+	if len(fallback) == 1 {
+		sink = fallback[0]
+	}
+	if len(outputs) == 0 {
+		return sink, built, closers, nil
+	}
+	out := levelOutput{sink: sink, outputs: make(map[string]log.Logger, len(outputs))}
+	for lvl, sinks := range outputs {
+		out.outputs[lvl] = sinks
+		if len(sinks) == 1 {
+			out.outputs[lvl] = sinks[0]
+		}
+	}
+	return out, built, closers, nil
+}
+
+// sinkTypeNames lists the types of sinks, e.g. "stdout,file".
+func sinkTypeNames(sinks []SinkConfig) string {
+	names := make([]string, 0, len(sinks))
+	for _, s := range sinks {
+		names = append(names, s.Type)
+	}
+	return strings.Join(names, ",")
+}
+
+// fanout hands entries to all its sinks.
+type fanout []log.Logger
+
+func (f fanout) Log(keyvals ...interface{}) error {
+	var err error
+	for _, sink := range f {
+		if sinkErr := sink.Log(keyvals...); sinkErr != nil && err == nil {
+			err = sinkErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package log
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-godin/log/correlation"
+)
+
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx carrying logger, e.g. a request scoped
+// Log with fields added by With, so it doesn't need to be passed down the
+// call stack.
+func NewContext(ctx context.Context, logger Log) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the Log stored with NewContext, or the package level
+// Log if ctx carries none.
+func FromContext(ctx context.Context) Log {
+	if l, ok := ctx.Value(loggerKey{}).(Log); ok {
+		return l
+	}
+	return std
+}
+
+// ContextExtractor returns keyvals pulled out of a context, e.g. the user or
+// tenant of a request.
+type ContextExtractor func(ctx context.Context) []interface{}
+
+var (
+	extractorsMtx sync.RWMutex
+	// the correlation ID is always added
+	extractors = []ContextExtractor{correlation.Fields}
+)
+
+// RegisterContextExtractor adds an extractor run by WithContext and
+// WithTrace, whose keyvals are added to all entries of the returned Log:
+//
+//	log.RegisterContextExtractor(func(ctx context.Context) []interface{} {
+//		if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+//			return []interface{}{"tenant", tenant}
+//		}
+//		return nil
+//	})
+func RegisterContextExtractor(extractor ContextExtractor) {
+	extractorsMtx.Lock()
+	defer extractorsMtx.Unlock()
+	extractors = append(extractors, extractor)
+}
+
+// WithContext adds the keyvals of the registered context extractors for ctx
+// to all entries of the returned Log.
+func (l Log) WithContext(ctx context.Context) Log {
+	return l.With(contextFields(ctx)...)
+}
+
+// contextFields runs the registered context extractors.
+func contextFields(ctx context.Context) []interface{} {
+	extractorsMtx.RLock()
+	defer extractorsMtx.RUnlock()
+	var keyvals []interface{}
+	for _, extract := range extractors {
+		keyvals = append(keyvals, extract(ctx)...)
+	}
+	return keyvals
+}
+
+// DebugCtx logs a debug entry bound to the span and the fields of ctx, see
+// WithTrace.
+func (l Log) DebugCtx(ctx context.Context, message string, keyvals ...interface{}) {
+	l.WithTrace(ctx).Debug(message, keyvals...)
+}
+
+// InfoCtx logs an info entry bound to the span and the fields of ctx, see
+// WithTrace.
+func (l Log) InfoCtx(ctx context.Context, message string, keyvals ...interface{}) {
+	l.WithTrace(ctx).Info(message, keyvals...)
+}
+
+// WarningCtx logs a warning entry bound to the span and the fields of ctx,
+// see WithTrace.
+func (l Log) WarningCtx(ctx context.Context, message string, keyvals ...interface{}) {
+	l.WithTrace(ctx).Warning(message, keyvals...)
+}
+
+// ErrorCtx logs an error entry bound to the span and the fields of ctx, see
+// WithTrace.
+func (l Log) ErrorCtx(ctx context.Context, message string, keyvals ...interface{}) {
+	l.WithTrace(ctx).Error(message, keyvals...)
+}
@@ -0,0 +1,100 @@
+// Package correlation provides the correlation ID tying together the log
+// entries of a request across services. The Middleware takes the ID from the
+// incoming request or generates one, stores it in the request context and
+// echoes it in the response. Logs created with WithContext or WithTrace of
+// the godin logger add it to every entry.
+package correlation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+const (
+	// Key is the key of the correlation ID field.
+	Key = "correlation_id"
+	// Header is the default header carrying the correlation ID.
+	Header = "X-Correlation-ID"
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the correlation ID.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the correlation ID of ctx, if any.
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextKey{}).(string)
+	return id, ok && id != ""
+}
+
+// Fields returns the correlation ID field of ctx, if any. It matches the
+// signature of the godin logger's context extractors.
+func Fields(ctx context.Context) []interface{} {
+	if id, ok := FromContext(ctx); ok {
+		return []interface{}{Key, id}
+	}
+	return nil
+}
+
+// Generate returns a new random correlation ID of 32 hex digits.
+func Generate() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+type options struct {
+	headers  []string
+	generate func() string
+}
+
+// Option sets a parameter for the Middleware.
+type Option func(*options)
+
+// Headers sets the request headers the correlation ID is taken from, in
+// order of preference, e.g. "X-Request-ID". The first one is echoed in the
+// response. Defaults to Header.
+func Headers(names ...string) Option {
+	return func(o *options) { o.headers = names }
+}
+
+// Generator replaces Generate to create the IDs of requests without one.
+func Generator(generate func() string) Option {
+	return func(o *options) { o.generate = generate }
+}
+
+// Middleware stores the correlation ID of incoming requests in their
+// context, generating one if the request carries none, and sets it as
+// response header.
+func Middleware(opts ...Option) func(http.Handler) http.Handler {
+	o := options{
+		headers:  []string{Header},
+		generate: Generate,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var id string
+			for _, name := range o.headers {
+				if id = r.Header.Get(name); id != "" {
+					break
+				}
+			}
+			if id == "" {
+				id = o.generate()
+			}
+			if len(o.headers) > 0 {
+				w.Header().Set(o.headers[0], id)
+			}
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+		})
+	}
+}
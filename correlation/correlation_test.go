@@ -0,0 +1,97 @@
+package correlation_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-godin/log"
+	"github.com/go-godin/log/correlation"
+	kitlog "github.com/go-kit/kit/log"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []correlation.Option
+		header     http.Header
+		want       string // empty for a generated ID
+		wantHeader string
+	}{
+		{name: "from header", header: http.Header{correlation.Header: {"c1"}}, want: "c1", wantHeader: correlation.Header},
+		{name: "generated", wantHeader: correlation.Header},
+		{name: "custom generator", opts: []correlation.Option{correlation.Generator(func() string { return "g1" })}, want: "g1", wantHeader: correlation.Header},
+		{
+			name:       "preferred header",
+			opts:       []correlation.Option{correlation.Headers("X-Request-ID", correlation.Header)},
+			header:     http.Header{correlation.Header: {"c1"}, "X-Request-Id": {"r1"}},
+			want:       "r1",
+			wantHeader: "X-Request-ID",
+		},
+		{
+			name:       "fallback header",
+			opts:       []correlation.Option{correlation.Headers("X-Request-ID", correlation.Header)},
+			header:     http.Header{correlation.Header: {"c1"}},
+			want:       "c1",
+			wantHeader: "X-Request-ID",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var id string
+			h := correlation.Middleware(tt.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				id, _ = correlation.FromContext(r.Context())
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for name, values := range tt.header {
+				r.Header.Set(name, values[0])
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if id == "" || (tt.want != "" && id != tt.want) {
+				t.Errorf("ID = %q, want %q", id, tt.want)
+			}
+			if echoed := w.Header().Get(tt.wantHeader); echoed != id {
+				t.Errorf("%s = %q, want %q", tt.wantHeader, echoed, id)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	a, b := correlation.Generate(), correlation.Generate()
+	if len(a) != 32 || a == b {
+		t.Errorf("Generate() = %q, %q, want distinct IDs of 32 hex digits", a, b)
+	}
+}
+
+func TestFields(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want interface{}
+	}{
+		{"with ID", correlation.NewContext(context.Background(), "c1"), "c1"},
+		{"empty ID", correlation.NewContext(context.Background(), ""), nil},
+		{"without ID", context.Background(), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := log.NewLogger(log.LevelInfo, log.WithSink(kitlog.NewJSONLogger(&buf)))
+			logger.WithContext(tt.ctx).Info("m")
+
+			var entry map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("decoding %q: %v", buf.String(), err)
+			}
+			if got := entry[correlation.Key]; got != tt.want {
+				t.Errorf("%s = %v, want %v", correlation.Key, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package log
+
+import "strconv"
+
+const (
+	DatadogTraceIDKey = "dd.trace_id"
+	DatadogSpanIDKey  = "dd.span_id"
+)
+
+// WithDatadogTraceFields additionally adds the trace and span ID of Logs
+// bound to a span by WithTrace as dd.trace_id and dd.span_id, in the decimal
+// format Datadog uses to correlate logs with traces.
+func WithDatadogTraceFields() Option {
+	return func(o *options) { o.datadogFields = true }
+}
+
+// datadogFields converts hex trace and span IDs to the Datadog fields.
+// Datadog uses the lower 64 bits of 128 bit trace IDs.
+func datadogFields(traceID, spanID string) []interface{} {
+	if len(traceID) > 16 {
+		traceID = traceID[len(traceID)-16:]
+	}
+	var keyvals []interface{}
+	if id, err := strconv.ParseUint(traceID, 16, 64); err == nil {
+		keyvals = append(keyvals, DatadogTraceIDKey, strconv.FormatUint(id, 10))
+	}
+	if id, err := strconv.ParseUint(spanID, 16, 64); err == nil {
+		keyvals = append(keyvals, DatadogSpanIDKey, strconv.FormatUint(id, 10))
+	}
+	return keyvals
+}
@@ -0,0 +1,158 @@
+package log
+
+import (
+	"sync"
+
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/level"
+	"github.com/go-kit/kit/log"
+)
+
+// BufferedKey is the key of the field marking entries which were held back
+// by WithDebugBuffer and written when an error occurred.
+const BufferedKey = "buffered"
+
+// maxBufferGroups bounds the amount of groups WithDebugBuffer keeps
+// entries for, the oldest group is dropped first.
+const maxBufferGroups = 1000
+
+// WithDebugBuffer keeps the most recent size entries which don't pass the
+// level filter, e.g. Debug entries of a Log at info, in memory. Once an
+// entry at error level or above is logged, the kept entries are written
+// first, marked with BufferedKey, so the context leading up to the failure
+// is preserved without always logging at debug level.
+//
+// With a groupKey like "request_id" entries are kept per value of that
+// field and only the entries of the failing request are written; entries
+// without the field are kept together. An empty groupKey keeps all entries
+// together.
+//
+// The entries of a group are kept until an error is logged for it, so the
+// groups of requests which succeed stay in memory until they're evicted:
+// at most 1000 groups are kept, the oldest is dropped first, which bounds
+// the buffer to 1000 × size entries. Choose size accordingly.
+func WithDebugBuffer(size int, groupKey string) Option {
+	return func(o *options) {
+		o.debugBuffer = size
+		o.debugBufferKey = groupKey
+	}
+}
+
+// debugBuffer holds back entries below the level until an error occurs.
+type debugBuffer struct {
+	next     log.Logger
+	levels   *AtomicLevel
+	size     int
+	groupKey string
+
+	mtx    sync.Mutex
+	groups map[string]*entryRing
+	order  []string
+}
+
+func newDebugBuffer(next log.Logger, levels *AtomicLevel, size int, groupKey string) *debugBuffer {
+	return &debugBuffer{
+		next:     next,
+		levels:   levels,
+		size:     size,
+		groupKey: groupKey,
+		groups:   make(map[string]*entryRing),
+	}
+}
+
+func (b *debugBuffer) Log(keyvals ...interface{}) error {
+	lvl, ok := level.FromKeyvals(keyvals)
+	if !ok {
+		return b.next.Log(keyvals...)
+	}
+
+	group := b.group(keyvals)
+	if lvl.Severity() >= level.ErrorValue().Severity() {
+		b.flush(group)
+		return b.next.Log(keyvals...)
+	}
+	if b.levels.passes(lvl, loggerName(keyvals)) {
+		return b.next.Log(keyvals...)
+	}
+
+	entry := make([]interface{}, len(keyvals), len(keyvals)+2)
+	copy(entry, keyvals)
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	ring, ok := b.groups[group]
+	if !ok {
+		if len(b.order) >= maxBufferGroups {
+			delete(b.groups, b.order[0])
+			b.order = b.order[1:]
+		}
+		ring = &entryRing{entries: make([][]interface{}, 0, b.size)}
+		b.groups[group] = ring
+		b.order = append(b.order, group)
+	}
+	ring.add(entry)
+	return nil
+}
+
+// flush writes the entries kept for the group to the sink, bypassing the
+// level filter but resolving Lazy values.
+func (b *debugBuffer) flush(group string) {
+	b.mtx.Lock()
+	ring, ok := b.groups[group]
+	if ok {
+		delete(b.groups, group)
+		for i, g := range b.order {
+			if g == group {
+				b.order = append(b.order[:i], b.order[i+1:]...)
+				break
+			}
+		}
+	}
+	b.mtx.Unlock()
+	if !ok {
+		return
+	}
+
+	sink := b.levels.sink()
+	for _, entry := range ring.ordered() {
+		_ = sink.Log(append(entry, BufferedKey, true)...)
+	}
+}
+
+func (b *debugBuffer) group(keyvals []interface{}) string {
+	if b.groupKey == "" {
+		return ""
+	}
+	if v, ok := fieldValue(keyvals, b.groupKey); ok {
+		return kv.String(v)
+	}
+	return ""
+}
+
+// loggerName returns the name of the Named logger an entry was logged with.
+func loggerName(keyvals []interface{}) string {
+	if v, ok := fieldValue(keyvals, LoggerKey); ok {
+		return kv.String(v)
+	}
+	return ""
+}
+
+// entryRing keeps the most recent entries.
+type entryRing struct {
+	entries [][]interface{}
+	next    int
+}
+
+func (r *entryRing) add(entry []interface{}) {
+	if len(r.entries) < cap(r.entries) {
+		r.entries = append(r.entries, entry)
+		return
+	}
+	r.entries[r.next] = entry
+	r.next = (r.next + 1) % len(r.entries)
+}
+
+// ordered returns the entries, oldest first.
+func (r *entryRing) ordered() [][]interface{} {
+	return append(r.entries[r.next:len(r.entries):len(r.entries)], r.entries[:r.next]...)
+}
@@ -0,0 +1,101 @@
+package log
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestDebugBuffer(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		groupKey string
+		log      func(Log)
+		want     []string // messages written, in order
+	}{
+		{
+			name: "no error",
+			log:  func(l Log) { l.Debug("d1"); l.Info("i1") },
+			want: []string{"i1"},
+		},
+		{
+			name: "error flushes",
+			log:  func(l Log) { l.Debug("d1"); l.Debug("d2"); l.Error("e") },
+			want: []string{"d1", "d2", "e"},
+		},
+		{
+			name: "ring keeps the latest",
+			log:  func(l Log) { l.Debug("d1"); l.Debug("d2"); l.Debug("d3"); l.Debug("d4"); l.Error("e") },
+			want: []string{"d2", "d3", "d4", "e"},
+		},
+		{
+			name:     "only the failing group",
+			groupKey: "request_id",
+			log: func(l Log) {
+				l.Debug("a", "request_id", "1")
+				l.Debug("b", "request_id", "2")
+				l.Error("e", "request_id", "2")
+			},
+			want: []string{"b", "e"},
+		},
+		{
+			name:     "entries without group",
+			groupKey: "request_id",
+			log: func(l Log) {
+				l.Debug("a")
+				l.Debug("b", "request_id", "1")
+				l.Error("e")
+			},
+			want: []string{"a", "e"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, out := newBufferLogger(LevelInfo, WithDebugBuffer(3, tt.groupKey))
+			tt.log(logger)
+
+			entries := out.entries(t)
+			if len(entries) != len(tt.want) {
+				t.Fatalf("wrote %d entries, want %d: %v", len(entries), len(tt.want), entries)
+			}
+			for i, entry := range entries {
+				if entry[MessageKey] != tt.want[i] {
+					t.Errorf("entry %d has message %v, want %s", i, entry[MessageKey], tt.want[i])
+				}
+				if _, buffered := entry[BufferedKey]; buffered != (entry["severity"] == LevelDebug) {
+					t.Errorf("entry %d: BufferedKey set = %v", i, buffered)
+				}
+			}
+		})
+	}
+}
+
+func TestDebugBufferResolvesLazy(t *testing.T) {
+	logger, out := newBufferLogger(LevelInfo, WithDebugBuffer(3, ""))
+	calls := 0
+	logger.Debug("d", "n", Lazy(func() interface{} { calls++; return 42 }))
+	if calls != 0 {
+		t.Fatalf("lazy value computed %d times before the flush", calls)
+	}
+	logger.Error("e")
+
+	entries := out.entries(t)
+	if len(entries) != 2 {
+		t.Fatalf("wrote %d entries, want 2", len(entries))
+	}
+	if n := entries[0]["n"]; n != float64(42) {
+		t.Errorf("n = %#v, want the resolved 42", n)
+	}
+}
+
+func TestDebugBufferEvictsGroups(t *testing.T) {
+	logger, out := newBufferLogger(LevelInfo, WithDebugBuffer(1, "request_id"))
+	for i := 0; i <= maxBufferGroups; i++ {
+		logger.Debug("d", "request_id", strconv.Itoa(i))
+	}
+	logger.Error("e", "request_id", "0")
+	logger.Error("e", "request_id", "1")
+
+	// the oldest group was evicted, the second is still kept
+	if n := len(out.entries(t)); n != 3 {
+		t.Errorf("wrote %d entries, want 3", n)
+	}
+}
@@ -0,0 +1,47 @@
+package log
+
+import (
+	"fmt"
+	"time"
+)
+
+// EnableDebugFor raises the level of the Log and all Logs derived from it to
+// debug for the duration d and reverts it afterwards, so incident responders
+// can't forget to turn the verbosity back down. Calling it again extends the
+// window, changing the level otherwise ends it. Both changes are emitted with
+// ConfigChanged.
+func (l Log) EnableDebugFor(d time.Duration) {
+	_ = l.changeLevelFor(LevelDebug, d, SourceAPI)
+}
+
+// changeLevelFor replaces the default level for the duration d and emits the
+// change and the revert.
+func (l Log) changeLevelFor(name string, d time.Duration, source string) error {
+	if d <= 0 {
+		return fmt.Errorf("invalid duration %v", d)
+	}
+	lvl, err := ParseLevel(name)
+	if err != nil {
+		return err
+	}
+
+	before := l.levels.name()
+	err = l.levels.setFor(string(lvl), d, func(previous string) {
+		l.ConfigChanged(ConfigChange{
+			Setting: "level",
+			Before:  string(lvl),
+			After:   previous,
+			Source:  source,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	l.ConfigChanged(ConfigChange{
+		Setting: "level",
+		Before:  before,
+		After:   string(lvl),
+		Source:  source,
+	})
+	return nil
+}
@@ -0,0 +1,147 @@
+// Package dedup collapses consecutive identical entries into one line
+// carrying the amount of repetitions, like syslog's "last message repeated
+// N times".
+package dedup
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-kit/kit/log"
+)
+
+// RepeatedKey is the key of the field carrying the amount of repetitions.
+const RepeatedKey = "repeated"
+
+// Collapser is a go-kit logger writing the first of consecutive identical
+// entries right away and holding back its repetitions during the window.
+// Once a different entry arrives, the window ends or Flush is called, the
+// last repetition is written with the repeated field set to the amount of
+// held back entries.
+type Collapser struct {
+	next   log.Logger
+	window time.Duration
+	ignore map[string]bool
+
+	mtx       sync.Mutex
+	signature string
+	last      []interface{}
+	repeated  int
+	timer     *time.Timer
+	// windows counts the timers started, so expire can tell stale ones
+	windows uint64
+}
+
+// Option sets a parameter for the Collapser.
+type Option func(*Collapser)
+
+// Window sets how long repetitions are held back. Defaults to 10 seconds.
+func Window(window time.Duration) Option {
+	return func(c *Collapser) { c.window = window }
+}
+
+// Ignore sets the keys not compared to tell whether entries are identical.
+// Defaults to "ts", "time" and "timestamp".
+func Ignore(keys ...string) Option {
+	return func(c *Collapser) {
+		c.ignore = make(map[string]bool, len(keys))
+		for _, key := range keys {
+			c.ignore[key] = true
+		}
+	}
+}
+
+// New wraps next and collapses the entries passed to it:
+//
+//	logger := log.NewLogger("info", log.WithSink(dedup.New(sink, dedup.Window(time.Minute))))
+func New(next log.Logger, options ...Option) *Collapser {
+	c := &Collapser{
+		next:   next,
+		window: 10 * time.Second,
+		ignore: map[string]bool{"ts": true, "time": true, "timestamp": true},
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+func (c *Collapser) Log(keyvals ...interface{}) error {
+	signature := c.signatureOf(keyvals)
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.last != nil && signature == c.signature {
+		// the caller may reuse the slice once Log returns
+		c.last = append(c.last[:0], keyvals...)
+		c.repeated++
+		if c.timer == nil {
+			c.windows++
+			window := c.windows
+			c.timer = time.AfterFunc(c.window, func() { c.expire(window) })
+		}
+		return nil
+	}
+
+	err := c.flush()
+	c.signature = signature
+	c.last = append([]interface{}(nil), keyvals...)
+	if nextErr := c.next.Log(keyvals...); err == nil {
+		err = nextErr
+	}
+	return err
+}
+
+// Flush writes the held back repetition, if any.
+func (c *Collapser) Flush() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.flush()
+}
+
+// expire ends the given window. Timers firing after their window was ended
+// by flush are ignored, since their Stop lost the race and the repetitions
+// held back belong to the next window.
+func (c *Collapser) expire(window uint64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.timer == nil || c.windows != window {
+		return
+	}
+	_ = c.flush()
+	// the following repetitions start a new entry
+	c.last = nil
+}
+
+func (c *Collapser) flush() error {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if c.repeated == 0 {
+		return nil
+	}
+	repeated := c.repeated
+	c.repeated = 0
+	return c.next.Log(append(c.last, RepeatedKey, repeated)...)
+}
+
+// signatureOf returns the string representation of the compared fields.
+func (c *Collapser) signatureOf(keyvals []interface{}) string {
+	var b strings.Builder
+	for i := 0; i < len(keyvals); i += 2 {
+		key := kv.Key(keyvals[i])
+		if c.ignore[key] {
+			continue
+		}
+		b.WriteString(key)
+		b.WriteByte('=')
+		if i+1 < len(keyvals) {
+			b.WriteString(kv.String(keyvals[i+1]))
+		}
+		b.WriteByte(0)
+	}
+	return b.String()
+}
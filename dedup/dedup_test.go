@@ -0,0 +1,126 @@
+package dedup
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-godin/log/internal/kv"
+)
+
+// recordingLogger records the message and repeated field of every entry.
+type recordingLogger struct {
+	mtx     sync.Mutex
+	entries []string
+}
+
+func (r *recordingLogger) Log(keyvals ...interface{}) error {
+	m := kv.Map(keyvals)
+	entry := fmt.Sprint(m["message"])
+	if n, ok := m[RepeatedKey]; ok {
+		entry += fmt.Sprintf(" x%v", n)
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *recordingLogger) logged() []string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return append([]string(nil), r.entries...)
+}
+
+func TestCollapser(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		entries [][]interface{}
+		want    []string
+	}{
+		{
+			name:    "distinct",
+			entries: [][]interface{}{{"message", "a"}, {"message", "b"}},
+			want:    []string{"a", "b"},
+		},
+		{
+			name:    "repetitions",
+			entries: [][]interface{}{{"message", "a"}, {"message", "a"}, {"message", "a"}, {"message", "b"}},
+			want:    []string{"a", "a x2", "b"},
+		},
+		{
+			name:    "timestamps ignored",
+			entries: [][]interface{}{{"ts", 1, "message", "a"}, {"ts", 2, "message", "a"}},
+			want:    []string{"a", "a x1"},
+		},
+		{
+			name:    "fields compared",
+			entries: [][]interface{}{{"message", "a", "n", 1}, {"message", "a", "n", 2}},
+			want:    []string{"a", "a"},
+		},
+		{
+			name:    "custom ignored keys",
+			options: []Option{Ignore("n")},
+			entries: [][]interface{}{{"message", "a", "n", 1}, {"message", "a", "n", 2}},
+			want:    []string{"a", "a x1"},
+		},
+		{
+			name:    "not consecutive",
+			entries: [][]interface{}{{"message", "a"}, {"message", "b"}, {"message", "a"}},
+			want:    []string{"a", "b", "a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &recordingLogger{}
+			c := New(next, tt.options...)
+			for _, entry := range tt.entries {
+				_ = c.Log(entry...)
+			}
+			_ = c.Flush()
+			if got := next.logged(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("logged %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollapserWindow(t *testing.T) {
+	next := &recordingLogger{}
+	c := New(next, Window(10*time.Millisecond))
+	_ = c.Log("message", "a")
+	_ = c.Log("message", "a")
+
+	deadline := time.Now().Add(time.Second)
+	for len(next.logged()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	// the window ended, the next repetition is written right away
+	_ = c.Log("message", "a")
+	if got, want := next.logged(), []string{"a", "a x1", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("logged %q, want %q", got, want)
+	}
+}
+
+func TestCollapserStaleTimer(t *testing.T) {
+	next := &recordingLogger{}
+	c := New(next, Window(time.Hour))
+	_ = c.Log("message", "a")
+	_ = c.Log("message", "a")
+	stale := c.windows
+	_ = c.Flush()
+	_ = c.Log("message", "a")
+
+	// the timer of the flushed window fires although it was stopped
+	c.expire(stale)
+	if got, want := next.logged(), []string{"a", "a x1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("logged %q, want %q", got, want)
+	}
+	_ = c.Flush()
+	if got, want := next.logged(), []string{"a", "a x1", "a x1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("logged %q after Flush, want %q", got, want)
+	}
+}
@@ -0,0 +1,62 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/go-godin/log/level"
+)
+
+// DeprecationsVariable is the environment variable controlling how
+// deprecations are announced: "silent" suppresses them, "error" escalates
+// them to Error entries. By default they're logged as Warning.
+const DeprecationsVariable = "LOG_DEPRECATIONS"
+
+// announcedDeprecations holds the features which have been announced by
+// this process.
+var announcedDeprecations sync.Map
+
+// Deprecated announces that feature is deprecated and will be removed in the
+// given version. Each feature is only announced once per process, no matter
+// how often it's used, as soon as the level of the Log lets the entry pass.
+// The entry carries the caller of Deprecated, i.e. the code still using the
+// feature, as CallerKey field in place of the one added by WithCaller.
+func (l Log) Deprecated(feature, removeInVersion string, keyvals ...interface{}) {
+	mode := strings.ToLower(os.Getenv(DeprecationsVariable))
+	if mode == "silent" {
+		return
+	}
+	lvl := level.WarnValue()
+	if mode == "error" {
+		lvl = level.ErrorValue()
+	}
+	// filtered announcements don't count, so the feature is announced once
+	// the level allows it. drops can't tell while recorders see all entries.
+	if l.levels != nil && !l.levels.accepts(lvl, l.name) {
+		return
+	}
+	if _, announced := announcedDeprecations.LoadOrStore(feature, true); announced {
+		return
+	}
+
+	caller := "unknown"
+	if _, file, line, ok := runtime.Caller(1); ok {
+		caller = shortCaller(file, line)
+	}
+
+	message := fmt.Sprintf("%s is deprecated and will be removed in %s", feature, removeInVersion)
+	keyvals = append([]interface{}{
+		"deprecated_feature", feature,
+		"remove_in", removeInVersion,
+		CallerKey, caller,
+	}, keyvals...)
+
+	if mode == "error" {
+		l.Error(message, keyvals...)
+		return
+	}
+	l.Warning(message, keyvals...)
+}
@@ -0,0 +1,99 @@
+package log
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+// resetDeprecations forgets the features announced so far, so tests can
+// announce them again.
+func resetDeprecations() {
+	announcedDeprecations.Range(func(feature, _ interface{}) bool {
+		announcedDeprecations.Delete(feature)
+		return true
+	})
+}
+
+func TestDeprecated(t *testing.T) {
+	t.Cleanup(resetDeprecations)
+	tests := []struct {
+		name     string
+		mode     string
+		opts     []Option
+		feature  string
+		announce int
+		want     string
+	}{
+		{name: "warning", feature: "warning feature", announce: 1, want: "warning"},
+		{name: "error", mode: "error", feature: "error feature", announce: 1, want: "error"},
+		{name: "silent", mode: "silent", feature: "silent feature", announce: 1},
+		{name: "once", feature: "repeated feature", announce: 3, want: "warning"},
+		{name: "with caller", opts: []Option{WithCaller(0)}, feature: "caller feature", announce: 1, want: "warning"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(DeprecationsVariable, tt.mode)
+			l, out := newBufferLogger(LevelDebug, tt.opts...)
+			var caller string
+			for i := 0; i < tt.announce; i++ {
+				l.Deprecated(tt.feature, "v2.0.0", "hint", "use something else")
+				_, file, line, _ := runtime.Caller(0)
+				caller = shortCaller(file, line-1)
+			}
+
+			entries := out.entries(t)
+			if tt.want == "" {
+				if len(entries) != 0 {
+					t.Fatalf("entries = %v, want none", entries)
+				}
+				return
+			}
+			if len(entries) != 1 {
+				t.Fatalf("entries = %v, want one", entries)
+			}
+			entry := entries[0]
+			if entry["severity"] != tt.want {
+				t.Errorf("severity = %v, want %s", entry["severity"], tt.want)
+			}
+			if entry["deprecated_feature"] != tt.feature || entry["remove_in"] != "v2.0.0" || entry["hint"] != "use something else" {
+				t.Errorf("entry = %v", entry)
+			}
+			if entry[CallerKey] != caller {
+				t.Errorf("caller = %v, want %s", entry[CallerKey], caller)
+			}
+			if n := strings.Count(out.String(), `"`+CallerKey+`"`); n != 1 {
+				t.Errorf("%d caller fields in %s", n, out)
+			}
+		})
+	}
+}
+
+func TestDeprecatedFiltered(t *testing.T) {
+	t.Setenv(DeprecationsVariable, "")
+	for _, tt := range []struct {
+		name string
+		opts []Option
+	}{
+		{name: "filtered"},
+		{name: "with recorder", opts: []Option{WithRecorder(log.NewNopLogger())}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(resetDeprecations)
+			l, out := newBufferLogger(LevelError, tt.opts...)
+			l.Deprecated("filtered feature", "v2.0.0")
+			if entries := out.entries(t); len(entries) != 0 {
+				t.Fatalf("entries = %v, want none", entries)
+			}
+
+			l.SetLevel(LevelWarning)
+			l.Deprecated("filtered feature", "v2.0.0")
+			entries := out.entries(t)
+			if len(entries) != 2 || entries[1]["deprecated_feature"] != "filtered feature" {
+				t.Errorf("entries = %v, want the level change and the deprecation", entries)
+			}
+		})
+	}
+}
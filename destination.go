@@ -0,0 +1,44 @@
+package log
+
+import "github.com/go-godin/log/route"
+
+// Destination is a hint directing a single entry to specific sinks of a
+// router created with route.New.
+type Destination route.Hint
+
+// To returns a destination hint which can be passed anywhere between the
+// keyvals of an entry, e.g.
+//
+//	logger.Info("user logged in", log.To("audit"), "user", id)
+//
+// The entry is then routed to the sinks registered under the given names
+// instead of the default sink.
+func To(destinations ...string) Destination {
+	return Destination(destinations)
+}
+
+// extractDestinations replaces Destination hints in key position by a single
+// route.Key pair at the end of keyvals.
+func extractDestinations(keyvals []interface{}) []interface{} {
+	found := false
+	for _, v := range keyvals {
+		if _, ok := v.(Destination); ok {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return keyvals
+	}
+
+	var hint route.Hint
+	list := make([]interface{}, 0, len(keyvals)+1)
+	for _, v := range keyvals {
+		if d, ok := v.(Destination); ok && len(list)%2 == 0 {
+			hint = append(hint, d...)
+			continue
+		}
+		list = append(list, v)
+	}
+	return append(list, route.Key, hint)
+}
@@ -0,0 +1,42 @@
+// Package echolog provides Echo middleware logging requests with the godin
+// logger, replacing Echo's logger middleware.
+package echolog
+
+import (
+	"time"
+
+	"github.com/go-godin/log"
+	"github.com/go-godin/log/httplog"
+	"github.com/labstack/echo/v4"
+)
+
+// Middleware logs one entry per request like httplog.Middleware, and passes
+// the request scoped Log on in the request context, see log.FromContext.
+// Errors returned by handlers are handed to Echo's error handler first, so
+// the status it responds with is logged. Server errors of Echo itself can be
+// logged through StdLogger:
+//
+//	e := echo.New()
+//	e.StdLogger = logger.StdLogger(log.LevelError)
+//	e.Use(echolog.Middleware(logger))
+func Middleware(logger log.Log, opts ...httplog.Option) echo.MiddlewareFunc {
+	l := httplog.New(logger, opts...)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			begin := time.Now()
+			rl, logged := l.Begin(c.Request())
+			if !logged {
+				return next(c)
+			}
+
+			c.SetRequest(c.Request().WithContext(log.NewContext(c.Request().Context(), rl)))
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			res := c.Response()
+			l.End(rl, c.Request(), res.Status, int(res.Size), begin)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package echolog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-godin/log"
+	"github.com/go-godin/log/httplog"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/labstack/echo/v4"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []httplog.Option
+		path       string
+		handler    echo.HandlerFunc
+		wantStatus float64 // zero if the request isn't logged
+		wantLevel  string
+		wantBytes  float64
+	}{
+		{
+			name:       "ok",
+			path:       "/",
+			handler:    func(c echo.Context) error { return c.String(http.StatusOK, "hello") },
+			wantStatus: 200,
+			wantLevel:  "info",
+			wantBytes:  5,
+		},
+		{
+			name:       "http error",
+			path:       "/",
+			handler:    func(c echo.Context) error { return echo.NewHTTPError(http.StatusNotFound) },
+			wantStatus: 404,
+			wantLevel:  "warning",
+		},
+		{
+			name:       "handler error",
+			path:       "/",
+			handler:    func(c echo.Context) error { return errors.New("boom") },
+			wantStatus: 500,
+			wantLevel:  "error",
+		},
+		{
+			name:    "excluded",
+			opts:    []httplog.Option{httplog.Exclude("/healthz")},
+			path:    "/healthz",
+			handler: func(c echo.Context) error { return c.NoContent(http.StatusOK) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := log.NewLogger(log.LevelDebug, log.WithSink(kitlog.NewJSONLogger(&buf)))
+			e := echo.New()
+			e.Use(Middleware(logger, tt.opts...))
+			e.GET(tt.path, tt.handler)
+			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if tt.wantStatus == 0 {
+				if buf.Len() != 0 {
+					t.Errorf("logged %s, want nothing", buf.String())
+				}
+				return
+			}
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != 1 {
+				t.Fatalf("logged %d entries, want 1: %s", len(lines), buf.String())
+			}
+			var entry map[string]interface{}
+			if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+				t.Fatalf("decoding %q: %v", lines[0], err)
+			}
+			if entry["status"] != tt.wantStatus || entry["severity"] != tt.wantLevel {
+				t.Errorf("status, severity = %v, %v, want %v, %s", entry["status"], entry["severity"], tt.wantStatus, tt.wantLevel)
+			}
+			if tt.wantBytes != 0 && entry["bytes"] != tt.wantBytes {
+				t.Errorf("bytes = %v, want %v", entry["bytes"], tt.wantBytes)
+			}
+		})
+	}
+}
+
+func TestMiddlewareContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewLogger(log.LevelDebug, log.WithSink(kitlog.NewJSONLogger(&buf)))
+	e := echo.New()
+	e.Use(Middleware(logger))
+	e.GET("/", func(c echo.Context) error {
+		log.FromContext(c.Request().Context()).Info("handling")
+		return c.NoContent(http.StatusNoContent)
+	})
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	e.ServeHTTP(httptest.NewRecorder(), r)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("logged %d entries, want 2: %s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("decoding %q: %v", line, err)
+		}
+		if entry[log.TraceIDKey] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("entry %q lacks the trace of the request", line)
+		}
+	}
+}
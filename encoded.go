@@ -0,0 +1,142 @@
+package log
+
+import (
+	"sync"
+
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-kit/kit/log"
+)
+
+// boundKey is the key of the single pair With binds its fields as, and the
+// Fields methods pass theirs as. The JSON sink copies the fields bound by With
+// encoded once and encodes typed fields without boxing, all other sinks
+// receive them expanded.
+type boundKey struct{}
+
+func (boundKey) String() string { return "bound" }
+
+// boundFields are the fields of a With call, encoded on first use.
+type boundFields struct {
+	keyvals []interface{}
+
+	once    sync.Once
+	encoded []encodedField
+	err     error
+}
+
+func (b *boundFields) encode() ([]encodedField, error) {
+	b.once.Do(func() {
+		b.encoded = make([]encodedField, 0, (len(b.keyvals)+1)/2)
+		for i := 0; i < len(b.keyvals); i += 2 {
+			var v interface{} = log.ErrMissingValue
+			if i+1 < len(b.keyvals) {
+				v = b.keyvals[i+1]
+			}
+			key := kv.Key(b.keyvals[i])
+			encoded, err := appendJSONField(nil, key, v)
+			if err != nil {
+				b.err = err
+				return
+			}
+			b.encoded = append(b.encoded, encodedField{key: key, encoded: encoded})
+		}
+	})
+	return b.encoded, b.err
+}
+
+// bind returns the pair binding keyvals for the JSON sink, or keyvals
+// themselves if they hold values resolved per entry or lack a value.
+func bind(keyvals []interface{}) []interface{} {
+	if len(keyvals)%2 != 0 {
+		return keyvals
+	}
+	for i := 1; i < len(keyvals); i += 2 {
+		switch keyvals[i].(type) {
+		case log.Valuer, Lazy:
+			return keyvals
+		}
+	}
+	return []interface{}{boundKey{}, &boundFields{keyvals: keyvals}}
+}
+
+// expandBound replaces the pairs bound by With or passed by the Fields
+// methods with their fields.
+func expandBound(keyvals []interface{}) []interface{} {
+	n := -1
+	for i := 0; i < len(keyvals); i += 2 {
+		if _, ok := keyvals[i].(boundKey); ok {
+			n = i
+			break
+		}
+	}
+	if n < 0 {
+		return keyvals
+	}
+
+	expanded := make([]interface{}, n, len(keyvals)+8)
+	copy(expanded, keyvals[:n])
+	for i := n; i < len(keyvals); i += 2 {
+		if _, ok := keyvals[i].(boundKey); ok && i+1 < len(keyvals) {
+			switch b := keyvals[i+1].(type) {
+			case *boundFields:
+				expanded = append(expanded, b.keyvals...)
+				continue
+			case *fieldList:
+				expanded = append(expanded, b.keyvals()...)
+				continue
+			case Field:
+				expanded = append(expanded, b.keyvals()...)
+				continue
+			}
+		}
+		expanded = append(expanded, keyvals[i:min(i+2, len(keyvals))]...)
+	}
+	return expanded
+}
+
+// fieldValue returns the value of the first field with the given key,
+// including the fields bound by With and the typed fields.
+func fieldValue(keyvals []interface{}, key string) (interface{}, bool) {
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if _, ok := keyvals[i].(boundKey); ok {
+			switch b := keyvals[i+1].(type) {
+			case *boundFields:
+				if v, ok := fieldValue(b.keyvals, key); ok {
+					return v, true
+				}
+			case *fieldList:
+				if v, ok := b.value(key); ok {
+					return v, true
+				}
+			case Field:
+				if b.key == key {
+					return b.Value(), true
+				}
+			}
+			continue
+		}
+		if kv.Key(keyvals[i]) == key {
+			return keyvals[i+1], true
+		}
+	}
+	return nil, false
+}
+
+// expanding hands entries to sinks other than the JSON sink with the fields
+// bound by With and the typed fields expanded.
+type expanding struct {
+	next log.Logger
+}
+
+func (e expanding) Log(keyvals ...interface{}) error {
+	return e.next.Log(expandBound(keyvals)...)
+}
+
+// bindable wraps sink unless it encodes the bound fields itself.
+func bindable(sink log.Logger) log.Logger {
+	switch sink.(type) {
+	case *jsonSink, expanding:
+		return sink
+	}
+	return expanding{next: sink}
+}
@@ -0,0 +1,34 @@
+package log
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-kit/kit/transport"
+)
+
+var _ transport.ErrorHandler = (*ErrorHandler)(nil)
+
+// ErrorHandler implements go-kit's transport.ErrorHandler, so the servers of
+// go-kit transports report errors through the Log:
+//
+//	httptransport.NewServer(endpoint, decode, encode, httptransport.ServerErrorHandler(log.NewErrorHandler(logger)))
+type ErrorHandler struct {
+	logger Log
+}
+
+// NewErrorHandler creates an ErrorHandler logging through logger.
+func NewErrorHandler(logger Log) *ErrorHandler {
+	return &ErrorHandler{logger: logger}
+}
+
+// Handle logs the error bound to the trace of ctx, see WithTrace. Requests
+// canceled by the client are logged as warning, all other errors as error.
+func (h *ErrorHandler) Handle(ctx context.Context, err error) {
+	l := h.logger.WithTrace(ctx)
+	if errors.Is(err, context.Canceled) {
+		l.Warning("transport error", "err", err)
+		return
+	}
+	l.Error("transport error", "err", err)
+}
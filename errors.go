@@ -0,0 +1,83 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// multiError wraps errors with an Unwrap() []error method, e.g. created by
+// errors.Join, so they're encoded as an array of their constituents instead
+// of a single concatenated message.
+type multiError struct {
+	err error
+}
+
+func (m multiError) Error() string   { return m.err.Error() }
+func (m multiError) Unwrap() []error { return unwrapMulti(m.err) }
+
+// MarshalJSON encodes each constituent with its type and message.
+func (m multiError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(describeErrors(unwrapMulti(m.err)))
+}
+
+type errorDescription struct {
+	Type    string             `json:"type"`
+	Message string             `json:"message"`
+	Errors  []errorDescription `json:"errors,omitempty"`
+}
+
+func describeErrors(errs []error) []errorDescription {
+	list := make([]errorDescription, 0, len(errs))
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		list = append(list, errorDescription{
+			Type:    fmt.Sprintf("%T", err),
+			Message: err.Error(),
+			Errors:  describeErrors(unwrapMulti(err)),
+		})
+	}
+	return list
+}
+
+// wrapMultiError returns err as multiError and true if it's a multi-error
+// which doesn't encode itself as JSON, err itself and false otherwise.
+func wrapMultiError(err error) (interface{}, bool) {
+	if _, ok := err.(json.Marshaler); ok || unwrapMulti(err) == nil {
+		return err, false
+	}
+	return multiError{err: err}, true
+}
+
+func unwrapMulti(err error) []error {
+	if u, ok := err.(interface{ Unwrap() []error }); ok {
+		return u.Unwrap()
+	}
+	return nil
+}
+
+// wrapMultiErrors replaces multi-error values in keyvals by multiError.
+// Values which encode themselves as JSON are left unmodified.
+func wrapMultiErrors(keyvals []interface{}) []interface{} {
+	var wrapped []interface{}
+	for i := 1; i < len(keyvals); i += 2 {
+		err, ok := keyvals[i].(error)
+		if !ok {
+			continue
+		}
+		v, ok := wrapMultiError(err)
+		if !ok {
+			continue
+		}
+		if wrapped == nil {
+			// copy on first change, the caller's keyvals must not be modified
+			wrapped = append([]interface{}(nil), keyvals...)
+		}
+		wrapped[i] = v
+	}
+	if wrapped == nil {
+		return keyvals
+	}
+	return wrapped
+}
@@ -0,0 +1,230 @@
+// Package escalate raises the severity of entries according to configurable
+// rules, e.g. a Warning which repeats too often within a short time becomes
+// an Error, or entries with specific codes are always reported as Error.
+// Keeping such policies in the pipeline spares the call sites from deciding.
+package escalate
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/level"
+	"github.com/go-kit/kit/log"
+)
+
+// FromKey is the key of the field carrying the original level of escalated entries.
+const FromKey = "escalated_from"
+
+// maxFingerprints bounds the amount of tracked fingerprints before expired
+// ones are swept.
+const maxFingerprints = 10000
+
+// Duration is a time.Duration which is encoded as string like "5m" in JSON.
+type Duration time.Duration
+
+// MarshalJSON encodes the duration as string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON decodes the duration from a string like "5m".
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Rule describes when entries are escalated. Rules can be decoded from JSON,
+// so they can be kept in the service configuration:
+//
+//	[
+//	  {"level": "warning", "threshold": 100, "window": "5m", "to": "error"},
+//	  {"field": "code", "values": ["DB_CORRUPT"], "to": "error"}
+//	]
+type Rule struct {
+	// Level restricts the rule to entries of this level. Empty matches all levels.
+	Level string `json:"level,omitempty"`
+	// Field and Values restrict the rule to entries whose field holds one of
+	// the values. Empty matches all entries.
+	Field  string   `json:"field,omitempty"`
+	Values []string `json:"values,omitempty"`
+	// Threshold and Window escalate matching entries only once more than
+	// Threshold entries with the same fingerprint (level and message) were
+	// seen within Window. A zero Threshold escalates every matching entry.
+	Threshold int      `json:"threshold,omitempty"`
+	Window    Duration `json:"window,omitempty"`
+	// To is the name of the level matching entries are escalated to.
+	To string `json:"to"`
+}
+
+type rule struct {
+	Rule
+	to     level.Value
+	values map[string]bool
+	seen   map[string][]time.Time
+}
+
+type escalator struct {
+	next  log.Logger
+	rules []*rule
+	err   error
+	now   func() time.Time
+
+	mtx sync.Mutex
+}
+
+// Option sets a parameter for the escalator.
+type Option func(*escalator)
+
+// Rules adds the given rules. Rules are evaluated in order, the first
+// matching one escalates the entry.
+func Rules(rules ...Rule) Option {
+	return func(e *escalator) {
+		for _, r := range rules {
+			to, ok := level.Parse(r.To)
+			if !ok {
+				e.err = fmt.Errorf("escalate: unknown level %q", r.To)
+				continue
+			}
+			compiled := &rule{Rule: r, to: to, seen: make(map[string][]time.Time)}
+			if len(r.Values) > 0 {
+				compiled.values = make(map[string]bool, len(r.Values))
+				for _, v := range r.Values {
+					compiled.values[v] = true
+				}
+			}
+			e.rules = append(e.rules, compiled)
+		}
+	}
+}
+
+// Repeated escalates entries of the level from to the level to once more
+// than threshold entries with the same message were seen within window.
+func Repeated(from string, threshold int, window time.Duration, to string) Option {
+	return Rules(Rule{Level: from, Threshold: threshold, Window: Duration(window), To: to})
+}
+
+// Codes always escalates entries whose field holds one of the codes to the level to.
+func Codes(field string, to string, codes ...string) Option {
+	return Rules(Rule{Field: field, Values: codes, To: to})
+}
+
+// New wraps next and escalates entries according to the rules. It fails if
+// a rule refers to an unknown level.
+func New(next log.Logger, options ...Option) (log.Logger, error) {
+	e := &escalator{
+		next: next,
+		now:  time.Now,
+	}
+	for _, option := range options {
+		option(e)
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e, nil
+}
+
+func (e *escalator) Log(keyvals ...interface{}) error {
+	lvl, ok := level.FromKeyvals(keyvals)
+	if !ok {
+		return e.next.Log(keyvals...)
+	}
+
+	if to := e.match(lvl, keyvals); to != nil && to != lvl {
+		escalated := make([]interface{}, 0, len(keyvals)+2)
+		for i := 0; i < len(keyvals); i++ {
+			if keyvals[i] == lvl && i%2 == 1 {
+				escalated = append(escalated, to)
+				continue
+			}
+			escalated = append(escalated, keyvals[i])
+		}
+		return e.next.Log(append(escalated, FromKey, lvl.String())...)
+	}
+	return e.next.Log(keyvals...)
+}
+
+// match returns the level of the first matching rule, or nil.
+func (e *escalator) match(lvl level.Value, keyvals []interface{}) level.Value {
+	var fingerprint string
+	for _, r := range e.rules {
+		if r.Level != "" && r.Level != lvl.String() {
+			continue
+		}
+		if r.Field != "" && !r.matchField(keyvals) {
+			continue
+		}
+		if r.Threshold <= 0 {
+			return r.to
+		}
+
+		if fingerprint == "" {
+			fingerprint = lvl.String() + "\x00" + message(keyvals)
+		}
+		if e.repeated(r, fingerprint) {
+			return r.to
+		}
+	}
+	return nil
+}
+
+func (r *rule) matchField(keyvals []interface{}) bool {
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if kv.Key(keyvals[i]) != r.Field {
+			continue
+		}
+		if r.values == nil || r.values[kv.String(keyvals[i+1])] {
+			return true
+		}
+	}
+	return false
+}
+
+// repeated records an occurrence of fingerprint and reports whether it was
+// seen more than the threshold of r within its window.
+func (e *escalator) repeated(r *rule, fingerprint string) bool {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	now := e.now()
+	cutoff := now.Add(-time.Duration(r.Window))
+	if len(r.seen) >= maxFingerprints {
+		for fp, times := range r.seen {
+			if times[len(times)-1].Before(cutoff) {
+				delete(r.seen, fp)
+			}
+		}
+	}
+
+	// only the most recent threshold+1 occurrences are needed to decide
+	times := r.seen[fingerprint]
+	for len(times) > 0 && times[0].Before(cutoff) {
+		times = times[1:]
+	}
+	if len(times) > r.Threshold {
+		times = times[1:]
+	}
+	times = append(times, now)
+	r.seen[fingerprint] = times
+	return len(times) > r.Threshold
+}
+
+func message(keyvals []interface{}) string {
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if kv.Key(keyvals[i]) == kv.MessageKey {
+			return kv.String(keyvals[i+1])
+		}
+	}
+	return ""
+}
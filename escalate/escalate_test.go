@@ -0,0 +1,140 @@
+package escalate
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/level"
+)
+
+// recordingLogger records the level and the escalated_from field of every entry.
+type recordingLogger struct {
+	entries []string
+}
+
+func (r *recordingLogger) Log(keyvals ...interface{}) error {
+	lvl, _ := level.FromKeyvals(keyvals)
+	entry := lvl.String()
+	if from, ok := kv.Map(keyvals)[FromKey]; ok {
+		entry += " from " + from.(string)
+	}
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func entry(lvl level.Value, message string, keyvals ...interface{}) []interface{} {
+	return append([]interface{}{level.Key(), lvl, kv.MessageKey, message}, keyvals...)
+}
+
+func TestEscalator(t *testing.T) {
+	warn := entry(level.WarnValue(), "slow")
+	tests := []struct {
+		name    string
+		options []Option
+		entries [][]interface{}
+		want    []string
+	}{
+		{
+			name:    "no rules",
+			entries: [][]interface{}{warn},
+			want:    []string{"warning"},
+		},
+		{
+			name:    "repeated",
+			options: []Option{Repeated("warning", 2, time.Minute, "error")},
+			entries: [][]interface{}{warn, warn, warn, warn},
+			want:    []string{"warning", "warning", "error from warning", "error from warning"},
+		},
+		{
+			name:    "repeated per message",
+			options: []Option{Repeated("warning", 1, time.Minute, "error")},
+			entries: [][]interface{}{warn, entry(level.WarnValue(), "other"), warn},
+			want:    []string{"warning", "warning", "error from warning"},
+		},
+		{
+			name:    "other level not counted",
+			options: []Option{Repeated("warning", 1, time.Minute, "error")},
+			entries: [][]interface{}{entry(level.InfoValue(), "slow"), entry(level.InfoValue(), "slow")},
+			want:    []string{"info", "info"},
+		},
+		{
+			name:    "codes",
+			options: []Option{Codes("code", "error", "DB_CORRUPT")},
+			entries: [][]interface{}{entry(level.InfoValue(), "m", "code", "DB_CORRUPT"), entry(level.InfoValue(), "m", "code", "OK")},
+			want:    []string{"error from info", "info"},
+		},
+		{
+			name:    "field without values",
+			options: []Option{Rules(Rule{Field: "panic", To: "error"})},
+			entries: [][]interface{}{entry(level.DebugValue(), "m", "panic", true), entry(level.DebugValue(), "m")},
+			want:    []string{"error from debug", "debug"},
+		},
+		{
+			name:    "same level kept",
+			options: []Option{Codes("code", "error", "X")},
+			entries: [][]interface{}{entry(level.ErrorValue(), "m", "code", "X")},
+			want:    []string{"error"},
+		},
+		{
+			name:    "first matching rule",
+			options: []Option{Codes("code", "warning", "X"), Codes("code", "error", "X")},
+			entries: [][]interface{}{entry(level.InfoValue(), "m", "code", "X")},
+			want:    []string{"warning from info"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &recordingLogger{}
+			e, err := New(next, tt.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, keyvals := range tt.entries {
+				_ = e.Log(keyvals...)
+			}
+			if !reflect.DeepEqual(next.entries, tt.want) {
+				t.Errorf("logged %q, want %q", next.entries, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscalatorWindow(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	next := &recordingLogger{}
+	l, _ := New(next, Repeated("warning", 1, time.Minute, "error"))
+	l.(*escalator).now = func() time.Time { return now }
+
+	warn := entry(level.WarnValue(), "slow")
+	_ = l.Log(warn...)
+	now = now.Add(2 * time.Minute)
+	_ = l.Log(warn...)
+	_ = l.Log(warn...)
+	if want := []string{"warning", "warning", "error from warning"}; !reflect.DeepEqual(next.entries, want) {
+		t.Errorf("logged %q, want %q", next.entries, want)
+	}
+}
+
+func TestNewUnknownLevel(t *testing.T) {
+	if _, err := New(&recordingLogger{}, Codes("code", "fatalest", "X")); err == nil {
+		t.Error("New accepted an unknown level")
+	}
+}
+
+func TestRuleJSON(t *testing.T) {
+	var rules []Rule
+	err := json.Unmarshal([]byte(`[{"level": "warning", "threshold": 100, "window": "5m", "to": "error"}]`), &rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Rule{{Level: "warning", Threshold: 100, Window: Duration(5 * time.Minute), To: "error"}}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("decoded %+v, want %+v", rules, want)
+	}
+	if _, err := json.Marshal(rules); err != nil {
+		t.Errorf("encoding the rules: %v", err)
+	}
+}
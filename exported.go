@@ -1,5 +1,7 @@
 package log
 
+import "context"
+
 var std = NewLogger("info")
 
 func SetLevel(level string) {
@@ -21,3 +23,27 @@ func Warning(message string, keyvals ...interface{}) {
 func Error(message string, keyvals ...interface{}) {
 	std.Error(message, keyvals...)
 }
+
+func Fatal(message string, keyvals ...interface{}) {
+	std.Fatal(message, keyvals...)
+}
+
+func Panic(message string, keyvals ...interface{}) {
+	std.Panic(message, keyvals...)
+}
+
+func DebugCtx(ctx context.Context, message string, keyvals ...interface{}) {
+	FromContext(ctx).DebugCtx(ctx, message, keyvals...)
+}
+
+func InfoCtx(ctx context.Context, message string, keyvals ...interface{}) {
+	FromContext(ctx).InfoCtx(ctx, message, keyvals...)
+}
+
+func WarningCtx(ctx context.Context, message string, keyvals ...interface{}) {
+	FromContext(ctx).WarningCtx(ctx, message, keyvals...)
+}
+
+func ErrorCtx(ctx context.Context, message string, keyvals ...interface{}) {
+	FromContext(ctx).ErrorCtx(ctx, message, keyvals...)
+}
@@ -0,0 +1,74 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/go-godin/log/level"
+	"github.com/go-kit/kit/log"
+)
+
+// exit terminates the process after a Fatal entry. It's a variable so it can
+// be replaced where exiting isn't desired.
+var exit = os.Exit
+
+var (
+	shutdownMtx   sync.Mutex
+	shutdownHooks []func()
+)
+
+// RegisterShutdownHook registers hook to run after a Fatal entry was logged
+// and all sinks were flushed, right before the process exits. Hooks run in
+// the order they were registered.
+func RegisterShutdownHook(hook func()) {
+	shutdownMtx.Lock()
+	defer shutdownMtx.Unlock()
+	shutdownHooks = append(shutdownHooks, hook)
+}
+
+// Fatal logs a message and arbitrary key-value pairs at the fatal level,
+// flushes all sinks, runs the registered shutdown hooks and exits the
+// process with status 1.
+func (l Log) Fatal(message string, keyvals ...interface{}) {
+	keyvals = prepare(keyvals)
+	l.handleTrace(message, keyvals)
+	l.markSpanFailed(message)
+	l.output(level.Fatal(l.logger()), message, keyvals)
+
+	flush(l.allSinks())
+	shutdownMtx.Lock()
+	hooks := shutdownHooks
+	shutdownMtx.Unlock()
+	for _, hook := range hooks {
+		hook()
+	}
+	exit(1)
+}
+
+// allSinks returns the sinks of the Log, including the current ones of a
+// config file, which are replaced by reloads.
+func (l Log) allSinks() []log.Logger {
+	configured := l.levels.configSinks()
+	if len(configured) == 0 {
+		return l.sinks
+	}
+	return append(append([]log.Logger(nil), l.sinks...), configured...)
+}
+
+// flush flushes all sinks implementing Flush, and closes those which can
+// only be drained by closing them.
+func flush(sinks []log.Logger) {
+	for _, sink := range sinks {
+		var err error
+		switch s := sink.(type) {
+		case interface{ Flush() error }:
+			err = s.Flush()
+		case interface{ Close() error }:
+			err = s.Close()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "log: flushing sink: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,358 @@
+package log
+
+import (
+	"math"
+	"time"
+
+	"github.com/go-godin/log/level"
+	"github.com/go-kit/kit/log"
+)
+
+// Scalar lists the types of the values of KV fields.
+type Scalar interface {
+	string | bool |
+		int | int8 | int16 | int32 | int64 |
+		uint | uint8 | uint16 | uint32 | uint64 |
+		float32 | float64
+}
+
+type fieldKind uint8
+
+const (
+	kindString fieldKind = iota
+	kindBool
+	kindInt
+	kindInt8
+	kindInt16
+	kindInt32
+	kindInt64
+	kindUint
+	kindUint8
+	kindUint16
+	kindUint32
+	kindUint64
+	kindFloat32
+	kindFloat64
+	kindDuration
+	kindTime
+	kindError
+)
+
+// Field is a typed key-value pair created with KV or one of the typed
+// constructors, e.g. String or Duration. Passed to the Fields methods, e.g.
+// InfoFields, the value is only boxed once the entry passes the level
+// filter.
+type Field struct {
+	key  string
+	kind fieldKind
+	num  uint64
+	str  string
+	// obj holds the error or the location of a time
+	obj interface{}
+}
+
+// KV creates a typed field for the Fields methods, e.g.
+//
+//	logger.InfoFields("request handled", log.KV("status", 200), log.KV("cached", true))
+//
+// A Field can also be passed between the keyvals of an entry, but is boxed
+// like any other value then, which costs more than passing the value itself.
+// The Fields methods don't box the fields.
+func KV[T Scalar](key string, v T) Field {
+	// a literal per case lets the compiler build the Field in place
+	switch x := any(v).(type) {
+	case string:
+		return Field{key: key, kind: kindString, str: x}
+	case bool:
+		if x {
+			return Field{key: key, kind: kindBool, num: 1}
+		}
+		return Field{key: key, kind: kindBool}
+	case int:
+		return Field{key: key, kind: kindInt, num: uint64(x)}
+	case int8:
+		return Field{key: key, kind: kindInt8, num: uint64(x)}
+	case int16:
+		return Field{key: key, kind: kindInt16, num: uint64(x)}
+	case int32:
+		return Field{key: key, kind: kindInt32, num: uint64(x)}
+	case int64:
+		return Field{key: key, kind: kindInt64, num: uint64(x)}
+	case uint:
+		return Field{key: key, kind: kindUint, num: uint64(x)}
+	case uint8:
+		return Field{key: key, kind: kindUint8, num: uint64(x)}
+	case uint16:
+		return Field{key: key, kind: kindUint16, num: uint64(x)}
+	case uint32:
+		return Field{key: key, kind: kindUint32, num: uint64(x)}
+	case uint64:
+		return Field{key: key, kind: kindUint64, num: x}
+	case float32:
+		return Field{key: key, kind: kindFloat32, num: uint64(math.Float32bits(x))}
+	case float64:
+		return Field{key: key, kind: kindFloat64, num: math.Float64bits(x)}
+	}
+	return Field{key: key}
+}
+
+// String creates a string field.
+func String(key, v string) Field {
+	return Field{key: key, kind: kindString, str: v}
+}
+
+// Int creates an int field.
+func Int(key string, v int) Field {
+	return Field{key: key, kind: kindInt, num: uint64(v)}
+}
+
+// Int64 creates an int64 field.
+func Int64(key string, v int64) Field {
+	return Field{key: key, kind: kindInt64, num: uint64(v)}
+}
+
+// Uint64 creates a uint64 field.
+func Uint64(key string, v uint64) Field {
+	return Field{key: key, kind: kindUint64, num: v}
+}
+
+// Float64 creates a float64 field.
+func Float64(key string, v float64) Field {
+	return Field{key: key, kind: kindFloat64, num: math.Float64bits(v)}
+}
+
+// Bool creates a bool field.
+func Bool(key string, v bool) Field {
+	return KV(key, v)
+}
+
+// Duration creates a time.Duration field.
+func Duration(key string, v time.Duration) Field {
+	return Field{key: key, kind: kindDuration, num: uint64(v)}
+}
+
+// Time creates a time.Time field. It's stored in nanoseconds since 1970,
+// so times before 1678 or after 2262 aren't supported.
+func Time(key string, v time.Time) Field {
+	return Field{key: key, kind: kindTime, num: uint64(v.UnixNano()), obj: v.Location()}
+}
+
+// Err creates the err field of an error.
+func Err(err error) Field {
+	return Field{key: "err", kind: kindError, obj: err}
+}
+
+// With returns a Log with the typed field bound to all its entries.
+// It's the generic counterpart of Log.With.
+func With[T Scalar](l Log, key string, v T) Log {
+	return l.WithFields(KV(key, v))
+}
+
+// WithFields returns a Log with the given fields bound to all its entries.
+func (l Log) WithFields(fields ...Field) Log {
+	if len(fields) == 0 {
+		return l
+	}
+	l.kitLogger = log.With(l.kitLogger, bind(fieldKeyvals(fields))...)
+	return l.bindTrace()
+}
+
+// DebugFields logs a message and typed fields at the debug level. Unlike
+// the keyvals of Debug, the values aren't boxed: the JSON sink encodes them
+// by their type, all other sinks receive them as keyvals once the entry
+// passed the level filter. Filtered entries don't allocate.
+func (l Log) DebugFields(message string, fields ...Field) {
+	lvl, ok := l.debugLevel()
+	if !ok {
+		return
+	}
+	l.output(level.With(l.logger(), lvl), message, bindFields(fields))
+}
+
+// InfoFields logs a message and typed fields at the info level.
+func (l Log) InfoFields(message string, fields ...Field) {
+	if l.levels.drops(level.InfoValue(), l.name) {
+		return
+	}
+	l.handleFieldsTrace(message, fields)
+	l.output(level.Info(l.logger()), message, bindFields(fields))
+}
+
+// WarningFields logs a message and typed fields at the warning level.
+func (l Log) WarningFields(message string, fields ...Field) {
+	if l.levels.drops(level.WarnValue(), l.name) {
+		return
+	}
+	l.handleFieldsTrace(message, fields)
+	l.output(level.Warn(l.logger()), message, bindFields(fields))
+}
+
+// ErrorFields logs a message and typed fields at the error level.
+func (l Log) ErrorFields(message string, fields ...Field) {
+	if l.levels.drops(level.ErrorValue(), l.name) {
+		l.markSpanFailed(message)
+		return
+	}
+	l.handleFieldsTrace(message, fields)
+	l.markSpanFailed(message)
+	l.output(level.Error(l.logger()), message, bindFields(fields))
+}
+
+// handleFieldsTrace annotates and tags the span like handleTrace, boxing the
+// values only if the Log has a span.
+func (l Log) handleFieldsTrace(message string, fields []Field) {
+	if l.span != nil {
+		l.handleTrace(message, fieldKeyvals(fields))
+	}
+}
+
+// fieldList holds the fields of an entry logged with one of the Fields
+// methods, passed as the value of a boundKey pair like the fields bound by
+// With. Up to four fields are held inline, so the list is a single
+// allocation.
+type fieldList struct {
+	fields []Field
+	inline [4]Field
+}
+
+// bindFields returns the pair handing a copy of fields to the sinks. The
+// copy is needed as entries may outlive the call, e.g. with WithAsync.
+func bindFields(fields []Field) []interface{} {
+	if len(fields) == 0 {
+		return nil
+	}
+	list := &fieldList{}
+	if len(fields) <= len(list.inline) {
+		list.fields = list.inline[:len(fields)]
+	} else {
+		list.fields = make([]Field, len(fields))
+	}
+	copy(list.fields, fields)
+	return []interface{}{boundKey{}, list}
+}
+
+// keyvals returns the keys and values of the fields, with multi-errors
+// wrapped as by prepare.
+func (f *fieldList) keyvals() []interface{} {
+	return wrapMultiErrors(fieldKeyvals(f.fields))
+}
+
+// value returns the value of the first field with the given key.
+func (f *fieldList) value(key string) (interface{}, bool) {
+	for _, field := range f.fields {
+		if field.key == key {
+			return field.Value(), true
+		}
+	}
+	return nil, false
+}
+
+// carriesError reports whether one of the fields holds an error, see
+// carriesError.
+func (f *fieldList) carriesError() bool {
+	for _, field := range f.fields {
+		if field.carriesError() {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldKeyvals returns the keys and values of the fields.
+func fieldKeyvals(fields []Field) []interface{} {
+	keyvals := make([]interface{}, 0, 2*len(fields))
+	for _, f := range fields {
+		keyvals = append(keyvals, f.key, f.Value())
+	}
+	return keyvals
+}
+
+// keyvals returns the key and value of the field, with a multi-error
+// wrapped as by prepare.
+func (f Field) keyvals() []interface{} {
+	if f.kind == kindError {
+		if err, ok := f.obj.(error); ok {
+			v, _ := wrapMultiError(err)
+			return []interface{}{f.key, v}
+		}
+	}
+	return []interface{}{f.key, f.Value()}
+}
+
+func (f Field) carriesError() bool {
+	return (f.key == "err" || f.key == "error") && (f.kind != kindError || !isNil(f.obj))
+}
+
+// Key returns the key of the field.
+func (f Field) Key() string {
+	return f.key
+}
+
+// Value returns the value of the field with its original type.
+func (f Field) Value() interface{} {
+	switch f.kind {
+	case kindBool:
+		return f.num == 1
+	case kindInt:
+		return int(f.num)
+	case kindInt8:
+		return int8(f.num)
+	case kindInt16:
+		return int16(f.num)
+	case kindInt32:
+		return int32(f.num)
+	case kindInt64:
+		return int64(f.num)
+	case kindUint:
+		return uint(f.num)
+	case kindUint8:
+		return uint8(f.num)
+	case kindUint16:
+		return uint16(f.num)
+	case kindUint32:
+		return uint32(f.num)
+	case kindUint64:
+		return f.num
+	case kindFloat32:
+		return math.Float32frombits(uint32(f.num))
+	case kindFloat64:
+		return math.Float64frombits(f.num)
+	case kindDuration:
+		return time.Duration(f.num)
+	case kindTime:
+		loc, _ := f.obj.(*time.Location)
+		if loc == nil {
+			loc = time.UTC
+		}
+		return time.Unix(0, int64(f.num)).In(loc)
+	case kindError:
+		return f.obj
+	default:
+		return f.str
+	}
+}
+
+// expandFields replaces Fields in key position by a boundKey pair holding
+// the Field, so the JSON sink encodes it without boxing the value.
+func expandFields(keyvals []interface{}) []interface{} {
+	found := false
+	for _, v := range keyvals {
+		if _, ok := v.(Field); ok {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return keyvals
+	}
+
+	list := make([]interface{}, 0, len(keyvals)+len(keyvals)/2)
+	for _, v := range keyvals {
+		if _, ok := v.(Field); ok && len(list)%2 == 0 {
+			list = append(list, boundKey{}, v)
+			continue
+		}
+		list = append(list, v)
+	}
+	return list
+}
@@ -0,0 +1,318 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/kit/log"
+)
+
+func TestFieldValue(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 123, time.FixedZone("CEST", 2*60*60))
+	err := errors.New("failed")
+	for _, tt := range []struct {
+		name  string
+		field Field
+		key   string
+		value interface{}
+	}{
+		{"KV string", KV("k", "v"), "k", "v"},
+		{"KV bool", KV("k", true), "k", true},
+		{"KV false", KV("k", false), "k", false},
+		{"KV int", KV("k", -1), "k", -1},
+		{"KV int8", KV("k", int8(math.MinInt8)), "k", int8(math.MinInt8)},
+		{"KV int16", KV("k", int16(-300)), "k", int16(-300)},
+		{"KV int32", KV("k", int32(-70000)), "k", int32(-70000)},
+		{"KV int64", KV("k", int64(math.MinInt64)), "k", int64(math.MinInt64)},
+		{"KV uint", KV("k", uint(1)), "k", uint(1)},
+		{"KV uint8", KV("k", uint8(255)), "k", uint8(255)},
+		{"KV uint16", KV("k", uint16(65535)), "k", uint16(65535)},
+		{"KV uint32", KV("k", uint32(math.MaxUint32)), "k", uint32(math.MaxUint32)},
+		{"KV uint64", KV("k", uint64(math.MaxUint64)), "k", uint64(math.MaxUint64)},
+		{"KV float32", KV("k", float32(1.5)), "k", float32(1.5)},
+		{"KV float64", KV("k", -2.25), "k", -2.25},
+		{"String", String("k", "v"), "k", "v"},
+		{"Int", Int("k", 42), "k", 42},
+		{"Int64", Int64("k", -42), "k", int64(-42)},
+		{"Uint64", Uint64("k", 42), "k", uint64(42)},
+		{"Float64", Float64("k", 0.5), "k", 0.5},
+		{"Bool", Bool("k", true), "k", true},
+		{"Duration", Duration("k", time.Second), "k", time.Second},
+		{"Time", Time("k", now), "k", now},
+		{"Err", Err(err), "err", err},
+		{"Err nil", Err(nil), "err", nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.field.Key(); got != tt.key {
+				t.Errorf("Key() = %q, want %q", got, tt.key)
+			}
+			got := tt.field.Value()
+			if want, ok := tt.value.(time.Time); ok {
+				if tm, ok := got.(time.Time); !ok || !tm.Equal(want) || tm.Location().String() != want.Location().String() {
+					t.Errorf("Value() = %v, want %v", got, want)
+				}
+				return
+			}
+			if got != tt.value {
+				t.Errorf("Value() = %#v, want %#v", got, tt.value)
+			}
+		})
+	}
+}
+
+func TestExpandFields(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		keyvals []interface{}
+		want    []interface{}
+	}{
+		{"no fields", []interface{}{"a", 1}, []interface{}{"a", 1}},
+		{"field only", []interface{}{KV("a", 1)}, []interface{}{boundKey{}, KV("a", 1)}},
+		{"mixed", []interface{}{"a", 1, KV("b", true), "c", "d"}, []interface{}{"a", 1, boundKey{}, KV("b", true), "c", "d"}},
+		{"field as value", []interface{}{"a", KV("b", 1)}, []interface{}{"a", KV("b", 1)}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expandFields(tt.keyvals); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldsMethods(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		level string
+		log   func(l Log)
+		want  []map[string]interface{}
+	}{
+		{
+			name:  "debug passes",
+			level: LevelDebug,
+			log:   func(l Log) { l.DebugFields("m", KV("a", 1)) },
+			want:  []map[string]interface{}{{"severity": "debug", "message": "m", "a": float64(1)}},
+		},
+		{
+			name:  "debug filtered",
+			level: LevelInfo,
+			log:   func(l Log) { l.DebugFields("m", KV("a", 1)) },
+		},
+		{
+			name:  "info",
+			level: LevelInfo,
+			log:   func(l Log) { l.InfoFields("m", String("a", "b"), Duration("d", time.Second)) },
+			want:  []map[string]interface{}{{"severity": "info", "message": "m", "a": "b", "d": "1s"}},
+		},
+		{
+			name:  "warning filtered",
+			level: LevelError,
+			log:   func(l Log) { l.WarningFields("m", KV("a", 1)) },
+		},
+		{
+			name:  "error",
+			level: LevelError,
+			log:   func(l Log) { l.ErrorFields("m", Err(errors.New("e"))) },
+			want:  []map[string]interface{}{{"severity": "error", "message": "m", "err": "e"}},
+		},
+		{
+			name:  "bound",
+			level: LevelInfo,
+			log:   func(l Log) { With(l.WithFields(KV("a", 1)), "b", true).Info("m") },
+			want:  []map[string]interface{}{{"severity": "info", "message": "m", "a": float64(1), "b": true}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, out := newBufferLogger(tt.level)
+			tt.log(logger)
+			if got := out.entries(t); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("entries = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFieldsEncoding asserts typed fields are written exactly like the same
+// values passed as keyvals, by the JSON sink and by other sinks.
+func TestFieldsEncoding(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 123, time.FixedZone("CEST", 2*60*60))
+	for _, tt := range []struct {
+		name  string
+		field Field
+	}{
+		{"string", String("k", "v")},
+		{"escaped string", String("k", "<a href=\"x\">\n\u2028")},
+		{"escaped key", String("k\"", "v")},
+		{"true", Bool("k", true)},
+		{"false", Bool("k", false)},
+		{"int", Int("k", -1)},
+		{"int8", KV("k", int8(math.MinInt8))},
+		{"int16", KV("k", int16(-300))},
+		{"int32", KV("k", int32(-70000))},
+		{"int64", Int64("k", math.MinInt64)},
+		{"uint8", KV("k", uint8(255))},
+		{"uint16", KV("k", uint16(65535))},
+		{"uint32", KV("k", uint32(math.MaxUint32))},
+		{"uint64", Uint64("k", math.MaxUint64)},
+		{"float32", KV("k", float32(0.1))},
+		{"small float32", KV("k", float32(1e-7))},
+		{"float64", Float64("k", -2.25)},
+		{"zero float64", Float64("k", 0)},
+		{"small float64", Float64("k", 1e-9)},
+		{"large float64", Float64("k", 1e21)},
+		{"NaN", Float64("k", math.NaN())},
+		{"infinity", KV("k", float32(math.Inf(1)))},
+		{"duration", Duration("k", 1500*time.Millisecond)},
+		{"time", Time("k", now)},
+		{"error", Err(errors.New("failed"))},
+		{"nil error", Err(nil)},
+		{"multi-error", Err(errors.Join(errors.New("a"), errors.New("b")))},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, sink := range []struct {
+				name string
+				new  func(w io.Writer) kitlog.Logger
+			}{
+				{"json sink", func(w io.Writer) kitlog.Logger { return newJSONSink(w) }},
+				{"other sink", kitlog.NewJSONLogger},
+			} {
+				var keyvals, fields, kv bytes.Buffer
+				NewLogger(LevelInfo, WithSink(sink.new(&keyvals))).Info("m", tt.field.Key(), tt.field.Value())
+				NewLogger(LevelInfo, WithSink(sink.new(&fields))).InfoFields("m", tt.field)
+				NewLogger(LevelInfo, WithSink(sink.new(&kv))).Info("m", tt.field)
+				if fields.String() != keyvals.String() {
+					t.Errorf("%s: InfoFields wrote %q, Info wrote %q", sink.name, fields.String(), keyvals.String())
+				}
+				if kv.String() != keyvals.String() {
+					t.Errorf("%s: Info with the Field wrote %q, with its value %q", sink.name, kv.String(), keyvals.String())
+				}
+			}
+		})
+	}
+}
+
+func TestFieldsLookup(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		fields     []Field
+		wantGroup  interface{}
+		wantErrors bool
+	}{
+		{"no fields", nil, nil, false},
+		{"field", []Field{String("request", "r1"), Int("n", 1)}, "r1", false},
+		{"error", []Field{Err(errors.New("e"))}, nil, true},
+		{"nil error", []Field{Err(nil)}, nil, false},
+		{"error string", []Field{String("error", "e")}, nil, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fieldKeyvals := make([]interface{}, 0, len(tt.fields))
+			for _, f := range tt.fields {
+				fieldKeyvals = append(fieldKeyvals, f)
+			}
+			for name, keyvals := range map[string][]interface{}{
+				"Fields methods": append([]interface{}{"a", 1}, bindFields(tt.fields)...),
+				"keyvals":        append([]interface{}{"a", 1}, expandFields(fieldKeyvals)...),
+			} {
+				if got, _ := fieldValue(keyvals, "request"); got != tt.wantGroup {
+					t.Errorf("%s: fieldValue() = %v, want %v", name, got, tt.wantGroup)
+				}
+				if got := carriesError(keyvals); got != tt.wantErrors {
+					t.Errorf("%s: carriesError() = %v, want %v", name, got, tt.wantErrors)
+				}
+			}
+		})
+	}
+}
+
+func TestFieldsCopied(t *testing.T) {
+	var logged [][]interface{}
+	sink := kitlog.LoggerFunc(func(keyvals ...interface{}) error {
+		logged = append(logged, keyvals)
+		return nil
+	})
+	want := []interface{}{"severity", "info", "message", "m", "a", 1, "b", 2, "c", 3, "d", 4, "e", 5}
+	for _, n := range []int{1, 5} {
+		logged = nil
+		logger := NewLogger(LevelInfo, WithSink(sink), WithAsync(4))
+		fields := []Field{Int("a", 1), Int("b", 2), Int("c", 3), Int("d", 4), Int("e", 5)}[:n]
+		logger.InfoFields("m", fields...)
+		fields[0] = Int("a", 10)
+		logger.Flush()
+		// the severity is a level.Value
+		if len(logged) != 1 || fmt.Sprint(logged[0]) != fmt.Sprint(want[:4+2*n]) {
+			t.Errorf("%d fields: logged %v, want %v", n, logged, want[:4+2*n])
+		}
+	}
+}
+
+func TestFilteredFieldsDontAllocate(t *testing.T) {
+	logger := NewLogger(LevelInfo, WithSink(&countingSink{}))
+	err := errors.New("failed")
+	for _, tt := range []struct {
+		name string
+		log  func()
+	}{
+		{"DebugFields", func() { logger.DebugFields("m", KV("a", 1), String("b", "c"), Err(err)) }},
+		{"named DebugFields", func() { logger.Named("db").DebugFields("m", Int64("a", 1)) }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if allocs := testing.AllocsPerRun(100, tt.log); allocs != 0 {
+				t.Errorf("%v allocations, want 0", allocs)
+			}
+		})
+	}
+}
+
+// BenchmarkFields compares passing values as keyvals and to the Fields
+// methods, for entries passing and failing the level filter.
+func BenchmarkFields(b *testing.B) {
+	for _, level := range []string{LevelDebug, LevelInfo} {
+		logger := NewLogger(level, WithSink(newJSONSink(io.Discard)))
+		for _, bb := range []struct {
+			name string
+			log  func(i int)
+		}{
+			{"keyvals", func(i int) { logger.Debug("m", "status", i, "cached", true, "path", "/") }},
+			{"DebugFields", func(i int) { logger.DebugFields("m", KV("status", i), KV("cached", true), KV("path", "/")) }},
+		} {
+			b.Run(level+"/"+bb.name, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					bb.log(i)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkKV compares passing values as keyvals, as KV fields between the
+// keyvals and as KV fields to the Fields methods, written by the JSON sink.
+func BenchmarkKV(b *testing.B) {
+	logger := NewLogger(LevelInfo, WithSink(newJSONSink(io.Discard)))
+	path := strings.Repeat("/", 2)
+	for _, bb := range []struct {
+		name string
+		log  func(i int)
+	}{
+		{"keyvals", func(i int) { logger.Info("m", "status", i, "path", path, "ratio", float64(i)/3, "cached", true) }},
+		{"KV keyvals", func(i int) {
+			logger.Info("m", KV("status", i), KV("path", path), KV("ratio", float64(i)/3), KV("cached", true))
+		}},
+		{"KV InfoFields", func(i int) {
+			logger.InfoFields("m", KV("status", i), KV("path", path), KV("ratio", float64(i)/3), KV("cached", true))
+		}},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				bb.log(i)
+			}
+		})
+	}
+}
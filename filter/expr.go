@@ -0,0 +1,377 @@
+package filter
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode"
+
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/level"
+	"github.com/go-kit/kit/log"
+)
+
+// levelAliases maps short level names accepted in expressions to the names
+// of the levels.
+var levelAliases = map[string]string{
+	"warn": "warning",
+	"err":  "error",
+}
+
+// Compile parses a filter expression into a predicate. Expressions compare
+// the level, the message or fields of an entry:
+//
+//	level >= warn || fields.component == "billing"
+//	!(message =~ "^health") && fields.status >= 500
+//
+// The operators are ==, !=, <, <=, >, >= and =~ (regular expression),
+// combined with &&, || and !, grouped with parentheses. Levels are compared
+// by severity, values which are both numbers numerically and all others as
+// strings. Bare words other than level, message and fields.<key> are strings.
+// Comparisons with missing fields never match, except !=.
+func Compile(expr string) (Predicate, error) {
+	p := &parser{tokens: tokenize(expr)}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("filter: %v in %q", err, expr)
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("filter: unexpected %q in %q", p.tokens[p.pos].text, expr)
+	}
+	return Predicate(n), nil
+}
+
+// Expression is a filter stage passing only entries matching an expression,
+// which can be replaced at runtime, e.g. from a config reload or an admin
+// endpoint. It's safe for concurrent use.
+type Expression struct {
+	next    log.Logger
+	current atomic.Pointer[compiled]
+}
+
+type compiled struct {
+	expr      string
+	predicate Predicate
+}
+
+// NewExpression wraps next and passes only entries matching expr, see
+// Compile. An empty expression passes all entries.
+func NewExpression(next log.Logger, expr string) (*Expression, error) {
+	e := &Expression{next: next}
+	if err := e.Set(expr); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Set replaces the expression. An invalid expression is rejected and the
+// previous one is kept.
+func (e *Expression) Set(expr string) error {
+	c := &compiled{expr: expr}
+	if strings.TrimSpace(expr) != "" {
+		p, err := Compile(expr)
+		if err != nil {
+			return err
+		}
+		c.predicate = p
+	}
+	e.current.Store(c)
+	return nil
+}
+
+// String returns the current expression.
+func (e *Expression) String() string {
+	return e.current.Load().expr
+}
+
+// Log hands the entry to the next logger if it matches the expression.
+func (e *Expression) Log(keyvals ...interface{}) error {
+	if p := e.current.Load().predicate; p != nil && !p(keyvals) {
+		return nil
+	}
+	return e.next.Log(keyvals...)
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "<", ">", "!", "(", ")"}
+
+func tokenize(expr string) []token {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			// quoted string, backslash escapes the next character
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(expr) && expr[j] != c; j++ {
+				if expr[j] == '\\' && j+1 < len(expr) {
+					j++
+				}
+				b.WriteByte(expr[j])
+			}
+			tokens = append(tokens, token{kind: tokenString, text: b.String()})
+			i = j + 1
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(expr[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op != "" {
+				tokens = append(tokens, token{kind: tokenOperator, text: op})
+				i += len(op)
+				continue
+			}
+			j := i
+			for j < len(expr) && (unicode.IsLetter(rune(expr[j])) || unicode.IsDigit(rune(expr[j])) || strings.IndexByte("._-:/", expr[j]) >= 0) {
+				j++
+			}
+			if j == i {
+				// unknown character, reported by the parser
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: expr[i:j]})
+			i = j
+		}
+	}
+	return tokens
+}
+
+type node func(keyvals []interface{}) bool
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek(op string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenOperator && p.tokens[p.pos].text == op
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("||") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(keyvals []interface{}) bool { return l(keyvals) || right(keyvals) }
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("&&") {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(keyvals []interface{}) bool { return l(keyvals) && right(keyvals) }
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.peek("!") {
+		p.pos++
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(keyvals []interface{}) bool { return !n(keyvals) }, nil
+	}
+	if p.peek("(") {
+		p.pos++
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peek(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return n, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenOperator {
+		return nil, fmt.Errorf("missing comparison after %q", left.text)
+	}
+	op := p.tokens[p.pos].text
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=", "=~":
+	default:
+		return nil, fmt.Errorf("unexpected %q after %q", op, left.text)
+	}
+	p.pos++
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return compare(left, op, right)
+}
+
+// operand is a reference to the level, the message or a field of the
+// entry, or a literal.
+type operand struct {
+	text    string
+	level   bool
+	ref     string // the key of the field
+	literal bool
+}
+
+func (p *parser) parseOperand() (operand, error) {
+	if p.pos >= len(p.tokens) {
+		return operand{}, fmt.Errorf("unexpected end")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	switch {
+	case t.kind == tokenString:
+		return operand{text: t.text, literal: true}, nil
+	case t.kind == tokenOperator:
+		return operand{}, fmt.Errorf("unexpected %q", t.text)
+	case t.text == "level":
+		return operand{text: t.text, level: true}, nil
+	case t.text == "message":
+		return operand{text: t.text, ref: kv.MessageKey}, nil
+	case strings.HasPrefix(t.text, "fields.") && len(t.text) > len("fields."):
+		return operand{text: t.text, ref: strings.TrimPrefix(t.text, "fields.")}, nil
+	default:
+		return operand{text: t.text, literal: true}, nil
+	}
+}
+
+func compare(left operand, op string, right operand) (node, error) {
+	if op == "=~" {
+		if !right.literal {
+			return nil, fmt.Errorf("=~ requires a regular expression on the right")
+		}
+		re, err := regexp.Compile(right.text)
+		if err != nil {
+			return nil, err
+		}
+		return func(keyvals []interface{}) bool {
+			v, ok := left.resolve(keyvals)
+			return ok && re.MatchString(v)
+		}, nil
+	}
+
+	if left.level || right.level {
+		for _, o := range []operand{left, right} {
+			if o.literal && levelSeverity(o.text) < 0 {
+				return nil, fmt.Errorf("unknown level %q", o.text)
+			}
+		}
+		return func(keyvals []interface{}) bool {
+			l, lok := left.resolve(keyvals)
+			r, rok := right.resolve(keyvals)
+			if !lok || !rok {
+				return op == "!="
+			}
+			return ordered(op, cmp.Compare(levelSeverity(l), levelSeverity(r)))
+		}, nil
+	}
+
+	return func(keyvals []interface{}) bool {
+		l, lok := left.resolve(keyvals)
+		r, rok := right.resolve(keyvals)
+		if !lok || !rok {
+			return op == "!="
+		}
+		lf, lerr := strconv.ParseFloat(l, 64)
+		rf, rerr := strconv.ParseFloat(r, 64)
+		if lerr == nil && rerr == nil {
+			return ordered(op, cmp.Compare(lf, rf))
+		}
+		return ordered(op, strings.Compare(l, r))
+	}, nil
+}
+
+// resolve returns the string value of the operand for the entry, and
+// whether the entry carries it.
+func (o operand) resolve(keyvals []interface{}) (string, bool) {
+	if o.literal {
+		return o.text, true
+	}
+	if o.level {
+		lvl, ok := level.FromKeyvals(keyvals)
+		if !ok {
+			return "", false
+		}
+		return lvl.String(), true
+	}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if kv.Key(keyvals[i]) == o.ref {
+			return kv.String(keyvals[i+1]), true
+		}
+	}
+	return "", false
+}
+
+// levelSeverity returns the severity of the named level, or -1.
+func levelSeverity(name string) int {
+	if alias, ok := levelAliases[name]; ok {
+		name = alias
+	}
+	v, ok := level.Parse(name)
+	if !ok {
+		return -1
+	}
+	return v.Severity()
+}
+
+// ordered reports whether the result of a comparison satisfies op.
+func ordered(op string, result int) bool {
+	switch op {
+	case "==":
+		return result == 0
+	case "!=":
+		return result != 0
+	case "<":
+		return result < 0
+	case "<=":
+		return result <= 0
+	case ">":
+		return result > 0
+	default: // ">="
+		return result >= 0
+	}
+}
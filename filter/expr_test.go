@@ -0,0 +1,144 @@
+package filter
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/go-godin/log/level"
+)
+
+func TestCompile(t *testing.T) {
+	entry := []interface{}{
+		level.Key(), level.WarnValue(),
+		"message", "health check failed",
+		"component", "billing",
+		"status", 503,
+		"path", "/v1/charge",
+	}
+	tests := []struct {
+		expr    string
+		matches bool
+	}{
+		{`level >= warn`, true},
+		{`level >= error`, false},
+		{`level == warning`, true},
+		{`level < err`, true},
+		{`level != info`, true},
+		{`fields.component == "billing"`, true},
+		{`fields.component == 'payment'`, false},
+		{`fields.component == billing`, true},
+		{`fields.status >= 500`, true},
+		{`fields.status > 1000`, false},
+		{`fields.status < 600 && fields.status >= 500`, true},
+		{`fields.path == /v1/charge`, true},
+		{`message =~ "^health"`, true},
+		{`!(message =~ "^health")`, false},
+		{`!(message =~ "^health") || fields.component == billing`, true},
+		{`level >= error || fields.status >= 500`, true},
+		{`level >= error && fields.status >= 500`, false},
+		{`fields.region == eu`, false},
+		{`fields.region != eu`, true},
+		{`fields.region < eu`, false},
+		{`"status" == "status"`, true},
+		{`message == "health \"check\" failed"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			p, err := Compile(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if matches := p(entry); matches != tt.matches {
+				t.Errorf("matches = %v, want %v", matches, tt.matches)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`level`,
+		`level >=`,
+		`level >= verbose`,
+		`(level >= warn`,
+		`level >= warn)`,
+		`message =~ "("`,
+		`message =~ fields.pattern`,
+		`level && message`,
+		`fields.status >= 500 fields.status`,
+		`fields.a == b #`,
+	} {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := Compile(expr); err == nil {
+				t.Errorf("Compile(%q) succeeded", expr)
+			}
+		})
+	}
+}
+
+func TestExpression(t *testing.T) {
+	next := &countingLogger{}
+	e, err := NewExpression(next, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	debug := []interface{}{level.Key(), level.DebugValue(), "message", "m"}
+
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr bool
+		want    string // the expression in effect
+		passes  bool
+	}{
+		{name: "empty", expr: "", want: "", passes: true},
+		{name: "set", expr: "level >= info", want: "level >= info", passes: false},
+		{name: "invalid kept", expr: "level >=", wantErr: true, want: "level >= info", passes: false},
+		{name: "replaced", expr: "level >= debug", want: "level >= debug", passes: true},
+		{name: "reset", expr: "  ", want: "  ", passes: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := e.Set(tt.expr); (err != nil) != tt.wantErr {
+				t.Fatalf("Set() = %v, want error %v", err, tt.wantErr)
+			}
+			if e.String() != tt.want {
+				t.Errorf("String() = %q, want %q", e.String(), tt.want)
+			}
+			before := next.n
+			_ = e.Log(debug...)
+			if passed := next.n > before; passed != tt.passes {
+				t.Errorf("passed = %v, want %v", passed, tt.passes)
+			}
+		})
+	}
+}
+
+func TestExpressionConcurrentSet(t *testing.T) {
+	e, err := NewExpression(discard{}, "level >= info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = e.Set("level >= warn")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = e.Log(level.Key(), level.InfoValue(), "message", "m")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+type discard struct{}
+
+func (discard) Log(...interface{}) error { return nil }
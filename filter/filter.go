@@ -0,0 +1,94 @@
+// Package filter drops entries by their fields, e.g. to silence a noisy
+// subsystem with component=payment without touching its code, or to only
+// pass the entries of some tenants.
+package filter
+
+import (
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-kit/kit/log"
+)
+
+// Predicate reports whether the fields of an entry match.
+type Predicate func(keyvals []interface{}) bool
+
+// Equals matches entries whose field key holds value, compared by its
+// string representation.
+func Equals(key, value string) Predicate {
+	return In(key, value)
+}
+
+// In matches entries whose field key holds one of the values, compared by
+// their string representation.
+func In(key string, values ...string) Predicate {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return Func(key, func(value interface{}) bool { return set[kv.String(value)] })
+}
+
+// Exists matches entries carrying the field key.
+func Exists(key string) Predicate {
+	return Func(key, func(interface{}) bool { return true })
+}
+
+// Func matches entries carrying the field key whose value satisfies match.
+func Func(key string, match func(value interface{}) bool) Predicate {
+	return func(keyvals []interface{}) bool {
+		for i := 0; i+1 < len(keyvals); i += 2 {
+			if kv.Key(keyvals[i]) == key && match(keyvals[i+1]) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not matches entries not matched by p.
+func Not(p Predicate) Predicate {
+	return func(keyvals []interface{}) bool { return !p(keyvals) }
+}
+
+type filter struct {
+	next  log.Logger
+	allow []Predicate
+	deny  []Predicate
+}
+
+// Option sets a parameter for the filter.
+type Option func(*filter)
+
+// Allow passes only entries matched by all predicates. Calling Allow
+// multiple times adds to the predicates.
+func Allow(predicates ...Predicate) Option {
+	return func(f *filter) { f.allow = append(f.allow, predicates...) }
+}
+
+// Deny drops entries matched by any of the predicates, e.g.
+// Deny(Equals("component", "payment")). Deny takes precedence over Allow.
+func Deny(predicates ...Predicate) Option {
+	return func(f *filter) { f.deny = append(f.deny, predicates...) }
+}
+
+// New wraps next and drops entries according to the predicates.
+func New(next log.Logger, options ...Option) log.Logger {
+	f := &filter{next: next}
+	for _, option := range options {
+		option(f)
+	}
+	return f
+}
+
+func (f *filter) Log(keyvals ...interface{}) error {
+	for _, p := range f.deny {
+		if p(keyvals) {
+			return nil
+		}
+	}
+	for _, p := range f.allow {
+		if !p(keyvals) {
+			return nil
+		}
+	}
+	return f.next.Log(keyvals...)
+}
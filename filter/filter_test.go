@@ -0,0 +1,45 @@
+package filter
+
+import (
+	"testing"
+)
+
+type countingLogger struct {
+	n int
+}
+
+func (c *countingLogger) Log(...interface{}) error {
+	c.n++
+	return nil
+}
+
+func TestFilter(t *testing.T) {
+	entry := []interface{}{"message", "charged", "component", "payment", "tenant", "acme", "status", 502}
+	tests := []struct {
+		name    string
+		options []Option
+		passes  bool
+	}{
+		{"no predicates", nil, true},
+		{"deny equals", []Option{Deny(Equals("component", "payment"))}, false},
+		{"deny other value", []Option{Deny(Equals("component", "billing"))}, true},
+		{"deny any", []Option{Deny(Equals("component", "billing"), Exists("tenant"))}, false},
+		{"allow in", []Option{Allow(In("tenant", "acme", "initech"))}, true},
+		{"allow not in", []Option{Allow(In("tenant", "initech"))}, false},
+		{"allow all", []Option{Allow(Exists("tenant"), Exists("region"))}, false},
+		{"allow added", []Option{Allow(Exists("tenant")), Allow(Exists("region"))}, false},
+		{"deny over allow", []Option{Allow(Exists("tenant")), Deny(Exists("tenant"))}, false},
+		{"number compared as string", []Option{Allow(Equals("status", "502"))}, true},
+		{"not", []Option{Allow(Not(Exists("region")))}, true},
+		{"func", []Option{Deny(Func("status", func(v interface{}) bool { return v.(int) >= 500 }))}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &countingLogger{}
+			_ = New(next, tt.options...).Log(entry...)
+			if passed := next.n == 1; passed != tt.passes {
+				t.Errorf("passed = %v, want %v", passed, tt.passes)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/level"
+)
+
+// Message matches entries with exactly the message.
+func Message(message string) Predicate {
+	return Func(kv.MessageKey, func(v interface{}) bool { return kv.String(v) == message })
+}
+
+// MessageContains matches entries whose message contains substr.
+func MessageContains(substr string) Predicate {
+	return Func(kv.MessageKey, func(v interface{}) bool { return strings.Contains(kv.String(v), substr) })
+}
+
+// MessageMatches matches entries whose message matches re.
+func MessageMatches(re *regexp.Regexp) Predicate {
+	return Func(kv.MessageKey, func(v interface{}) bool { return re.MatchString(kv.String(v)) })
+}
+
+// Rule describes entries to suppress, e.g. known-noisy messages of vendored
+// code. All non-empty conditions must match. Rules can be decoded from JSON,
+// so they can be kept in the service configuration:
+//
+//	[
+//	  {"message": "connection reset by peer"},
+//	  {"level": "warning", "regex": "^retrying request \\d+"}
+//	]
+type Rule struct {
+	// Level restricts the rule to entries of this level.
+	Level string `json:"level,omitempty"`
+	// Message matches the exact message.
+	Message string `json:"message,omitempty"`
+	// Contains matches messages containing the substring.
+	Contains string `json:"contains,omitempty"`
+	// Regex matches messages matching the regular expression.
+	Regex string `json:"regex,omitempty"`
+}
+
+// Suppression compiles the rules into a predicate matching entries matched
+// by any of them, to be passed to Deny. It fails for invalid regular
+// expressions and rules without conditions.
+func Suppression(rules ...Rule) (Predicate, error) {
+	compiled := make([][]Predicate, 0, len(rules))
+	for _, r := range rules {
+		var conditions []Predicate
+		if r.Level != "" {
+			name := r.Level
+			conditions = append(conditions, func(keyvals []interface{}) bool {
+				lvl, ok := level.FromKeyvals(keyvals)
+				return ok && lvl.String() == name
+			})
+		}
+		if r.Message != "" {
+			conditions = append(conditions, Message(r.Message))
+		}
+		if r.Contains != "" {
+			conditions = append(conditions, MessageContains(r.Contains))
+		}
+		if r.Regex != "" {
+			re, err := regexp.Compile(r.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("filter: invalid regex %q: %v", r.Regex, err)
+			}
+			conditions = append(conditions, MessageMatches(re))
+		}
+		if len(conditions) == 0 {
+			return nil, fmt.Errorf("filter: suppression rule without conditions")
+		}
+		compiled = append(compiled, conditions)
+	}
+
+	return func(keyvals []interface{}) bool {
+	rules:
+		for _, conditions := range compiled {
+			for _, c := range conditions {
+				if !c(keyvals) {
+					continue rules
+				}
+			}
+			return true
+		}
+		return false
+	}, nil
+}
@@ -0,0 +1,68 @@
+package filter
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/go-godin/log/level"
+)
+
+func TestMessagePredicates(t *testing.T) {
+	entry := []interface{}{"message", "retrying request 3"}
+	tests := []struct {
+		name      string
+		predicate Predicate
+		matches   bool
+	}{
+		{"message", Message("retrying request 3"), true},
+		{"other message", Message("retrying request"), false},
+		{"contains", MessageContains("request"), true},
+		{"doesn't contain", MessageContains("response"), false},
+		{"matches", MessageMatches(regexp.MustCompile(`^retrying request \d+$`)), true},
+		{"doesn't match", MessageMatches(regexp.MustCompile(`^request`)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if matches := tt.predicate(entry); matches != tt.matches {
+				t.Errorf("matches = %v, want %v", matches, tt.matches)
+			}
+		})
+	}
+}
+
+func TestSuppression(t *testing.T) {
+	warn := []interface{}{level.Key(), level.WarnValue(), "message", "retrying request 3"}
+	info := []interface{}{level.Key(), level.InfoValue(), "message", "connection reset by peer"}
+	tests := []struct {
+		name     string
+		rules    []Rule
+		wantErr  bool
+		suppress [2]bool // warn, info
+	}{
+		{name: "message", rules: []Rule{{Message: "connection reset by peer"}}, suppress: [2]bool{false, true}},
+		{name: "contains", rules: []Rule{{Contains: "request"}}, suppress: [2]bool{true, false}},
+		{name: "level and regex", rules: []Rule{{Level: "warning", Regex: `^retrying request \d+`}}, suppress: [2]bool{true, false}},
+		{name: "all conditions", rules: []Rule{{Level: "info", Contains: "request"}}, suppress: [2]bool{false, false}},
+		{name: "any rule", rules: []Rule{{Contains: "request"}, {Level: "info"}}, suppress: [2]bool{true, true}},
+		{name: "invalid regex", rules: []Rule{{Regex: "("}}, wantErr: true},
+		{name: "no conditions", rules: []Rule{{}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Suppression(tt.rules...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Suppression() = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			for i, entry := range [][]interface{}{warn, info} {
+				next := &countingLogger{}
+				_ = New(next, Deny(p)).Log(entry...)
+				if suppressed := next.n == 0; suppressed != tt.suppress[i] {
+					t.Errorf("entry %d: suppressed = %v, want %v", i, suppressed, tt.suppress[i])
+				}
+			}
+		})
+	}
+}
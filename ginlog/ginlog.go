@@ -0,0 +1,38 @@
+// Package ginlog provides Gin middleware logging requests with the godin
+// logger, replacing gin.Logger.
+package ginlog
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-godin/log"
+	"github.com/go-godin/log/httplog"
+)
+
+// Middleware logs one entry per request like httplog.Middleware, and passes
+// the request scoped Log on in the request context, see log.FromContext.
+// Use it with gin.New instead of gin.Default, which adds gin's own logger:
+//
+//	router := gin.New()
+//	router.Use(gin.Recovery(), ginlog.Middleware(logger, httplog.Exclude("/healthz")))
+func Middleware(logger log.Log, opts ...httplog.Option) gin.HandlerFunc {
+	l := httplog.New(logger, opts...)
+	return func(c *gin.Context) {
+		begin := time.Now()
+		rl, logged := l.Begin(c.Request)
+		if !logged {
+			c.Next()
+			return
+		}
+
+		c.Request = c.Request.WithContext(log.NewContext(c.Request.Context(), rl))
+		c.Next()
+
+		size := c.Writer.Size()
+		if size < 0 {
+			size = 0
+		}
+		l.End(rl, c.Request, c.Writer.Status(), size, begin)
+	}
+}
@@ -0,0 +1,122 @@
+package ginlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-godin/log"
+	"github.com/go-godin/log/httplog"
+	kitlog "github.com/go-kit/kit/log"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []httplog.Option
+		path       string
+		handler    gin.HandlerFunc
+		wantStatus float64 // zero if the request isn't logged
+		wantLevel  string
+		wantBytes  float64
+	}{
+		{
+			name:       "ok",
+			path:       "/",
+			handler:    func(c *gin.Context) { c.String(http.StatusOK, "hello") },
+			wantStatus: 200,
+			wantLevel:  "info",
+			wantBytes:  5,
+		},
+		{
+			name:       "no body",
+			path:       "/",
+			handler:    func(c *gin.Context) { c.Status(http.StatusNoContent) },
+			wantStatus: 204,
+			wantLevel:  "info",
+		},
+		{
+			name:       "client error",
+			path:       "/",
+			handler:    func(c *gin.Context) { c.AbortWithStatus(http.StatusNotFound) },
+			wantStatus: 404,
+			wantLevel:  "warning",
+		},
+		{
+			name:       "server error",
+			path:       "/",
+			handler:    func(c *gin.Context) { c.AbortWithStatus(http.StatusServiceUnavailable) },
+			wantStatus: 503,
+			wantLevel:  "error",
+		},
+		{
+			name:    "excluded",
+			opts:    []httplog.Option{httplog.Exclude("/healthz")},
+			path:    "/healthz",
+			handler: func(c *gin.Context) { c.Status(http.StatusOK) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := log.NewLogger(log.LevelDebug, log.WithSink(kitlog.NewJSONLogger(&buf)))
+			router := gin.New()
+			router.Use(Middleware(logger, tt.opts...))
+			router.GET(tt.path, tt.handler)
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if tt.wantStatus == 0 {
+				if buf.Len() != 0 {
+					t.Errorf("logged %s, want nothing", buf.String())
+				}
+				return
+			}
+			var entry map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("decoding %q: %v", buf.String(), err)
+			}
+			if entry["status"] != tt.wantStatus || entry["severity"] != tt.wantLevel {
+				t.Errorf("status, severity = %v, %v, want %v, %s", entry["status"], entry["severity"], tt.wantStatus, tt.wantLevel)
+			}
+			if entry["bytes"] != tt.wantBytes {
+				t.Errorf("bytes = %v, want %v", entry["bytes"], tt.wantBytes)
+			}
+		})
+	}
+}
+
+func TestMiddlewareContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewLogger(log.LevelDebug, log.WithSink(kitlog.NewJSONLogger(&buf)))
+	router := gin.New()
+	router.Use(Middleware(logger))
+	router.GET("/", func(c *gin.Context) {
+		log.FromContext(c.Request.Context()).Info("handling")
+		c.Status(http.StatusNoContent)
+	})
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("logged %d entries, want 2: %s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("decoding %q: %v", line, err)
+		}
+		if entry[log.TraceIDKey] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("entry %q lacks the trace of the request", line)
+		}
+	}
+}
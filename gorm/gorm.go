@@ -0,0 +1,122 @@
+// Package gorm provides a GORM logger writing database logs through the
+// godin logger.
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-godin/log"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// Logger implements gorm's logger.Interface. Queries are logged as debug,
+// slow queries as warning and failed ones as error, all bound to the trace
+// of their context, see log.WithTrace:
+//
+//	db, err := gorm.Open(dialector, &gorm.Config{Logger: gormlog.New(logger)})
+type Logger struct {
+	logger         log.Log
+	mode           gormlogger.LogLevel
+	slowThreshold  time.Duration
+	ignoreNotFound bool
+}
+
+// Option sets a parameter for the Logger.
+type Option func(*Logger)
+
+// SlowThreshold sets the duration from which queries are logged as slow.
+// Defaults to 200ms, zero disables it.
+func SlowThreshold(threshold time.Duration) Option {
+	return func(l *Logger) { l.slowThreshold = threshold }
+}
+
+// IgnoreRecordNotFound stops queries failing with gorm.ErrRecordNotFound
+// from being logged as errors.
+func IgnoreRecordNotFound() Option {
+	return func(l *Logger) { l.ignoreNotFound = true }
+}
+
+// New creates a Logger writing through logger. All queries are handed to the
+// Log, its level decides which are written, unless LogMode restricts them.
+func New(logger log.Log, opts ...Option) *Logger {
+	l := &Logger{
+		logger:        logger,
+		mode:          gormlogger.Info,
+		slowThreshold: 200 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// LogMode returns a copy of the Logger restricted to the given gorm level.
+func (l *Logger) LogMode(mode gormlogger.LogLevel) gormlogger.Interface {
+	c := *l
+	c.mode = mode
+	return &c
+}
+
+// Info logs a message of gorm as info.
+func (l *Logger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.mode >= gormlogger.Info {
+		l.logger.WithTrace(ctx).Info(fmt.Sprintf(msg, data...), callerHint)
+	}
+}
+
+// Warn logs a message of gorm as warning.
+func (l *Logger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.mode >= gormlogger.Warn {
+		l.logger.WithTrace(ctx).Warning(fmt.Sprintf(msg, data...), callerHint)
+	}
+}
+
+// Error logs a message of gorm as error.
+func (l *Logger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.mode >= gormlogger.Error {
+		l.logger.WithTrace(ctx).Error(fmt.Sprintf(msg, data...), callerHint)
+	}
+}
+
+// Trace logs a query with its SQL, the rows affected and the duration in
+// seconds.
+func (l *Logger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.mode <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	failed := err != nil && !(l.ignoreNotFound && errors.Is(err, gormlogger.ErrRecordNotFound))
+	slow := l.slowThreshold > 0 && elapsed > l.slowThreshold
+	tl := l.logger.WithTrace(ctx)
+	// fc renders the SQL, it's only called for queries which are logged
+	switch {
+	case failed && l.mode >= gormlogger.Error:
+		if tl.IsLevelEnabled(log.LevelError) {
+			tl.Error("query failed", append(queryFields(fc, elapsed), "err", err, callerHint)...)
+		}
+	case slow && l.mode >= gormlogger.Warn:
+		if tl.IsLevelEnabled(log.LevelWarning) {
+			tl.Warning("slow query", append(queryFields(fc, elapsed), "threshold", l.slowThreshold.Seconds(), callerHint)...)
+		}
+	case l.mode >= gormlogger.Info:
+		if tl.IsLevelEnabled(log.LevelDebug) {
+			tl.Debug("query", append(queryFields(fc, elapsed), callerHint)...)
+		}
+	}
+}
+
+// callerHint reports the code calling gorm as call site of the entries.
+var callerHint = log.CallerOutside("gorm.io/")
+
+func queryFields(fc func() (string, int64), elapsed time.Duration) []interface{} {
+	sql, rows := fc()
+	keyvals := []interface{}{"sql", sql, "duration", elapsed.Seconds()}
+	if rows >= 0 {
+		keyvals = append(keyvals, "rows", rows)
+	}
+	return keyvals
+}
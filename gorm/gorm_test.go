@@ -0,0 +1,71 @@
+package gorm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-godin/log"
+	kitlog "github.com/go-kit/kit/log"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestTrace(t *testing.T) {
+	sampled := log.ContextWithTrace(context.Background(), log.Traceparent{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true})
+	failure := errors.New("connection reset")
+	tests := []struct {
+		name      string
+		level     string
+		opts      []log.Option
+		ctx       context.Context
+		mode      gormlogger.LogLevel
+		elapsed   time.Duration
+		err       error
+		wantLevel string
+	}{
+		{name: "query", level: log.LevelDebug, mode: gormlogger.Info, wantLevel: "debug"},
+		{name: "query below the level", level: log.LevelInfo, mode: gormlogger.Info},
+		{name: "query of a sampled trace", level: log.LevelInfo, opts: []log.Option{log.WithTraceSampledDebug()}, ctx: sampled, mode: gormlogger.Info, wantLevel: "debug"},
+		{name: "slow query", level: log.LevelInfo, mode: gormlogger.Info, elapsed: time.Second, wantLevel: "warning"},
+		{name: "slow query below the level", level: log.LevelError, mode: gormlogger.Info, elapsed: time.Second},
+		{name: "failed query", level: log.LevelInfo, mode: gormlogger.Info, err: failure, wantLevel: "error"},
+		{name: "not found", level: log.LevelInfo, mode: gormlogger.Error, err: gormlogger.ErrRecordNotFound, wantLevel: "error"},
+		{name: "silent", level: log.LevelDebug, mode: gormlogger.Silent, err: failure},
+		{name: "mode below the query", level: log.LevelDebug, mode: gormlogger.Warn},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := log.NewLogger(tt.level, append([]log.Option{log.WithSink(kitlog.NewJSONLogger(&buf))}, tt.opts...)...)
+			l := New(logger).LogMode(tt.mode)
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+
+			rendered := false
+			fc := func() (string, int64) {
+				rendered = true
+				return "SELECT 1", 1
+			}
+			l.Trace(ctx, time.Now().Add(-tt.elapsed), fc, tt.err)
+
+			if tt.wantLevel == "" {
+				if buf.Len() > 0 || rendered {
+					t.Errorf("logged %s, rendered the query: %v", buf.String(), rendered)
+				}
+				return
+			}
+			var entry map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatal(err)
+			}
+			if entry["severity"] != tt.wantLevel || entry["sql"] != "SELECT 1" || entry["rows"] != float64(1) {
+				t.Errorf("entry = %v, want a %s entry of the query", entry, tt.wantLevel)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package log
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+type goOptions struct {
+	repanic bool
+}
+
+// GoOption sets a parameter for Go.
+type GoOption func(*goOptions)
+
+// Repanic makes Go panic again with the recovered value once it's logged,
+// crashing the process as an unrecovered panic would.
+func Repanic() GoOption {
+	return func(o *goOptions) { o.repanic = true }
+}
+
+// Go runs f in a new goroutine. A panic of f is recovered and logged as
+// error with the panic value and the stack trace, so it isn't lost with the
+// process:
+//
+//	logger.Go(func() { consume(ctx, queue) }, log.Repanic())
+func (l Log) Go(f func(), opts ...GoOption) {
+	var o goOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	go func() {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			l.Error("goroutine panicked", "panic", fmt.Sprint(p), StacktraceKey, string(debug.Stack()))
+			if o.repanic {
+				panic(p)
+			}
+		}()
+		f()
+	}()
+}
+
+// Go runs f in a new goroutine, logging its panic with the default logger,
+// see Log.Go.
+func Go(f func(), opts ...GoOption) {
+	std.Go(f, opts...)
+}
@@ -0,0 +1,141 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/go-godin/log"
+	stdgrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// retryAttemptKey is the metadata key the retry interceptor of
+// go-grpc-middleware stores the number of the attempt in, AttemptMetadataKey,
+// spelled as upstream.
+const retryAttemptKey = "x-retry-attempty"
+
+// callerHint reports the code calling grpc as call site of the entries,
+// which is none for the RPCs served.
+var callerHint = log.CallerOutside("google.golang.org/grpc")
+
+// UnaryClientInterceptor logs every outbound RPC attempt with the method,
+// target, code and duration in seconds, bound to the trace of the call
+// context. Retries are logged with their attempt if the interceptor is
+// chained after a retry interceptor.
+func UnaryClientInterceptor(logger log.Log, opts ...Option) stdgrpc.UnaryClientInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *stdgrpc.ClientConn, invoker stdgrpc.UnaryInvoker, callOpts ...stdgrpc.CallOption) error {
+		finish := o.call(ctx, logger, method, cc)
+		err := invoker(ctx, method, req, reply, cc, callOpts...)
+		finish(err)
+		return err
+	}
+}
+
+// StreamClientInterceptor logs outbound streams like UnaryClientInterceptor
+// does RPCs, once they end: RecvMsg returned an error or the response of a
+// stream without server streaming, Header or SendMsg failed, or the context
+// of the stream is done.
+func StreamClientInterceptor(logger log.Log, opts ...Option) stdgrpc.StreamClientInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, desc *stdgrpc.StreamDesc, cc *stdgrpc.ClientConn, method string, streamer stdgrpc.Streamer, callOpts ...stdgrpc.CallOption) (stdgrpc.ClientStream, error) {
+		finish := o.call(ctx, logger, method, cc)
+		cs, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			finish(err)
+			return nil, err
+		}
+		return newClientStream(ctx, cs, desc, finish), nil
+	}
+}
+
+// call returns the function logging an outbound RPC once it finished.
+func (o options) call(ctx context.Context, logger log.Log, method string, cc *stdgrpc.ClientConn) func(err error) {
+	begin := time.Now()
+	keyvals := []interface{}{"method", method}
+	if cc != nil {
+		keyvals = append(keyvals, "target", cc.Target())
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if attempt := md.Get(retryAttemptKey); len(attempt) > 0 {
+			keyvals = append(keyvals, "attempt", attempt[0])
+		}
+	}
+
+	return func(err error) {
+		code := status.Code(err)
+		fields := append(keyvals, "code", code.String(), "duration", time.Since(begin).Seconds())
+		if err != nil {
+			fields = append(fields, "err", err)
+		}
+		WithTrace(logger, ctx).At(o.level(method, code), "rpc call finished", append(fields, callerHint)...)
+	}
+}
+
+// clientStream logs the stream once it ends.
+type clientStream struct {
+	stdgrpc.ClientStream
+	serverStreams bool
+	once          sync.Once
+	done          chan struct{}
+	finish        func(err error)
+}
+
+func newClientStream(ctx context.Context, cs stdgrpc.ClientStream, desc *stdgrpc.StreamDesc, finish func(err error)) *clientStream {
+	s := &clientStream{
+		ClientStream:  cs,
+		serverStreams: desc.ServerStreams,
+		done:          make(chan struct{}),
+		finish:        finish,
+	}
+	// the stream is released once its context is done, even if it's never
+	// received from to the end
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.end(status.FromContextError(ctx.Err()).Err())
+		case <-s.done:
+		}
+	}()
+	return s
+}
+
+// end logs the stream the first time it's called.
+func (s *clientStream) end(err error) {
+	s.once.Do(func() {
+		close(s.done)
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+		s.finish(err)
+	})
+}
+
+func (s *clientStream) Header() (metadata.MD, error) {
+	md, err := s.ClientStream.Header()
+	if err != nil {
+		s.end(err)
+	}
+	return md, err
+}
+
+func (s *clientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	// io.EOF reports the stream ended, its status is returned by RecvMsg
+	if err != nil && !errors.Is(err, io.EOF) {
+		s.end(err)
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil || !s.serverStreams {
+		s.end(err)
+	}
+	return err
+}
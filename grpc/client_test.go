@@ -0,0 +1,226 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-godin/log"
+	kitlog "github.com/go-kit/kit/log"
+	stdgrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// syncBuffer collects the JSON lines written by a Log from several
+// goroutines.
+type syncBuffer struct {
+	mtx sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) entries(t *testing.T) []map[string]interface{} {
+	t.Helper()
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("decoding %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func newBufferLogger() (log.Log, *syncBuffer) {
+	buf := &syncBuffer{}
+	return log.NewLogger(log.LevelDebug, log.WithSink(kitlog.NewJSONLogger(buf))), buf
+}
+
+func TestUnaryClientInterceptorAttempt(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  func(ctx context.Context) context.Context
+		want interface{}
+	}{
+		{
+			name: "first attempt",
+			ctx:  func(ctx context.Context) context.Context { return ctx },
+		},
+		{
+			// as the retry interceptor of go-grpc-middleware sets it
+			name: "retry",
+			ctx: func(ctx context.Context) context.Context {
+				md, _ := metadata.FromOutgoingContext(ctx)
+				md = md.Copy()
+				md.Set(retryAttemptKey, "2")
+				return metadata.NewOutgoingContext(ctx, md)
+			},
+			want: "2",
+		},
+		{
+			name: "appended",
+			ctx: func(ctx context.Context) context.Context {
+				return metadata.AppendToOutgoingContext(ctx, "x-retry-attempty", "1")
+			},
+			want: "1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, buf := newBufferLogger()
+			interceptor := UnaryClientInterceptor(logger)
+			ctx := tt.ctx(metadata.NewOutgoingContext(context.Background(), metadata.Pairs("user", "1")))
+			invoker := func(context.Context, string, interface{}, interface{}, *stdgrpc.ClientConn, ...stdgrpc.CallOption) error {
+				return nil
+			}
+			if err := interceptor(ctx, "/svc/Method", nil, nil, nil, invoker); err != nil {
+				t.Fatal(err)
+			}
+
+			entries := buf.entries(t)
+			if len(entries) != 1 {
+				t.Fatalf("entries = %v, want one", entries)
+			}
+			if attempt := entries[0]["attempt"]; attempt != tt.want {
+				t.Errorf("attempt = %v, want %v", attempt, tt.want)
+			}
+		})
+	}
+}
+
+// fakeStream returns the errors set for the methods of a ClientStream.
+type fakeStream struct {
+	stdgrpc.ClientStream
+	headerErr error
+	sendErr   error
+	recvErrs  []error
+}
+
+func (s *fakeStream) Header() (metadata.MD, error) { return nil, s.headerErr }
+
+func (s *fakeStream) SendMsg(interface{}) error { return s.sendErr }
+
+func (s *fakeStream) CloseSend() error { return nil }
+
+func (s *fakeStream) RecvMsg(interface{}) error {
+	if len(s.recvErrs) == 0 {
+		return nil
+	}
+	err := s.recvErrs[0]
+	s.recvErrs = s.recvErrs[1:]
+	return err
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+	tests := []struct {
+		name          string
+		serverStreams bool
+		stream        *fakeStream
+		use           func(cs stdgrpc.ClientStream, cancel func())
+		want          codes.Code
+	}{
+		{
+			name:          "server stream received to the end",
+			serverStreams: true,
+			stream:        &fakeStream{recvErrs: []error{nil, nil, io.EOF}},
+			use: func(cs stdgrpc.ClientStream, _ func()) {
+				for cs.RecvMsg(nil) == nil {
+				}
+			},
+			want: codes.OK,
+		},
+		{
+			name:   "client stream closed and received",
+			stream: &fakeStream{},
+			use: func(cs stdgrpc.ClientStream, _ func()) {
+				_ = cs.SendMsg(nil)
+				_ = cs.CloseSend()
+				_ = cs.RecvMsg(nil)
+			},
+			want: codes.OK,
+		},
+		{
+			name:          "receive failed",
+			serverStreams: true,
+			stream:        &fakeStream{recvErrs: []error{nil, unavailable}},
+			use: func(cs stdgrpc.ClientStream, _ func()) {
+				for cs.RecvMsg(nil) == nil {
+				}
+			},
+			want: codes.Unavailable,
+		},
+		{
+			name:          "send failed",
+			serverStreams: true,
+			stream:        &fakeStream{sendErr: unavailable},
+			use:           func(cs stdgrpc.ClientStream, _ func()) { _ = cs.SendMsg(nil) },
+			want:          codes.Unavailable,
+		},
+		{
+			name:          "header failed",
+			serverStreams: true,
+			stream:        &fakeStream{headerErr: unavailable},
+			use:           func(cs stdgrpc.ClientStream, _ func()) { _, _ = cs.Header() },
+			want:          codes.Unavailable,
+		},
+		{
+			name:          "context canceled",
+			serverStreams: true,
+			stream:        &fakeStream{},
+			use:           func(_ stdgrpc.ClientStream, cancel func()) { cancel() },
+			want:          codes.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, buf := newBufferLogger()
+			interceptor := StreamClientInterceptor(logger)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			streamer := func(context.Context, *stdgrpc.StreamDesc, *stdgrpc.ClientConn, string, ...stdgrpc.CallOption) (stdgrpc.ClientStream, error) {
+				return tt.stream, nil
+			}
+			cs, err := interceptor(ctx, &stdgrpc.StreamDesc{ServerStreams: tt.serverStreams, ClientStreams: true}, nil, "/svc/Stream", streamer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.use(cs, cancel)
+
+			var entries []map[string]interface{}
+			for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+				if entries = buf.entries(t); len(entries) > 0 {
+					break
+				}
+			}
+			// further calls and the end of the context don't log again
+			_ = cs.RecvMsg(nil)
+			cancel()
+			time.Sleep(10 * time.Millisecond)
+			if entries = buf.entries(t); len(entries) != 1 {
+				t.Fatalf("entries = %v, want one", entries)
+			}
+			if code := entries[0]["code"]; code != tt.want.String() {
+				t.Errorf("code = %v, want %s", code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+// Package grpc integrates the godin logger with gRPC services.
+package grpc
+
+import (
+	"context"
+	"net/http"
+	"net/textproto"
+
+	"github.com/go-godin/log"
+	"google.golang.org/grpc/metadata"
+)
+
+// ContextWithTrace returns a copy of ctx carrying the trace context of the
+// incoming metadata, taken from the b3 or traceparent headers as by
+// log.TraceFromHeader, for services propagating traces as plain metadata.
+// Spans of ctx take precedence in WithTrace.
+func ContextWithTrace(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	tp, ok := log.TraceFromHeader(header(md))
+	if !ok {
+		return ctx
+	}
+	return log.ContextWithTrace(ctx, tp)
+}
+
+// WithTrace binds l to the span of ctx or the trace context of its incoming
+// metadata, see ContextWithTrace.
+func WithTrace(l log.Log, ctx context.Context) log.Log {
+	return l.WithTrace(ContextWithTrace(ctx))
+}
+
+// header converts metadata with its lower case keys to an http.Header.
+func header(md metadata.MD) http.Header {
+	h := make(http.Header, len(md))
+	for key, values := range md {
+		h[textproto.CanonicalMIMEHeaderKey(key)] = values
+	}
+	return h
+}
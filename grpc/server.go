@@ -0,0 +1,129 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-godin/log"
+	"github.com/go-godin/log/level"
+	stdgrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type options struct {
+	methodLevels map[string]string
+	codeLevel    func(code codes.Code) string
+}
+
+// Option sets a parameter for the interceptors.
+type Option func(*options)
+
+// MethodLevel sets the level of the finish entries of the method, e.g.
+// MethodLevel("/grpc.health.v1.Health/Check", log.LevelDebug) to hide health
+// checks. It overrides the level selected by CodeLevel.
+func MethodLevel(method, lvl string) Option {
+	return func(o *options) { o.methodLevels[method] = lvl }
+}
+
+// CodeLevel sets the function selecting the level of finish entries by the
+// code of the RPC. Defaults to DefaultCodeLevel.
+func CodeLevel(codeLevel func(code codes.Code) string) Option {
+	return func(o *options) { o.codeLevel = codeLevel }
+}
+
+// DefaultCodeLevel logs successful RPCs as info, RPCs failing due to the
+// caller, e.g. with InvalidArgument or NotFound, as warning and all others
+// as error.
+func DefaultCodeLevel(code codes.Code) string {
+	switch code {
+	case codes.OK:
+		return log.LevelInfo
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated, codes.FailedPrecondition, codes.OutOfRange:
+		return log.LevelWarning
+	default:
+		return log.LevelError
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		methodLevels: make(map[string]string),
+		codeLevel:    DefaultCodeLevel,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// level returns the level of the finish entry of an RPC.
+func (o options) level(method string, code codes.Code) level.Value {
+	name, ok := o.methodLevels[method]
+	if !ok {
+		name = o.codeLevel(code)
+	}
+	if lvl, err := log.ParseLevel(name); err == nil {
+		name = string(lvl)
+	}
+	if v, ok := level.Parse(name); ok {
+		return v
+	}
+	return level.InfoValue()
+}
+
+// UnaryServerInterceptor logs the start of RPCs as debug and their finish
+// with the method, code, duration in seconds and peer. The handler receives
+// a Log bound to the trace of the RPC in its context, see log.FromContext
+// and WithTrace.
+func UnaryServerInterceptor(logger log.Log, opts ...Option) stdgrpc.UnaryServerInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, req interface{}, info *stdgrpc.UnaryServerInfo, handler stdgrpc.UnaryHandler) (interface{}, error) {
+		ctx, finish := o.start(ctx, logger, info.FullMethod)
+		resp, err := handler(ctx, req)
+		finish(err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor logs streams like UnaryServerInterceptor does RPCs.
+func StreamServerInterceptor(logger log.Log, opts ...Option) stdgrpc.StreamServerInterceptor {
+	o := newOptions(opts)
+	return func(srv interface{}, ss stdgrpc.ServerStream, info *stdgrpc.StreamServerInfo, handler stdgrpc.StreamHandler) error {
+		ctx, finish := o.start(ss.Context(), logger, info.FullMethod)
+		err := handler(srv, serverStream{ServerStream: ss, ctx: ctx})
+		finish(err)
+		return err
+	}
+}
+
+// start logs the start of an RPC and returns its context carrying the
+// request scoped Log, and the function logging its finish.
+func (o options) start(ctx context.Context, logger log.Log, method string) (context.Context, func(err error)) {
+	begin := time.Now()
+	l := WithTrace(logger, ctx).With("method", method)
+	var keyvals []interface{}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		keyvals = append(keyvals, "peer", p.Addr.String())
+	}
+	l.Debug("rpc started", append(keyvals, callerHint)...)
+
+	return log.NewContext(ctx, l), func(err error) {
+		code := status.Code(err)
+		fields := append(keyvals, "code", code.String(), "duration", time.Since(begin).Seconds())
+		if err != nil {
+			fields = append(fields, "err", err)
+		}
+		l.At(o.level(method, code), "rpc finished", append(fields, callerHint)...)
+	}
+}
+
+// serverStream replaces the context of a stream.
+type serverStream struct {
+	stdgrpc.ServerStream
+	ctx context.Context
+}
+
+func (s serverStream) Context() context.Context { return s.ctx }
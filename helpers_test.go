@@ -0,0 +1,50 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// outputBuffer collects the JSON lines written by a Log.
+type outputBuffer struct {
+	mtx sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *outputBuffer) String() string {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.buf.String()
+}
+
+// entries decodes the lines written so far.
+func (b *outputBuffer) entries(t testing.TB) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("decoding %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// newBufferLogger returns a Log writing JSON lines to the returned buffer.
+func newBufferLogger(level string, opts ...Option) (Log, *outputBuffer) {
+	out := &outputBuffer{}
+	return NewLogger(level, append([]Option{WithSink(newJSONSink(out))}, opts...)...), out
+}
@@ -0,0 +1,193 @@
+// Package httplog logs the requests of net/http servers with the godin
+// logger.
+package httplog
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-godin/log"
+	"github.com/go-godin/log/level"
+)
+
+type options struct {
+	levels  [6]string
+	exclude map[string]bool
+	message string
+}
+
+// Option sets a parameter for the Middleware and the Transport.
+type Option func(*options)
+
+// Level sets the level of requests answered with a status of the given
+// class, e.g. Level(4, log.LevelInfo) for 4xx. Defaults to info for 1xx to
+// 3xx, warning for 4xx and error for 5xx.
+func Level(class int, lvl string) Option {
+	return func(o *options) {
+		if class >= 1 && class <= 5 {
+			o.levels[class] = lvl
+		}
+	}
+}
+
+// Exclude skips requests to the given paths, e.g. "/healthz".
+func Exclude(paths ...string) Option {
+	return func(o *options) {
+		for _, path := range paths {
+			o.exclude[path] = true
+		}
+	}
+}
+
+// Message sets the message of the entries. Defaults to "request".
+func Message(message string) Option {
+	return func(o *options) { o.message = message }
+}
+
+// Logger logs served requests. It's used by Middleware and the middleware
+// of web frameworks, e.g. ginlog.
+type Logger struct {
+	logger log.Log
+	options
+}
+
+// New creates a Logger writing through logger.
+func New(logger log.Log, opts ...Option) *Logger {
+	return &Logger{logger: logger, options: newOptions("request", opts)}
+}
+
+func newOptions(message string, opts []Option) options {
+	o := options{
+		levels:  [6]string{log.LevelInfo, log.LevelInfo, log.LevelInfo, log.LevelInfo, log.LevelWarning, log.LevelError},
+		exclude: make(map[string]bool),
+		message: message,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// callerHint reports the code calling net/http as call site of the entries,
+// which is none for the requests served.
+var callerHint = log.CallerOutside("net/http.")
+
+// Begin returns the Log of the request, bound to its trace as by
+// log.WithRequest, and whether the request is logged.
+func (l *Logger) Begin(r *http.Request) (log.Log, bool) {
+	if l.exclude[r.URL.Path] {
+		return l.logger, false
+	}
+	return l.logger.WithRequest(r), true
+}
+
+// End logs the request served since begin with the Log returned by Begin.
+func (l *Logger) End(rl log.Log, r *http.Request, status, bytes int, begin time.Time) {
+	rl.At(l.level(status), l.message,
+		"method", r.Method,
+		"path", r.URL.Path,
+		"status", status,
+		"bytes", bytes,
+		"duration", time.Since(begin).Seconds(),
+		"remote_addr", r.RemoteAddr,
+		callerHint,
+	)
+}
+
+// Middleware logs one entry per request with its method, path, status,
+// bytes written, duration in seconds and the remote address. The entry
+// carries the trace fields of the request, see log.WithRequest. The Log
+// passed on in the request context, see log.FromContext, carries them too.
+func Middleware(logger log.Log, opts ...Option) func(http.Handler) http.Handler {
+	l := New(logger, opts...)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			begin := time.Now()
+			rl, logged := l.Begin(r)
+			if !logged {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, r.WithContext(log.NewContext(r.Context(), rl)))
+			l.End(rl, r, rw.status, rw.bytes, begin)
+		})
+	}
+}
+
+// level returns the level of responses with status.
+func (o options) level(status int) level.Value {
+	class := status / 100
+	if class < 1 || class > 5 {
+		class = 5
+	}
+	lvl, ok := level.Parse(canonical(o.levels[class]))
+	if !ok {
+		return level.InfoValue()
+	}
+	return lvl
+}
+
+// canonical returns the name of the level, accepting aliases as ParseLevel.
+func canonical(name string) string {
+	if lvl, err := log.ParseLevel(name); err == nil {
+		return string(lvl)
+	}
+	return strings.ToLower(name)
+}
+
+// responseWriter records the status and the amount of bytes written.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Flush implements http.Flusher, if the wrapped writer does.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, if the wrapped writer does, e.g. for
+// WebSocket upgrades. Hijacked requests are logged with status 101 unless a
+// status was written before.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil && !w.wroteHeader {
+		w.status = http.StatusSwitchingProtocols
+		w.wroteHeader = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,172 @@
+package httplog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-godin/log"
+	kitlog "github.com/go-kit/kit/log"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		path      string
+		handler   http.HandlerFunc
+		wantLevel string // empty if the request isn't logged
+		status    float64
+		bytes     float64
+	}{
+		{
+			name:      "ok",
+			path:      "/",
+			handler:   func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("hello")) },
+			wantLevel: "info",
+			status:    200,
+			bytes:     5,
+		},
+		{
+			name:      "client error",
+			path:      "/",
+			handler:   func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) },
+			wantLevel: "warning",
+			status:    404,
+		},
+		{
+			name:      "server error",
+			path:      "/",
+			handler:   func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusBadGateway) },
+			wantLevel: "error",
+			status:    502,
+		},
+		{
+			name:      "custom level alias",
+			opts:      []Option{Level(4, "warn")},
+			path:      "/",
+			handler:   func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusConflict) },
+			wantLevel: "warning",
+			status:    409,
+		},
+		{
+			name:      "custom level",
+			opts:      []Option{Level(4, log.LevelInfo)},
+			path:      "/",
+			handler:   func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) },
+			wantLevel: "info",
+			status:    404,
+		},
+		{
+			name: "first status wins",
+			path: "/",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			wantLevel: "info",
+			status:    201,
+		},
+		{
+			name:    "excluded",
+			opts:    []Option{Exclude("/healthz")},
+			path:    "/healthz",
+			handler: func(w http.ResponseWriter, r *http.Request) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := log.NewLogger(log.LevelDebug, log.WithSink(kitlog.NewJSONLogger(&buf)))
+			h := Middleware(logger, tt.opts...)(tt.handler)
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if tt.wantLevel == "" {
+				if buf.Len() != 0 {
+					t.Errorf("logged %s, want nothing", buf.String())
+				}
+				return
+			}
+			var entry map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("decoding %q: %v", buf.String(), err)
+			}
+			if entry["severity"] != tt.wantLevel {
+				t.Errorf("severity = %v, want %s", entry["severity"], tt.wantLevel)
+			}
+			if entry["status"] != tt.status || entry["bytes"] != tt.bytes {
+				t.Errorf("status, bytes = %v, %v, want %v, %v", entry["status"], entry["bytes"], tt.status, tt.bytes)
+			}
+			if entry["path"] != tt.path || entry["method"] != http.MethodGet {
+				t.Errorf("method, path = %v, %v", entry["method"], entry["path"])
+			}
+		})
+	}
+}
+
+// hijackRecorder is a ResponseRecorder supporting http.Hijacker.
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	client, server := net.Pipe()
+	_ = client.Close()
+	return server, bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)), nil
+}
+
+func TestMiddlewareHijack(t *testing.T) {
+	tests := []struct {
+		name       string
+		hijacker   bool
+		wantErr    bool
+		wantStatus float64
+	}{
+		{name: "hijacker", hijacker: true, wantStatus: http.StatusSwitchingProtocols},
+		{name: "no hijacker", wantErr: true, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := log.NewLogger(log.LevelDebug, log.WithSink(kitlog.NewJSONLogger(&buf)))
+			var hijackErr error
+			h := Middleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hj, ok := w.(http.Hijacker)
+				if !ok {
+					t.Fatal("the response writer hides http.Hijacker")
+				}
+				var conn net.Conn
+				if conn, _, hijackErr = hj.Hijack(); hijackErr == nil {
+					_ = conn.Close()
+				}
+			}))
+
+			var w http.ResponseWriter = httptest.NewRecorder()
+			recorder := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+			if tt.hijacker {
+				w = recorder
+			}
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ws", nil))
+
+			if (hijackErr != nil) != tt.wantErr {
+				t.Fatalf("Hijack() = %v, want error %v", hijackErr, tt.wantErr)
+			}
+			if recorder.hijacked != tt.hijacker {
+				t.Errorf("hijacked = %v, want %v", recorder.hijacked, tt.hijacker)
+			}
+			var entry map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("decoding %q: %v", buf.String(), err)
+			}
+			if entry["status"] != tt.wantStatus {
+				t.Errorf("status = %v, want %v", entry["status"], tt.wantStatus)
+			}
+		})
+	}
+}
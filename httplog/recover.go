@@ -0,0 +1,47 @@
+package httplog
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/go-godin/log"
+)
+
+// Recover returns middleware recovering panics of the handler. The panic is
+// logged as error with its value, the stack trace and the method, path and
+// remote address of the request, bound to its trace as by log.WithRequest.
+// The client receives a 500 unless the handler already wrote the header.
+// http.ErrAbortHandler is passed on, as net/http expects it.
+//
+// Chain it inside Middleware to have the request logged with status 500:
+//
+//	handler = httplog.Middleware(logger)(httplog.Recover(logger)(handler))
+func Recover(logger log.Log) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				logger.WithRequest(r).Error("panic recovered",
+					"panic", fmt.Sprint(p),
+					log.StacktraceKey, string(debug.Stack()),
+					"method", r.Method,
+					"path", r.URL.Path,
+					"remote_addr", r.RemoteAddr,
+					callerHint,
+				)
+				if !rw.wroteHeader {
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				}
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package httplog
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-godin/log"
+	"github.com/go-godin/log/correlation"
+)
+
+type attemptKey struct{}
+
+// ContextWithAttempt returns a copy of ctx carrying the number of the
+// attempt of an outbound request, which Transport logs. Retry loops set it
+// for every attempt.
+func ContextWithAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// Transport is an http.RoundTripper logging outbound requests with their
+// method, URL, status, duration in seconds and attempt, see
+// ContextWithAttempt. Entries carry the trace and context fields of the
+// request context, see log.WithTrace. The trace context stored with
+// log.ContextWithTrace and the correlation ID are propagated to the called
+// service unless the request sets their headers.
+//
+// The options of Middleware apply, requests failing without a response are
+// logged as error. The message defaults to "outbound request".
+//
+//	client := &http.Client{Transport: httplog.NewTransport(http.DefaultTransport, logger)}
+type Transport struct {
+	base   http.RoundTripper
+	logger log.Log
+	options
+}
+
+// NewTransport creates a Transport sending requests through base, or
+// http.DefaultTransport if nil.
+func NewTransport(base http.RoundTripper, logger log.Log, opts ...Option) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{base: base, logger: logger, options: newOptions("outbound request", opts)}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx := r.Context()
+	r = propagate(r)
+	if t.exclude[r.URL.Path] {
+		return t.base.RoundTrip(r)
+	}
+
+	begin := time.Now()
+	resp, err := t.base.RoundTrip(r)
+
+	keyvals := []interface{}{
+		"method", r.Method,
+		"url", r.URL.Redacted(),
+		"duration", time.Since(begin).Seconds(),
+	}
+	if attempt, ok := ctx.Value(attemptKey{}).(int); ok {
+		keyvals = append(keyvals, "attempt", attempt)
+	}
+	l := t.logger.WithTrace(ctx)
+	if err != nil {
+		l.Error(t.message, append(keyvals, "err", err, callerHint)...)
+		return resp, err
+	}
+	l.At(t.level(resp.StatusCode), t.message, append(keyvals, "status", resp.StatusCode, callerHint)...)
+	return resp, nil
+}
+
+// propagate returns a clone of r carrying the trace context and correlation
+// ID of its context in headers it doesn't set, or r if there are none.
+func propagate(r *http.Request) *http.Request {
+	ctx := r.Context()
+	var headers [][2]string
+	if tp, ok := log.TraceparentFromContext(ctx); ok && r.Header.Get(log.TraceparentHeader) == "" {
+		headers = append(headers, [2]string{log.TraceparentHeader, tp.String()})
+	}
+	if id, ok := correlation.FromContext(ctx); ok && r.Header.Get(correlation.Header) == "" {
+		headers = append(headers, [2]string{correlation.Header, id})
+	}
+	if len(headers) == 0 {
+		return r
+	}
+
+	// a RoundTripper must not modify the request
+	r = r.Clone(ctx)
+	for _, h := range headers {
+		r.Header.Set(h[0], h[1])
+	}
+	return r
+}
@@ -0,0 +1,171 @@
+// Package batch buffers the entries of network sinks and hands them to the
+// sink in batches bounded by the amount of entries, their size and the time
+// the oldest entry waits.
+package batch
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config holds the limits of a Batcher.
+type Config struct {
+	// MaxEntries is the maximum amount of entries of a batch, zero means
+	// no limit.
+	MaxEntries int
+	// MaxBytes is the maximum size of a batch, zero means no limit. A
+	// single larger entry is sent on its own.
+	MaxBytes int
+	// MaxLatency is the maximum time an entry is buffered before it's sent.
+	// With zero, batches are only sent once they're full, on Flush and on
+	// Close.
+	MaxLatency time.Duration
+	// QueueSize is the amount of entries buffered while a batch is sent.
+	QueueSize int
+	// DropWhenFull makes Add drop entries instead of blocking once the
+	// queue is full.
+	DropWhenFull bool
+
+	// ErrQueueFull is returned by Add for dropped entries.
+	ErrQueueFull error
+	// ErrClosed is returned by Add after Close.
+	ErrClosed error
+}
+
+// Batcher collects entries in a background worker and calls send with every
+// batch. send is only called by the worker, batches are sent one at a time.
+type Batcher[T any] struct {
+	cfg  Config
+	size func(T) int
+	send func([]T)
+
+	queue   chan T
+	flushes chan chan struct{}
+	done    chan struct{}
+	mtx     sync.RWMutex
+	closed  bool
+	dropped atomic.Uint64
+}
+
+// New starts a Batcher handing batches to send. size returns the size of an
+// entry in bytes.
+func New[T any](cfg Config, size func(T) int, send func([]T)) *Batcher[T] {
+	b := &Batcher[T]{
+		cfg:     cfg,
+		size:    size,
+		send:    send,
+		queue:   make(chan T, cfg.QueueSize),
+		flushes: make(chan chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Add enqueues the entry for the next batch.
+func (b *Batcher[T]) Add(entry T) error {
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	if b.closed {
+		return b.cfg.ErrClosed
+	}
+	if b.cfg.DropWhenFull {
+		select {
+		case b.queue <- entry:
+			return nil
+		default:
+			b.dropped.Add(1)
+			return b.cfg.ErrQueueFull
+		}
+	}
+	b.queue <- entry
+	return nil
+}
+
+// Dropped returns the amount of entries dropped because the queue was full.
+func (b *Batcher[T]) Dropped() uint64 {
+	return b.dropped.Load()
+}
+
+// Flush sends all entries enqueued so far and blocks until they're sent.
+func (b *Batcher[T]) Flush() {
+	b.mtx.RLock()
+	if b.closed {
+		b.mtx.RUnlock()
+		return
+	}
+	ack := make(chan struct{})
+	b.flushes <- ack
+	b.mtx.RUnlock()
+	<-ack
+}
+
+// Close stops accepting new entries, sends the remaining ones and stops the
+// worker. It returns once the worker stopped, also when called again.
+func (b *Batcher[T]) Close() {
+	b.mtx.Lock()
+	if b.closed {
+		b.mtx.Unlock()
+		<-b.done
+		return
+	}
+	b.closed = true
+	close(b.queue)
+	b.mtx.Unlock()
+
+	<-b.done
+}
+
+func (b *Batcher[T]) run() {
+	defer close(b.done)
+
+	// a nil channel never delivers, so there's no latency flush without
+	// MaxLatency
+	var tick <-chan time.Time
+	if b.cfg.MaxLatency > 0 {
+		ticker := time.NewTicker(b.cfg.MaxLatency)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	var batch []T
+	size := 0
+	flush := func() {
+		if len(batch) > 0 {
+			b.send(batch)
+		}
+		batch, size = nil, 0
+	}
+	add := func(entry T) {
+		n := b.size(entry)
+		limited := b.cfg.MaxBytes > 0
+		if limited && size+n > b.cfg.MaxBytes && len(batch) > 0 {
+			flush()
+		}
+		batch = append(batch, entry)
+		size += n
+		if b.cfg.MaxEntries > 0 && len(batch) >= b.cfg.MaxEntries || limited && size >= b.cfg.MaxBytes {
+			flush()
+		}
+	}
+
+	for {
+		select {
+		case entry, ok := <-b.queue:
+			if !ok {
+				flush()
+				return
+			}
+			add(entry)
+		case ack := <-b.flushes:
+			for n := len(b.queue); n > 0; n-- {
+				add(<-b.queue)
+			}
+			flush()
+			close(ack)
+		case <-tick:
+			flush()
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package batch
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	errQueueFull = errors.New("queue full")
+	errClosed    = errors.New("closed")
+)
+
+// recorder collects the batches handed to send.
+type recorder struct {
+	mtx     sync.Mutex
+	batches [][]string
+}
+
+func (r *recorder) send(batch []string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.batches = append(r.batches, append([]string(nil), batch...))
+}
+
+func (r *recorder) sent() [][]string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.batches
+}
+
+func TestBatcher(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		entries []string
+		flush   bool
+		wait    time.Duration
+		want    [][]string
+	}{
+		{
+			name:    "max entries",
+			cfg:     Config{MaxEntries: 2, MaxBytes: 100, MaxLatency: time.Hour},
+			entries: []string{"a", "b", "c", "d", "e"},
+			want:    [][]string{{"a", "b"}, {"c", "d"}},
+		},
+		{
+			name:    "max bytes",
+			cfg:     Config{MaxEntries: 10, MaxBytes: 4, MaxLatency: time.Hour},
+			entries: []string{"aa", "bb", "ccc", "dd"},
+			want:    [][]string{{"aa", "bb"}, {"ccc"}},
+		},
+		{
+			name:    "entry larger than max bytes",
+			cfg:     Config{MaxEntries: 10, MaxBytes: 4, MaxLatency: time.Hour},
+			entries: []string{"a", "bbbbbb", "c"},
+			want:    [][]string{{"a"}, {"bbbbbb"}},
+		},
+		{
+			name:    "no limits",
+			cfg:     Config{},
+			entries: []string{"a", "b", "c"},
+			wait:    20 * time.Millisecond,
+		},
+		{
+			name:    "flush without limits",
+			cfg:     Config{},
+			entries: []string{"a", "b", "c"},
+			flush:   true,
+			want:    [][]string{{"a", "b", "c"}},
+		},
+		{
+			name:    "max latency",
+			cfg:     Config{MaxEntries: 10, MaxLatency: time.Millisecond},
+			entries: []string{"a", "b"},
+			wait:    50 * time.Millisecond,
+			want:    [][]string{{"a", "b"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rec recorder
+			tt.cfg.QueueSize = 10
+			b := New(tt.cfg, func(s string) int { return len(s) }, rec.send)
+			defer b.Close()
+			for _, entry := range tt.entries {
+				if err := b.Add(entry); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.flush {
+				b.Flush()
+			}
+			time.Sleep(tt.wait)
+			if tt.wait == 0 && !tt.flush {
+				// let the worker take the entries off the queue
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			if got := rec.sent(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("batches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatcherClose(t *testing.T) {
+	var rec recorder
+	b := New(Config{ErrClosed: errClosed, QueueSize: 10}, func(string) int { return 0 }, rec.send)
+	_ = b.Add("a")
+	b.Close()
+	b.Close()
+	b.Flush()
+
+	if got, want := rec.sent(), [][]string{{"a"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("batches = %v, want %v", got, want)
+	}
+	if err := b.Add("b"); err != errClosed {
+		t.Errorf("Add after Close returned %v, want %v", err, errClosed)
+	}
+}
+
+func TestBatcherDropWhenFull(t *testing.T) {
+	release := make(chan struct{})
+	sending := make(chan struct{}, 1)
+	b := New(Config{MaxEntries: 1, QueueSize: 1, DropWhenFull: true, ErrQueueFull: errQueueFull},
+		func(string) int { return 0 },
+		func([]string) {
+			select {
+			case sending <- struct{}{}:
+			default:
+			}
+			<-release
+		})
+	defer b.Close()
+
+	_ = b.Add("sent")
+	<-sending
+	if err := b.Add("queued"); err != nil {
+		t.Fatalf("Add returned %v with room in the queue", err)
+	}
+	if err := b.Add("dropped"); err != errQueueFull {
+		t.Errorf("Add returned %v, want %v", err, errQueueFull)
+	}
+	if n := b.Dropped(); n != 1 {
+		t.Errorf("Dropped() = %d, want 1", n)
+	}
+	close(release)
+}
+
+func TestOptions(t *testing.T) {
+	cfg := Config{MaxEntries: 500, QueueSize: 10000, ErrClosed: errClosed}
+	for _, opt := range []Option{MaxEntries(10), MaxBytes(1 << 10), MaxLatency(0), QueueSize(1), DropWhenFull()} {
+		opt(&cfg)
+	}
+	want := Config{MaxEntries: 10, MaxBytes: 1 << 10, QueueSize: 1, DropWhenFull: true, ErrClosed: errClosed}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("config = %+v, want %+v", cfg, want)
+	}
+}
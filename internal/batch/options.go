@@ -0,0 +1,33 @@
+package batch
+
+import "time"
+
+// Option sets a parameter of a Config. The sinks wrap them in their own
+// options, so batching is configured the same way for all of them.
+type Option func(*Config)
+
+// MaxEntries sets the maximum amount of entries of a batch.
+func MaxEntries(entries int) Option {
+	return func(c *Config) { c.MaxEntries = entries }
+}
+
+// MaxBytes sets the maximum size of a batch.
+func MaxBytes(bytes int) Option {
+	return func(c *Config) { c.MaxBytes = bytes }
+}
+
+// MaxLatency sets the maximum time an entry is buffered before it's sent.
+func MaxLatency(latency time.Duration) Option {
+	return func(c *Config) { c.MaxLatency = latency }
+}
+
+// QueueSize sets the amount of entries buffered while a batch is sent.
+func QueueSize(size int) Option {
+	return func(c *Config) { c.QueueSize = size }
+}
+
+// DropWhenFull makes Add drop entries instead of blocking once the queue is
+// full.
+func DropWhenFull() Option {
+	return func(c *Config) { c.DropWhenFull = true }
+}
@@ -0,0 +1,109 @@
+// Package kv contains helpers shared by the sinks to work with the key-value
+// pairs handed to a go-kit logger.
+package kv
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/go-kit/kit/log"
+)
+
+// MessageKey is the key of the message of an entry, log.MessageKey of the
+// godin logger.
+const MessageKey = "message"
+
+// Map converts keyvals into a map the same way go-kit's JSON logger does, so
+// sinks produce documents identical to the default stdout output.
+func Map(keyvals []interface{}) map[string]interface{} {
+	n := (len(keyvals) + 1) / 2 // +1 to handle case when len is odd
+	m := make(map[string]interface{}, n)
+	for i := 0; i < len(keyvals); i += 2 {
+		k := keyvals[i]
+		var v interface{} = log.ErrMissingValue
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		merge(m, k, v)
+	}
+	return m
+}
+
+// Key returns the string representation of a key.
+func Key(k interface{}) string {
+	switch x := k.(type) {
+	case string:
+		return x
+	case fmt.Stringer:
+		return safeString(x)
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+// String returns the string representation of a value, resolving errors and
+// fmt.Stringer like the JSON encoding does.
+func String(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case error:
+		if s, ok := safeError(x).(string); ok {
+			return s
+		}
+		return ""
+	case fmt.Stringer:
+		return safeString(x)
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+func merge(dst map[string]interface{}, k, v interface{}) {
+	dst[Key(k)] = Value(v)
+}
+
+// Value returns the value as it's JSON encoded: errors and fmt.Stringer are
+// converted to strings, unless they implement json.Marshaler or
+// encoding.TextMarshaler, which json.Marshal handles.
+func Value(v interface{}) interface{} {
+	switch x := v.(type) {
+	case json.Marshaler:
+	case encoding.TextMarshaler:
+	case error:
+		return safeError(x)
+	case fmt.Stringer:
+		return safeString(x)
+	}
+	return v
+}
+
+func safeString(str fmt.Stringer) (s string) {
+	defer func() {
+		if panicVal := recover(); panicVal != nil {
+			if v := reflect.ValueOf(str); v.Kind() == reflect.Ptr && v.IsNil() {
+				s = "NULL"
+			} else {
+				panic(panicVal)
+			}
+		}
+	}()
+	s = str.String()
+	return
+}
+
+func safeError(err error) (s interface{}) {
+	defer func() {
+		if panicVal := recover(); panicVal != nil {
+			if v := reflect.ValueOf(err); v.Kind() == reflect.Ptr && v.IsNil() {
+				s = nil
+			} else {
+				panic(panicVal)
+			}
+		}
+	}()
+	s = err.Error()
+	return
+}
@@ -0,0 +1,98 @@
+package kv
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+type stringer struct{ s string }
+
+func (s *stringer) String() string { return s.s }
+
+type failure struct{ msg string }
+
+func (f *failure) Error() string { return f.msg }
+
+type key int
+
+func (key) String() string { return "custom" }
+
+func TestMap(t *testing.T) {
+	var nilStringer *stringer
+	var nilError *failure
+	tests := []struct {
+		name    string
+		keyvals []interface{}
+		want    map[string]interface{}
+	}{
+		{"empty", nil, map[string]interface{}{}},
+		{"pairs", []interface{}{"a", 1, "b", "x"}, map[string]interface{}{"a": 1, "b": "x"}},
+		{"missing value", []interface{}{"a"}, map[string]interface{}{"a": log.ErrMissingValue.Error()}},
+		{"stringer key", []interface{}{key(1), 1}, map[string]interface{}{"custom": 1}},
+		{"other key", []interface{}{42, 1}, map[string]interface{}{"42": 1}},
+		{"error value", []interface{}{"err", errors.New("boom")}, map[string]interface{}{"err": "boom"}},
+		{"nil error value", []interface{}{"err", nilError}, map[string]interface{}{"err": nil}},
+		{"stringer value", []interface{}{"s", &stringer{"x"}}, map[string]interface{}{"s": "x"}},
+		{"nil stringer value", []interface{}{"s", nilStringer}, map[string]interface{}{"s": "NULL"}},
+		{"last key wins", []interface{}{"a", 1, "a", 2}, map[string]interface{}{"a": 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Map(tt.keyvals); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Map() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValue(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	ip := net.ParseIP("10.0.0.1")
+	tests := []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{"plain", 42, 42},
+		{"error", errors.New("boom"), "boom"},
+		{"stringer", &stringer{"x"}, "x"},
+		// json.Marshaler and encoding.TextMarshaler are left to json.Marshal
+		{"marshaler", ts, ts},
+		{"text marshaler", ip, ip},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Value(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Value() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	var nilError *failure
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"string", "x", "x"},
+		{"number", 1.5, "1.5"},
+		{"error", errors.New("boom"), "boom"},
+		{"nil error", nilError, ""},
+		{"stringer", &stringer{"x"}, "x"},
+		{"nil", nil, "<nil>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := String(tt.value); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
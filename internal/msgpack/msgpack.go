@@ -0,0 +1,472 @@
+// Package msgpack implements the subset of the MessagePack format needed by
+// the sinks speaking msgpack based protocols.
+package msgpack
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"time"
+)
+
+// Encoder appends MessagePack encoded values to a buffer.
+type Encoder struct {
+	buf *bytes.Buffer
+}
+
+// NewEncoder returns an Encoder writing to buf.
+func NewEncoder(buf *bytes.Buffer) *Encoder {
+	return &Encoder{buf: buf}
+}
+
+// EncodeNil writes nil.
+func (e *Encoder) EncodeNil() {
+	e.buf.WriteByte(0xc0)
+}
+
+// EncodeBool writes a boolean.
+func (e *Encoder) EncodeBool(v bool) {
+	if v {
+		e.buf.WriteByte(0xc3)
+		return
+	}
+	e.buf.WriteByte(0xc2)
+}
+
+// EncodeInt writes a signed integer using the smallest representation.
+func (e *Encoder) EncodeInt(v int64) {
+	switch {
+	case v >= 0:
+		e.EncodeUint(uint64(v))
+	case v >= -32:
+		e.buf.WriteByte(byte(v))
+	case v >= math.MinInt8:
+		e.buf.WriteByte(0xd0)
+		e.buf.WriteByte(byte(v))
+	case v >= math.MinInt16:
+		e.buf.WriteByte(0xd1)
+		e.writeUint16(uint16(v))
+	case v >= math.MinInt32:
+		e.buf.WriteByte(0xd2)
+		e.writeUint32(uint32(v))
+	default:
+		e.buf.WriteByte(0xd3)
+		e.writeUint64(uint64(v))
+	}
+}
+
+// EncodeUint writes an unsigned integer using the smallest representation.
+func (e *Encoder) EncodeUint(v uint64) {
+	switch {
+	case v <= 0x7f:
+		e.buf.WriteByte(byte(v))
+	case v <= math.MaxUint8:
+		e.buf.WriteByte(0xcc)
+		e.buf.WriteByte(byte(v))
+	case v <= math.MaxUint16:
+		e.buf.WriteByte(0xcd)
+		e.writeUint16(uint16(v))
+	case v <= math.MaxUint32:
+		e.buf.WriteByte(0xce)
+		e.writeUint32(uint32(v))
+	default:
+		e.buf.WriteByte(0xcf)
+		e.writeUint64(v)
+	}
+}
+
+// EncodeFloat writes a 64 bit float.
+func (e *Encoder) EncodeFloat(v float64) {
+	e.buf.WriteByte(0xcb)
+	e.writeUint64(math.Float64bits(v))
+}
+
+// EncodeString writes a string.
+func (e *Encoder) EncodeString(v string) {
+	n := len(v)
+	switch {
+	case n <= 31:
+		e.buf.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		e.buf.WriteByte(0xd9)
+		e.buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		e.buf.WriteByte(0xda)
+		e.writeUint16(uint16(n))
+	default:
+		e.buf.WriteByte(0xdb)
+		e.writeUint32(uint32(n))
+	}
+	e.buf.WriteString(v)
+}
+
+// EncodeBytes writes a binary value.
+func (e *Encoder) EncodeBytes(v []byte) {
+	n := len(v)
+	switch {
+	case n <= math.MaxUint8:
+		e.buf.WriteByte(0xc4)
+		e.buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		e.buf.WriteByte(0xc5)
+		e.writeUint16(uint16(n))
+	default:
+		e.buf.WriteByte(0xc6)
+		e.writeUint32(uint32(n))
+	}
+	e.buf.Write(v)
+}
+
+// EncodeArrayHeader starts an array of n elements.
+func (e *Encoder) EncodeArrayHeader(n int) {
+	switch {
+	case n <= 15:
+		e.buf.WriteByte(0x90 | byte(n))
+	case n <= math.MaxUint16:
+		e.buf.WriteByte(0xdc)
+		e.writeUint16(uint16(n))
+	default:
+		e.buf.WriteByte(0xdd)
+		e.writeUint32(uint32(n))
+	}
+}
+
+// EncodeMapHeader starts a map of n key-value pairs.
+func (e *Encoder) EncodeMapHeader(n int) {
+	switch {
+	case n <= 15:
+		e.buf.WriteByte(0x80 | byte(n))
+	case n <= math.MaxUint16:
+		e.buf.WriteByte(0xde)
+		e.writeUint16(uint16(n))
+	default:
+		e.buf.WriteByte(0xdf)
+		e.writeUint32(uint32(n))
+	}
+}
+
+// EncodeExt writes an extension value of the given type.
+func (e *Encoder) EncodeExt(typ int8, data []byte) {
+	n := len(data)
+	switch n {
+	case 1:
+		e.buf.WriteByte(0xd4)
+	case 2:
+		e.buf.WriteByte(0xd5)
+	case 4:
+		e.buf.WriteByte(0xd6)
+	case 8:
+		e.buf.WriteByte(0xd7)
+	case 16:
+		e.buf.WriteByte(0xd8)
+	default:
+		switch {
+		case n <= math.MaxUint8:
+			e.buf.WriteByte(0xc7)
+			e.buf.WriteByte(byte(n))
+		case n <= math.MaxUint16:
+			e.buf.WriteByte(0xc8)
+			e.writeUint16(uint16(n))
+		default:
+			e.buf.WriteByte(0xc9)
+			e.writeUint32(uint32(n))
+		}
+	}
+	e.buf.WriteByte(byte(typ))
+	e.buf.Write(data)
+}
+
+// Encode writes an arbitrary value. Values without a native MessagePack
+// representation are encoded the way encoding/json would represent them.
+func (e *Encoder) Encode(v interface{}) error {
+	switch x := v.(type) {
+	case nil:
+		e.EncodeNil()
+	case bool:
+		e.EncodeBool(x)
+	case int:
+		e.EncodeInt(int64(x))
+	case int8:
+		e.EncodeInt(int64(x))
+	case int16:
+		e.EncodeInt(int64(x))
+	case int32:
+		e.EncodeInt(int64(x))
+	case int64:
+		e.EncodeInt(x)
+	case uint:
+		e.EncodeUint(uint64(x))
+	case uint8:
+		e.EncodeUint(uint64(x))
+	case uint16:
+		e.EncodeUint(uint64(x))
+	case uint32:
+		e.EncodeUint(uint64(x))
+	case uint64:
+		e.EncodeUint(x)
+	case float32:
+		e.EncodeFloat(float64(x))
+	case float64:
+		e.EncodeFloat(x)
+	case string:
+		e.EncodeString(x)
+	case []byte:
+		e.EncodeBytes(x)
+	case time.Time:
+		e.EncodeString(x.Format(time.RFC3339Nano))
+	case time.Duration:
+		e.EncodeString(x.String())
+	case []interface{}:
+		e.EncodeArrayHeader(len(x))
+		for _, item := range x {
+			if err := e.Encode(item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		e.EncodeMapHeader(len(x))
+		for k, item := range x {
+			e.EncodeString(k)
+			if err := e.Encode(item); err != nil {
+				return err
+			}
+		}
+	default:
+		return e.encodeJSON(v)
+	}
+	return nil
+}
+
+// encodeJSON round-trips v through encoding/json, so structs, slices and
+// types implementing json.Marshaler look the same as in the JSON output.
+func (e *Encoder) encodeJSON(v interface{}) error {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		e.EncodeNil()
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return err
+	}
+	return e.encodeGeneric(generic)
+}
+
+func (e *Encoder) encodeGeneric(v interface{}) error {
+	switch x := v.(type) {
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			e.EncodeInt(i)
+			return nil
+		}
+		f, err := x.Float64()
+		if err != nil {
+			return err
+		}
+		e.EncodeFloat(f)
+	case []interface{}:
+		e.EncodeArrayHeader(len(x))
+		for _, item := range x {
+			if err := e.encodeGeneric(item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		e.EncodeMapHeader(len(x))
+		for k, item := range x {
+			e.EncodeString(k)
+			if err := e.encodeGeneric(item); err != nil {
+				return err
+			}
+		}
+	default:
+		return e.Encode(x)
+	}
+	return nil
+}
+
+func (e *Encoder) writeUint16(v uint16) {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	e.buf.Write(b[:])
+}
+
+func (e *Encoder) writeUint32(v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	e.buf.Write(b[:])
+}
+
+func (e *Encoder) writeUint64(v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	e.buf.Write(b[:])
+}
+
+// ErrUnsupported is returned by Decode for values it can't represent.
+var ErrUnsupported = errors.New("msgpack: unsupported type")
+
+// Decoder reads MessagePack values from a stream.
+type Decoder struct {
+	r *bufio.Reader
+}
+
+// NewDecoder returns a Decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Decode reads the next value. Maps are returned as map[string]interface{},
+// arrays as []interface{}, binary data as []byte and extensions as []byte
+// without their type.
+func (d *Decoder) Decode() (interface{}, error) {
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xe0 == 0xa0:
+		return d.readString(int(c & 0x1f))
+	case c&0xf0 == 0x90:
+		return d.readArray(int(c & 0x0f))
+	case c&0xf0 == 0x80:
+		return d.readMap(int(c & 0x0f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.readUint(1 << (c - 0xcc))
+		return int64(u), err
+	case 0xd0:
+		u, err := d.readUint(1)
+		return int64(int8(u)), err
+	case 0xd1:
+		u, err := d.readUint(2)
+		return int64(int16(u)), err
+	case 0xd2:
+		u, err := d.readUint(4)
+		return int64(int32(u)), err
+	case 0xd3:
+		u, err := d.readUint(8)
+		return int64(u), err
+	case 0xca:
+		u, err := d.readUint(4)
+		return float64(math.Float32frombits(uint32(u))), err
+	case 0xcb:
+		u, err := d.readUint(8)
+		return math.Float64frombits(u), err
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.readUint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.readString(int(n))
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.readUint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return d.readBytes(int(n))
+	case 0xdc, 0xdd:
+		n, err := d.readUint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.readArray(int(n))
+	case 0xde, 0xdf:
+		n, err := d.readUint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.readMap(int(n))
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.readExt(1 << (c - 0xd4))
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.readUint(1 << (c - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.readExt(int(n))
+	}
+	return nil, fmt.Errorf("%v: 0x%x", ErrUnsupported, c)
+}
+
+func (d *Decoder) readUint(n int) (uint64, error) {
+	b, err := d.readBytes(n)
+	if err != nil {
+		return 0, err
+	}
+	var u uint64
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	return u, nil
+}
+
+func (d *Decoder) readBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := io.ReadFull(d.r, b)
+	return b, err
+}
+
+func (d *Decoder) readString(n int) (string, error) {
+	b, err := d.readBytes(n)
+	return string(b), err
+}
+
+func (d *Decoder) readExt(n int) ([]byte, error) {
+	if _, err := d.r.ReadByte(); err != nil { // extension type
+		return nil, err
+	}
+	return d.readBytes(n)
+}
+
+func (d *Decoder) readArray(n int) ([]interface{}, error) {
+	arr := make([]interface{}, n)
+	for i := range arr {
+		v, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+		arr[i] = v
+	}
+	return arr, nil
+}
+
+func (d *Decoder) readMap(n int) (map[string]interface{}, error) {
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.Decode()
+		if err != nil {
+			return nil, err
+		}
+		m[fmt.Sprint(k)] = v
+	}
+	return m, nil
+}
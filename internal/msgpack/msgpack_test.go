@@ -0,0 +1,125 @@
+package msgpack
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeBytes(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  []byte
+	}{
+		{"nil", nil, []byte{0xc0}},
+		{"false", false, []byte{0xc2}},
+		{"true", true, []byte{0xc3}},
+		{"positive fixint", 7, []byte{0x07}},
+		{"negative fixint", -3, []byte{0xfd}},
+		{"uint8", 200, []byte{0xcc, 0xc8}},
+		{"int8", -100, []byte{0xd0, 0x9c}},
+		{"uint16", 1000, []byte{0xcd, 0x03, 0xe8}},
+		{"int16", -1000, []byte{0xd1, 0xfc, 0x18}},
+		{"uint32", 100000, []byte{0xce, 0x00, 0x01, 0x86, 0xa0}},
+		{"fixstr", "hi", []byte{0xa2, 'h', 'i'}},
+		{"bin", []byte{1, 2}, []byte{0xc4, 0x02, 1, 2}},
+		{"fixarray", []interface{}{1, "a"}, []byte{0x92, 0x01, 0xa1, 'a'}},
+		{"fixmap", map[string]interface{}{"a": 1}, []byte{0x81, 0xa1, 'a', 0x01}},
+		{"float", 1.5, []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := NewEncoder(&buf).Encode(tt.value); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), tt.want) {
+				t.Errorf("encoded % x, want % x", buf.Bytes(), tt.want)
+			}
+		})
+	}
+}
+
+type point struct {
+	X int    `json:"x"`
+	Y string `json:"y"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 0, 0, 5, time.UTC)
+	var nilPoint *point
+	tests := []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{"int64", int64(math.MinInt64), int64(math.MinInt64)},
+		{"int32", int32(math.MinInt32), int64(math.MinInt32)},
+		{"uint64", uint64(math.MaxUint32 + 1), int64(math.MaxUint32 + 1)},
+		{"float32", float32(0.5), 0.5},
+		{"str8", strings.Repeat("a", 40), strings.Repeat("a", 40)},
+		{"str16", strings.Repeat("a", 300), strings.Repeat("a", 300)},
+		{"array16", make([]interface{}, 20), make([]interface{}, 20)},
+		{"time", ts, ts.Format(time.RFC3339Nano)},
+		{"duration", 1500 * time.Millisecond, "1.5s"},
+		{"struct", point{X: 1, Y: "a"}, map[string]interface{}{"x": int64(1), "y": "a"}},
+		{"nil pointer", nilPoint, nil},
+		{"nested", map[string]interface{}{"p": []interface{}{true, 2.5}}, map[string]interface{}{"p": []interface{}{true, 2.5}}},
+		{"int slice", []int{1, 2}, []interface{}{int64(1), int64(2)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := NewEncoder(&buf).Encode(tt.value); err != nil {
+				t.Fatal(err)
+			}
+			got, err := NewDecoder(&buf).Decode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decoded %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeExt(t *testing.T) {
+	for _, n := range []int{1, 2, 4, 8, 16, 3, 300} {
+		var buf bytes.Buffer
+		data := bytes.Repeat([]byte{0xab}, n)
+		NewEncoder(&buf).EncodeExt(0, data)
+		got, err := NewDecoder(&buf).Decode()
+		if err != nil {
+			t.Fatalf("%d bytes: %v", n, err)
+		}
+		if !bytes.Equal(got.([]byte), data) {
+			t.Errorf("%d bytes: decoded % x", n, got)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, data := range [][]byte{
+		{0xc1},            // never used
+		{0xa3, 'a'},       // truncated string
+		{0x92, 0x01},      // truncated array
+		{0xcd, 0x01},      // truncated uint16
+		{0x81, 0xa1, 'a'}, // map without value
+	} {
+		if _, err := NewDecoder(bytes.NewReader(data)).Decode(); err == nil {
+			t.Errorf("decoding % x succeeded", data)
+		}
+	}
+}
+
+func TestEncodeUnsupported(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(make(chan int)); err == nil {
+		t.Error("encoding a channel succeeded")
+	}
+}
@@ -0,0 +1,225 @@
+package log
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-kit/kit/log"
+)
+
+// jsonSink writes entries as JSON lines in the format of go-kit's JSON
+// logger: keys sorted, the last of duplicate keys wins, errors and
+// fmt.Stringer encoded as strings. Fields bound with With are encoded once
+// and copied into every entry.
+type jsonSink struct {
+	w io.Writer
+}
+
+// newJSONSink creates a jsonSink writing to w. Writes are serialized.
+func newJSONSink(w io.Writer) *jsonSink {
+	return &jsonSink{w: log.NewSyncWriter(w)}
+}
+
+// encodedField is a field encoded as `"key":value`, held in encoded or at
+// start:end of the scratch buffer of the entry.
+type encodedField struct {
+	key        string
+	encoded    []byte
+	start, end int
+}
+
+type jsonEntry struct {
+	fields  []encodedField
+	scratch []byte
+	buf     []byte
+}
+
+var jsonEntryPool = sync.Pool{
+	New: func() interface{} { return &jsonEntry{fields: make([]encodedField, 0, 16)} },
+}
+
+func (s *jsonSink) Log(keyvals ...interface{}) error {
+	e := jsonEntryPool.Get().(*jsonEntry)
+	defer func() {
+		clear(e.fields)
+		e.fields = e.fields[:0]
+		e.scratch = e.scratch[:0]
+		e.buf = e.buf[:0]
+		jsonEntryPool.Put(e)
+	}()
+
+	for i := 0; i < len(keyvals); i += 2 {
+		var v interface{} = log.ErrMissingValue
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		if _, ok := keyvals[i].(boundKey); ok {
+			switch b := v.(type) {
+			case *boundFields:
+				fields, err := b.encode()
+				if err != nil {
+					return err
+				}
+				e.fields = append(e.fields, fields...)
+				continue
+			case *fieldList:
+				for _, f := range b.fields {
+					if err := e.appendTypedField(f); err != nil {
+						return err
+					}
+				}
+				continue
+			case Field:
+				if err := e.appendTypedField(b); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		key := kv.Key(keyvals[i])
+		start := len(e.scratch)
+		var err error
+		if e.scratch, err = appendJSONField(e.scratch, key, v); err != nil {
+			return err
+		}
+		e.fields = append(e.fields, encodedField{key: key, start: start, end: len(e.scratch)})
+	}
+
+	// the last of duplicate keys wins, as with a map
+	sort.SliceStable(e.fields, func(i, j int) bool { return e.fields[i].key < e.fields[j].key })
+	e.buf = append(e.buf, '{')
+	first := true
+	for i, f := range e.fields {
+		if i+1 < len(e.fields) && e.fields[i+1].key == f.key {
+			continue
+		}
+		if !first {
+			e.buf = append(e.buf, ',')
+		}
+		first = false
+		if f.encoded != nil {
+			e.buf = append(e.buf, f.encoded...)
+		} else {
+			e.buf = append(e.buf, e.scratch[f.start:f.end]...)
+		}
+	}
+	e.buf = append(e.buf, '}', '\n')
+	_, err := s.w.Write(e.buf)
+	return err
+}
+
+// appendJSONField appends the field encoded as go-kit's JSON logger does.
+func appendJSONField(buf []byte, key string, v interface{}) ([]byte, error) {
+	buf = appendJSONString(buf, key)
+	buf = append(buf, ':')
+	return appendJSONValue(buf, v)
+}
+
+func appendJSONValue(buf []byte, v interface{}) ([]byte, error) {
+	v = kv.Value(v)
+	switch x := v.(type) {
+	case nil:
+		return append(buf, "null"...), nil
+	case string:
+		return appendJSONString(buf, x), nil
+	case bool:
+		return strconv.AppendBool(buf, x), nil
+	case int:
+		return strconv.AppendInt(buf, int64(x), 10), nil
+	case int64:
+		return strconv.AppendInt(buf, x, 10), nil
+	case int32:
+		return strconv.AppendInt(buf, int64(x), 10), nil
+	case uint:
+		return strconv.AppendUint(buf, uint64(x), 10), nil
+	case uint64:
+		return strconv.AppendUint(buf, x, 10), nil
+	case uint32:
+		return strconv.AppendUint(buf, uint64(x), 10), nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return buf, err
+	}
+	return append(buf, b...), nil
+}
+
+func (e *jsonEntry) appendTypedField(f Field) error {
+	start := len(e.scratch)
+	var err error
+	if e.scratch, err = appendTypedField(e.scratch, f); err != nil {
+		return err
+	}
+	e.fields = append(e.fields, encodedField{key: f.key, start: start, end: len(e.scratch)})
+	return nil
+}
+
+// appendTypedField appends the typed field encoded like its value passed as
+// keyval. Durations, times and errors are left to appendJSONValue.
+func appendTypedField(buf []byte, f Field) ([]byte, error) {
+	buf = appendJSONString(buf, f.key)
+	buf = append(buf, ':')
+	switch f.kind {
+	case kindString:
+		return appendJSONString(buf, f.str), nil
+	case kindBool:
+		return strconv.AppendBool(buf, f.num == 1), nil
+	case kindInt, kindInt8, kindInt16, kindInt32, kindInt64:
+		return strconv.AppendInt(buf, int64(f.num), 10), nil
+	case kindUint, kindUint8, kindUint16, kindUint32, kindUint64:
+		return strconv.AppendUint(buf, f.num, 10), nil
+	case kindFloat32:
+		if x := math.Float32frombits(uint32(f.num)); !math.IsNaN(float64(x)) && !math.IsInf(float64(x), 0) {
+			return appendJSONFloat(buf, float64(x), 32), nil
+		}
+	case kindFloat64:
+		if x := math.Float64frombits(f.num); !math.IsNaN(x) && !math.IsInf(x, 0) {
+			return appendJSONFloat(buf, x, 64), nil
+		}
+	case kindError:
+		if err, ok := f.obj.(error); ok {
+			v, _ := wrapMultiError(err)
+			return appendJSONValue(buf, v)
+		}
+	}
+	return appendJSONValue(buf, f.Value())
+}
+
+// appendJSONFloat appends the finite float f of the given bit size as
+// encoding/json does.
+func appendJSONFloat(buf []byte, f float64, bits int) []byte {
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	buf = strconv.AppendFloat(buf, f, format, -1, bits)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		if n := len(buf); n >= 4 && buf[n-4] == 'e' && buf[n-3] == '-' && buf[n-2] == '0' {
+			buf[n-2] = buf[n-1]
+			buf = buf[:n-1]
+		}
+	}
+	return buf
+}
+
+// appendJSONString appends s as JSON string. Strings which encoding/json
+// would escape are left to it, so the output is identical.
+func appendJSONString(buf []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c >= 0x7f || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' {
+			b, _ := json.Marshal(s)
+			return append(buf, b...)
+		}
+	}
+	buf = append(buf, '"')
+	buf = append(buf, s...)
+	return append(buf, '"')
+}
@@ -0,0 +1,116 @@
+// Package kgolog logs the produce and consume events, rebalances and errors
+// of franz-go Kafka clients with the godin logger.
+package kgolog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-godin/log"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// Hooks logs produced and consumed records as debug with their topic,
+// partition and offset, and failed produces as error. Records are bound to
+// the trace of their context, see log.WithTrace:
+//
+//	client, err := kgo.NewClient(kgo.WithHooks(kgolog.NewHooks(logger)), kgo.WithLogger(kgolog.NewLogger(logger)))
+type Hooks struct {
+	logger log.Log
+}
+
+var (
+	_ kgo.HookProduceRecordUnbuffered = (*Hooks)(nil)
+	_ kgo.HookFetchRecordUnbuffered   = (*Hooks)(nil)
+)
+
+// NewHooks creates Hooks logging through logger.
+func NewHooks(logger log.Log) *Hooks {
+	return &Hooks{logger: logger}
+}
+
+// OnProduceRecordUnbuffered logs a record once it's produced or failed.
+func (h *Hooks) OnProduceRecordUnbuffered(r *kgo.Record, err error) {
+	l := h.record(r)
+	if err != nil {
+		l.Error("producing record failed", "topic", r.Topic, "err", err)
+		return
+	}
+	l.Debug("produced record", "topic", r.Topic, "partition", r.Partition, "offset", r.Offset)
+}
+
+// OnFetchRecordUnbuffered logs a record once it's polled.
+func (h *Hooks) OnFetchRecordUnbuffered(r *kgo.Record, polled bool) {
+	if polled {
+		h.record(r).Debug("consumed record", "topic", r.Topic, "partition", r.Partition, "offset", r.Offset)
+	}
+}
+
+func (h *Hooks) record(r *kgo.Record) log.Log {
+	if r.Context != nil {
+		return h.logger.WithTrace(r.Context)
+	}
+	return h.logger
+}
+
+// Rebalances returns the options logging partitions assigned to and revoked
+// from the group member as info, and lost partitions as warning. They
+// replace callbacks set with kgo.OnPartitionsAssigned etc.
+func Rebalances(logger log.Log) []kgo.Opt {
+	return []kgo.Opt{
+		kgo.OnPartitionsAssigned(func(_ context.Context, _ *kgo.Client, partitions map[string][]int32) {
+			logger.Info("partitions assigned", "partitions", fmt.Sprint(partitions))
+		}),
+		kgo.OnPartitionsRevoked(func(_ context.Context, _ *kgo.Client, partitions map[string][]int32) {
+			logger.Info("partitions revoked", "partitions", fmt.Sprint(partitions))
+		}),
+		kgo.OnPartitionsLost(func(_ context.Context, _ *kgo.Client, partitions map[string][]int32) {
+			logger.Warning("partitions lost", "partitions", fmt.Sprint(partitions))
+		}),
+	}
+}
+
+// NewLogger returns a kgo.Logger writing the internal logs of the client,
+// including its errors, through logger.
+func NewLogger(logger log.Log) kgo.Logger {
+	return kgoLogger{logger: logger}
+}
+
+type kgoLogger struct {
+	logger log.Log
+}
+
+func (l kgoLogger) Level() kgo.LogLevel {
+	for _, lvl := range []kgo.LogLevel{kgo.LogLevelDebug, kgo.LogLevelInfo, kgo.LogLevelWarn, kgo.LogLevelError} {
+		if l.logger.IsLevelEnabled(levelFor(lvl)) {
+			return lvl
+		}
+	}
+	return kgo.LogLevelNone
+}
+
+func (l kgoLogger) Log(lvl kgo.LogLevel, msg string, keyvals ...interface{}) {
+	switch levelFor(lvl) {
+	case log.LevelDebug:
+		l.logger.Debug(msg, keyvals...)
+	case log.LevelInfo:
+		l.logger.Info(msg, keyvals...)
+	case log.LevelWarning:
+		l.logger.Warning(msg, keyvals...)
+	default:
+		l.logger.Error(msg, keyvals...)
+	}
+}
+
+func levelFor(lvl kgo.LogLevel) log.Level {
+	switch lvl {
+	case kgo.LogLevelDebug:
+		return log.LevelDebug
+	case kgo.LogLevelInfo:
+		return log.LevelInfo
+	case kgo.LogLevelWarn:
+		return log.LevelWarning
+	default:
+		return log.LevelError
+	}
+}
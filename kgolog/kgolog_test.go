@@ -0,0 +1,164 @@
+package kgolog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-godin/log"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func newLogger(t *testing.T, lvl string) (log.Log, func() []map[string]interface{}) {
+	t.Helper()
+	var buf bytes.Buffer
+	logger := log.NewLogger(lvl, log.WithSink(kitlog.NewJSONLogger(&buf)))
+	return logger, func() []map[string]interface{} {
+		var entries []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var entry map[string]interface{}
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("decoding %q: %v", line, err)
+			}
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+}
+
+func TestHooks(t *testing.T) {
+	traced := log.ContextWithTrace(context.Background(), log.Traceparent{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"})
+	record := &kgo.Record{Topic: "orders", Partition: 3, Offset: 42}
+	tests := []struct {
+		name        string
+		hook        func(h *Hooks)
+		wantMessage string // empty if nothing is logged
+		wantLevel   string
+		wantTrace   bool
+	}{
+		{
+			name:        "produced",
+			hook:        func(h *Hooks) { h.OnProduceRecordUnbuffered(record, nil) },
+			wantMessage: "produced record",
+			wantLevel:   "debug",
+		},
+		{
+			name:        "produce failed",
+			hook:        func(h *Hooks) { h.OnProduceRecordUnbuffered(record, errors.New("timeout")) },
+			wantMessage: "producing record failed",
+			wantLevel:   "error",
+		},
+		{
+			name:        "consumed",
+			hook:        func(h *Hooks) { h.OnFetchRecordUnbuffered(record, true) },
+			wantMessage: "consumed record",
+			wantLevel:   "debug",
+		},
+		{
+			name: "fetched but not polled",
+			hook: func(h *Hooks) { h.OnFetchRecordUnbuffered(record, false) },
+		},
+		{
+			name: "traced record",
+			hook: func(h *Hooks) {
+				r := *record
+				r.Context = traced
+				h.OnProduceRecordUnbuffered(&r, nil)
+			},
+			wantMessage: "produced record",
+			wantLevel:   "debug",
+			wantTrace:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, entries := newLogger(t, log.LevelDebug)
+			tt.hook(NewHooks(logger))
+
+			logged := entries()
+			if tt.wantMessage == "" {
+				if len(logged) != 0 {
+					t.Errorf("logged %v, want nothing", logged)
+				}
+				return
+			}
+			if len(logged) != 1 {
+				t.Fatalf("logged %d entries, want 1", len(logged))
+			}
+			entry := logged[0]
+			if entry[log.MessageKey] != tt.wantMessage || entry["severity"] != tt.wantLevel {
+				t.Errorf("message, severity = %v, %v, want %s, %s", entry[log.MessageKey], entry["severity"], tt.wantMessage, tt.wantLevel)
+			}
+			if entry["topic"] != "orders" {
+				t.Errorf("topic = %v, want orders", entry["topic"])
+			}
+			if _, traced := entry[log.TraceIDKey]; traced != tt.wantTrace {
+				t.Errorf("trace_id set = %v, want %v", traced, tt.wantTrace)
+			}
+		})
+	}
+}
+
+func TestLogger(t *testing.T) {
+	tests := []struct {
+		level     string
+		wantLevel kgo.LogLevel
+	}{
+		{log.LevelDebug, kgo.LogLevelDebug},
+		{log.LevelInfo, kgo.LogLevelInfo},
+		{log.LevelWarning, kgo.LogLevelWarn},
+		{log.LevelError, kgo.LogLevelError},
+		{log.LevelPanic, kgo.LogLevelNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			logger, _ := newLogger(t, tt.level)
+			if lvl := NewLogger(logger).Level(); lvl != tt.wantLevel {
+				t.Errorf("Level() = %v, want %v", lvl, tt.wantLevel)
+			}
+		})
+	}
+
+	for _, tt := range []struct {
+		level     kgo.LogLevel
+		wantLevel string
+	}{
+		{kgo.LogLevelDebug, "debug"},
+		{kgo.LogLevelInfo, "info"},
+		{kgo.LogLevelWarn, "warning"},
+		{kgo.LogLevelError, "error"},
+	} {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			logger, entries := newLogger(t, log.LevelDebug)
+			NewLogger(logger).Log(tt.level, "metadata refreshed", "broker", 1)
+			logged := entries()
+			if len(logged) != 1 || logged[0]["severity"] != tt.wantLevel || logged[0]["broker"] != float64(1) {
+				t.Errorf("logged %v, want one %s entry with the broker", logged, tt.wantLevel)
+			}
+		})
+	}
+}
+
+func TestRebalances(t *testing.T) {
+	logger, entries := newLogger(t, log.LevelDebug)
+	opts := Rebalances(logger)
+	if len(opts) != 3 {
+		t.Fatalf("Rebalances returned %d options, want 3", len(opts))
+	}
+	// the callbacks are only called by a running group member
+	client, err := kgo.NewClient(append(opts, kgo.ConsumerGroup("g"), kgo.ConsumeTopics("t"))...)
+	if err != nil {
+		t.Fatalf("the options are rejected: %v", err)
+	}
+	client.Close()
+	if logged := entries(); len(logged) != 0 {
+		t.Errorf("logged %v before any rebalance", logged)
+	}
+}
@@ -0,0 +1,54 @@
+package log
+
+import (
+	"fmt"
+
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-kit/kit/log"
+)
+
+// Lazy is a value computed only if its entry passes the level filter, for
+// values which are expensive to compute:
+//
+//	logger.Debug("cache state", "entries", log.Lazy(func() interface{} { return cache.Dump() }))
+//
+// Sinks receiving entries before the level filter, e.g. recorders, and
+// spans get the value as a string. With WithAsync the function runs on the
+// worker goroutine, so it must not depend on state changing after the call.
+type Lazy func() interface{}
+
+// String returns the string representation of the computed value.
+func (f Lazy) String() string {
+	return kv.String(f())
+}
+
+// Stringer returns a Lazy deferring the call of s.String until the entry
+// passed the level filter.
+func Stringer(s fmt.Stringer) Lazy {
+	return func() interface{} { return s.String() }
+}
+
+// lazyResolver replaces Lazy values by their result before handing entries
+// to next.
+type lazyResolver struct {
+	next log.Logger
+}
+
+func (r lazyResolver) Log(keyvals ...interface{}) error {
+	var resolved []interface{}
+	for i := 1; i < len(keyvals); i += 2 {
+		f, ok := keyvals[i].(Lazy)
+		if !ok {
+			continue
+		}
+		if resolved == nil {
+			// the slice belongs to the caller
+			resolved = append([]interface{}(nil), keyvals...)
+		}
+		resolved[i] = f()
+	}
+	if resolved == nil {
+		return r.next.Log(keyvals...)
+	}
+	return r.next.Log(resolved...)
+}
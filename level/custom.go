@@ -0,0 +1,65 @@
+package level
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+)
+
+var (
+	registryMtx sync.RWMutex
+	registry    = map[string]*levelValue{}
+)
+
+// Register creates a custom level, e.g. "notice" or "critical" for schemes
+// derived from syslog. Its severity orders it among the built-in levels,
+// which are debug (100), info (200), warning (300), error (400), fatal (500)
+// and panic (600), so Register("notice", 250) is filtered like a level
+// between info and warning. Names are lowercased, since levels are parsed
+// ignoring case. Register fails if the name is taken.
+func Register(name string, severity int) (Value, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return nil, fmt.Errorf("level: empty name")
+	}
+	if severity >= int(levelNone) {
+		return nil, fmt.Errorf("level: severity of %q is out of range", name)
+	}
+
+	registryMtx.Lock()
+	defer registryMtx.Unlock()
+	if _, ok := parse(name); ok {
+		return nil, fmt.Errorf("level: %q is already registered", name)
+	}
+	v := &levelValue{level: level(severity), name: name}
+	registry[name] = v
+	return v, nil
+}
+
+// With returns a logger that includes a Key/v pair, the counterpart of
+// Debug, Info etc. for custom levels.
+func With(logger log.Logger, v Value) log.Logger {
+	return log.WithPrefix(logger, Key(), v)
+}
+
+// Parse returns the level value with the given name, e.g. "warning", which
+// may be a custom one created with Register.
+func Parse(name string) (Value, bool) {
+	registryMtx.RLock()
+	defer registryMtx.RUnlock()
+	return parse(name)
+}
+
+func parse(name string) (Value, bool) {
+	for _, v := range []*levelValue{debugValue, infoValue, warnValue, errorValue, fatalValue, panicValue} {
+		if v.name == name {
+			return v, true
+		}
+	}
+	if v, ok := registry[name]; ok {
+		return v, true
+	}
+	return nil, false
+}
@@ -1,7 +1,22 @@
 // This is a modified version of the go-kit/kit/log/level package to fit godin's need.
 package level
 
-import "github.com/go-kit/kit/log"
+import (
+	"math"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+)
+
+// Panic returns a logger that includes a Key/PanicValue pair.
+func Panic(logger log.Logger) log.Logger {
+	return log.WithPrefix(logger, Key(), PanicValue())
+}
+
+// Fatal returns a logger that includes a Key/FatalValue pair.
+func Fatal(logger log.Logger) log.Logger {
+	return log.WithPrefix(logger, Key(), FatalValue())
+}
 
 // Error returns a logger that includes a Key/ErrorValue pair.
 func Error(logger log.Logger) log.Logger {
@@ -31,6 +46,7 @@ func Debug(logger log.Logger) log.Logger {
 func NewFilter(next log.Logger, options ...Option) log.Logger {
 	l := &logger{
 		next: next,
+		min:  levelNone,
 	}
 	for _, option := range options {
 		option(l)
@@ -40,8 +56,9 @@ func NewFilter(next log.Logger, options ...Option) log.Logger {
 
 type logger struct {
 	next           log.Logger
-	allowed        level
+	min            level
 	squelchNoLevel bool
+	allowForced    bool
 	errNotAllowed  error
 	errNoLevel     error
 }
@@ -51,7 +68,7 @@ func (l *logger) Log(keyvals ...interface{}) error {
 	for i := 1; i < len(keyvals); i += 2 {
 		if v, ok := keyvals[i].(*levelValue); ok {
 			hasLevel = true
-			levelAllowed = l.allowed&v.level != 0
+			levelAllowed = v.forced && l.allowForced || v.level >= l.min
 			break
 		}
 	}
@@ -72,33 +89,56 @@ func AllowAll() Option {
 	return AllowDebug()
 }
 
-// AllowDebug allows error, warn, info and debug level log events to pass.
+// AllowDebug allows debug level log events and all more severe ones to pass.
 func AllowDebug() Option {
-	return allowed(levelError | levelWarn | levelInfo | levelDebug)
+	return allowed(levelDebug)
 }
 
-// AllowInfo allows error, warn and info level log events to pass.
+// AllowInfo allows info level log events and all more severe ones to pass.
 func AllowInfo() Option {
-	return allowed(levelError | levelWarn | levelInfo)
+	return allowed(levelInfo)
 }
 
-// AllowWarn allows error and warn level log events to pass.
+// AllowWarn allows warn level log events and all more severe ones to pass.
 func AllowWarn() Option {
-	return allowed(levelError | levelWarn)
+	return allowed(levelWarn)
 }
 
-// AllowError allows only error level log events to pass.
+// AllowError allows error level log events and all more severe ones to pass.
 func AllowError() Option {
 	return allowed(levelError)
 }
 
+// AllowFatal allows fatal and panic level log events to pass.
+func AllowFatal() Option {
+	return allowed(levelFatal)
+}
+
+// AllowPanic allows only panic level log events to pass.
+func AllowPanic() Option {
+	return allowed(levelPanic)
+}
+
 // AllowNone allows no leveled log events to pass.
 func AllowNone() Option {
-	return allowed(0)
+	return allowed(levelNone)
+}
+
+// Allow allows log events of the given level, which may be a custom one
+// created with Register, and all more severe ones to pass.
+func Allow(v Value) Option {
+	return allowed(level(v.Severity()))
+}
+
+// AllowForced lets values returned by Force pass regardless of their level,
+// e.g. for the main filter of a logger, but not the filters of sinks which
+// only accept severe entries.
+func AllowForced() Option {
+	return func(l *logger) { l.allowForced = true }
 }
 
-func allowed(allowed level) Option {
-	return func(l *logger) { l.allowed = allowed }
+func allowed(min level) Option {
+	return func(l *logger) { l.min = min }
 }
 
 // ErrNotAllowed sets the error to return from Log when it squelches a log
@@ -157,6 +197,7 @@ func (l *injector) Log(keyvals ...interface{}) error {
 // defined in this package from all other values.
 type Value interface {
 	String() string
+	Severity() int
 	levelVal()
 }
 
@@ -164,6 +205,12 @@ type Value interface {
 // package.
 func Key() interface{} { return key }
 
+// PanicValue returns the unique value added to log events by Panic.
+func PanicValue() Value { return panicValue }
+
+// FatalValue returns the unique value added to log events by Fatal.
+func FatalValue() Value { return fatalValue }
+
 // ErrorValue returns the unique value added to log events by Error.
 func ErrorValue() Value { return errorValue }
 
@@ -182,25 +229,65 @@ var (
 	// []interface{} later.
 	key interface{} = "severity"
 
+	panicValue = &levelValue{level: levelPanic, name: "panic"}
+	fatalValue = &levelValue{level: levelFatal, name: "fatal"}
 	errorValue = &levelValue{level: levelError, name: "error"}
 	warnValue  = &levelValue{level: levelWarn, name: "warning"}
 	infoValue  = &levelValue{level: levelInfo, name: "info"}
 	debugValue = &levelValue{level: levelDebug, name: "debug"}
 )
 
-type level byte
+// level is the severity of a level. The built-in levels leave gaps, so
+// custom levels can be registered in between.
+type level int
 
 const (
-	levelDebug level = 1 << iota
-	levelInfo
-	levelWarn
-	levelError
+	levelDebug level = 100
+	levelInfo  level = 200
+	levelWarn  level = 300
+	levelError level = 400
+	levelFatal level = 500
+	levelPanic level = 600
+	levelNone  level = math.MaxInt32
 )
 
 type levelValue struct {
 	name string
 	level
+	forced bool
 }
 
 func (v *levelValue) String() string { return v.name }
+func (v *levelValue) Severity() int  { return int(v.level) }
 func (v *levelValue) levelVal()      {}
+
+// Force returns a value of the same level which passes the filters created
+// with AllowForced, e.g. for Debug entries of sampled traces.
+func Force(v Value) Value {
+	if forced, ok := forcedValues.Load(v); ok {
+		return forced.(Value)
+	}
+	lv := *v.(*levelValue)
+	lv.forced = true
+	forced, _ := forcedValues.LoadOrStore(v, &lv)
+	return forced.(Value)
+}
+
+// forcedValues caches the forced value of each level.
+var forcedValues sync.Map
+
+// IsForced reports whether v was returned by Force.
+func IsForced(v Value) bool {
+	lv, ok := v.(*levelValue)
+	return ok && lv.forced
+}
+
+// FromKeyvals returns the level contained in keyvals, if any.
+func FromKeyvals(keyvals []interface{}) (Value, bool) {
+	for i := 1; i < len(keyvals); i += 2 {
+		if v, ok := keyvals[i].(*levelValue); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
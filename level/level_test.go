@@ -0,0 +1,110 @@
+package level
+
+import (
+	"testing"
+
+	"github.com/go-kit/kit/log"
+)
+
+type countingLogger struct {
+	n int
+}
+
+func (c *countingLogger) Log(...interface{}) error {
+	c.n++
+	return nil
+}
+
+func TestFilter(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		options []Option
+		value   Value
+		passes  bool
+	}{
+		{"debug at debug", []Option{AllowDebug()}, DebugValue(), true},
+		{"debug at info", []Option{AllowInfo()}, DebugValue(), false},
+		{"error at warn", []Option{AllowWarn()}, ErrorValue(), true},
+		{"panic at fatal", []Option{AllowFatal()}, PanicValue(), true},
+		{"panic at none", []Option{AllowNone()}, PanicValue(), false},
+		{"forced at info", []Option{AllowInfo()}, Force(DebugValue()), false},
+		{"forced at info allowing forced", []Option{AllowInfo(), AllowForced()}, Force(DebugValue()), true},
+		{"forced at error only", []Option{Allow(ErrorValue())}, Force(DebugValue()), false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &countingLogger{}
+			_ = NewFilter(next, tt.options...).Log(Key(), tt.value, "message", "m")
+			if passed := next.n == 1; passed != tt.passes {
+				t.Errorf("passed = %v, want %v", passed, tt.passes)
+			}
+		})
+	}
+}
+
+func TestFilterWithoutLevel(t *testing.T) {
+	for _, squelch := range []bool{false, true} {
+		next := &countingLogger{}
+		_ = NewFilter(next, AllowError(), SquelchNoLevel(squelch)).Log("message", "m")
+		if passed := next.n == 1; passed == squelch {
+			t.Errorf("SquelchNoLevel(%v): passed = %v", squelch, passed)
+		}
+	}
+}
+
+func TestForce(t *testing.T) {
+	forced := Force(DebugValue())
+	if !IsForced(forced) || IsForced(DebugValue()) {
+		t.Fatal("IsForced doesn't tell forced values apart")
+	}
+	if forced != Force(DebugValue()) {
+		t.Error("Force returns a new value per call")
+	}
+	if forced.String() != DebugValue().String() || forced.Severity() != DebugValue().Severity() {
+		t.Errorf("forced value %v differs from its level", forced)
+	}
+	if v, ok := FromKeyvals([]interface{}{Key(), forced}); !ok || v != forced {
+		t.Errorf("FromKeyvals() = %v, %v", v, ok)
+	}
+}
+
+var _ log.Logger = (*countingLogger)(nil)
+
+func TestRegister(t *testing.T) {
+	cleanup := t.Cleanup
+	for _, tt := range []struct {
+		name     string
+		register string
+		severity int
+		want     string
+		wantErr  bool
+	}{
+		{"lower case", "test-notice", 250, "test-notice", false},
+		{"upper case", "TEST-ALERT", 350, "test-alert", false},
+		{"taken in other case", "Test-Notice", 260, "", true},
+		{"built-in", "Warning", 300, "", true},
+		{"empty", " ", 300, "", true},
+		{"out of range", "test-never", int(levelNone), "", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := Register(tt.register, tt.severity)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Register(%q) = %v, want error %v", tt.register, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			// unregistered after all rows, which rely on the earlier ones
+			cleanup(func() {
+				registryMtx.Lock()
+				delete(registry, tt.want)
+				registryMtx.Unlock()
+			})
+			if v.String() != tt.want || v.Severity() != tt.severity {
+				t.Errorf("Register(%q) = %s (%d), want %s (%d)", tt.register, v, v.Severity(), tt.want, tt.severity)
+			}
+			if parsed, ok := Parse(tt.want); !ok || parsed != v {
+				t.Errorf("Parse(%q) = %v, %v, want the registered level", tt.want, parsed, ok)
+			}
+		})
+	}
+}
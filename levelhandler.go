@@ -0,0 +1,70 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// levelPayload is the body of requests and responses of the LevelHandler.
+type levelPayload struct {
+	Level string `json:"level"`
+	// Duration limits the change to a window, e.g. "5m".
+	Duration string `json:"duration,omitempty"`
+	// Until is the end of a temporary level.
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// LevelHandler returns an http.Handler to inspect and change the level at
+// runtime, e.g. on the admin port: GET responds with the current level as
+// {"level":"info"}, PUT sets it from a body of the same form, or from the
+// "level" form value. A duration like {"level":"debug","duration":"5m"}
+// reverts the level afterwards, as EnableDebugFor does; the response then
+// carries its end as "until". Every change is emitted with ConfigChanged.
+func (l Log) LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var payload levelPayload
+			if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+				payload.Level = r.FormValue("level")
+				payload.Duration = r.FormValue("duration")
+			} else if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				http.Error(w, "malformed request body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if payload.Level == "" {
+				http.Error(w, "missing level", http.StatusBadRequest)
+				return
+			}
+			var err error
+			if payload.Duration != "" {
+				d, parseErr := time.ParseDuration(payload.Duration)
+				if parseErr != nil {
+					http.Error(w, "invalid duration: "+parseErr.Error(), http.StatusBadRequest)
+					return
+				}
+				err = l.changeLevelFor(payload.Level, d, SourceHTTP)
+			} else {
+				err = l.changeLevel(payload.Level, SourceHTTP)
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		response := levelPayload{Level: l.levels.name()}
+		if until := l.levels.until(); !until.IsZero() {
+			response.Until = &until
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	})
+}
@@ -0,0 +1,79 @@
+package log
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-godin/log/level"
+)
+
+// Level is the name of a level, e.g. LevelWarning, or of a custom level
+// created with level.Register.
+type Level string
+
+func (lvl Level) String() string { return string(lvl) }
+
+// levelAliases maps common alternative spellings to the names of the levels.
+var levelAliases = map[string]Level{
+	"trace":     LevelDebug,
+	"dbg":       LevelDebug,
+	"inf":       LevelInfo,
+	"warn":      LevelWarning,
+	"wrn":       LevelWarning,
+	"err":       LevelError,
+	"critical":  LevelFatal,
+	"crit":      LevelFatal,
+	"emergency": LevelPanic,
+}
+
+// syslogLevels maps the numeric syslog severities to the levels.
+var syslogLevels = []Level{
+	0: LevelPanic,   // emergency
+	1: LevelFatal,   // alert
+	2: LevelFatal,   // critical
+	3: LevelError,   // error
+	4: LevelWarning, // warning
+	5: LevelInfo,    // notice
+	6: LevelInfo,    // informational
+	7: LevelDebug,   // debug
+}
+
+// ParseLevel returns the level with the given name, ignoring case. Besides
+// the names of the levels it accepts the aliases "trace" (debug), "warn",
+// "err", "critical" (fatal, unless registered as custom level) and the
+// numeric syslog severities 0 (panic) to 7 (debug).
+func ParseLevel(name string) (Level, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if _, ok := level.Parse(name); ok {
+		return Level(name), nil
+	}
+	if alias, ok := levelAliases[name]; ok {
+		return alias, nil
+	}
+	if n, err := strconv.Atoi(name); err == nil {
+		if n < 0 || n >= len(syslogLevels) {
+			return "", fmt.Errorf("unknown level %q, syslog severities range from 0 to 7", name)
+		}
+		return syslogLevels[n], nil
+	}
+	return "", fmt.Errorf("unknown level %q", name)
+}
+
+// IsLevelEnabled reports whether entries of the level are logged, e.g. to
+// skip computing expensive fields of debug entries:
+//
+//	if logger.IsLevelEnabled(log.LevelDebug) {
+//		logger.Debug("cache state", "entries", cache.Dump())
+//	}
+//
+// With WithTraceSampledDebug, debug entries of a Log bound to a trace are
+// enabled if the trace is sampled, as Debug decides.
+func (l Log) IsLevelEnabled(lvl Level) bool {
+	if lvl == LevelDebug && l.levels != nil && l.levels.sampledDebug {
+		if tc, ok := l.traceContext(); ok {
+			return tc.Sampled
+		}
+	}
+	return l.levels.Enabled(lvl, l.name)
+}
@@ -4,12 +4,12 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"strings"
-	"time"
+	"sync"
 
+	"github.com/go-godin/log/internal/kv"
 	"github.com/go-godin/log/level"
+	"github.com/go-godin/log/retention"
 	"github.com/go-kit/kit/log"
-	stdzipkin "github.com/openzipkin/zipkin-go"
 )
 
 // Logger is the default logging interface which is used throughout all godin services.
@@ -23,30 +23,157 @@ type Logger interface {
 	WithTrace(ctx context.Context) Log
 }
 
+// CtxLogger is a Logger which also logs with the trace and fields of a
+// context, see DebugCtx. Log implements it.
+type CtxLogger interface {
+	Logger
+	DebugCtx(ctx context.Context, message string, keyvals ...interface{})
+	InfoCtx(ctx context.Context, message string, keyvals ...interface{})
+	WarningCtx(ctx context.Context, message string, keyvals ...interface{})
+	ErrorCtx(ctx context.Context, message string, keyvals ...interface{})
+}
+
+// FatalLogger is a Logger which also logs entries ending the process or
+// goroutine, see Fatal and Panic. Log implements it.
+type FatalLogger interface {
+	Logger
+	Fatal(message string, keyvals ...interface{})
+	Panic(message string, keyvals ...interface{})
+}
+
+var (
+	_ CtxLogger   = Log{}
+	_ FatalLogger = Log{}
+)
+
 const (
 	LevelDebug          = "debug"
 	LevelInfo           = "info"
 	LevelWarning        = "warning"
 	LevelError          = "error"
-	MessageKey          = "message"
+	LevelFatal          = "fatal"
+	LevelPanic          = "panic"
+	MessageKey          = kv.MessageKey
+	TraceIDKey          = "trace_id"
+	SpanIDKey           = "span_id"
+	SampledKey          = "sampled"
 	EnvironmentVariable = "LOG_LEVEL"
 )
 
 type Log struct {
 	kitLogger log.Logger
-	span      stdzipkin.Span
+	// traced is kitLogger with the fields bound by WithSpan and WithTrace,
+	// which are replaced instead of added on each call
+	traced  log.Logger
+	spanKVs []interface{}
+	ctxKVs  []interface{}
+	span    SpanRecorder
+	levels  *AtomicLevel
+	name    string
+	sinks   []log.Logger
 }
 
-// NewLogger creates a new, leveled Log. The given level is the allowed minimal level.
-func NewLogger(logLevel string) Log {
-	levelOpt, err := evaluateLogLevel(logLevel)
+// NewLogger creates a new, leveled Log. The given level is the allowed minimal level,
+// optionally followed by overrides for Named loggers, e.g. "info,storage=debug".
+func NewLogger(logLevel string, opts ...Option) Log {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	// the unwrapped sinks are kept to flush them on Fatal
+	var sinks []log.Logger
+	if !o.fileSinks {
+		sinks = append(sinks, o.sink)
+	}
+	sinks = append(sinks, o.recorders...)
+	for _, out := range o.outputs {
+		sinks = append(sinks, out)
+	}
+	for _, s := range o.minLevelSinks {
+		sinks = append(sinks, s.sink)
+	}
+
+	// only the JSON sink receives the fields bound by With encoded, the sinks
+	// of a config file are wrapped when they're built
+	if !o.fileSinks {
+		o.sink = bindable(o.sink)
+	}
+	for lvl, out := range o.outputs {
+		o.outputs[lvl] = bindable(out)
+	}
+	for i, r := range o.recorders {
+		o.recorders[i] = bindable(r)
+	}
+
+	if o.goKitCompat {
+		o.sink = goKitCompat{next: o.sink}
+		for lvl, out := range o.outputs {
+			o.outputs[lvl] = goKitCompat{next: out}
+		}
+	}
 
 	var kitLogger log.Logger
-	kitLogger = log.NewJSONLogger(log.NewSyncWriter(os.Stdout))
-	kitLogger = level.NewFilter(kitLogger, levelOpt)
+	kitLogger = o.sink
+	if len(o.outputs) > 0 {
+		kitLogger = levelOutput{sink: o.sink, outputs: o.outputs}
+	}
+	levels, err := newAtomicLevel(kitLogger, logLevel)
+	levels.sampledDebug = o.sampledDebug
+	levels.datadogFields = o.datadogFields
+	levels.spanTags = o.spanTags
+	levels.caller, levels.callerSkip = o.caller, o.callerSkip
+	levels.unfiltered = len(o.recorders) > 0 || o.debugBuffer > 0 || o.promoteErrors
+	kitLogger = levels
+	if o.debugBuffer > 0 {
+		kitLogger = newDebugBuffer(kitLogger, levels, o.debugBuffer, o.debugBufferKey)
+	}
+
+	// sinks with their own level receive entries independently of the level filter
+	recorders := o.recorders
+	for _, s := range o.minLevelSinks {
+		lvl, lvlErr := ParseLevel(s.minLevel)
+		if lvlErr != nil {
+			if err == nil {
+				err = lvlErr
+			}
+			continue
+		}
+		v, _ := level.Parse(string(lvl))
+		if levels.floor == nil || v.Severity() < levels.floor.Severity() {
+			levels.floor = v
+		}
+		sink := bindable(s.sink)
+		if o.goKitCompat {
+			sink = goKitCompat{next: sink}
+		}
+		recorders = append(recorders, level.NewFilter(sink, level.Allow(v)))
+	}
+	if len(recorders) > 0 {
+		kitLogger = recording{next: kitLogger, recorders: recorders}
+	}
+	if o.promoteErrors {
+		kitLogger = errorPromotion{next: kitLogger}
+	}
+	if o.async > 0 {
+		// drained first when flushing, so the queued entries reach the sinks
+		var queue asyncQueue = make(chanQueue, o.async)
+		if o.ringBuffer {
+			queue = newRingQueue(o.async)
+		}
+		async := newAsyncSink(kitLogger, queue, o.overflow, o.onDrop)
+		kitLogger = async
+		sinks = append([]log.Logger{async}, sinks...)
+	}
+	if o.timestamp != nil {
+		// bound by the caller, so queued entries keep the time they were logged
+		kitLogger = log.With(kitLogger, TimeKey, o.timestamp)
+	}
 
 	log := Log{
 		kitLogger: kitLogger,
+		levels:    levels,
+		sinks:     sinks,
 	}
 
 	// the error from evaluateLogLevel needs to be logged
@@ -58,59 +185,158 @@ func NewLogger(logLevel string) Log {
 }
 
 // NewLoggerFromEnv creates a new Log, configuring the log level using an environment variable.
-func NewLoggerFromEnv() Log {
+func NewLoggerFromEnv(opts ...Option) Log {
 	levelStr := os.Getenv(EnvironmentVariable)
-	return NewLogger(levelStr)
+	return NewLogger(levelStr, opts...)
 }
 
+// SetLevel changes the level of the Log and all Logs derived from it, and
+// emits the change. Unknown levels fall back to info.
 func (l Log) SetLevel(logLevel string) {
-	lvl, err := evaluateLogLevel(logLevel)
-	if err != nil {
-		lvl = level.AllowInfo()
+	if _, err := evaluateLogLevel(logLevel); err != nil {
+		logLevel = LevelInfo
 	}
-	l.kitLogger = level.NewFilter(l.kitLogger, lvl)
+	_ = l.changeLevel(logLevel, SourceAPI)
 }
 
+// AtomicLevel returns the level shared by the Log and all Logs derived from it.
+func (l Log) AtomicLevel() *AtomicLevel {
+	return l.levels
+}
+
+// WithTrace binds the Log to the span of ctx, as found by the span sources
+// registered with RegisterSpanSource, see WithSpan. The fields of the
+// registered context extractors are added as by WithContext. Calling it on a
+// traced Log replaces the span and the fields of the previous context.
 func (l Log) WithTrace(ctx context.Context) Log {
-	if span := stdzipkin.SpanFromContext(ctx); span != nil {
-		return Log{
-			kitLogger: l.kitLogger,
-			span:      span,
-		}
+	l = l.withSpan(spanFromContext(ctx))
+	l.ctxKVs = contextFields(ctx)
+	return l.bindTrace()
+}
+
+// WithSpan binds the Log to span, so messages are recorded as annotations
+// (events) and key-value pairs as tags (attributes) of the span. Error, Fatal
+// and Panic additionally mark the span as failed. The trace and span ID and
+// the sampling decision are added to every entry as trace_id, span_id and
+// sampled, to correlate logs with traces, replacing those of a previously
+// bound span. A nil span unbinds the Log.
+func (l Log) WithSpan(span SpanRecorder) Log {
+	return l.withSpan(span).bindTrace()
+}
+
+// withSpan sets the span and its trace fields without binding them.
+func (l Log) withSpan(span SpanRecorder) Log {
+	l.span = span
+	l.spanKVs = nil
+	if tc, ok := l.traceContext(); ok {
+		l.spanKVs = l.traceFields(tc.TraceID, tc.SpanID, tc.Sampled)
 	}
-	return Log{
-		kitLogger: l.kitLogger,
-		span:      nil,
+	return l
+}
+
+// bindTrace binds the trace and context fields to kitLogger in traced.
+func (l Log) bindTrace() Log {
+	l.traced = nil
+	if len(l.spanKVs) > 0 || len(l.ctxKVs) > 0 {
+		keyvals := append(append([]interface{}{}, l.spanKVs...), l.ctxKVs...)
+		l.traced = log.With(l.kitLogger, bind(prepare(keyvals))...)
 	}
+	return l
+}
+
+// logger returns the logger entries are written to.
+func (l Log) logger() log.Logger {
+	if l.traced != nil {
+		return l.traced
+	}
+	return l.kitLogger
+}
+
+// traceFields returns the fields correlating entries with a trace.
+func (l Log) traceFields(traceID, spanID string, sampled bool) []interface{} {
+	keyvals := []interface{}{TraceIDKey, traceID, SpanIDKey, spanID, SampledKey, sampled}
+	if l.levels != nil && l.levels.datadogFields {
+		keyvals = append(keyvals, datadogFields(traceID, spanID)...)
+	}
+	return keyvals
 }
 
 // Log redirects to go-kit/log.Log
 func (l Log) Log(keyvals ...interface{}) {
+	keyvals = prepare(keyvals)
 	l.handleTrace("", keyvals)
-	_ = l.kitLogger.Log(keyvals...)
+	caller, ok, keyvals := l.caller(keyvals)
+	if ok {
+		keyvals = append([]interface{}{CallerKey, caller}, keyvals...)
+	}
+	if l.name != "" {
+		keyvals = append([]interface{}{LoggerKey, l.name}, keyvals...)
+	}
+	_ = l.logger().Log(keyvals...)
 }
 
 // Debug will log a message and arbitrary key-value pairs
 func (l Log) Debug(message string, keyvals ...interface{}) {
-	_ = level.Debug(l.kitLogger).Log(l.mergeKeyValues(message, keyvals)...)
+	lvl, ok := l.debugLevel()
+	if !ok {
+		return
+	}
+	keyvals = prepare(keyvals)
+	l.output(level.With(l.logger(), lvl), message, keyvals)
+}
+
+// debugLevel returns the level debug entries are logged at and whether they
+// pass: with WithTraceSampledDebug, entries of sampled traces are forced past the
+// level filter and all others dropped.
+func (l Log) debugLevel() (level.Value, bool) {
+	lvl := level.DebugValue()
+	if tc, ok := l.traceContext(); ok && l.levels != nil && l.levels.sampledDebug {
+		return level.Force(lvl), tc.Sampled
+	}
+	return lvl, !l.levels.drops(lvl, l.name)
 }
 
 // Info will log a message and arbitrary key-value pairs
 func (l Log) Info(message string, keyvals ...interface{}) {
+	if l.levels.drops(level.InfoValue(), l.name) {
+		return
+	}
+	keyvals = prepare(keyvals)
 	l.handleTrace(message, keyvals)
-	_ = level.Info(l.kitLogger).Log(l.mergeKeyValues(message, keyvals)...)
+	l.output(level.Info(l.logger()), message, keyvals)
 }
 
 // Warning will log a message and arbitrary key-value pairs
 func (l Log) Warning(message string, keyvals ...interface{}) {
+	if l.levels.drops(level.WarnValue(), l.name) {
+		return
+	}
+	keyvals = prepare(keyvals)
 	l.handleTrace(message, keyvals)
-	_ = level.Warn(l.kitLogger).Log(l.mergeKeyValues(message, keyvals)...)
+	l.output(level.Warn(l.logger()), message, keyvals)
 }
 
 // Error will log a message and arbitrary key-value pairs
 func (l Log) Error(message string, keyvals ...interface{}) {
+	if l.levels.drops(level.ErrorValue(), l.name) {
+		l.markSpanFailed(message)
+		return
+	}
+	keyvals = prepare(keyvals)
+	l.handleTrace(message, keyvals)
+	l.markSpanFailed(message)
+	l.output(level.Error(l.logger()), message, keyvals)
+}
+
+// At will log a message and arbitrary key-value pairs at the given level,
+// e.g. a custom one created with level.Register.
+func (l Log) At(lvl level.Value, message string, keyvals ...interface{}) {
+	if l.levels.drops(lvl, l.name) {
+		return
+	}
+	keyvals = prepare(keyvals)
 	l.handleTrace(message, keyvals)
-	_ = level.Error(l.kitLogger).Log(l.mergeKeyValues(message, keyvals)...)
+	l.output(level.With(l.logger(), lvl), message, keyvals)
 }
 
 func (l Log) With(keyvals ...interface{}) Log {
@@ -118,32 +344,69 @@ func (l Log) With(keyvals ...interface{}) Log {
 		return l
 	}
 
-	kitLogger := log.With(l.kitLogger, keyvals...)
+	// encoded once by the JSON sink instead of per entry
+	l.kitLogger = log.With(l.kitLogger, bind(prepare(keyvals))...)
+	return l.bindTrace()
+}
 
-	return Log{
-		kitLogger: kitLogger,
-		span:      l.span,
-	}
+// WithRetention binds a retention policy (e.g. "30d", "1y") to all entries of
+// the returned Log. Single entries can override it by passing retention.Key
+// with their keyvals. Sinks supporting retention routing pick it up.
+func (l Log) WithRetention(policy string) Log {
+	return l.With(retention.Key, policy)
 }
 
 func (l Log) handleTrace(message string, keyvals []interface{}) {
 	if l.span != nil {
 		if message != "" {
-			l.span.Annotate(time.Now(), message)
+			l.span.Annotate(message)
+		}
+		var policy spanTagPolicy
+		if l.levels != nil {
+			policy = l.levels.spanTags
 		}
+		if policy.disabled {
+			return
+		}
+		tagged := 0
 		for i := 0; i < len(keyvals); i += 2 {
 			if i >= len(keyvals) || i+1 >= len(keyvals) {
 				break // break only for the uneven keyval combination, all others will be tagged
 			}
-			l.span.Tag(fmt.Sprint(keyvals[i]), fmt.Sprint(keyvals[i+1]))
+			if policy.max > 0 && tagged >= policy.max {
+				break
+			}
+			key := fmt.Sprint(keyvals[i])
+			if policy.keys != nil && !policy.keys[key] {
+				continue
+			}
+			l.span.Tag(key, fmt.Sprint(keyvals[i+1]))
+			tagged++
 		}
 	}
 }
 
+// markSpanFailed marks the span as failed, so traces with logged errors are
+// highlighted.
+func (l Log) markSpanFailed(message string) {
+	if l.span != nil {
+		l.span.SetError(message)
+	}
+}
+
+// prepare expands typed fields and destination hints and wraps multi-errors
+// before keyvals are handed to go-kit.
+func prepare(keyvals []interface{}) []interface{} {
+	return wrapMultiErrors(extractDestinations(expandFields(keyvals)))
+}
+
 // evaluateLogLevel maps a given logLevel as string (e.g. from an ENV variable) to a level Option.
-// If the passed logLevel does not exist, all levels will be enabled by default.
+// Aliases are accepted as by ParseLevel. If the passed logLevel does not exist, all levels will be
+// enabled by default.
 func evaluateLogLevel(logLevel string) (level.Option, error) {
-	logLevel = strings.ToLower(logLevel)
+	if lvl, err := ParseLevel(logLevel); err == nil {
+		logLevel = string(lvl)
+	}
 	switch logLevel {
 	case LevelDebug:
 		return level.AllowDebug(), nil
@@ -153,23 +416,65 @@ func evaluateLogLevel(logLevel string) (level.Option, error) {
 		return level.AllowWarn(), nil
 	case LevelError:
 		return level.AllowError(), nil
+	case LevelFatal:
+		return level.AllowFatal(), nil
+	case LevelPanic:
+		return level.AllowPanic(), nil
 	default:
+		if v, ok := level.Parse(logLevel); ok {
+			return level.Allow(v), nil
+		}
 		return level.AllowAll(), fmt.Errorf("no log-level passed, falling back to debug")
 	}
 }
 
-// mergeKeyValues will append the level and message field to already existing keyvals
-func (l Log) mergeKeyValues(message string, keyvals []interface{}) []interface{} {
-	var list []interface{}
-	var levelData []interface{}
+// levelName returns the name of the level evaluateLogLevel selects for logLevel.
+func levelName(logLevel string) string {
+	if lvl, err := ParseLevel(logLevel); err == nil {
+		return string(lvl)
+	}
+	return LevelDebug
+}
+
+// entryPool holds the buffers entries are built in. The loggers created by
+// the level package copy the keyvals into an entry of their own, so the
+// buffer can be reused once Log returned.
+var entryPool = sync.Pool{
+	New: func() interface{} { return &entryBuffer{keyvals: make([]interface{}, 0, 32)} },
+}
+
+type entryBuffer struct {
+	keyvals []interface{}
+}
+
+// maxPooledKeyvals limits the size of pooled buffers, so single huge entries
+// don't pin memory.
+const maxPooledKeyvals = 256
 
+// output hands the message, the name of the Log and keyvals to logger,
+// which must be created by the level package, e.g. level.Info(l.logger()).
+func (l Log) output(logger log.Logger, message string, keyvals []interface{}) {
+	buf := entryPool.Get().(*entryBuffer)
+	entry := buf.keyvals[:0]
 	if message != "" {
-		levelData = append(levelData, MessageKey)
-		levelData = append(levelData, message)
+		entry = append(entry, MessageKey, message)
+	}
+	if l.name != "" {
+		entry = append(entry, LoggerKey, l.name)
 	}
+	caller, ok, keyvals := l.caller(keyvals)
+	if ok {
+		entry = append(entry, CallerKey, caller)
+	}
+	entry = append(entry, keyvals...)
 
-	list = append(list, levelData...)
-	list = append(list, keyvals...)
+	_ = logger.Log(entry...)
 
-	return list
+	if cap(entry) > maxPooledKeyvals {
+		return
+	}
+	// drop the references to the values
+	clear(entry)
+	buf.keyvals = entry[:0]
+	entryPool.Put(buf)
 }
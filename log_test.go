@@ -0,0 +1,502 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// baselineLogger implements only the methods of the original Logger
+// interface, as external implementations and mocks do.
+type baselineLogger struct{}
+
+func (baselineLogger) Log(...interface{})                {}
+func (baselineLogger) Debug(string, ...interface{})      {}
+func (baselineLogger) Info(string, ...interface{})       {}
+func (baselineLogger) Warning(string, ...interface{})    {}
+func (baselineLogger) Error(string, ...interface{})      {}
+func (baselineLogger) With(...interface{}) Log           { return Log{} }
+func (baselineLogger) WithTrace(ctx context.Context) Log { return Log{} }
+
+// external implementations of Logger keep compiling
+var _ Logger = baselineLogger{}
+
+func TestLeveledMethods(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		level    string
+		log      func(l Log)
+		severity string
+	}{
+		{"debug", LevelDebug, func(l Log) { l.Debug("m") }, LevelDebug},
+		{"info", LevelDebug, func(l Log) { l.Info("m") }, LevelInfo},
+		{"warning", LevelDebug, func(l Log) { l.Warning("m") }, LevelWarning},
+		{"error", LevelDebug, func(l Log) { l.Error("m") }, LevelError},
+		{"debug filtered", LevelInfo, func(l Log) { l.Debug("m") }, ""},
+		{"info filtered", LevelWarning, func(l Log) { l.Info("m") }, ""},
+		{"warning filtered", LevelError, func(l Log) { l.Warning("m") }, ""},
+		{"error at error", LevelError, func(l Log) { l.Error("m") }, LevelError},
+		{"info ctx", LevelInfo, func(l Log) { l.InfoCtx(context.Background(), "m") }, LevelInfo},
+		{"alias", "warn", func(l Log) { l.Info("m"); l.Warning("m") }, LevelWarning},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, out := newBufferLogger(tt.level)
+			tt.log(logger)
+			entries := out.entries(t)
+			if tt.severity == "" {
+				if len(entries) != 0 {
+					t.Fatalf("entries = %v, want none", entries)
+				}
+				return
+			}
+			if len(entries) != 1 {
+				t.Fatalf("entries = %v, want 1", entries)
+			}
+			if got := entries[0]["severity"]; got != tt.severity {
+				t.Errorf("severity = %v, want %v", got, tt.severity)
+			}
+			if got := entries[0][MessageKey]; got != "m" {
+				t.Errorf("message = %v, want m", got)
+			}
+		})
+	}
+}
+
+func TestWith(t *testing.T) {
+	logger, out := newBufferLogger(LevelInfo)
+	base := logger.With("a", 1, "b", "x")
+	base.With("b", "y").Info("m", "c", true)
+	base.Info("m")
+
+	entries := out.entries(t)
+	if len(entries) != 2 {
+		t.Fatalf("entries = %v", entries)
+	}
+	if entries[0]["a"] != float64(1) || entries[0]["b"] != "y" || entries[0]["c"] != true {
+		t.Errorf("first entry = %v", entries[0])
+	}
+	if entries[1]["b"] != "x" {
+		t.Errorf("With changed the parent Log: %v", entries[1])
+	}
+}
+
+func TestReportRuntimeStats(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		interval time.Duration
+		reported bool
+	}{
+		{"interval", time.Millisecond, true},
+		{"zero interval", 0, false},
+		{"negative interval", -time.Second, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, out := newBufferLogger(LevelInfo)
+			stop := logger.ReportRuntimeStats(tt.interval, LevelInfo)
+			time.Sleep(20 * time.Millisecond)
+			stop()
+			stop()
+
+			entries := out.entries(t)
+			if reported := len(entries) > 0; reported != tt.reported {
+				t.Fatalf("reported = %v, want %v", reported, tt.reported)
+			}
+			if tt.reported && entries[0][LoggerKey] != "runtime" {
+				t.Errorf("logger = %v, want runtime", entries[0][LoggerKey])
+			}
+		})
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		status      int
+		level       string
+		until       bool
+	}{
+		{name: "get", method: http.MethodGet, status: http.StatusOK, level: LevelInfo},
+		{name: "put", method: http.MethodPut, body: `{"level":"debug"}`, status: http.StatusOK, level: LevelDebug},
+		{name: "put alias", method: http.MethodPut, body: `{"level":"WARN"}`, status: http.StatusOK, level: LevelWarning},
+		{name: "put form", method: http.MethodPut, contentType: "application/x-www-form-urlencoded", body: "level=error", status: http.StatusOK, level: LevelError},
+		{name: "put duration", method: http.MethodPut, body: `{"level":"debug","duration":"5m"}`, status: http.StatusOK, level: LevelDebug, until: true},
+		{name: "unknown level", method: http.MethodPut, body: `{"level":"verbose"}`, status: http.StatusBadRequest, level: LevelInfo},
+		{name: "missing level", method: http.MethodPut, body: `{}`, status: http.StatusBadRequest, level: LevelInfo},
+		{name: "malformed body", method: http.MethodPut, body: `{"level":`, status: http.StatusBadRequest, level: LevelInfo},
+		{name: "invalid duration", method: http.MethodPut, body: `{"level":"debug","duration":"soon"}`, status: http.StatusBadRequest, level: LevelInfo},
+		{name: "post", method: http.MethodPost, body: `{"level":"debug"}`, status: http.StatusMethodNotAllowed, level: LevelInfo},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, _ := newBufferLogger(LevelInfo)
+			req := httptest.NewRequest(tt.method, "/level", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			logger.LevelHandler().ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if got := logger.AtomicLevel().Level(); got != tt.level {
+				t.Errorf("level = %s, want %s", got, tt.level)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var payload levelPayload
+			if err := json.NewDecoder(rec.Body).Decode(&payload); err != nil {
+				t.Fatal(err)
+			}
+			if payload.Level != tt.level || (payload.Until != nil) != tt.until {
+				t.Errorf("response = %+v, want level %s, until %v", payload, tt.level, tt.until)
+			}
+		})
+	}
+}
+
+func TestBumpLevel(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		level   string
+		verbose bool
+		want    string
+	}{
+		{"info more verbose", LevelInfo, true, LevelDebug},
+		{"debug more verbose", LevelDebug, true, LevelDebug},
+		{"info less verbose", LevelInfo, false, LevelWarning},
+		{"warning less verbose", LevelWarning, false, LevelError},
+		{"error less verbose", LevelError, false, LevelError},
+		{"fatal more verbose", LevelFatal, true, LevelError},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, out := newBufferLogger(tt.level)
+			logger.bumpLevel(tt.verbose, SourceSignal)
+
+			if got := logger.AtomicLevel().Level(); got != tt.want {
+				t.Errorf("level = %s, want %s", got, tt.want)
+			}
+			changes := 0
+			for _, entry := range out.entries(t) {
+				if entry[MessageKey] == ConfigChangedMessage && entry["source"] == SourceSignal {
+					changes++
+				}
+			}
+			if want := map[bool]int{true: 1, false: 0}[tt.level != tt.want]; changes != want {
+				t.Errorf("%d changes emitted, want %d", changes, want)
+			}
+		})
+	}
+}
+
+// flushCounter is a JSON sink counting how often it's flushed.
+type flushCounter struct {
+	*jsonSink
+	flushed int
+}
+
+func (f *flushCounter) Flush() error {
+	f.flushed++
+	return nil
+}
+
+func TestFatal(t *testing.T) {
+	var events []string
+	defer func(previous func(int)) { exit = previous }(exit)
+	exit = func(code int) { events = append(events, fmt.Sprintf("exit %d", code)) }
+	RegisterShutdownHook(func() { events = append(events, "hook") })
+
+	out := &outputBuffer{}
+	sink := &flushCounter{jsonSink: newJSONSink(out)}
+	logger := NewLogger(LevelError, WithSink(sink), WithAsync(8))
+	logger.Fatal("m", "k", "v")
+
+	entries := out.entries(t)
+	if len(entries) != 1 || entries[0]["severity"] != LevelFatal || entries[0]["k"] != "v" {
+		t.Fatalf("entries = %v, want the fatal entry", entries)
+	}
+	if sink.flushed != 1 {
+		t.Errorf("sink flushed %d times, want 1", sink.flushed)
+	}
+	if got, want := strings.Join(events, ","), "hook,exit 1"; got != want {
+		t.Errorf("events = %s, want %s", got, want)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	logger, out := newBufferLogger(LevelError)
+	func() {
+		defer func() {
+			if r := recover(); r != "m" {
+				t.Errorf("recovered %v, want m", r)
+			}
+		}()
+		logger.Panic("m", "k", "v")
+	}()
+
+	entries := out.entries(t)
+	if len(entries) != 1 {
+		t.Fatalf("entries = %v, want the panic entry", entries)
+	}
+	entry := entries[0]
+	if entry["severity"] != LevelPanic || entry["k"] != "v" {
+		t.Errorf("entry = %v", entry)
+	}
+	if stack, _ := entry[StacktraceKey].(string); !strings.Contains(stack, "TestPanic") {
+		t.Errorf("%s = %q, want the stack of the caller", StacktraceKey, stack)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		want    Level
+		wantErr bool
+	}{
+		{"info", LevelInfo, false},
+		{" WARNING ", LevelWarning, false},
+		{"warn", LevelWarning, false},
+		{"Trace", LevelDebug, false},
+		{"err", LevelError, false},
+		{"crit", LevelFatal, false},
+		{"emergency", LevelPanic, false},
+		{"0", LevelPanic, false},
+		{"3", LevelError, false},
+		{"5", LevelInfo, false},
+		{"7", LevelDebug, false},
+		{"8", "", true},
+		{"-1", "", true},
+		{"verbose", "", true},
+		{"", "", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLevel(tt.name)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseLevel(%q) = %q, %v, want %q, error %v", tt.name, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseLevelSpec(t *testing.T) {
+	for _, tt := range []struct {
+		spec  string
+		def   string
+		named map[string]string
+	}{
+		{"info", "info", nil},
+		{"info,storage=debug", "info", map[string]string{"storage": "debug"}},
+		{" warn , storage = debug , storage.s3=error", "warn", map[string]string{"storage": "debug", "storage.s3": "error"}},
+		{"storage=debug", "", map[string]string{"storage": "debug"}},
+		{"", "", nil},
+	} {
+		def, named := parseLevelSpec(tt.spec)
+		if def != tt.def || !reflect.DeepEqual(named, tt.named) {
+			t.Errorf("parseLevelSpec(%q) = %q, %v, want %q, %v", tt.spec, def, named, tt.def, tt.named)
+		}
+	}
+}
+
+func TestIsLevelEnabled(t *testing.T) {
+	logger, _ := newBufferLogger("warn,storage=debug,storage.cache=error")
+	for _, tt := range []struct {
+		logger  string
+		level   Level
+		enabled bool
+	}{
+		{"", LevelInfo, false},
+		{"", LevelWarning, true},
+		{"", "unknown", false},
+		{"storage", LevelDebug, true},
+		{"storage.s3", LevelDebug, true},
+		{"storage.cache", LevelWarning, false},
+		{"other", LevelInfo, false},
+	} {
+		l := logger
+		if tt.logger != "" {
+			l = l.Named(tt.logger)
+		}
+		if got := l.IsLevelEnabled(tt.level); got != tt.enabled {
+			t.Errorf("%q.IsLevelEnabled(%q) = %v, want %v", tt.logger, tt.level, got, tt.enabled)
+		}
+	}
+}
+
+func TestEnableDebugFor(t *testing.T) {
+	logger, out := newBufferLogger(LevelWarning)
+	logger.EnableDebugFor(time.Hour)
+	// extending the window keeps the level reverted to
+	logger.EnableDebugFor(20 * time.Millisecond)
+	if got := logger.AtomicLevel().Level(); got != LevelDebug {
+		t.Fatalf("level = %s, want %s", got, LevelDebug)
+	}
+	waitFor(t, "the revert", func() bool { return logger.AtomicLevel().Level() == LevelWarning })
+
+	var changes []string
+	for _, entry := range out.entries(t) {
+		if entry[MessageKey] == ConfigChangedMessage {
+			changes = append(changes, fmt.Sprintf("%v>%v", entry["before"], entry["after"]))
+		}
+	}
+	if got, want := strings.Join(changes, ","), "warning>debug,debug>debug,debug>warning"; got != want {
+		t.Errorf("changes = %s, want %s", got, want)
+	}
+}
+
+func TestEnableDebugForEndedBySetLevel(t *testing.T) {
+	logger, _ := newBufferLogger(LevelWarning)
+	logger.EnableDebugFor(10 * time.Millisecond)
+	logger.SetLevel(LevelError)
+	time.Sleep(30 * time.Millisecond)
+	if got := logger.AtomicLevel().Level(); got != LevelError {
+		t.Errorf("level = %s, want %s kept after the window", got, LevelError)
+	}
+}
+
+func TestSetLevelDerived(t *testing.T) {
+	logger, out := newBufferLogger(LevelError)
+	derived := []Log{
+		logger.With("k", "v"),
+		logger.Named("child"),
+		logger.WithTrace(context.Background()),
+	}
+	logger.SetLevel(LevelInfo)
+	for _, l := range derived {
+		l.Info("m")
+	}
+	if n := len(out.entries(t)) - 1; n != len(derived) {
+		t.Errorf("%d derived Logs logged at the new level, want %d", n, len(derived))
+	}
+
+	if err := logger.AtomicLevel().SetLevel("verbose"); err == nil {
+		t.Error("AtomicLevel.SetLevel accepted an unknown level")
+	}
+	if got := logger.AtomicLevel().Level(); got != LevelInfo {
+		t.Errorf("level = %s after an unknown level, want %s kept", got, LevelInfo)
+	}
+	logger.SetLevel("verbose")
+	if got := logger.AtomicLevel().Level(); got != LevelInfo {
+		t.Errorf("level = %s, want the fallback %s", got, LevelInfo)
+	}
+	logger.SetLevel("warn")
+	if got := derived[0].AtomicLevel().Level(); got != LevelWarning {
+		t.Errorf("derived level = %s, want %s", got, LevelWarning)
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		level Level
+		want  string
+	}{
+		{"error", LevelError, LevelError},
+		{"alias", "warn", LevelWarning},
+		{"unknown level", "verbose", LevelInfo},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, out := newBufferLogger(LevelDebug)
+			logger.StdLogger(tt.level).Printf("http: TLS handshake error from %s\n", "10.0.0.1")
+
+			entries := out.entries(t)
+			if len(entries) != 1 {
+				t.Fatalf("entries = %v, want 1", entries)
+			}
+			if entries[0]["severity"] != tt.want || entries[0][MessageKey] != "http: TLS handshake error from 10.0.0.1" {
+				t.Errorf("entry = %v, want the line at %s", entries[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	ctx, err := ContextWithTraceparent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name     string
+		err      error
+		severity string
+	}{
+		{"error", errors.New("decoding request failed"), LevelError},
+		{"canceled", fmt.Errorf("reading body: %w", context.Canceled), LevelWarning},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, out := newBufferLogger(LevelInfo)
+			NewErrorHandler(logger).Handle(ctx, tt.err)
+
+			entry := out.entries(t)[0]
+			if entry["severity"] != tt.severity || entry["err"] != tt.err.Error() {
+				t.Errorf("entry = %v, want the error at %s", entry, tt.severity)
+			}
+			if entry[TraceIDKey] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Errorf("entry = %v, want it bound to the trace of the context", entry)
+			}
+		})
+	}
+}
+
+func TestCaptureCmd(t *testing.T) {
+	logger, out := newBufferLogger(LevelDebug)
+	cmd := exec.Command("/usr/bin/pg_dump", "app")
+	flush := logger.CaptureCmd(cmd, LevelInfo, "warn")
+
+	// written as the started command would
+	_, _ = io.WriteString(cmd.Stdout, "first\r\nsec")
+	_, _ = io.WriteString(cmd.Stderr, "failed\n")
+	_, _ = io.WriteString(cmd.Stdout, "ond\nunterminated")
+	flush()
+
+	var got []string
+	for _, entry := range out.entries(t) {
+		if entry[CmdKey] != "pg_dump" {
+			t.Errorf("%s = %v, want pg_dump", CmdKey, entry[CmdKey])
+		}
+		got = append(got, fmt.Sprintf("%v %v %v", entry["stream"], entry["severity"], entry[MessageKey]))
+	}
+	want := []string{
+		"stdout info first",
+		"stderr warning failed",
+		"stdout info second",
+		"stdout info unterminated",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %q, want %q", got, want)
+	}
+}
+
+func TestGo(t *testing.T) {
+	logger, out := newBufferLogger(LevelDebug)
+	done := make(chan struct{})
+	logger.Go(func() {
+		defer close(done)
+		panic("queue closed")
+	})
+	<-done
+	waitFor(t, "the panic entry", func() bool { return out.String() != "" })
+
+	entries := out.entries(t)
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	entry := entries[0]
+	if entry["severity"] != "error" || entry["panic"] != "queue closed" {
+		t.Errorf("entry = %v, want an error with the panic value", entry)
+	}
+	if stack, _ := entry[StacktraceKey].(string); !strings.Contains(stack, "TestGo") {
+		t.Errorf("%s = %q, want the panicking goroutine's stack", StacktraceKey, stack)
+	}
+}
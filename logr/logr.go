@@ -0,0 +1,66 @@
+// Package logr provides a logr.LogSink writing through the godin logger, so
+// components built on logr, e.g. controller-runtime and client-go, share its
+// format and level filtering.
+package logr
+
+import (
+	"github.com/go-godin/log"
+	stdlogr "github.com/go-logr/logr"
+)
+
+// NewLogger returns a logr.Logger writing through logger:
+//
+//	ctrl.SetLogger(logr.NewLogger(logger))
+func NewLogger(logger log.Log) stdlogr.Logger {
+	return stdlogr.New(NewSink(logger))
+}
+
+// NewSink returns a logr.LogSink writing through logger. Info entries of
+// verbosity 0 are logged as info, all more verbose ones as debug. Names
+// added with WithName become Named loggers, so their level can be set
+// separately, e.g. "info,controller=debug".
+func NewSink(logger log.Log) stdlogr.LogSink {
+	return &sink{logger: logger}
+}
+
+type sink struct {
+	logger log.Log
+}
+
+func (s *sink) Init(stdlogr.RuntimeInfo) {}
+
+func (s *sink) Enabled(verbosity int) bool {
+	return s.logger.IsLevelEnabled(levelFor(verbosity))
+}
+
+func (s *sink) Info(verbosity int, msg string, keysAndValues ...interface{}) {
+	keysAndValues = append(keysAndValues[:len(keysAndValues):len(keysAndValues)], callerHint)
+	if levelFor(verbosity) == log.LevelDebug {
+		s.logger.Debug(msg, keysAndValues...)
+		return
+	}
+	s.logger.Info(msg, keysAndValues...)
+}
+
+func (s *sink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.logger.Error(msg, append(append([]interface{}{"err", err}, keysAndValues...), callerHint)...)
+}
+
+func (s *sink) WithValues(keysAndValues ...interface{}) stdlogr.LogSink {
+	return &sink{logger: s.logger.With(keysAndValues...)}
+}
+
+func (s *sink) WithName(name string) stdlogr.LogSink {
+	return &sink{logger: s.logger.Named(name)}
+}
+
+// callerHint reports the code calling logr as call site of the entries.
+var callerHint = log.CallerOutside("github.com/go-logr/logr.")
+
+// levelFor maps a logr verbosity to a level.
+func levelFor(verbosity int) log.Level {
+	if verbosity > 0 {
+		return log.LevelDebug
+	}
+	return log.LevelInfo
+}
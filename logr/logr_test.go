@@ -0,0 +1,110 @@
+package logr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-godin/log"
+	kitlog "github.com/go-kit/kit/log"
+	stdlogr "github.com/go-logr/logr"
+)
+
+func TestLogger(t *testing.T) {
+	tests := []struct {
+		name  string
+		level string
+		log   func(l stdlogr.Logger)
+		want  map[string]interface{} // the checked fields, nil if nothing is logged
+	}{
+		{
+			name:  "info",
+			level: log.LevelInfo,
+			log:   func(l stdlogr.Logger) { l.Info("reconciled", "pods", 3) },
+			want:  map[string]interface{}{"message": "reconciled", "severity": "info", "pods": float64(3)},
+		},
+		{
+			name:  "verbose info",
+			level: log.LevelDebug,
+			log:   func(l stdlogr.Logger) { l.V(2).Info("cache synced") },
+			want:  map[string]interface{}{"message": "cache synced", "severity": "debug"},
+		},
+		{
+			name:  "verbose info below the level",
+			level: log.LevelInfo,
+			log:   func(l stdlogr.Logger) { l.V(1).Info("cache synced") },
+		},
+		{
+			name:  "error",
+			level: log.LevelError,
+			log:   func(l stdlogr.Logger) { l.Error(errors.New("conflict"), "update failed", "pod", "web-1") },
+			want:  map[string]interface{}{"message": "update failed", "severity": "error", "err": "conflict", "pod": "web-1"},
+		},
+		{
+			name:  "values",
+			level: log.LevelInfo,
+			log:   func(l stdlogr.Logger) { l.WithValues("namespace", "prod").Info("reconciled") },
+			want:  map[string]interface{}{"message": "reconciled", "namespace": "prod"},
+		},
+		{
+			name:  "names",
+			level: log.LevelInfo,
+			log:   func(l stdlogr.Logger) { l.WithName("controller").WithName("pods").Info("reconciled") },
+			want:  map[string]interface{}{"message": "reconciled", log.LoggerKey: "controller.pods"},
+		},
+		{
+			name:  "level of a name",
+			level: "info,controller=debug",
+			log:   func(l stdlogr.Logger) { l.WithName("controller").V(1).Info("cache synced") },
+			want:  map[string]interface{}{"message": "cache synced", "severity": "debug", log.LoggerKey: "controller"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := log.NewLogger(tt.level, log.WithSink(kitlog.NewJSONLogger(&buf)))
+			tt.log(NewLogger(logger))
+
+			if tt.want == nil {
+				if buf.Len() != 0 {
+					t.Errorf("logged %s, want nothing", buf.String())
+				}
+				return
+			}
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != 1 {
+				t.Fatalf("logged %d entries, want 1: %s", len(lines), buf.String())
+			}
+			var entry map[string]interface{}
+			if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+				t.Fatalf("decoding %q: %v", lines[0], err)
+			}
+			for key, value := range tt.want {
+				if entry[key] != value {
+					t.Errorf("%s = %v, want %v", key, entry[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	tests := []struct {
+		level     string
+		verbosity int
+		enabled   bool
+	}{
+		{log.LevelInfo, 0, true},
+		{log.LevelInfo, 1, false},
+		{log.LevelDebug, 5, true},
+		{log.LevelError, 0, false},
+	}
+	for _, tt := range tests {
+		logger := log.NewLogger(tt.level, log.WithSink(kitlog.NewNopLogger()))
+		if enabled := NewLogger(logger).V(tt.verbosity).Enabled(); enabled != tt.enabled {
+			t.Errorf("V(%d).Enabled() at %s = %v, want %v", tt.verbosity, tt.level, enabled, tt.enabled)
+		}
+	}
+}
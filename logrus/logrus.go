@@ -0,0 +1,86 @@
+// Package logrus provides a logrus.Hook writing entries through the godin
+// logger, so packages still logging with logrus can be migrated one by one.
+package logrus
+
+import (
+	"sort"
+
+	"github.com/go-godin/log"
+	"github.com/go-godin/log/level"
+	stdlogrus "github.com/sirupsen/logrus"
+)
+
+// Hook forwards logrus entries to a Log, so they pass its level filter and
+// sinks. Fields are added as keyvals sorted by key, the error field is
+// renamed to err. Entries with a context are bound to its span and fields,
+// see log.WithTrace. Discard the output of the logrus logger to avoid
+// duplicates:
+//
+//	logrus.SetOutput(io.Discard)
+//	logrus.AddHook(logrus.NewHook(logger))
+type Hook struct {
+	logger log.Log
+}
+
+// NewHook creates a Hook writing through logger.
+func NewHook(logger log.Log) *Hook {
+	return &Hook{logger: logger}
+}
+
+// Levels returns all levels, the level filter of the Log applies.
+func (h *Hook) Levels() []stdlogrus.Level {
+	return stdlogrus.AllLevels
+}
+
+// Fire writes the entry. Fatal and panic entries are logged at their level
+// without exiting or panicking, logrus does so after its hooks ran.
+func (h *Hook) Fire(e *stdlogrus.Entry) error {
+	keys := make([]string, 0, len(e.Data))
+	for key := range e.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	keyvals := make([]interface{}, 0, 2*len(keys))
+	for _, key := range keys {
+		name := key
+		if key == stdlogrus.ErrorKey {
+			name = "err"
+		}
+		keyvals = append(keyvals, name, e.Data[key])
+	}
+
+	caller := callerHint
+	if e.Caller != nil {
+		caller = log.CallerPC(e.Caller.PC)
+	}
+	keyvals = append(keyvals, caller)
+
+	l := h.logger
+	if e.Context != nil {
+		l = l.WithTrace(e.Context)
+	}
+	l.At(levelFor(e.Level), e.Message, keyvals...)
+	return nil
+}
+
+// callerHint reports the code calling logrus as call site of the entries,
+// unless logrus reports the caller itself.
+var callerHint = log.CallerOutside("github.com/sirupsen/logrus.")
+
+// levelFor maps a logrus level to a level, trace becomes debug.
+func levelFor(lvl stdlogrus.Level) level.Value {
+	switch lvl {
+	case stdlogrus.PanicLevel:
+		return level.PanicValue()
+	case stdlogrus.FatalLevel:
+		return level.FatalValue()
+	case stdlogrus.ErrorLevel:
+		return level.ErrorValue()
+	case stdlogrus.WarnLevel:
+		return level.WarnValue()
+	case stdlogrus.InfoLevel:
+		return level.InfoValue()
+	default:
+		return level.DebugValue()
+	}
+}
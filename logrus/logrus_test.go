@@ -0,0 +1,61 @@
+// The frames of the module are skipped by log.WithCaller, so the call sites
+// are tested from outside the package.
+package logrus_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/go-godin/log"
+	"github.com/go-godin/log/logrus"
+	kitlog "github.com/go-kit/kit/log"
+	stdlogrus "github.com/sirupsen/logrus"
+)
+
+// here returns the call site of its caller as reported by log.WithCaller.
+func here() string {
+	_, file, line, _ := runtime.Caller(1)
+	return filepath.Join(filepath.Base(filepath.Dir(file)), filepath.Base(file)) + ":" + strconv.Itoa(line)
+}
+
+func TestHookCaller(t *testing.T) {
+	tests := []struct {
+		name         string
+		reportCaller bool
+		log          func(l *stdlogrus.Logger)
+	}{
+		{
+			name: "entry method",
+			log:  func(l *stdlogrus.Logger) { l.WithField("want", here()).Warn("test") },
+		},
+		{
+			name:         "reported caller",
+			reportCaller: true,
+			log:          func(l *stdlogrus.Logger) { l.WithField("want", here()).Error("test") },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := log.NewLogger(log.LevelDebug, log.WithSink(kitlog.NewJSONLogger(&buf)), log.WithCaller(0))
+			l := stdlogrus.New()
+			l.SetOutput(io.Discard)
+			l.SetReportCaller(tt.reportCaller)
+			l.AddHook(logrus.NewHook(logger))
+			tt.log(l)
+
+			var entry map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatal(err)
+			}
+			if caller, want := entry[log.CallerKey], entry["want"]; caller != want {
+				t.Errorf("caller = %v, want %v", caller, want)
+			}
+		})
+	}
+}
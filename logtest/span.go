@@ -0,0 +1,167 @@
+// Package logtest provides helpers to test code which logs through godin's Log.
+package logtest
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-godin/log"
+)
+
+func init() {
+	log.RegisterSpanSource(func(ctx context.Context) log.SpanRecorder {
+		if s, ok := ctx.Value(spanKey{}).(*Span); ok {
+			return s
+		}
+		return nil
+	})
+}
+
+type spanKey struct{}
+
+// Annotation is a timed event recorded by Span.
+type Annotation struct {
+	Time  time.Time
+	Value string
+}
+
+// Span is a fake span recording the annotations and tags produced by a Log
+// created with WithTrace or WithSpan.
+type Span struct {
+	mtx         sync.Mutex
+	traceparent log.Traceparent
+	annotations []Annotation
+	tags        map[string]string
+}
+
+// NewSpan creates an empty fake span of a sampled trace.
+func NewSpan() *Span {
+	return &Span{
+		traceparent: log.Traceparent{
+			TraceID: "00000000000000000000000000000001",
+			SpanID:  "0000000000000001",
+			Sampled: true,
+		},
+		tags: make(map[string]string),
+	}
+}
+
+// NewContext returns a copy of ctx carrying the span, ready to be passed to WithTrace.
+func (s *Span) NewContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+// TraceContext returns the trace context added to the entries of the Log.
+func (s *Span) TraceContext() log.Traceparent {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.traceparent
+}
+
+// SetTraceContext sets the trace context added to the entries of the Log,
+// e.g. of a trace which isn't sampled.
+func (s *Span) SetTraceContext(tp log.Traceparent) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.traceparent = tp
+}
+
+// Annotate records a timed event.
+func (s *Span) Annotate(value string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.annotations = append(s.annotations, Annotation{Time: time.Now(), Value: value})
+}
+
+// Tag records a tag, overriding existing values of the same key.
+func (s *Span) Tag(key, value string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.tags[key] = value
+}
+
+// SetError marks the span as failed by tagging the message as error.
+func (s *Span) SetError(message string) {
+	s.Tag("error", message)
+}
+
+// Annotations returns the recorded annotations in the order they were added.
+func (s *Span) Annotations() []Annotation {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]Annotation(nil), s.annotations...)
+}
+
+// Tags returns a copy of the recorded tags.
+func (s *Span) Tags() map[string]string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	tags := make(map[string]string, len(s.tags))
+	for k, v := range s.tags {
+		tags[k] = v
+	}
+	return tags
+}
+
+// AssertAnnotated fails the test if any of the values hasn't been annotated.
+func (s *Span) AssertAnnotated(t testing.TB, values ...string) {
+	t.Helper()
+	recorded := s.Annotations()
+	for _, v := range values {
+		if indexOf(recorded, v, 0) < 0 {
+			t.Errorf("span has no annotation %q, got %s", v, formatAnnotations(recorded))
+		}
+	}
+}
+
+// AssertAnnotationOrder fails the test unless the values have been annotated
+// in the given order. Other annotations may occur in between.
+func (s *Span) AssertAnnotationOrder(t testing.TB, values ...string) {
+	t.Helper()
+	recorded := s.Annotations()
+	pos := 0
+	for _, v := range values {
+		i := indexOf(recorded, v, pos)
+		if i < 0 {
+			t.Errorf("span has no annotation %q after position %d, want order %q, got %s", v, pos, values, formatAnnotations(recorded))
+			return
+		}
+		pos = i + 1
+	}
+}
+
+// AssertAnnotationTiming fails the test unless all annotations carry a time
+// within [notBefore, notAfter] and their times never decrease, so consumers
+// can rely on them to reconstruct the request timeline.
+func (s *Span) AssertAnnotationTiming(t testing.TB, notBefore, notAfter time.Time) {
+	t.Helper()
+	recorded := s.Annotations()
+	for i, a := range recorded {
+		if a.Time.Before(notBefore) || a.Time.After(notAfter) {
+			t.Errorf("annotation %q at %s is outside of [%s, %s]", a.Value, a.Time.Format(time.RFC3339Nano), notBefore.Format(time.RFC3339Nano), notAfter.Format(time.RFC3339Nano))
+		}
+		if i > 0 && a.Time.Before(recorded[i-1].Time) {
+			t.Errorf("annotation %q at %s happened before the preceding annotation %q at %s", a.Value, a.Time.Format(time.RFC3339Nano), recorded[i-1].Value, recorded[i-1].Time.Format(time.RFC3339Nano))
+		}
+	}
+}
+
+func indexOf(annotations []Annotation, value string, from int) int {
+	for i := from; i < len(annotations); i++ {
+		if annotations[i].Value == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func formatAnnotations(annotations []Annotation) string {
+	values := make([]string, len(annotations))
+	for i, a := range annotations {
+		values[i] = a.Value
+	}
+	return "[" + strings.Join(values, ", ") + "]"
+}
@@ -0,0 +1,40 @@
+package logtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-godin/log"
+)
+
+func TestSpanRecordsEntries(t *testing.T) {
+	span := NewSpan()
+	ctx := span.NewContext(context.Background())
+	logger := log.NewLogger("debug", log.WithSink(nopSink{})).WithTrace(ctx)
+
+	before := time.Now()
+	logger.Info("started", "user", "u1")
+	logger.Error("failed")
+	span.AssertAnnotated(t, "started", "failed")
+	span.AssertAnnotationOrder(t, "started", "failed")
+	span.AssertAnnotationTiming(t, before, time.Now())
+
+	for key, want := range map[string]string{"user": "u1", "error": "failed"} {
+		if got := span.Tags()[key]; got != want {
+			t.Errorf("tag %s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestSpanTraceContext(t *testing.T) {
+	span := NewSpan()
+	span.SetTraceContext(log.Traceparent{TraceID: "1", SpanID: "2", Sampled: false})
+	if got := span.TraceContext(); got.TraceID != "1" || got.SpanID != "2" || got.Sampled {
+		t.Errorf("TraceContext() = %+v", got)
+	}
+}
+
+type nopSink struct{}
+
+func (nopSink) Log(...interface{}) error { return nil }
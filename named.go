@@ -0,0 +1,98 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/level"
+	"github.com/go-kit/kit/log"
+)
+
+// LoggerKey is the key of the field carrying the name of a Named logger.
+const LoggerKey = "logger"
+
+// Named returns a child Log whose entries carry the name in the LoggerKey
+// field. Naming a named Log joins the names with dots, e.g. "storage.s3".
+//
+// The level of named loggers can be overridden by appending name=level pairs
+// to the level passed to NewLogger, e.g. LOG_LEVEL="info,storage=debug"
+// raises the verbosity of "storage" and all its children only. The most
+// specific name wins.
+func (l Log) Named(name string) Log {
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	l.name = name
+	return l
+}
+
+// parseLevelSpec splits a level specification like "info,storage=debug"
+// into the default level and the levels of named loggers.
+func parseLevelSpec(spec string) (string, map[string]string) {
+	var def string
+	var named map[string]string
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		name, lvl, ok := strings.Cut(part, "=")
+		if !ok {
+			def = part
+			continue
+		}
+		if named == nil {
+			named = make(map[string]string)
+		}
+		named[strings.TrimSpace(name)] = strings.TrimSpace(lvl)
+	}
+	return def, named
+}
+
+// namedFilter applies the level filter registered for the name of a Named
+// logger, falling back to the default filter.
+type namedFilter struct {
+	fallback log.Logger
+	named    map[string]log.Logger
+}
+
+// newNamedFilter creates level filters in front of next for the given levels
+// of named loggers.
+func newNamedFilter(next, fallback log.Logger, levels map[string]string) (namedFilter, error) {
+	f := namedFilter{fallback: fallback, named: make(map[string]log.Logger, len(levels))}
+	var err error
+	for name, lvl := range levels {
+		opt, lvlErr := evaluateLogLevel(lvl)
+		if lvlErr != nil {
+			err = fmt.Errorf("unknown log-level %q for logger %q, using the default", lvl, name)
+			continue
+		}
+		f.named[name] = level.NewFilter(next, opt, level.AllowForced())
+	}
+	return f, err
+}
+
+func (f namedFilter) Log(keyvals ...interface{}) error {
+	if name, ok := fieldValue(keyvals, LoggerKey); ok {
+		if filter, ok := lookupNamed(f.named, kv.String(name)); ok {
+			return filter.Log(keyvals...)
+		}
+	}
+	return f.fallback.Log(keyvals...)
+}
+
+// lookupNamed returns the value registered for the most specific name of
+// the named logger, i.e. "storage" for "storage.s3" unless "storage.s3" is
+// registered itself.
+func lookupNamed[T any](m map[string]T, name string) (T, bool) {
+	for name != "" {
+		if v, ok := m[name]; ok {
+			return v, true
+		}
+		dot := strings.LastIndexByte(name, '.')
+		if dot < 0 {
+			break
+		}
+		name = name[:dot]
+	}
+	var zero T
+	return zero, false
+}
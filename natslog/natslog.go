@@ -0,0 +1,73 @@
+// Package natslog logs the messages consumed by NATS subscribers with the
+// godin logger.
+package natslog
+
+import (
+	"context"
+	"net/http"
+	"net/textproto"
+	"time"
+
+	"github.com/go-godin/log"
+	"github.com/go-godin/log/correlation"
+	"github.com/nats-io/nats.go"
+)
+
+// Handler processes a message.
+type Handler func(ctx context.Context, msg *nats.Msg) error
+
+// Middleware logs the start of every message as debug and its finish with
+// the subject, delivery attempt and duration in seconds, as info or as error
+// if the handler failed. The handler receives a Log in its context, see
+// log.FromContext, carrying these fields, the trace of the message headers
+// (b3 or traceparent) and its correlation ID. Messages without correlation
+// ID get a new one.
+func Middleware(logger log.Log) func(Handler) Handler {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *nats.Msg) error {
+			begin := time.Now()
+
+			h := header(msg.Header)
+			if tp, ok := log.TraceFromHeader(h); ok {
+				ctx = log.ContextWithTrace(ctx, tp)
+			}
+			id := h.Get(correlation.Header)
+			if id == "" {
+				id = correlation.Generate()
+			}
+			ctx = correlation.NewContext(ctx, id)
+
+			keyvals := []interface{}{"subject", msg.Subject, "attempt", attempt(msg)}
+			l := logger.WithTrace(ctx).With(keyvals...)
+			l.Debug("consume started")
+
+			err := next(log.NewContext(ctx, l), msg)
+			if err != nil {
+				l.Error("consume failed", "duration", time.Since(begin).Seconds(), "err", err)
+				return err
+			}
+			l.Info("consume finished", "duration", time.Since(begin).Seconds())
+			return nil
+		}
+	}
+}
+
+// attempt returns the delivery attempt of JetStream messages, and 1 for
+// core NATS messages which aren't redelivered.
+func attempt(msg *nats.Msg) uint64 {
+	if md, err := msg.Metadata(); err == nil {
+		return md.NumDelivered
+	}
+	return 1
+}
+
+// header converts the message headers, whose keys are case sensitive, to an
+// http.Header.
+func header(nh nats.Header) http.Header {
+	h := make(http.Header, len(nh))
+	for key, values := range nh {
+		key = textproto.CanonicalMIMEHeaderKey(key)
+		h[key] = append(h[key], values...)
+	}
+	return h
+}
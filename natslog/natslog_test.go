@@ -0,0 +1,117 @@
+package natslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-godin/log"
+	"github.com/go-godin/log/correlation"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/nats-io/nats.go"
+)
+
+func TestMiddleware(t *testing.T) {
+	failure := errors.New("rejected")
+	tests := []struct {
+		name            string
+		msg             *nats.Msg
+		err             error
+		wantMessage     string
+		wantLevel       string
+		wantAttempt     float64
+		wantCorrelation string // empty for a generated one
+		wantTrace       string
+	}{
+		{
+			name:        "finished",
+			msg:         &nats.Msg{Subject: "orders.created"},
+			wantMessage: "consume finished",
+			wantLevel:   "info",
+			wantAttempt: 1,
+		},
+		{
+			name:        "failed",
+			msg:         &nats.Msg{Subject: "orders.created"},
+			err:         failure,
+			wantMessage: "consume failed",
+			wantLevel:   "error",
+			wantAttempt: 1,
+		},
+		{
+			name: "jetstream redelivery",
+			msg: &nats.Msg{
+				Subject: "orders.created",
+				Reply:   "$JS.ACK.ORDERS.worker.3.10.5.1700000000000000000.0",
+				Sub:     &nats.Subscription{},
+			},
+			wantMessage: "consume finished",
+			wantLevel:   "info",
+			wantAttempt: 3,
+		},
+		{
+			name:        "reply without jetstream metadata",
+			msg:         &nats.Msg{Subject: "orders.created", Reply: "_INBOX.1", Sub: &nats.Subscription{}},
+			wantMessage: "consume finished",
+			wantLevel:   "info",
+			wantAttempt: 1,
+		},
+		{
+			name:            "correlation ID header",
+			msg:             &nats.Msg{Subject: "orders.created", Header: nats.Header{"x-correlation-id": {"c1"}}},
+			wantMessage:     "consume finished",
+			wantLevel:       "info",
+			wantAttempt:     1,
+			wantCorrelation: "c1",
+		},
+		{
+			name:        "traceparent header",
+			msg:         &nats.Msg{Subject: "orders.created", Header: nats.Header{"traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}},
+			wantMessage: "consume finished",
+			wantLevel:   "info",
+			wantAttempt: 1,
+			wantTrace:   "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := log.NewLogger(log.LevelInfo, log.WithSink(kitlog.NewJSONLogger(&buf)))
+			var handlerCorrelation string
+			h := Middleware(logger)(func(ctx context.Context, msg *nats.Msg) error {
+				handlerCorrelation, _ = correlation.FromContext(ctx)
+				return tt.err
+			})
+			if err := h(context.Background(), tt.msg); err != tt.err {
+				t.Fatalf("handler returned %v, want %v", err, tt.err)
+			}
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != 1 {
+				t.Fatalf("logged %d entries, want 1: %s", len(lines), buf.String())
+			}
+			var entry map[string]interface{}
+			if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+				t.Fatalf("decoding %q: %v", lines[0], err)
+			}
+			if entry[log.MessageKey] != tt.wantMessage || entry["severity"] != tt.wantLevel {
+				t.Errorf("message, severity = %v, %v, want %s, %s", entry[log.MessageKey], entry["severity"], tt.wantMessage, tt.wantLevel)
+			}
+			if entry["attempt"] != tt.wantAttempt {
+				t.Errorf("attempt = %v, want %v", entry["attempt"], tt.wantAttempt)
+			}
+			if entry["subject"] != tt.msg.Subject {
+				t.Errorf("subject = %v, want %s", entry["subject"], tt.msg.Subject)
+			}
+			if handlerCorrelation == "" || (tt.wantCorrelation != "" && handlerCorrelation != tt.wantCorrelation) {
+				t.Errorf("correlation ID = %q, want %q", handlerCorrelation, tt.wantCorrelation)
+			}
+			if tt.wantTrace != "" && entry[log.TraceIDKey] != tt.wantTrace {
+				t.Errorf("trace_id = %v, want %s", entry[log.TraceIDKey], tt.wantTrace)
+			}
+		})
+	}
+}
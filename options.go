@@ -0,0 +1,138 @@
+package log
+
+import (
+	"io"
+	"os"
+
+	"github.com/go-godin/log/level"
+	"github.com/go-kit/kit/log"
+)
+
+// Option configures a Log created by NewLogger.
+type Option func(*options)
+
+type options struct {
+	sink          log.Logger
+	outputs       map[string]log.Logger
+	recorders     []log.Logger
+	goKitCompat   bool
+	promoteErrors bool
+	minLevelSinks []minLevelSink
+
+	debugBuffer    int
+	debugBufferKey string
+	sampledDebug   bool
+	datadogFields  bool
+	spanTags       spanTagPolicy
+	async          int
+	overflow       OverflowPolicy
+	onDrop         func(keyvals []interface{})
+	ringBuffer     bool
+	caller         bool
+	callerSkip     int
+	timestamp      log.Valuer
+	// fileSinks is set by NewLoggerFromFile, whose sinks are resolved
+	// through the AtomicLevel since reloads replace them
+	fileSinks bool
+}
+
+type minLevelSink struct {
+	sink     log.Logger
+	minLevel string
+}
+
+func defaultOptions() options {
+	return options{
+		sink: newJSONSink(os.Stdout),
+	}
+}
+
+// WithSink replaces the default JSON output on stdout with the given sink.
+// Every entry passing the level filter is handed to sink.Log.
+func WithSink(sink log.Logger) Option {
+	return func(o *options) { o.sink = sink }
+}
+
+// WithLevelSink hands entries of the given levels to sink instead of the
+// sink set by WithSink. Entries of all other levels are not affected. The
+// levels accept the aliases of ParseLevel.
+func WithLevelSink(sink log.Logger, levels ...string) Option {
+	return func(o *options) {
+		if o.outputs == nil {
+			o.outputs = make(map[string]log.Logger)
+		}
+		for _, lvl := range levels {
+			o.outputs[outputLevel(lvl)] = sink
+		}
+	}
+}
+
+// outputLevel returns the name of the level as parsed by ParseLevel, or name
+// itself if it's no level.
+func outputLevel(name string) string {
+	if lvl, err := ParseLevel(name); err == nil {
+		return string(lvl)
+	}
+	return name
+}
+
+// WithOutput writes entries of the given levels as JSON to w.
+func WithOutput(w io.Writer, levels ...string) Option {
+	return WithLevelSink(newJSONSink(w), levels...)
+}
+
+// WithStdStreams writes Debug and Info entries to stdout and Warning, Error,
+// Fatal and Panic entries to stderr, so container platforms can tell them apart.
+func WithStdStreams() Option {
+	return func(o *options) {
+		WithOutput(os.Stdout, LevelDebug, LevelInfo)(o)
+		WithOutput(os.Stderr, LevelWarning, LevelError, LevelFatal, LevelPanic)(o)
+	}
+}
+
+// WithMinLevelSink additionally hands entries of minLevel and all more
+// severe levels to sink, independently of the level of the Log, e.g. to keep
+// debug entries in a file while stdout only receives info entries:
+//
+//	log.NewLogger("info", log.WithMinLevelSink(file, log.LevelDebug), log.WithMinLevelSink(sentry, log.LevelError))
+func WithMinLevelSink(sink log.Logger, minLevel string) Option {
+	return func(o *options) {
+		o.minLevelSinks = append(o.minLevelSinks, minLevelSink{sink: sink, minLevel: minLevel})
+	}
+}
+
+// WithRecorder hands every entry to recorder before the level filter is
+// applied, so it receives entries of all levels, e.g. to retain recent debug
+// output in a ring buffer while only info entries reach the sink.
+func WithRecorder(recorder log.Logger) Option {
+	return func(o *options) { o.recorders = append(o.recorders, recorder) }
+}
+
+// levelOutput hands entries to the output registered for their level and
+// all other entries to the sink.
+type levelOutput struct {
+	sink    log.Logger
+	outputs map[string]log.Logger
+}
+
+func (l levelOutput) Log(keyvals ...interface{}) error {
+	if lvl, ok := level.FromKeyvals(keyvals); ok {
+		if out, ok := l.outputs[lvl.String()]; ok {
+			return out.Log(keyvals...)
+		}
+	}
+	return l.sink.Log(keyvals...)
+}
+
+// recording hands every entry to the recorders and then to next.
+type recording struct {
+	next      log.Logger
+	recorders []log.Logger
+}
+
+func (r recording) Log(keyvals ...interface{}) error {
+	for _, recorder := range r.recorders {
+		_ = recorder.Log(keyvals...)
+	}
+	return r.next.Log(keyvals...)
+}
@@ -0,0 +1,401 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestWithLevelSink(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		levels   []string
+		log      func(Log)
+		diverted bool
+	}{
+		{"name", []string{LevelWarning}, func(l Log) { l.Warning("m") }, true},
+		{"alias", []string{"warn"}, func(l Log) { l.Warning("m") }, true},
+		{"upper case", []string{"ERROR"}, func(l Log) { l.Error("m") }, true},
+		{"syslog severity", []string{"3"}, func(l Log) { l.Error("m") }, true},
+		{"trace alias", []string{"trace"}, func(l Log) { l.Debug("m") }, true},
+		{"other level", []string{"warn"}, func(l Log) { l.Info("m") }, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			diverted := &outputBuffer{}
+			logger, out := newBufferLogger(LevelDebug, WithLevelSink(newJSONSink(diverted), tt.levels...))
+			tt.log(logger)
+
+			want, other := out, diverted
+			if tt.diverted {
+				want, other = diverted, out
+			}
+			if n := len(want.entries(t)); n != 1 {
+				t.Errorf("%d entries reached the expected sink, want 1", n)
+			}
+			if n := len(other.entries(t)); n != 0 {
+				t.Errorf("%d entries reached the other sink, want 0", n)
+			}
+		})
+	}
+}
+
+func TestBuildSinksLevels(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		levels  []string
+		wantErr bool
+	}{
+		{"names", []string{LevelError, LevelWarning}, false},
+		{"aliases", []string{"err", "warn"}, false},
+		{"unknown", []string{"verbose"}, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := FileConfig{Sinks: []SinkConfig{{Type: "stderr", Levels: tt.levels}}}
+			sink, _, _, err := buildSinks(cfg, false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildSinks() = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			out, ok := sink.(levelOutput)
+			if !ok {
+				t.Fatalf("sink is %T, want levelOutput", sink)
+			}
+			for _, lvl := range []string{LevelError, LevelWarning} {
+				if _, ok := out.outputs[lvl]; !ok {
+					t.Errorf("no output for %q", lvl)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	want := FileConfig{
+		Level:  "info,storage=debug",
+		Format: "logfmt",
+		Sinks:  []SinkConfig{{Type: "file", Path: "/var/log/errors.log", Levels: []string{"error"}}},
+	}
+	for _, tt := range []struct {
+		name    string
+		file    string
+		data    string
+		wantErr bool
+	}{
+		{"yaml", "log.yaml", "level: info,storage=debug\nformat: logfmt\nsinks:\n  - type: file\n    path: /var/log/errors.log\n    levels: [error]\n", false},
+		{"json", "log.JSON", `{"level":"info,storage=debug","format":"logfmt","sinks":[{"type":"file","path":"/var/log/errors.log","levels":["error"]}]}`, false},
+		{"json as yaml", "log.conf", `{"level":"info,storage=debug","format":"logfmt","sinks":[{"type":"file","path":"/var/log/errors.log","levels":["error"]}]}`, false},
+		{"invalid", "log.json", `{"level":`, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := LoadConfig(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfig() = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(cfg, want) {
+				t.Errorf("LoadConfig() = %+v, want %+v", cfg, want)
+			}
+		})
+	}
+}
+
+// configSink is a sink type registered for the config file tests, which
+// records the entries by the name option of the sink.
+type configSink struct {
+	mtx     sync.Mutex
+	entries int
+	flushed int
+	closed  bool
+}
+
+func (s *configSink) Log(...interface{}) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.entries++
+	return nil
+}
+
+func (s *configSink) Flush() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.flushed++
+	return nil
+}
+
+func (s *configSink) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *configSink) state() (entries, flushed int, closed bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.entries, s.flushed, s.closed
+}
+
+var (
+	configSinksMtx sync.Mutex
+	configSinks    = map[string]*configSink{}
+)
+
+func init() {
+	RegisterSinkType("test", func(config SinkConfig) (log.Logger, error) {
+		configSinksMtx.Lock()
+		defer configSinksMtx.Unlock()
+		s := &configSink{}
+		configSinks[config.Options["name"]] = s
+		return s, nil
+	})
+}
+
+// testConfigSink returns the sink created for the name, or nil.
+func testConfigSink(name string) *configSink {
+	configSinksMtx.Lock()
+	defer configSinksMtx.Unlock()
+	return configSinks[name]
+}
+
+// waitFor polls cond until it holds or a second passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func testSinkConfig(name, level string) string {
+	return "level: " + level + "\nsinks:\n  - type: test\n    options:\n      name: " + name + "\n"
+}
+
+func TestNewLoggerFromFileBindable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.yaml")
+	if err := os.WriteFile(path, []byte("level: info\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewLoggerFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the JSON sink encodes the fields bound by With itself
+	if _, ok := l.levels.next.(*jsonSink); !ok {
+		t.Errorf("sink is %T, want *jsonSink", l.levels.next)
+	}
+}
+
+func TestWatchConfigReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.yaml")
+	if err := os.WriteFile(path, []byte(testSinkConfig("reload-before", LevelInfo)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewLoggerFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop, err := l.WatchConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	if err := os.WriteFile(path, []byte(testSinkConfig("reload-after", LevelDebug)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the level change", func() bool { return l.AtomicLevel().Level() == LevelDebug })
+
+	before, after := testConfigSink("reload-before"), testConfigSink("reload-after")
+	if err := l.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, flushed, closed := before.state(); flushed != 0 || !closed {
+		t.Errorf("replaced sink flushed %d times, closed %v, want 0, true", flushed, closed)
+	}
+	if _, flushed, _ := after.state(); flushed != 1 {
+		t.Errorf("new sink flushed %d times, want 1", flushed)
+	}
+	l.Debug("m")
+	if entries, _, _ := after.state(); entries == 0 {
+		t.Error("new sink received no entries")
+	}
+}
+
+func TestWatchConfigSymlinkSwap(t *testing.T) {
+	// the layout of a mounted Kubernetes ConfigMap, which is updated by
+	// replacing the ..data symlink
+	dir, output := t.TempDir(), filepath.Join(t.TempDir(), "out.log")
+	write := func(version, level string) {
+		t.Helper()
+		if err := os.Mkdir(filepath.Join(dir, version), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, version, "log.yaml"), []byte("level: "+level+"\nsinks:\n  - type: file\n    path: "+output+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(version, filepath.Join(dir, "..data_tmp")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("..v1", LevelInfo)
+	path := filepath.Join(dir, "log.yaml")
+	if err := os.Symlink(filepath.Join("..data", "log.yaml"), path); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := NewLoggerFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	stop, err := l.WatchConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	write("..v2", LevelError)
+	waitFor(t, "the level change", func() bool { return l.AtomicLevel().Level() == LevelError })
+	l.Info("dropped")
+	l.Error("written")
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("decoding %q: %v", line, err)
+		}
+		messages = append(messages, fmt.Sprint(entry[MessageKey]))
+	}
+	if want := []string{ConfigChangedMessage, "written"}; !reflect.DeepEqual(messages, want) {
+		t.Errorf("messages in %s = %q, want %q", output, messages, want)
+	}
+}
+
+func TestWithOutput(t *testing.T) {
+	errs, warnings := &outputBuffer{}, &outputBuffer{}
+	logger, out := newBufferLogger(LevelDebug, WithOutput(errs, LevelError, LevelFatal), WithOutput(warnings, "warn"))
+	logger = logger.With("k", "v")
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Warning("warning")
+	logger.Error("error")
+
+	for _, tt := range []struct {
+		name     string
+		out      *outputBuffer
+		messages []string
+	}{
+		{"sink", out, []string{"debug", "info"}},
+		{"warnings", warnings, []string{"warning"}},
+		{"errors", errs, []string{"error"}},
+	} {
+		var messages []string
+		for _, entry := range tt.out.entries(t) {
+			messages = append(messages, entry[MessageKey].(string))
+			if entry["k"] != "v" {
+				t.Errorf("%s: bound field missing in %v", tt.name, entry)
+			}
+		}
+		if !reflect.DeepEqual(messages, tt.messages) {
+			t.Errorf("%s received %q, want %q", tt.name, messages, tt.messages)
+		}
+	}
+}
+
+func TestWithGoKitCompat(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		log   func(Log)
+		level string
+	}{
+		{"debug", func(l Log) { l.Debug("m") }, "debug"},
+		{"info", func(l Log) { l.Info("m") }, "info"},
+		{"warning", func(l Log) { l.Warning("m") }, "warn"},
+		{"error", func(l Log) { l.Error("m") }, "error"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := &outputBuffer{}
+			logger, out := newBufferLogger(LevelDebug, WithGoKitCompat(), WithOutput(errs, LevelError))
+			tt.log(logger)
+
+			entries := append(out.entries(t), errs.entries(t)...)
+			if len(entries) != 1 {
+				t.Fatalf("entries = %v, want 1", entries)
+			}
+			entry := entries[0]
+			if entry["level"] != tt.level || entry["msg"] != "m" {
+				t.Errorf("entry = %v, want level %s and msg m", entry, tt.level)
+			}
+			if _, ok := entry["severity"]; ok {
+				t.Errorf("severity kept in %v", entry)
+			}
+			if _, ok := entry[MessageKey]; ok {
+				t.Errorf("%s kept in %v", MessageKey, entry)
+			}
+		})
+	}
+}
+
+func TestCachedTimestamp(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var now time.Time
+	timestamp := cachedTimestamp(func() time.Time { return now })
+	for _, tt := range []struct {
+		name string
+		now  time.Time
+		want string
+	}{
+		{"whole second", base, "2024-05-01T12:00:00.000Z"},
+		{"same second", base.Add(123456789), "2024-05-01T12:00:00.123Z"},
+		{"next second", base.Add(time.Second + 7*time.Millisecond), "2024-05-01T12:00:01.007Z"},
+		{"back in time", base.Add(-time.Millisecond), "2024-05-01T11:59:59.999Z"},
+		{"converted to UTC", base.In(time.FixedZone("CEST", 2*60*60)), "2024-05-01T12:00:00.000Z"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			now = tt.now
+			if got := timestamp(); got != tt.want {
+				t.Errorf("timestamp = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithTimestamp(t *testing.T) {
+	logger, out := newBufferLogger(LevelInfo, WithTimestamp())
+	before := time.Now().Truncate(time.Millisecond)
+	logger.Info("ready")
+	after := time.Now()
+
+	stamp, _ := out.entries(t)[0][TimeKey].(string)
+	got, err := time.Parse(time.RFC3339Nano, stamp)
+	if err != nil {
+		t.Fatalf("%s = %q: %v", TimeKey, stamp, err)
+	}
+	if got.Before(before) || got.After(after) {
+		t.Errorf("%s = %s, want between %s and %s", TimeKey, got, before, after)
+	}
+}
@@ -0,0 +1,21 @@
+package log
+
+import (
+	"runtime/debug"
+
+	"github.com/go-godin/log/level"
+)
+
+// StacktraceKey is the key of the stack trace attached to Panic entries.
+const StacktraceKey = "stacktrace"
+
+// Panic logs a message and arbitrary key-value pairs together with the stack
+// trace at the panic level and then panics with the message.
+func (l Log) Panic(message string, keyvals ...interface{}) {
+	keyvals = prepare(keyvals)
+	l.handleTrace(message, keyvals)
+	l.markSpanFailed(message)
+	keyvals = append(keyvals[:len(keyvals):len(keyvals)], StacktraceKey, string(debug.Stack()))
+	l.output(level.Panic(l.logger()), message, keyvals)
+	panic(message)
+}
@@ -0,0 +1,74 @@
+package log
+
+import (
+	"reflect"
+
+	"github.com/go-godin/log/escalate"
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/level"
+	"github.com/go-kit/kit/log"
+)
+
+// WithErrorPromotion promotes entries carrying a non-nil "err" or "error"
+// field to at least the warning level, so mistakes like
+// logger.Info("failed", "err", err) still surface on error dashboards.
+// Promoted entries carry their original level in the escalate.FromKey field.
+func WithErrorPromotion() Option {
+	return func(o *options) { o.promoteErrors = true }
+}
+
+// errorPromotion raises the level of entries carrying an error before they
+// reach the level filter.
+type errorPromotion struct {
+	next log.Logger
+}
+
+func (p errorPromotion) Log(keyvals ...interface{}) error {
+	lvl, ok := level.FromKeyvals(keyvals)
+	if !ok || lvl.Severity() >= level.WarnValue().Severity() || !carriesError(keyvals) {
+		return p.next.Log(keyvals...)
+	}
+
+	promoted := make([]interface{}, len(keyvals), len(keyvals)+2)
+	copy(promoted, keyvals)
+	for i := 1; i < len(promoted); i += 2 {
+		if promoted[i] == lvl {
+			promoted[i] = level.WarnValue()
+			break
+		}
+	}
+	return p.next.Log(append(promoted, escalate.FromKey, lvl.String())...)
+}
+
+func carriesError(keyvals []interface{}) bool {
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		switch b := keyvals[i+1].(type) {
+		case *boundFields:
+			if carriesError(b.keyvals) {
+				return true
+			}
+		case *fieldList:
+			if b.carriesError() {
+				return true
+			}
+		case Field:
+			if b.carriesError() {
+				return true
+			}
+		}
+		if key := kv.Key(keyvals[i]); (key == "err" || key == "error") && !isNil(keyvals[i+1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// isNil reports whether v is nil or a nil pointer, like an error returned as
+// a typed nil.
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
@@ -0,0 +1,46 @@
+package log
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-godin/log/escalate"
+)
+
+type customError struct{}
+
+func (*customError) Error() string { return "custom" }
+
+func TestErrorPromotion(t *testing.T) {
+	var typedNil *customError
+	for _, tt := range []struct {
+		name     string
+		log      func(Log)
+		severity string
+		promoted bool
+	}{
+		{"info with err", func(l Log) { l.Info("m", "err", errors.New("boom")) }, LevelWarning, true},
+		{"debug with error", func(l Log) { l.Debug("m", "error", errors.New("boom")) }, LevelWarning, true},
+		{"info without error", func(l Log) { l.Info("m", "n", 1) }, LevelInfo, false},
+		{"nil error", func(l Log) { l.Info("m", "err", nil) }, LevelInfo, false},
+		{"typed nil error", func(l Log) { l.Info("m", "err", typedNil) }, LevelInfo, false},
+		{"error kept", func(l Log) { l.Error("m", "err", errors.New("boom")) }, LevelError, false},
+		{"bound error", func(l Log) { l.With("err", errors.New("boom")).Info("m") }, LevelWarning, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, out := newBufferLogger(LevelDebug, WithErrorPromotion())
+			tt.log(logger)
+
+			entries := out.entries(t)
+			if len(entries) != 1 {
+				t.Fatalf("wrote %d entries, want 1", len(entries))
+			}
+			if severity := entries[0]["severity"]; severity != tt.severity {
+				t.Errorf("severity = %v, want %s", severity, tt.severity)
+			}
+			if _, promoted := entries[0][escalate.FromKey]; promoted != tt.promoted {
+				t.Errorf("%s set = %v, want %v", escalate.FromKey, promoted, tt.promoted)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+// Package ratelimit limits how often entries with the same message are
+// written, so a tight retry loop logging the same error doesn't emit
+// millions of identical lines.
+package ratelimit
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-kit/kit/log"
+)
+
+// SuppressedKey is the key of the field carrying the amount of entries
+// suppressed during the previous interval of the key.
+const SuppressedKey = "suppressed"
+
+// maxKeys is the amount of keys tracked before expired ones are removed.
+const maxKeys = 10000
+
+// Limiter is a go-kit logger passing at most a fixed amount of entries per
+// key and interval. The first entry passing after entries of its key were
+// suppressed carries their amount in the suppressed field.
+type Limiter struct {
+	next     log.Logger
+	burst    int
+	interval time.Duration
+	key      func(keyvals []interface{}) string
+	now      func() time.Time
+
+	mtx        sync.Mutex
+	windows    map[string]*window
+	suppressed atomic.Uint64
+}
+
+type window struct {
+	end        time.Time
+	count      int
+	suppressed int
+}
+
+// Option sets a parameter for the Limiter.
+type Option func(*Limiter)
+
+// Limit passes up to burst entries per key during every interval. Defaults
+// to 10 per second.
+func Limit(burst int, interval time.Duration) Option {
+	return func(l *Limiter) { l.burst, l.interval = burst, interval }
+}
+
+// Key sets the function returning the key entries are limited by, e.g. to
+// limit by message and error. Defaults to the message.
+func Key(key func(keyvals []interface{}) string) Option {
+	return func(l *Limiter) { l.key = key }
+}
+
+// Field limits entries by the value of the field key instead of the
+// message, e.g. Field("err").
+func Field(key string) Option {
+	return Key(func(keyvals []interface{}) string { return value(keyvals, key) })
+}
+
+// New wraps next and limits the entries passed to it:
+//
+//	logger := log.NewLogger("info", log.WithSink(ratelimit.New(sink, ratelimit.Limit(5, time.Minute))))
+func New(next log.Logger, options ...Option) *Limiter {
+	l := &Limiter{
+		next:     next,
+		burst:    10,
+		interval: time.Second,
+		key:      func(keyvals []interface{}) string { return value(keyvals, kv.MessageKey) },
+		now:      time.Now,
+		windows:  make(map[string]*window),
+	}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+func (l *Limiter) Log(keyvals ...interface{}) error {
+	key := l.key(keyvals)
+	now := l.now()
+
+	l.mtx.Lock()
+	w, ok := l.windows[key]
+	if !ok {
+		if len(l.windows) >= maxKeys {
+			l.prune(now)
+		}
+		w = &window{}
+		l.windows[key] = w
+	}
+	var suppressed int
+	if !now.Before(w.end) {
+		suppressed = w.suppressed
+		*w = window{end: now.Add(l.interval)}
+	}
+	if w.count >= l.burst {
+		w.suppressed++
+		l.mtx.Unlock()
+		l.suppressed.Add(1)
+		return nil
+	}
+	w.count++
+	l.mtx.Unlock()
+
+	if suppressed > 0 {
+		keyvals = append(keyvals[:len(keyvals):len(keyvals)], SuppressedKey, suppressed)
+	}
+	return l.next.Log(keyvals...)
+}
+
+// Suppressed returns the amount of entries suppressed so far.
+func (l *Limiter) Suppressed() uint64 {
+	return l.suppressed.Load()
+}
+
+// prune removes the windows which ended, dropping the counts of the entries
+// they suppressed.
+func (l *Limiter) prune(now time.Time) {
+	for key, w := range l.windows {
+		if !now.Before(w.end) {
+			delete(l.windows, key)
+		}
+	}
+}
+
+func value(keyvals []interface{}, key string) string {
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if kv.Key(keyvals[i]) == key {
+			return kv.String(keyvals[i+1])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-godin/log/internal/kv"
+)
+
+// recordingLogger records the message and suppressed field of every entry.
+type recordingLogger struct {
+	entries []string
+}
+
+func (r *recordingLogger) Log(keyvals ...interface{}) error {
+	m := kv.Map(keyvals)
+	entry := fmt.Sprint(m[kv.MessageKey])
+	if n, ok := m[SuppressedKey]; ok {
+		entry += fmt.Sprintf(" (%v suppressed)", n)
+	}
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func TestLimiter(t *testing.T) {
+	type entry struct {
+		after   time.Duration // since the first entry
+		keyvals []interface{}
+	}
+	tests := []struct {
+		name           string
+		options        []Option
+		entries        []entry
+		want           []string
+		wantSuppressed uint64
+	}{
+		{
+			name:    "within the burst",
+			options: []Option{Limit(2, time.Second)},
+			entries: []entry{{0, []interface{}{"message", "a"}}, {0, []interface{}{"message", "a"}}},
+			want:    []string{"a", "a"},
+		},
+		{
+			name:    "beyond the burst",
+			options: []Option{Limit(2, time.Second)},
+			entries: []entry{
+				{0, []interface{}{"message", "a"}},
+				{0, []interface{}{"message", "a"}},
+				{0, []interface{}{"message", "a"}},
+				{0, []interface{}{"message", "b"}},
+			},
+			want:           []string{"a", "a", "b"},
+			wantSuppressed: 1,
+		},
+		{
+			name:    "next interval reports the suppressed entries",
+			options: []Option{Limit(1, time.Second)},
+			entries: []entry{
+				{0, []interface{}{"message", "a"}},
+				{0, []interface{}{"message", "a"}},
+				{500 * time.Millisecond, []interface{}{"message", "a"}},
+				{time.Second, []interface{}{"message", "a"}},
+				{time.Second, []interface{}{"message", "a"}},
+				{3 * time.Second, []interface{}{"message", "a"}},
+			},
+			want:           []string{"a", "a (2 suppressed)", "a (1 suppressed)"},
+			wantSuppressed: 3,
+		},
+		{
+			name:    "field",
+			options: []Option{Limit(1, time.Second), Field("err")},
+			entries: []entry{
+				{0, []interface{}{"message", "a", "err", "timeout"}},
+				{0, []interface{}{"message", "b", "err", "timeout"}},
+				{0, []interface{}{"message", "c", "err", "refused"}},
+			},
+			want:           []string{"a", "c"},
+			wantSuppressed: 1,
+		},
+		{
+			name: "key",
+			options: []Option{Limit(1, time.Second), Key(func(keyvals []interface{}) string {
+				return value(keyvals, kv.MessageKey) + value(keyvals, "err")
+			})},
+			entries: []entry{
+				{0, []interface{}{"message", "a", "err", "timeout"}},
+				{0, []interface{}{"message", "a", "err", "refused"}},
+				{0, []interface{}{"message", "a", "err", "timeout"}},
+			},
+			want:           []string{"a", "a"},
+			wantSuppressed: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &recordingLogger{}
+			l := New(next, tt.options...)
+			begin := time.Now()
+			for _, e := range tt.entries {
+				l.now = func() time.Time { return begin.Add(e.after) }
+				if err := l.Log(e.keyvals...); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !reflect.DeepEqual(next.entries, tt.want) {
+				t.Errorf("logged %q, want %q", next.entries, tt.want)
+			}
+			if n := l.Suppressed(); n != tt.wantSuppressed {
+				t.Errorf("Suppressed() = %d, want %d", n, tt.wantSuppressed)
+			}
+		})
+	}
+}
+
+func TestLimiterPrune(t *testing.T) {
+	l := New(&recordingLogger{}, Limit(1, time.Second))
+	begin := time.Now()
+	l.now = func() time.Time { return begin }
+	for i := 0; i < maxKeys; i++ {
+		l.Log("message", fmt.Sprint(i))
+	}
+	l.now = func() time.Time { return begin.Add(time.Second) }
+	l.Log("message", "new")
+	if len(l.windows) != 1 {
+		t.Errorf("%d windows tracked after pruning, want 1", len(l.windows))
+	}
+}
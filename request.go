@@ -0,0 +1,74 @@
+package log
+
+import (
+	"net/http"
+	"strings"
+)
+
+// RequestIDKey is the key of the field carrying the X-Request-ID header.
+const RequestIDKey = "request_id"
+
+// FromRequest returns the Log of the request context, see FromContext, with
+// the trace and request fields of the request added by WithRequest.
+func FromRequest(r *http.Request) Log {
+	return FromContext(r.Context()).WithRequest(r)
+}
+
+// WithRequest binds the Log to the trace of the request, for handlers that
+// aren't wrapped by a tracing middleware. The span of the request context is
+// used as by WithTrace. Otherwise the trace_id, span_id and sampled fields
+// are taken from the B3 headers (X-B3-TraceId etc. or the single b3 header),
+// falling back to the W3C traceparent header. The X-Request-ID header is
+// added as request_id.
+func (l Log) WithRequest(r *http.Request) Log {
+	l = l.WithTrace(r.Context())
+	if l.span == nil {
+		if tp, ok := TraceFromHeader(r.Header); ok {
+			l = l.WithSpan(tp)
+		}
+	}
+	if id := r.Header.Get("X-Request-ID"); id != "" {
+		l = l.With(RequestIDKey, id)
+	}
+	return l
+}
+
+// TraceFromHeader returns the trace context of the B3 headers, falling back
+// to the W3C traceparent header.
+func TraceFromHeader(h http.Header) (Traceparent, bool) {
+	if tp, ok := b3FromHeader(h); ok {
+		return tp, true
+	}
+	tp, err := ParseTraceparent(h.Get(TraceparentHeader))
+	return tp, err == nil
+}
+
+// b3FromHeader parses the B3 propagation headers, preferring the single
+// header "b3: {TraceId}-{SpanId}-{SamplingState}-{ParentSpanId}".
+func b3FromHeader(h http.Header) (Traceparent, bool) {
+	traceID, spanID := h.Get("X-B3-TraceId"), h.Get("X-B3-SpanId")
+	sampling := h.Get("X-B3-Sampled")
+	if h.Get("X-B3-Flags") == "1" {
+		sampling = "d"
+	}
+	if single := h.Get("b3"); single != "" {
+		parts := strings.Split(single, "-")
+		if len(parts) < 2 {
+			return Traceparent{}, false
+		}
+		traceID, spanID, sampling = parts[0], parts[1], ""
+		if len(parts) > 2 {
+			sampling = parts[2]
+		}
+	}
+
+	traceID, spanID = strings.ToLower(traceID), strings.ToLower(spanID)
+	if !isHex(traceID, 16) && !isHex(traceID, 32) || !isHex(spanID, 16) {
+		return Traceparent{}, false
+	}
+	return Traceparent{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: sampling == "1" || sampling == "d" || sampling == "true",
+	}, true
+}
@@ -0,0 +1,66 @@
+// Package retention defines the optional retention field which tells sinks
+// how long an entry should be kept, e.g. "30d" or "1y". Sinks translate it
+// into whatever their backend uses to apply retention policies, like index
+// names, stream labels or partitions.
+package retention
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-godin/log/internal/kv"
+)
+
+// Key is the key of the retention field.
+const Key = "retention"
+
+// FromKeyvals returns the retention policy contained in keyvals, if any.
+// If the key occurs multiple times, the last one wins, so per-entry values
+// override the ones bound to a logger.
+func FromKeyvals(keyvals []interface{}) (string, bool) {
+	var policy string
+	found := false
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if kv.Key(keyvals[i]) == Key {
+			policy = kv.String(keyvals[i+1])
+			found = true
+		}
+	}
+	return policy, found
+}
+
+// Parse converts a retention policy to a duration. Besides the units
+// understood by time.ParseDuration it accepts "d" (days), "w" (weeks) and
+// "y" (365 days).
+func Parse(policy string) (time.Duration, error) {
+	policy = strings.TrimSpace(policy)
+	if policy == "" {
+		return 0, fmt.Errorf("retention: empty policy")
+	}
+
+	unit := policy[len(policy)-1]
+	const day = 24 * time.Hour
+	var factor time.Duration
+	switch unit {
+	case 'd':
+		factor = day
+	case 'w':
+		factor = 7 * day
+	case 'y':
+		factor = 365 * day
+	default:
+		d, err := time.ParseDuration(policy)
+		if err != nil {
+			return 0, fmt.Errorf("retention: invalid policy %q", policy)
+		}
+		return d, nil
+	}
+
+	n, err := strconv.Atoi(policy[:len(policy)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("retention: invalid policy %q", policy)
+	}
+	return time.Duration(n) * factor, nil
+}
@@ -0,0 +1,61 @@
+package retention
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFromKeyvals(t *testing.T) {
+	tests := []struct {
+		name      string
+		keyvals   []interface{}
+		want      string
+		wantFound bool
+	}{
+		{"absent", []interface{}{"message", "a"}, "", false},
+		{"present", []interface{}{"message", "a", Key, "30d"}, "30d", true},
+		{"last wins", []interface{}{Key, "30d", "message", "a", Key, "1y"}, "1y", true},
+		{"missing value", []interface{}{"message", "a", Key}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := FromKeyvals(tt.keyvals)
+			if got != tt.want || found != tt.wantFound {
+				t.Errorf("FromKeyvals() = %q, %v, want %q, %v", got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	const day = 24 * time.Hour
+	tests := []struct {
+		policy  string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30d", 30 * day, false},
+		{"2w", 14 * day, false},
+		{"1y", 365 * day, false},
+		{" 7d ", 7 * day, false},
+		{"90m", 90 * time.Minute, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"", 0, true},
+		{"d", 0, true},
+		{"0d", 0, true},
+		{"-1d", 0, true},
+		{"1.5d", 0, true},
+		{"forever", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			got, err := Parse(tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package log
+
+import (
+	"sync/atomic"
+)
+
+// WithRingBuffer makes WithAsync enqueue entries onto a lock-free ring
+// buffer instead of a channel, so callers logging concurrently don't contend
+// on the lock of the channel. Handing an entry over is cheaper than with a
+// channel (see BenchmarkAsyncQueue), which only shows for services logging
+// hundreds of thousands of entries per second; for most services building
+// and writing entries dominates (see BenchmarkAsync). The size of the queue
+// is rounded up to a power of two.
+//
+//	logger := log.NewLogger("info", log.WithAsync(1<<16), log.WithRingBuffer())
+func WithRingBuffer() Option {
+	return func(o *options) { o.ringBuffer = true }
+}
+
+// cacheLinePad keeps the positions of the producers and the consumer on
+// separate cache lines.
+type cacheLinePad [64]byte
+
+// ringQueue is a bounded multi-producer queue after Dmitry Vyukov's design:
+// every cell carries a sequence number telling producers and consumers
+// whether it's free or filled for their position, so positions are claimed
+// with a single CAS. The worker is the only regular consumer, producers
+// dequeue only to drop the oldest entry.
+type ringQueue struct {
+	cells []ringCell
+	mask  uint64
+
+	_    cacheLinePad
+	tail atomic.Uint64
+	_    cacheLinePad
+	head atomic.Uint64
+	_    cacheLinePad
+
+	// the worker sleeps on wake while the queue is empty, producers on space
+	// while it's full
+	sleeping int32
+	waiting  int32
+	closed   int32
+	wake     chan struct{}
+	space    chan struct{}
+}
+
+type ringCell struct {
+	seq atomic.Uint64
+	e   asyncEntry
+}
+
+func newRingQueue(size int) *ringQueue {
+	n := 2
+	for n < size {
+		n <<= 1
+	}
+	q := &ringQueue{
+		cells: make([]ringCell, n),
+		mask:  uint64(n - 1),
+		wake:  make(chan struct{}, 1),
+		space: make(chan struct{}, 1),
+	}
+	for i := range q.cells {
+		q.cells[i].seq.Store(uint64(i))
+	}
+	return q
+}
+
+func (q *ringQueue) push(e asyncEntry) {
+	if q.tryPush(e) {
+		return
+	}
+	atomic.AddInt32(&q.waiting, 1)
+	for !q.tryPush(e) {
+		<-q.space
+	}
+	atomic.AddInt32(&q.waiting, -1)
+}
+
+func (q *ringQueue) tryPush(e asyncEntry) bool {
+	pos := q.tail.Load()
+	for {
+		cell := &q.cells[pos&q.mask]
+		seq := cell.seq.Load()
+		switch diff := int64(seq - pos); {
+		case diff == 0:
+			if q.tail.CompareAndSwap(pos, pos+1) {
+				cell.e = e
+				cell.seq.Store(pos + 1)
+				if atomic.LoadInt32(&q.sleeping) == 1 && atomic.CompareAndSwapInt32(&q.sleeping, 1, 0) {
+					notify(q.wake)
+				}
+				return true
+			}
+			pos = q.tail.Load()
+		case diff < 0:
+			// the cell still holds the entry of the previous round
+			return false
+		default:
+			pos = q.tail.Load()
+		}
+	}
+}
+
+func (q *ringQueue) tryPop() (asyncEntry, bool) {
+	pos := q.head.Load()
+	for {
+		cell := &q.cells[pos&q.mask]
+		seq := cell.seq.Load()
+		switch diff := int64(seq - (pos + 1)); {
+		case diff == 0:
+			if q.head.CompareAndSwap(pos, pos+1) {
+				e := cell.e
+				cell.e = asyncEntry{}
+				cell.seq.Store(pos + q.mask + 1)
+				if atomic.LoadInt32(&q.waiting) > 0 {
+					notify(q.space)
+				}
+				return e, true
+			}
+			pos = q.head.Load()
+		case diff < 0:
+			return asyncEntry{}, false
+		default:
+			pos = q.head.Load()
+		}
+	}
+}
+
+func (q *ringQueue) pop() (asyncEntry, bool) {
+	for {
+		if e, ok := q.tryPop(); ok {
+			return e, true
+		}
+		if atomic.LoadInt32(&q.closed) == 1 {
+			// entries enqueued before close are still drained
+			return q.tryPop()
+		}
+		// checked again after announcing the sleep, so an entry enqueued
+		// in between isn't missed
+		atomic.StoreInt32(&q.sleeping, 1)
+		if e, ok := q.tryPop(); ok {
+			atomic.StoreInt32(&q.sleeping, 0)
+			return e, true
+		}
+		if atomic.LoadInt32(&q.closed) == 1 {
+			continue
+		}
+		<-q.wake
+	}
+}
+
+func (q *ringQueue) close() {
+	atomic.StoreInt32(&q.closed, 1)
+	atomic.StoreInt32(&q.sleeping, 0)
+	notify(q.wake)
+}
+
+// notify notifies the receiver of c without blocking.
+func notify(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,105 @@
+// Package route directs entries to specific sinks based on destination hints
+// attached at the call site, e.g. logger.Info("login", log.To("audit")).
+package route
+
+import (
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-kit/kit/log"
+)
+
+// Key is the key under which destination hints are attached to entries.
+const Key = "destination"
+
+// Hint lists the names of the sinks an entry should be routed to.
+type Hint []string
+
+type router struct {
+	fallback  log.Logger
+	sinks     map[string]log.Logger
+	keepHints bool
+}
+
+// Option sets a parameter for the router.
+type Option func(*router)
+
+// Sink registers a sink under the given destination name.
+func Sink(name string, sink log.Logger) Option {
+	return func(r *router) { r.sinks[name] = sink }
+}
+
+// KeepHints keeps the destination field in the entries handed to the sinks.
+// By default it is removed.
+func KeepHints() Option {
+	return func(r *router) { r.keepHints = true }
+}
+
+// New returns a logger which hands entries carrying a destination hint to the
+// registered sinks of that name, and all other entries to fallback. Entries
+// with hints which don't match any registered sink go to fallback as well.
+func New(fallback log.Logger, options ...Option) log.Logger {
+	r := &router{
+		fallback: fallback,
+		sinks:    make(map[string]log.Logger),
+	}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+func (r *router) Log(keyvals ...interface{}) error {
+	hint, rest := extract(keyvals)
+	if !r.keepHints {
+		keyvals = rest
+	}
+
+	var err error
+	routed := false
+	for _, name := range hint {
+		sink, ok := r.sinks[name]
+		if !ok {
+			continue
+		}
+		routed = true
+		if serr := sink.Log(keyvals...); serr != nil && err == nil {
+			err = serr
+		}
+	}
+	if !routed {
+		return r.fallback.Log(keyvals...)
+	}
+	return err
+}
+
+// extract returns the merged destination hints of keyvals and keyvals
+// without them.
+func extract(keyvals []interface{}) (Hint, []interface{}) {
+	var hint Hint
+	found := false
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if kv.Key(keyvals[i]) != Key {
+			continue
+		}
+		found = true
+		switch v := keyvals[i+1].(type) {
+		case Hint:
+			hint = append(hint, v...)
+		case []string:
+			hint = append(hint, v...)
+		case string:
+			hint = append(hint, v)
+		}
+	}
+	if !found {
+		return nil, keyvals
+	}
+
+	rest := make([]interface{}, 0, len(keyvals))
+	for i := 0; i < len(keyvals); i += 2 {
+		if i+1 < len(keyvals) && kv.Key(keyvals[i]) == Key {
+			continue
+		}
+		rest = append(rest, keyvals[i:min(i+2, len(keyvals))]...)
+	}
+	return hint, rest
+}
@@ -0,0 +1,107 @@
+package route
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// recordingLogger records the keyvals of every entry, or fails with err.
+type recordingLogger struct {
+	entries []string
+	err     error
+}
+
+func (r *recordingLogger) Log(keyvals ...interface{}) error {
+	r.entries = append(r.entries, strings.TrimSuffix(fmt.Sprintln(keyvals...), "\n"))
+	return r.err
+}
+
+func TestRouter(t *testing.T) {
+	tests := []struct {
+		name         string
+		keepHints    bool
+		keyvals      []interface{}
+		wantFallback []string
+		wantAudit    []string
+		wantSecurity []string
+	}{
+		{
+			name:         "no hint",
+			keyvals:      []interface{}{"message", "a"},
+			wantFallback: []string{"message a"},
+		},
+		{
+			name:      "hint",
+			keyvals:   []interface{}{"message", "a", Key, Hint{"audit"}},
+			wantAudit: []string{"message a"},
+		},
+		{
+			name:         "several sinks",
+			keyvals:      []interface{}{Key, []string{"audit", "security"}, "message", "a"},
+			wantAudit:    []string{"message a"},
+			wantSecurity: []string{"message a"},
+		},
+		{
+			name:         "merged hints",
+			keyvals:      []interface{}{Key, "audit", "message", "a", Key, Hint{"security"}},
+			wantAudit:    []string{"message a"},
+			wantSecurity: []string{"message a"},
+		},
+		{
+			name:         "unknown sink",
+			keyvals:      []interface{}{"message", "a", Key, Hint{"billing"}},
+			wantFallback: []string{"message a"},
+		},
+		{
+			name:      "kept hints",
+			keepHints: true,
+			keyvals:   []interface{}{"message", "a", Key, "audit"},
+			wantAudit: []string{"message a destination audit"},
+		},
+		{
+			name:         "missing value",
+			keyvals:      []interface{}{"message", "a", Key},
+			wantFallback: []string{"message a destination"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fallback, audit, security := &recordingLogger{}, &recordingLogger{}, &recordingLogger{}
+			options := []Option{Sink("audit", audit), Sink("security", security)}
+			if tt.keepHints {
+				options = append(options, KeepHints())
+			}
+			if err := New(fallback, options...).Log(tt.keyvals...); err != nil {
+				t.Fatal(err)
+			}
+			for _, c := range []struct {
+				name string
+				got  []string
+				want []string
+			}{
+				{"fallback", fallback.entries, tt.wantFallback},
+				{"audit", audit.entries, tt.wantAudit},
+				{"security", security.entries, tt.wantSecurity},
+			} {
+				if !reflect.DeepEqual(c.got, c.want) {
+					t.Errorf("%s received %q, want %q", c.name, c.got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRouterErrors(t *testing.T) {
+	failure := errors.New("unavailable")
+	audit, security := &recordingLogger{err: failure}, &recordingLogger{}
+	r := New(&recordingLogger{}, Sink("audit", audit), Sink("security", security))
+	if err := r.Log("message", "a", Key, Hint{"audit", "security"}); err != failure {
+		t.Errorf("Log() = %v, want %v", err, failure)
+	}
+	if len(security.entries) != 1 {
+		t.Error("the failing sink kept the entry from the other sinks")
+	}
+}
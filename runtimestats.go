@@ -0,0 +1,79 @@
+package log
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ReportRuntimeStats logs the runtime statistics of the process every
+// interval at the given level, which falls back to info if unknown, until
+// stop is called. An entry holds the number of goroutines, the heap in
+// bytes (heap_alloc, heap_inuse, heap_sys) and objects, the number of
+// garbage collections of the interval and their total and longest pause in
+// seconds. Entries are logged by the Named logger "runtime", so its level
+// can be set separately:
+//
+//	stop := logger.ReportRuntimeStats(time.Minute, log.LevelInfo)
+//	defer stop()
+//
+// Intervals which aren't positive report nothing and return a no-op stop.
+func (l Log) ReportRuntimeStats(interval time.Duration, lvl Level) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+	v := levelValue(lvl)
+	l = l.Named("runtime")
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var prev runtime.MemStats
+		runtime.ReadMemStats(&prev)
+		for {
+			select {
+			case <-ticker.C:
+				var ms runtime.MemStats
+				runtime.ReadMemStats(&ms)
+				total, longest := gcPauses(&prev, &ms)
+				l.At(v, "runtime stats",
+					"goroutines", runtime.NumGoroutine(),
+					"heap_alloc", ms.HeapAlloc,
+					"heap_inuse", ms.HeapInuse,
+					"heap_sys", ms.HeapSys,
+					"heap_objects", ms.HeapObjects,
+					"gc_count", ms.NumGC-prev.NumGC,
+					"gc_pause_total", total.Seconds(),
+					"gc_pause_max", longest.Seconds(),
+				)
+				prev = ms
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// gcPauses returns the total and the longest pause of the collections since
+// prev. Only the last len(PauseNs) pauses are known to the runtime.
+func gcPauses(prev, cur *runtime.MemStats) (total, longest time.Duration) {
+	n := cur.NumGC - prev.NumGC
+	if n > uint32(len(cur.PauseNs)) {
+		n = uint32(len(cur.PauseNs))
+	}
+	for i := uint32(0); i < n; i++ {
+		pause := time.Duration(cur.PauseNs[(cur.NumGC-1-i)%uint32(len(cur.PauseNs))])
+		total += pause
+		if pause > longest {
+			longest = pause
+		}
+	}
+	return total, longest
+}
@@ -0,0 +1,175 @@
+// Package sample thins out chatty log levels: entries of a level are either
+// kept with a fixed probability, or the first ones of every interval are
+// kept and only every nth after them, so hot code paths can stay
+// instrumented without flooding the sinks.
+package sample
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	godin "github.com/go-godin/log"
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/level"
+	"github.com/go-kit/kit/log"
+)
+
+// buckets is the number of counters of Burst. Messages sharing a counter
+// are sampled together.
+const buckets = 4096
+
+// policy decides whether an entry of a level is kept.
+type policy interface {
+	keep(message string, now time.Time) bool
+}
+
+// Sampler is a go-kit logger dropping entries according to the policies of
+// their level. Entries of levels without a policy, without a level or of a
+// level forced by the godin logger pass.
+type Sampler struct {
+	next     log.Logger
+	policies map[string]policy
+	now      func() time.Time
+	dropped  atomic.Uint64
+	err      error
+}
+
+// Option sets a parameter for the Sampler.
+type Option func(*Sampler)
+
+// Rate keeps entries of the level, e.g. "debug", with a probability of 1 in
+// n. n below 2 keeps all entries. The level accepts the aliases of
+// log.ParseLevel.
+func Rate(lvl string, n int) Option {
+	return func(s *Sampler) {
+		name, ok := s.level(lvl)
+		if !ok {
+			return
+		}
+		if n < 2 {
+			delete(s.policies, name)
+			return
+		}
+		s.policies[name] = ratePolicy(n)
+	}
+}
+
+// Burst keeps the first entries of the level with the same message during
+// every interval and only every nth of the following ones. n below 1
+// drops them all. The level accepts the aliases of log.ParseLevel.
+func Burst(lvl string, first int, interval time.Duration, n int) Option {
+	return func(s *Sampler) {
+		name, ok := s.level(lvl)
+		if !ok {
+			return
+		}
+		s.policies[name] = &burstPolicy{first: uint64(first), interval: interval, thereafter: uint64(n)}
+	}
+}
+
+// level returns the name of the level the policies are looked up by, which
+// entries of the level carry. It records unknown levels as the error of New.
+func (s *Sampler) level(name string) (string, bool) {
+	lvl, err := godin.ParseLevel(name)
+	if err != nil {
+		if s.err == nil {
+			s.err = fmt.Errorf("sample: %w", err)
+		}
+		return "", false
+	}
+	return string(lvl), true
+}
+
+// New wraps next and samples entries according to the options. It returns
+// an error if an option names an unknown level.
+//
+//	sampler, err := sample.New(sink, sample.Rate("debug", 100), sample.Burst("info", 100, time.Second, 10))
+//	if err != nil {
+//		return err
+//	}
+//	logger := log.NewLogger("debug", log.WithSink(sampler))
+func New(next log.Logger, options ...Option) (*Sampler, error) {
+	s := &Sampler{
+		next:     next,
+		policies: make(map[string]policy),
+		now:      time.Now,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s, nil
+}
+
+func (s *Sampler) Log(keyvals ...interface{}) error {
+	lvl, ok := level.FromKeyvals(keyvals)
+	if !ok || level.IsForced(lvl) {
+		return s.next.Log(keyvals...)
+	}
+	p, ok := s.policies[lvl.String()]
+	if !ok || p.keep(message(keyvals), s.now()) {
+		return s.next.Log(keyvals...)
+	}
+	s.dropped.Add(1)
+	return nil
+}
+
+// Dropped returns the amount of entries dropped so far.
+func (s *Sampler) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+func message(keyvals []interface{}) string {
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if kv.Key(keyvals[i]) == kv.MessageKey {
+			return kv.String(keyvals[i+1])
+		}
+	}
+	return ""
+}
+
+type ratePolicy int
+
+func (n ratePolicy) keep(string, time.Time) bool {
+	return rand.IntN(int(n)) == 0
+}
+
+type burstPolicy struct {
+	first      uint64
+	interval   time.Duration
+	thereafter uint64
+
+	mtx      sync.Mutex
+	counters [buckets]counter
+}
+
+type counter struct {
+	resetAt time.Time
+	count   uint64
+}
+
+func (p *burstPolicy) keep(message string, now time.Time) bool {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(message))
+
+	p.mtx.Lock()
+	c := &p.counters[h.Sum32()%buckets]
+	if !now.Before(c.resetAt) {
+		c.resetAt = now.Add(p.interval)
+		c.count = 0
+	}
+	c.count++
+	count := c.count
+	p.mtx.Unlock()
+
+	if count <= p.first {
+		return true
+	}
+	return p.thereafter > 0 && (count-p.first)%p.thereafter == 0
+}
@@ -0,0 +1,153 @@
+package sample
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/level"
+)
+
+// countingLogger counts the entries passed to it.
+type countingLogger struct {
+	count int
+}
+
+func (c *countingLogger) Log(...interface{}) error {
+	c.count++
+	return nil
+}
+
+func entry(lvl level.Value, message string) []interface{} {
+	return []interface{}{level.Key(), lvl, kv.MessageKey, message}
+}
+
+func TestSampler(t *testing.T) {
+	debug := entry(level.DebugValue(), "cache hit")
+	tests := []struct {
+		name    string
+		options []Option
+		entries [][]interface{}
+		want    int
+	}{
+		{
+			name:    "no policy",
+			entries: [][]interface{}{debug, debug, debug},
+			want:    3,
+		},
+		{
+			name:    "other level",
+			options: []Option{Burst("debug", 0, time.Minute, 0)},
+			entries: [][]interface{}{entry(level.InfoValue(), "a"), entry(level.InfoValue(), "a")},
+			want:    2,
+		},
+		{
+			name:    "no level",
+			options: []Option{Burst("debug", 0, time.Minute, 0)},
+			entries: [][]interface{}{{kv.MessageKey, "a"}},
+			want:    1,
+		},
+		{
+			name:    "forced level",
+			options: []Option{Burst("debug", 0, time.Minute, 0)},
+			entries: [][]interface{}{entry(level.Force(level.DebugValue()), "a")},
+			want:    1,
+		},
+		{
+			name:    "rate of 1 keeps all",
+			options: []Option{Burst("debug", 0, time.Minute, 0), Rate("debug", 1)},
+			entries: [][]interface{}{debug, debug, debug},
+			want:    3,
+		},
+		{
+			name:    "burst",
+			options: []Option{Burst("debug", 2, time.Minute, 3)},
+			entries: [][]interface{}{debug, debug, debug, debug, debug, debug, debug, debug},
+			want:    4, // the first 2, then the 3rd and 6th of the remaining 6
+		},
+		{
+			name:    "burst drops all after the first",
+			options: []Option{Burst("debug", 1, time.Minute, 0)},
+			entries: [][]interface{}{debug, debug, debug},
+			want:    1,
+		},
+		{
+			name:    "alias",
+			options: []Option{Burst("warn", 1, time.Minute, 0)},
+			entries: [][]interface{}{entry(level.WarnValue(), "a"), entry(level.WarnValue(), "a")},
+			want:    1,
+		},
+		{
+			name:    "upper case",
+			options: []Option{Burst("DEBUG", 1, time.Minute, 0), Rate(" Debug ", 1)},
+			entries: [][]interface{}{debug, debug, debug},
+			want:    3,
+		},
+		{
+			name:    "burst per message",
+			options: []Option{Burst("debug", 1, time.Minute, 0)},
+			entries: [][]interface{}{debug, entry(level.DebugValue(), "cache miss"), debug},
+			want:    2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &countingLogger{}
+			s, err := New(next, tt.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, keyvals := range tt.entries {
+				if err := s.Log(keyvals...); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if next.count != tt.want {
+				t.Errorf("passed %d entries, want %d", next.count, tt.want)
+			}
+			if dropped := s.Dropped(); dropped != uint64(len(tt.entries)-tt.want) {
+				t.Errorf("Dropped() = %d, want %d", dropped, len(tt.entries)-tt.want)
+			}
+		})
+	}
+}
+
+func TestSamplerBurstInterval(t *testing.T) {
+	next := &countingLogger{}
+	s, err := New(next, Burst("debug", 1, time.Second, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	begin := time.Now()
+	for _, after := range []time.Duration{0, 500 * time.Millisecond, time.Second, 1500 * time.Millisecond, 2 * time.Second} {
+		s.now = func() time.Time { return begin.Add(after) }
+		s.Log(entry(level.DebugValue(), "a")...)
+	}
+	if next.count != 3 {
+		t.Errorf("passed %d entries, want the first of each of the 3 intervals", next.count)
+	}
+}
+
+func TestSamplerRate(t *testing.T) {
+	next := &countingLogger{}
+	s, err := New(next, Rate("debug", 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const n = 10000
+	for i := 0; i < n; i++ {
+		s.Log(entry(level.DebugValue(), "a")...)
+	}
+	// the expected 1000 kept entries have a standard deviation of 30
+	if next.count < 800 || next.count > 1200 {
+		t.Errorf("kept %d of %d entries at a rate of 1 in 10", next.count, n)
+	}
+}
+
+func TestNewUnknownLevel(t *testing.T) {
+	for _, option := range []Option{Rate("verbose", 10), Burst("verbose", 1, time.Second, 0)} {
+		if s, err := New(&countingLogger{}, Rate("debug", 10), option); err == nil {
+			t.Errorf("New() = %v, want an error for the unknown level", s)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package log
+
+// WithTraceSampledDebug ties Debug entries of Logs carrying a span (see
+// WithTrace) to the sampling decision of the trace: they are logged for
+// sampled traces regardless of the level, and dropped for all others. So
+// verbose logging follows the sampling rate of the tracing. Logs without a
+// span are not affected.
+func WithTraceSampledDebug() Option {
+	return func(o *options) { o.sampledDebug = true }
+}
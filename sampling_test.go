@@ -0,0 +1,74 @@
+package log
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTraceSampledDebug(t *testing.T) {
+	sampled := ContextWithTrace(context.Background(), Traceparent{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true})
+	unsampled := ContextWithTrace(context.Background(), Traceparent{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"})
+	for _, tt := range []struct {
+		name       string
+		ctx        context.Context
+		wantMain   int
+		wantErrors int
+		wantDebug  int
+	}{
+		{"sampled", sampled, 1, 0, 1},
+		{"not sampled", unsampled, 0, 0, 0},
+		{"no trace", context.Background(), 0, 0, 1},
+		{"span without trace context", ContextWithTrace(context.Background(), Traceparent{}), 0, 0, 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			errorSink, debugSink := &outputBuffer{}, &outputBuffer{}
+			logger, out := newBufferLogger(LevelInfo,
+				WithTraceSampledDebug(),
+				WithMinLevelSink(newJSONSink(errorSink), LevelError),
+				WithMinLevelSink(newJSONSink(debugSink), LevelDebug),
+			)
+			logger.WithTrace(tt.ctx).Debug("details")
+
+			if got := len(out.entries(t)); got != tt.wantMain {
+				t.Errorf("%d entries reached the sink, want %d", got, tt.wantMain)
+			}
+			// a forced entry must not reach sinks accepting only severe entries
+			if got := len(errorSink.entries(t)); got != tt.wantErrors {
+				t.Errorf("%d entries reached the error sink, want %d", got, tt.wantErrors)
+			}
+			// sinks accepting debug entries receive them independently of the trace
+			if got := len(debugSink.entries(t)); got != tt.wantDebug {
+				t.Errorf("%d entries reached the debug sink, want %d", got, tt.wantDebug)
+			}
+		})
+	}
+}
+
+func TestIsLevelEnabledSampledDebug(t *testing.T) {
+	sampled := ContextWithTrace(context.Background(), Traceparent{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true})
+	unsampled := ContextWithTrace(context.Background(), Traceparent{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"})
+	for _, tt := range []struct {
+		name  string
+		level string
+		ctx   context.Context
+		want  bool
+	}{
+		{"sampled", LevelInfo, sampled, true},
+		{"not sampled", LevelDebug, unsampled, false},
+		{"no trace", LevelInfo, context.Background(), false},
+		{"no trace at debug", LevelDebug, context.Background(), true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, out := newBufferLogger(tt.level, WithTraceSampledDebug())
+			l := logger.WithTrace(tt.ctx)
+			if got := l.IsLevelEnabled(LevelDebug); got != tt.want {
+				t.Errorf("IsLevelEnabled(debug) = %v, want %v", got, tt.want)
+			}
+			// agrees with Debug
+			l.Debug("details")
+			if logged := len(out.entries(t)) > 0; logged != tt.want {
+				t.Errorf("Debug logged: %v, want %v", logged, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+// Package saramalog logs the produce and consume events, rebalances and
+// errors of sarama Kafka clients with the godin logger.
+package saramalog
+
+import (
+	"fmt"
+
+	"github.com/IBM/sarama"
+	"github.com/go-godin/log"
+)
+
+// Interceptor logs produced and consumed messages as debug with their
+// topic, partition and offset:
+//
+//	i := saramalog.NewInterceptor(logger)
+//	config.Producer.Interceptors = []sarama.ProducerInterceptor{i}
+//	config.Consumer.Interceptors = []sarama.ConsumerInterceptor{i}
+//
+// The internal logs of sarama can be written through the Log too:
+//
+//	sarama.Logger = logger.StdLogger(log.LevelDebug)
+type Interceptor struct {
+	logger log.Log
+}
+
+var (
+	_ sarama.ProducerInterceptor = (*Interceptor)(nil)
+	_ sarama.ConsumerInterceptor = (*Interceptor)(nil)
+)
+
+// NewInterceptor creates an Interceptor logging through logger.
+func NewInterceptor(logger log.Log) *Interceptor {
+	return &Interceptor{logger: logger}
+}
+
+// OnSend logs a message about to be produced. The partition and offset are
+// only known once it's acknowledged.
+func (i *Interceptor) OnSend(m *sarama.ProducerMessage) {
+	i.logger.Debug("producing message", "topic", m.Topic)
+}
+
+// OnConsume logs a consumed message.
+func (i *Interceptor) OnConsume(m *sarama.ConsumerMessage) {
+	i.logger.Debug("consumed message", "topic", m.Topic, "partition", m.Partition, "offset", m.Offset)
+}
+
+// LogProducerErrors logs the errors of an async producer, until errs is
+// closed:
+//
+//	go saramalog.LogProducerErrors(logger, producer.Errors())
+func LogProducerErrors(logger log.Log, errs <-chan *sarama.ProducerError) {
+	for err := range errs {
+		keyvals := []interface{}{"err", err.Err}
+		if m := err.Msg; m != nil {
+			keyvals = append(keyvals, "topic", m.Topic, "partition", m.Partition, "offset", m.Offset)
+		}
+		logger.Error("producing message failed", keyvals...)
+	}
+}
+
+// LogErrors logs the errors of a consumer group, until errs is closed:
+//
+//	go saramalog.LogErrors(logger, group.Errors())
+func LogErrors(logger log.Log, errs <-chan error) {
+	for err := range errs {
+		logger.Error("consumer group failed", "err", err)
+	}
+}
+
+// ConsumerGroupHandler wraps handler to log rebalances: the partitions
+// assigned to the member when a session starts and revoked when it ends.
+func ConsumerGroupHandler(logger log.Log, handler sarama.ConsumerGroupHandler) sarama.ConsumerGroupHandler {
+	return groupHandler{ConsumerGroupHandler: handler, logger: logger}
+}
+
+type groupHandler struct {
+	sarama.ConsumerGroupHandler
+	logger log.Log
+}
+
+func (h groupHandler) Setup(session sarama.ConsumerGroupSession) error {
+	h.logger.Info("partitions assigned", sessionFields(session)...)
+	return h.ConsumerGroupHandler.Setup(session)
+}
+
+func (h groupHandler) Cleanup(session sarama.ConsumerGroupSession) error {
+	h.logger.Info("partitions revoked", sessionFields(session)...)
+	return h.ConsumerGroupHandler.Cleanup(session)
+}
+
+func sessionFields(session sarama.ConsumerGroupSession) []interface{} {
+	return []interface{}{
+		"member_id", session.MemberID(),
+		"generation", session.GenerationID(),
+		"partitions", fmt.Sprint(session.Claims()),
+	}
+}
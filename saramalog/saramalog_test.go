@@ -0,0 +1,140 @@
+package saramalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/go-godin/log"
+	kitlog "github.com/go-kit/kit/log"
+)
+
+func newLogger(t *testing.T) (log.Log, func() []map[string]interface{}) {
+	t.Helper()
+	var buf bytes.Buffer
+	logger := log.NewLogger(log.LevelDebug, log.WithSink(kitlog.NewJSONLogger(&buf)))
+	return logger, func() []map[string]interface{} {
+		var entries []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var entry map[string]interface{}
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("decoding %q: %v", line, err)
+			}
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+}
+
+// session is a sarama.ConsumerGroupSession of a member.
+type session struct {
+	sarama.ConsumerGroupSession
+}
+
+func (session) MemberID() string           { return "member-1" }
+func (session) GenerationID() int32        { return 7 }
+func (session) Claims() map[string][]int32 { return map[string][]int32{"orders": {0, 1}} }
+
+// handler records the calls of the consumer group.
+type handler struct {
+	calls *[]string
+}
+
+func (h handler) Setup(sarama.ConsumerGroupSession) error {
+	*h.calls = append(*h.calls, "setup")
+	return nil
+}
+
+func (h handler) Cleanup(sarama.ConsumerGroupSession) error {
+	*h.calls = append(*h.calls, "cleanup")
+	return nil
+}
+
+func (handler) ConsumeClaim(sarama.ConsumerGroupSession, sarama.ConsumerGroupClaim) error {
+	return nil
+}
+
+func TestSaramaLogging(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(logger log.Log)
+		want []map[string]interface{} // the checked fields of the entries
+	}{
+		{
+			name: "send",
+			log:  func(logger log.Log) { NewInterceptor(logger).OnSend(&sarama.ProducerMessage{Topic: "orders"}) },
+			want: []map[string]interface{}{{"message": "producing message", "severity": "debug", "topic": "orders"}},
+		},
+		{
+			name: "consume",
+			log: func(logger log.Log) {
+				NewInterceptor(logger).OnConsume(&sarama.ConsumerMessage{Topic: "orders", Partition: 2, Offset: 42})
+			},
+			want: []map[string]interface{}{{"message": "consumed message", "severity": "debug", "partition": float64(2), "offset": float64(42)}},
+		},
+		{
+			name: "producer errors",
+			log: func(logger log.Log) {
+				errs := make(chan *sarama.ProducerError, 2)
+				errs <- &sarama.ProducerError{Msg: &sarama.ProducerMessage{Topic: "orders", Partition: 1}, Err: errors.New("timeout")}
+				errs <- &sarama.ProducerError{Err: errors.New("closed")}
+				close(errs)
+				LogProducerErrors(logger, errs)
+			},
+			want: []map[string]interface{}{
+				{"message": "producing message failed", "severity": "error", "topic": "orders", "err": "timeout"},
+				{"message": "producing message failed", "severity": "error", "err": "closed"},
+			},
+		},
+		{
+			name: "consumer group errors",
+			log: func(logger log.Log) {
+				errs := make(chan error, 1)
+				errs <- errors.New("rebalance failed")
+				close(errs)
+				LogErrors(logger, errs)
+			},
+			want: []map[string]interface{}{{"message": "consumer group failed", "severity": "error", "err": "rebalance failed"}},
+		},
+		{
+			name: "rebalance",
+			log: func(logger log.Log) {
+				var calls []string
+				h := ConsumerGroupHandler(logger, handler{calls: &calls})
+				_ = h.Setup(session{})
+				_ = h.Cleanup(session{})
+				if strings.Join(calls, ",") != "setup,cleanup" {
+					t.Errorf("the wrapped handler was called with %v", calls)
+				}
+			},
+			want: []map[string]interface{}{
+				{"message": "partitions assigned", "severity": "info", "member_id": "member-1", "generation": float64(7), "partitions": "map[orders:[0 1]]"},
+				{"message": "partitions revoked", "severity": "info", "member_id": "member-1"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, entries := newLogger(t)
+			tt.log(logger)
+
+			logged := entries()
+			if len(logged) != len(tt.want) {
+				t.Fatalf("logged %d entries, want %d: %v", len(logged), len(tt.want), logged)
+			}
+			for i, want := range tt.want {
+				for key, value := range want {
+					if logged[i][key] != value {
+						t.Errorf("entry %d: %s = %v, want %v", i, key, logged[i][key], value)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+// Package selftrace makes slow operations of the logging pipeline itself
+// visible in traces. Operations are timed and only reported as spans if they
+// exceed a threshold, so fast operations don't cost any tracing overhead.
+// The spans are started by a Starter, e.g. zipkin.SelfTrace of the
+// tracing/zipkin module, so only services which trace depend on a tracer.
+package selftrace
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-godin/log"
+)
+
+// Span is a span started by a Starter.
+type Span interface {
+	log.SpanRecorder
+	// Finish ends the span.
+	Finish()
+}
+
+// Starter starts the span of an operation which began at start.
+type Starter func(name string, start time.Time) Span
+
+// Tracer reports slow pipeline operations to the spans of a Starter.
+// A nil *Tracer is valid and does nothing.
+type Tracer struct {
+	start     Starter
+	threshold time.Duration
+}
+
+// New creates a Tracer which reports operations taking at least threshold.
+func New(start Starter, threshold time.Duration) *Tracer {
+	return &Tracer{
+		start:     start,
+		threshold: threshold,
+	}
+}
+
+// Operation is a single timed pipeline operation, e.g. a sink flush.
+type Operation struct {
+	tracer      *Tracer
+	name        string
+	start       time.Time
+	mtx         sync.Mutex
+	tags        map[string]string
+	annotations []annotation
+	retries     int
+}
+
+// annotation is a retry, elapsed after the start of the operation.
+type annotation struct {
+	elapsed time.Duration
+	retry   int
+	cause   error
+}
+
+func (a annotation) String() string {
+	value := "retry " + strconv.Itoa(a.retry) + " after " + a.elapsed.Round(time.Millisecond).String()
+	if a.cause != nil {
+		value += ": " + a.cause.Error()
+	}
+	return value
+}
+
+// Start begins timing an operation with the given span name, e.g. "log.sink.flush".
+func (t *Tracer) Start(name string) *Operation {
+	if t == nil || t.start == nil {
+		return nil
+	}
+	return &Operation{
+		tracer: t,
+		name:   name,
+		start:  time.Now(),
+	}
+}
+
+// Tag records a tag which is added to the span if the operation is reported.
+func (o *Operation) Tag(key, value string) {
+	if o == nil {
+		return
+	}
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	if o.tags == nil {
+		o.tags = make(map[string]string)
+	}
+	o.tags[key] = value
+}
+
+// Retry records a retry together with its cause, so retry storms show up as
+// annotations on the span.
+func (o *Operation) Retry(cause error) {
+	if o == nil {
+		return
+	}
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	o.retries++
+	o.annotations = append(o.annotations, annotation{elapsed: time.Since(o.start), retry: o.retries, cause: cause})
+}
+
+// End finishes the operation and reports it as span if it exceeded the
+// threshold or any retries happened. A non-nil err marks the span as failed.
+// Since spans are only started once the operation ended, retries are
+// annotated with the time elapsed since the start, e.g. "retry 1 after
+// 120ms: connection refused".
+func (o *Operation) End(err error) {
+	if o == nil {
+		return
+	}
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	if time.Since(o.start) < o.tracer.threshold && o.retries == 0 && err == nil {
+		return
+	}
+
+	span := o.tracer.start(o.name, o.start)
+	for k, v := range o.tags {
+		span.Tag(k, v)
+	}
+	for _, a := range o.annotations {
+		span.Annotate(a.String())
+	}
+	if o.retries > 0 {
+		span.Tag("retries", strconv.Itoa(o.retries))
+	}
+	if err != nil {
+		span.SetError(err.Error())
+	}
+	span.Finish()
+}
@@ -0,0 +1,127 @@
+package selftrace
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeSpan struct {
+	name        string
+	start       time.Time
+	annotations []string
+	tags        map[string]string
+	err         string
+	finished    bool
+}
+
+func (s *fakeSpan) Annotate(message string) { s.annotations = append(s.annotations, message) }
+func (s *fakeSpan) Tag(key, value string)   { s.tags[key] = value }
+func (s *fakeSpan) SetError(message string) { s.err = message }
+func (s *fakeSpan) Finish()                 { s.finished = true }
+
+// recordingStarter returns a Starter appending the started spans to spans.
+func recordingStarter(spans *[]*fakeSpan) Starter {
+	return func(name string, start time.Time) Span {
+		span := &fakeSpan{name: name, start: start, tags: make(map[string]string)}
+		*spans = append(*spans, span)
+		return span
+	}
+}
+
+func TestOperationEnd(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		threshold time.Duration
+		run       func(op *Operation)
+		reported  bool
+		tags      map[string]string
+		retries   []string
+		err       string
+	}{
+		{
+			name:      "fast",
+			threshold: time.Hour,
+			run:       func(op *Operation) { op.Tag("sink", "es"); op.End(nil) },
+		},
+		{
+			name:      "slow",
+			threshold: 0,
+			run:       func(op *Operation) { op.Tag("sink", "es"); op.End(nil) },
+			reported:  true,
+			tags:      map[string]string{"sink": "es"},
+		},
+		{
+			name:      "fast retried",
+			threshold: time.Hour,
+			run: func(op *Operation) {
+				op.Retry(errors.New("connection refused"))
+				op.Retry(nil)
+				op.End(nil)
+			},
+			reported: true,
+			tags:     map[string]string{"retries": "2"},
+			retries:  []string{"retry 1 after ", "retry 2 after "},
+		},
+		{
+			name:      "fast failed",
+			threshold: time.Hour,
+			run:       func(op *Operation) { op.End(errors.New("failed")) },
+			reported:  true,
+			tags:      map[string]string{},
+			err:       "failed",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var spans []*fakeSpan
+			tracer := New(recordingStarter(&spans), tt.threshold)
+			before := time.Now()
+			tt.run(tracer.Start("log.sink.flush"))
+
+			if !tt.reported {
+				if len(spans) != 0 {
+					t.Fatalf("%d spans reported, want none", len(spans))
+				}
+				return
+			}
+			if len(spans) != 1 {
+				t.Fatalf("%d spans reported, want 1", len(spans))
+			}
+			span := spans[0]
+			if span.name != "log.sink.flush" || !span.finished || span.start.Before(before) {
+				t.Errorf("span %q started at %s, finished %v", span.name, span.start, span.finished)
+			}
+			if !reflect.DeepEqual(span.tags, tt.tags) {
+				t.Errorf("tags = %v, want %v", span.tags, tt.tags)
+			}
+			if len(span.annotations) != len(tt.retries) {
+				t.Fatalf("annotations = %q, want %d", span.annotations, len(tt.retries))
+			}
+			for i, prefix := range tt.retries {
+				if !strings.HasPrefix(span.annotations[i], prefix) {
+					t.Errorf("annotation %q, want prefix %q", span.annotations[i], prefix)
+				}
+			}
+			if tt.retries != nil && !strings.HasSuffix(span.annotations[0], ": connection refused") {
+				t.Errorf("annotation %q lacks the cause", span.annotations[0])
+			}
+			if span.err != tt.err {
+				t.Errorf("error = %q, want %q", span.err, tt.err)
+			}
+		})
+	}
+}
+
+func TestNilTracer(t *testing.T) {
+	var tracer *Tracer
+	op := tracer.Start("op")
+	op.Tag("k", "v")
+	op.Retry(errors.New("e"))
+	op.End(errors.New("e"))
+
+	if op := New(nil, 0).Start("op"); op != nil {
+		t.Fatal("Tracer without Starter started an operation")
+	}
+}
@@ -0,0 +1,39 @@
+//go:build !windows
+
+package log
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// HandleLevelSignals changes the level at runtime on signals: SIGUSR1 makes
+// the level one step more verbose (e.g. info to debug), SIGUSR2 one step less
+// verbose. Every change is emitted with ConfigChanged. Signals are handled
+// until stop is called.
+func (l Log) HandleLevelSignals() (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				l.bumpLevel(sig == syscall.SIGUSR1, SourceSignal)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+		})
+	}
+}
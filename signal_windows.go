@@ -0,0 +1,6 @@
+package log
+
+// HandleLevelSignals does nothing on Windows, which lacks SIGUSR1 and SIGUSR2.
+func (l Log) HandleLevelSignals() (stop func()) {
+	return func() {}
+}
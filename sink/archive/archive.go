@@ -0,0 +1,323 @@
+// Package archive provides a sink accumulating entries into gzip compressed
+// NDJSON chunks which are periodically uploaded to object storage like S3 or
+// GCS, for cheap long-term archival directly from the service.
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-godin/log/internal/kv"
+)
+
+var (
+	// ErrQueueFull is returned by Log if the sink drops entries when its queue is full.
+	ErrQueueFull = errors.New("archive: queue is full, entry dropped")
+	// ErrClosed is returned by Log after the sink has been closed.
+	ErrClosed = errors.New("archive: sink is closed")
+)
+
+// Uploader stores a chunk under the given object key.
+type Uploader interface {
+	Upload(ctx context.Context, key string, body []byte) error
+}
+
+// Sink buffers entries into chunks and uploads them once they're large or
+// old enough. It implements the go-kit log.Logger interface and can be
+// passed to log.WithSink.
+type Sink struct {
+	uploader      Uploader
+	prefix        string
+	partition     string
+	host          string
+	chunkBytes    int
+	flushInterval time.Duration
+	timeout       time.Duration
+	queueSize     int
+	dropWhenFull  bool
+	maxRetries    int
+	backoff       time.Duration
+	maxBackoff    time.Duration
+	errorHandler  func(error)
+
+	queue   chan []byte
+	flushes chan chan struct{}
+	done    chan struct{}
+	mtx     sync.RWMutex
+	closed  bool
+	dropped atomic.Uint64
+}
+
+// Option sets a parameter for the Sink.
+type Option func(*Sink)
+
+// Prefix sets the prefix of all object keys, e.g. "logs/billing/".
+func Prefix(prefix string) Option {
+	return func(s *Sink) { s.prefix = prefix }
+}
+
+// Partition sets the time layout of the key segment partitioning the
+// chunks, formatted with the UTC start time of the chunk. Defaults to
+// "year=2006/month=01/day=02/hour=15", which query engines like Athena
+// pick up as partitions.
+func Partition(layout string) Option {
+	return func(s *Sink) { s.partition = layout }
+}
+
+// Host sets the name which distinguishes the chunks of this process in the
+// object keys. Defaults to the hostname.
+func Host(host string) Option {
+	return func(s *Sink) { s.host = host }
+}
+
+// ChunkSize sets the amount of uncompressed bytes after which a chunk is
+// uploaded. Defaults to 64MB.
+func ChunkSize(bytes int) Option {
+	return func(s *Sink) { s.chunkBytes = bytes }
+}
+
+// FlushInterval sets the maximum age of a chunk before it's uploaded. Zero
+// uploads chunks only once they're full, on Flush and on Close. Defaults to
+// five minutes.
+func FlushInterval(interval time.Duration) Option {
+	return func(s *Sink) { s.flushInterval = interval }
+}
+
+// Timeout sets the timeout of a single upload. Defaults to one minute.
+func Timeout(timeout time.Duration) Option {
+	return func(s *Sink) { s.timeout = timeout }
+}
+
+// QueueSize sets the amount of entries buffered in memory while a chunk is
+// being uploaded. Once the queue is full, Log blocks unless DropWhenFull is
+// set. Defaults to 10000.
+func QueueSize(size int) Option {
+	return func(s *Sink) { s.queueSize = size }
+}
+
+// DropWhenFull instructs Log to drop entries and return ErrQueueFull instead of
+// blocking the caller if the queue is full.
+func DropWhenFull() Option {
+	return func(s *Sink) { s.dropWhenFull = true }
+}
+
+// Retry configures how often failed uploads are retried. The delay between
+// attempts starts at backoff and doubles up to maxBackoff.
+// Defaults to 5 retries, starting at one second up to one minute.
+func Retry(maxRetries int, backoff, maxBackoff time.Duration) Option {
+	return func(s *Sink) {
+		s.maxRetries = maxRetries
+		s.backoff = backoff
+		s.maxBackoff = maxBackoff
+	}
+}
+
+// ErrorHandler sets the function called with errors which occur while
+// uploading chunks. By default errors are written to stderr.
+func ErrorHandler(handler func(error)) Option {
+	return func(s *Sink) { s.errorHandler = handler }
+}
+
+// New creates a Sink uploading chunks with the uploader and starts its
+// background worker. Chunks are stored under keys like
+// "<prefix>year=2024/month=05/day=17/hour=13/<host>-<unix nanos>.ndjson.gz".
+func New(uploader Uploader, opts ...Option) *Sink {
+	hostname, _ := os.Hostname()
+	s := &Sink{
+		uploader:      uploader,
+		partition:     "year=2006/month=01/day=02/hour=15",
+		host:          hostname,
+		chunkBytes:    64 << 20,
+		flushInterval: 5 * time.Minute,
+		timeout:       time.Minute,
+		queueSize:     10000,
+		maxRetries:    5,
+		backoff:       time.Second,
+		maxBackoff:    time.Minute,
+		errorHandler: func(err error) {
+			fmt.Fprintf(os.Stderr, "archive sink: %v\n", err)
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.queue = make(chan []byte, s.queueSize)
+	s.flushes = make(chan chan struct{})
+	s.done = make(chan struct{})
+	go s.run()
+
+	return s
+}
+
+// Log encodes the entry and enqueues it for the current chunk.
+func (s *Sink) Log(keyvals ...interface{}) error {
+	line, err := json.Marshal(kv.Map(keyvals))
+	if err != nil {
+		return err
+	}
+
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	if s.closed {
+		return ErrClosed
+	}
+	if s.dropWhenFull {
+		select {
+		case s.queue <- line:
+			return nil
+		default:
+			s.dropped.Add(1)
+			return ErrQueueFull
+		}
+	}
+	s.queue <- line
+	return nil
+}
+
+// Dropped returns the amount of entries dropped because the queue was full.
+func (s *Sink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Flush uploads the current chunk and blocks until the upload is done.
+func (s *Sink) Flush() error {
+	s.mtx.RLock()
+	if s.closed {
+		s.mtx.RUnlock()
+		return nil
+	}
+	ack := make(chan struct{})
+	s.flushes <- ack
+	s.mtx.RUnlock()
+	<-ack
+	return nil
+}
+
+// Close stops accepting new entries, uploads the last chunk and stops the worker.
+func (s *Sink) Close() error {
+	s.mtx.Lock()
+	if s.closed {
+		s.mtx.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.mtx.Unlock()
+
+	<-s.done
+	return nil
+}
+
+// chunk is a gzip compressed chunk being filled.
+type chunk struct {
+	start   time.Time
+	size    int
+	entries int
+	buf     bytes.Buffer
+	gz      *gzip.Writer
+}
+
+func (s *Sink) run() {
+	defer close(s.done)
+
+	// chunks are compressed while they grow, so they're not batched with
+	// internal/batch, which holds the entries until they're sent
+	var tick <-chan time.Time
+	if s.flushInterval > 0 {
+		ticker := time.NewTicker(s.flushInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	var c *chunk
+	add := func(line []byte) {
+		if c == nil {
+			c = &chunk{start: time.Now()}
+			c.gz = gzip.NewWriter(&c.buf)
+		}
+		_, _ = c.gz.Write(line)
+		_, _ = c.gz.Write([]byte{'\n'})
+		c.size += len(line) + 1
+		c.entries++
+		if c.size >= s.chunkBytes {
+			s.upload(c)
+			c = nil
+		}
+	}
+
+	for {
+		select {
+		case line, ok := <-s.queue:
+			if !ok {
+				s.upload(c)
+				return
+			}
+			add(line)
+		case ack := <-s.flushes:
+			for n := len(s.queue); n > 0; n-- {
+				add(<-s.queue)
+			}
+			s.upload(c)
+			c = nil
+			close(ack)
+		case <-tick:
+			if c != nil && time.Since(c.start) >= s.flushInterval {
+				s.upload(c)
+				c = nil
+			}
+		}
+	}
+}
+
+// key returns the object key of a chunk started at start.
+func (s *Sink) key(start time.Time) string {
+	var b strings.Builder
+	b.WriteString(s.prefix)
+	if s.partition != "" {
+		b.WriteString(start.UTC().Format(s.partition))
+		b.WriteByte('/')
+	}
+	fmt.Fprintf(&b, "%s-%d.ndjson.gz", s.host, start.UnixNano())
+	return b.String()
+}
+
+// upload completes the chunk and uploads it, retrying failed attempts.
+func (s *Sink) upload(c *chunk) {
+	if c == nil || c.entries == 0 {
+		return
+	}
+	if err := c.gz.Close(); err != nil {
+		s.errorHandler(fmt.Errorf("dropping %d entries, compressing failed: %v", c.entries, err))
+		return
+	}
+	key := s.key(c.start)
+
+	var err error
+	backoff := s.backoff
+	for attempt := 0; attempt <= s.maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			if backoff *= 2; backoff > s.maxBackoff {
+				backoff = s.maxBackoff
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		err = s.uploader.Upload(ctx, key, c.buf.Bytes())
+		cancel()
+		if err == nil {
+			return
+		}
+	}
+	s.errorHandler(fmt.Errorf("dropping chunk %s with %d entries after %d attempts: %v", key, c.entries, s.maxRetries+1, err))
+}
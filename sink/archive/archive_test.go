@@ -0,0 +1,233 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// recordingUploader records the uploaded chunks, failing the first
+// failures attempts.
+type recordingUploader struct {
+	mtx      sync.Mutex
+	failures int
+	attempts int
+	keys     []string
+	chunks   [][]string
+}
+
+func (u *recordingUploader) Upload(_ context.Context, key string, body []byte) error {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	u.attempts++
+	if u.failures > 0 {
+		u.failures--
+		return errors.New("unavailable")
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		return err
+	}
+	u.keys = append(u.keys, key)
+	u.chunks = append(u.chunks, strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"))
+	return nil
+}
+
+func TestSink(t *testing.T) {
+	tests := []struct {
+		name         string
+		options      []Option
+		failures     int
+		entries      int
+		flush        bool
+		wantChunks   []int // the amount of entries per chunk
+		wantAttempts int
+		wantErrors   int
+	}{
+		{
+			name:         "uploaded on close",
+			entries:      3,
+			wantChunks:   []int{3},
+			wantAttempts: 1,
+		},
+		{
+			name:         "uploaded on flush",
+			entries:      2,
+			flush:        true,
+			wantChunks:   []int{2},
+			wantAttempts: 1,
+		},
+		{
+			name: "full chunks",
+			// every entry is {"message":"entry","n":1} plus the newline
+			options:      []Option{ChunkSize(50)},
+			entries:      5,
+			wantChunks:   []int{2, 2, 1},
+			wantAttempts: 3,
+		},
+		{
+			name:         "retried",
+			options:      []Option{Retry(2, time.Millisecond, time.Millisecond)},
+			failures:     2,
+			entries:      1,
+			wantChunks:   []int{1},
+			wantAttempts: 3,
+		},
+		{
+			name:         "dropped after the retries",
+			options:      []Option{Retry(1, time.Millisecond, time.Millisecond)},
+			failures:     2,
+			entries:      1,
+			wantAttempts: 2,
+			wantErrors:   1,
+		},
+		{
+			name:    "nothing to upload",
+			entries: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &recordingUploader{failures: tt.failures}
+			var errs []error
+			options := append([]Option{Host("web-1"), ErrorHandler(func(err error) { errs = append(errs, err) })}, tt.options...)
+			s := New(u, options...)
+			for i := 0; i < tt.entries; i++ {
+				if err := s.Log("message", "entry", "n", 1); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.flush {
+				s.Flush()
+				if len(u.chunks) != len(tt.wantChunks) {
+					t.Errorf("uploaded %d chunks on flush, want %d", len(u.chunks), len(tt.wantChunks))
+				}
+			}
+			s.Close()
+
+			if len(u.chunks) != len(tt.wantChunks) {
+				t.Fatalf("uploaded %d chunks, want %d", len(u.chunks), len(tt.wantChunks))
+			}
+			for i, n := range tt.wantChunks {
+				if len(u.chunks[i]) != n {
+					t.Errorf("chunk %d has %d entries, want %d", i, len(u.chunks[i]), n)
+				}
+				if u.chunks[i][0] != `{"message":"entry","n":1}` {
+					t.Errorf("chunk %d starts with %s", i, u.chunks[i][0])
+				}
+			}
+			if u.attempts != tt.wantAttempts {
+				t.Errorf("%d upload attempts, want %d", u.attempts, tt.wantAttempts)
+			}
+			if len(errs) != tt.wantErrors {
+				t.Errorf("reported errors %v, want %d", errs, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestSinkClosed(t *testing.T) {
+	s := New(&recordingUploader{})
+	s.Close()
+	if err := s.Log("message", "late"); err != ErrClosed {
+		t.Errorf("Log() after Close = %v, want ErrClosed", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("closing twice: %v", err)
+	}
+}
+
+func TestSinkKey(t *testing.T) {
+	start := time.Date(2024, 5, 17, 13, 4, 5, 6, time.FixedZone("CEST", 2*60*60))
+	tests := []struct {
+		name    string
+		options []Option
+		want    string
+	}{
+		{"default", nil, "year=2024/month=05/day=17/hour=11/web-1-1715943845000000006.ndjson.gz"},
+		{"prefix", []Option{Prefix("logs/billing/")}, "logs/billing/year=2024/month=05/day=17/hour=11/web-1-1715943845000000006.ndjson.gz"},
+		{"partition", []Option{Partition("2006-01-02")}, "2024-05-17/web-1-1715943845000000006.ndjson.gz"},
+		{"no partition", []Option{Partition("")}, "web-1-1715943845000000006.ndjson.gz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(&recordingUploader{}, append([]Option{Host("web-1")}, tt.options...)...)
+			defer s.Close()
+			if key := s.key(start); key != tt.want {
+				t.Errorf("key() = %s, want %s", key, tt.want)
+			}
+		})
+	}
+}
+
+type s3Client struct {
+	input *s3.PutObjectInput
+}
+
+func (c *s3Client) PutObject(_ context.Context, input *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	c.input = input
+	return &s3.PutObjectOutput{}, nil
+}
+
+func TestS3(t *testing.T) {
+	client := &s3Client{}
+	if err := S3(client, "archive").Upload(context.Background(), "a/b.ndjson.gz", []byte("chunk")); err != nil {
+		t.Fatal(err)
+	}
+	in := client.input
+	if *in.Bucket != "archive" || *in.Key != "a/b.ndjson.gz" || *in.ContentLength != 5 || *in.ContentEncoding != "gzip" {
+		t.Errorf("PutObject(%s, %s, %d, %s)", *in.Bucket, *in.Key, *in.ContentLength, *in.ContentEncoding)
+	}
+}
+
+// roundTripper answers requests with status and records them.
+type roundTripper struct {
+	status int
+	req    *http.Request
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.req = req
+	return &http.Response{StatusCode: rt.status, Body: io.NopCloser(strings.NewReader("denied")), Request: req}, nil
+}
+
+func TestGCS(t *testing.T) {
+	tests := []struct {
+		status  int
+		wantErr bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusForbidden, true},
+	}
+	for _, tt := range tests {
+		rt := &roundTripper{status: tt.status}
+		u, err := GCS(context.Background(), "archive", &http.Client{Transport: rt})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = u.Upload(context.Background(), "logs/a b.ndjson.gz", []byte("chunk"))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("status %d: Upload() = %v, want error %v", tt.status, err, tt.wantErr)
+		}
+		const want = "https://storage.googleapis.com/upload/storage/v1/b/archive/o?uploadType=media&name=logs%2Fa+b.ndjson.gz"
+		if got := rt.req.URL.String(); got != want {
+			t.Errorf("uploaded to %s, want %s", got, want)
+		}
+		if enc := rt.req.Header.Get("Content-Encoding"); enc != "gzip" {
+			t.Errorf("Content-Encoding = %q, want gzip", enc)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/oauth2/google"
+)
+
+const gcsScope = "https://www.googleapis.com/auth/devstorage.read_write"
+
+type gcsUploader struct {
+	client *http.Client
+	bucket string
+}
+
+// GCS returns an Uploader storing chunks in the given Cloud Storage bucket.
+// If client is nil, an authenticated client is created using the
+// application default credentials.
+func GCS(ctx context.Context, bucket string, client *http.Client) (Uploader, error) {
+	if client == nil {
+		var err error
+		client, err = google.DefaultClient(ctx, gcsScope)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return gcsUploader{client: client, bucket: bucket}, nil
+}
+
+func (u gcsUploader) Upload(ctx context.Context, key string, body []byte) error {
+	endpoint := fmt.Sprintf("https://storage.googleapis.com/upload/storage/v1/b/%s/o?uploadType=media&name=%s",
+		url.PathEscape(u.bucket), url.QueryEscape(key))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Content-Encoding", "gzip")
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("upload failed with status %d: %s", resp.StatusCode, msg)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
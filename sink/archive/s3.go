@@ -0,0 +1,37 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3Client is the subset of the S3 API used by the S3 uploader.
+// It is implemented by *s3.Client.
+type S3Client interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+type s3Uploader struct {
+	client S3Client
+	bucket string
+}
+
+// S3 returns an Uploader storing chunks in the given S3 bucket.
+func S3(client S3Client, bucket string) Uploader {
+	return s3Uploader{client: client, bucket: bucket}
+}
+
+func (u s3Uploader) Upload(ctx context.Context, key string, body []byte) error {
+	_, err := u.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:          aws.String(u.bucket),
+		Key:             aws.String(key),
+		Body:            bytes.NewReader(body),
+		ContentLength:   aws.Int64(int64(len(body))),
+		ContentType:     aws.String("application/x-ndjson"),
+		ContentEncoding: aws.String("gzip"),
+	})
+	return err
+}
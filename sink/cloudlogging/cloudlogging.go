@@ -0,0 +1,309 @@
+// Package cloudlogging provides a sink which writes log entries directly to
+// the Google Cloud Logging API, for services which don't run behind a
+// logging agent.
+package cloudlogging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-godin/log/internal/batch"
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/level"
+	"github.com/go-godin/log/selftrace"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	endpoint   = "https://logging.googleapis.com/v2/entries:write"
+	writeScope = "https://www.googleapis.com/auth/logging.write"
+)
+
+var (
+	// ErrQueueFull is returned by Log if the sink drops entries when its queue is full.
+	ErrQueueFull = errors.New("cloudlogging: queue is full, entry dropped")
+	// ErrClosed is returned by Log after the sink has been closed.
+	ErrClosed = errors.New("cloudlogging: sink is closed")
+)
+
+// severities maps godin's levels to Cloud Logging severities.
+var severities = map[level.Value]string{
+	level.DebugValue(): "DEBUG",
+	level.InfoValue():  "INFO",
+	level.WarnValue():  "WARNING",
+	level.ErrorValue(): "ERROR",
+}
+
+// Sink buffers log entries and writes them to Cloud Logging in batches. It
+// implements the go-kit log.Logger interface and can be passed to log.WithSink.
+type Sink struct {
+	client       *http.Client
+	endpoint     string
+	projectID    string
+	logName      string
+	resource     *Resource
+	labels       map[string]string
+	batching     batch.Config
+	maxRetries   int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	errorHandler func(error)
+	tracer       *selftrace.Tracer
+
+	batcher *batch.Batcher[entry]
+}
+
+type entry struct {
+	Severity    string                 `json:"severity"`
+	Timestamp   string                 `json:"timestamp"`
+	JSONPayload map[string]interface{} `json:"jsonPayload"`
+}
+
+type writeRequest struct {
+	LogName        string            `json:"logName"`
+	Resource       *Resource         `json:"resource"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Entries        []entry           `json:"entries"`
+	PartialSuccess bool              `json:"partialSuccess"`
+}
+
+// Option sets a parameter for the Sink.
+type Option func(*Sink)
+
+// HTTPClient sets the authorized client used to call the API. Defaults to
+// a client using Google's application default credentials.
+func HTTPClient(client *http.Client) Option {
+	return func(s *Sink) { s.client = client }
+}
+
+// ProjectID sets the project entries are written to. Defaults to the project
+// of the detected resource.
+func ProjectID(id string) Option {
+	return func(s *Sink) { s.projectID = id }
+}
+
+// MonitoredResource overrides the detected monitored resource.
+func MonitoredResource(resource Resource) Option {
+	return func(s *Sink) { s.resource = &resource }
+}
+
+// Labels sets labels attached to every entry.
+func Labels(labels map[string]string) Option {
+	return func(s *Sink) { s.labels = labels }
+}
+
+// BatchSize sets the maximum amount of entries written with a single call.
+// Defaults to 500.
+func BatchSize(entries int) Option {
+	return batching(batch.MaxEntries(entries))
+}
+
+// FlushInterval sets the maximum time an entry is buffered before it's sent.
+// Zero sends batches only once they're full, on Flush and on Close. Defaults
+// to one second.
+func FlushInterval(interval time.Duration) Option {
+	return batching(batch.MaxLatency(interval))
+}
+
+// QueueSize sets the amount of entries buffered in memory while a batch is
+// being sent. Once the queue is full, Log blocks unless DropWhenFull is set.
+// Defaults to 10000.
+func QueueSize(size int) Option {
+	return batching(batch.QueueSize(size))
+}
+
+// DropWhenFull instructs Log to drop entries and return ErrQueueFull instead of
+// blocking the caller if the queue is full.
+func DropWhenFull() Option {
+	return batching(batch.DropWhenFull())
+}
+
+// batching applies options of the batcher to the Sink.
+func batching(opts ...batch.Option) Option {
+	return func(s *Sink) {
+		for _, opt := range opts {
+			opt(&s.batching)
+		}
+	}
+}
+
+// Retry configures how often failed calls are retried. The delay between
+// attempts starts at backoff and doubles up to maxBackoff.
+// Defaults to 3 retries, starting at 100ms up to 5s.
+func Retry(maxRetries int, backoff, maxBackoff time.Duration) Option {
+	return func(s *Sink) {
+		s.maxRetries = maxRetries
+		s.backoff = backoff
+		s.maxBackoff = maxBackoff
+	}
+}
+
+// ErrorHandler sets the function called with errors which occur while
+// writing entries. By default errors are written to stderr.
+func ErrorHandler(handler func(error)) Option {
+	return func(s *Sink) { s.errorHandler = handler }
+}
+
+// Tracer reports slow flushes and retries of the sink as spans.
+func Tracer(tracer *selftrace.Tracer) Option {
+	return func(s *Sink) { s.tracer = tracer }
+}
+
+// New creates a Sink writing to the log with the given name (e.g. "my-service")
+// and starts its background worker. Unless configured explicitly, the
+// monitored resource is detected from the environment (GKE, GCE or global).
+func New(ctx context.Context, logName string, opts ...Option) (*Sink, error) {
+	s := &Sink{
+		endpoint:   endpoint,
+		logName:    logName,
+		maxRetries: 3,
+		backoff:    100 * time.Millisecond,
+		maxBackoff: 5 * time.Second,
+		errorHandler: func(err error) {
+			fmt.Fprintf(os.Stderr, "cloudlogging sink: %v\n", err)
+		},
+		batching: batch.Config{
+			MaxEntries:   500,
+			MaxLatency:   time.Second,
+			QueueSize:    10000,
+			ErrQueueFull: ErrQueueFull,
+			ErrClosed:    ErrClosed,
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.client == nil {
+		client, err := google.DefaultClient(ctx, writeScope)
+		if err != nil {
+			return nil, err
+		}
+		s.client = client
+	}
+	if s.resource == nil {
+		resource := DetectResource(ctx)
+		s.resource = &resource
+	}
+	if s.projectID == "" {
+		s.projectID = s.resource.Labels["project_id"]
+	}
+	if s.projectID == "" {
+		return nil, errors.New("cloudlogging: no project id configured or detected")
+	}
+	if s.resource.Labels["project_id"] == "" {
+		labels := map[string]string{"project_id": s.projectID}
+		for k, v := range s.resource.Labels {
+			labels[k] = v
+		}
+		s.resource.Labels = labels
+	}
+
+	s.batcher = batch.New(s.batching, func(entry) int { return 0 }, s.send)
+
+	return s, nil
+}
+
+// Log maps the entry to a Cloud Logging entry and enqueues it for the next batch.
+func (s *Sink) Log(keyvals ...interface{}) error {
+	severity := "DEFAULT"
+	if lvl, ok := level.FromKeyvals(keyvals); ok {
+		severity = severities[lvl]
+	}
+	payload := kv.Map(keyvals)
+	delete(payload, kv.Key(level.Key()))
+
+	e := entry{
+		Severity:    severity,
+		Timestamp:   time.Now().UTC().Format(time.RFC3339Nano),
+		JSONPayload: payload,
+	}
+	return s.batcher.Add(e)
+}
+
+// Dropped returns the amount of entries dropped because the queue was full.
+func (s *Sink) Dropped() uint64 {
+	return s.batcher.Dropped()
+}
+
+// Flush sends all entries enqueued so far and blocks until they're written.
+func (s *Sink) Flush() error {
+	s.batcher.Flush()
+	return nil
+}
+
+// Close stops accepting new entries, sends the remaining ones and stops the worker.
+func (s *Sink) Close() error {
+	s.batcher.Close()
+	return nil
+}
+
+// send writes the batch, retrying with backoff on transport errors,
+// throttling and server side failures.
+func (s *Sink) send(entries []entry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(writeRequest{
+		LogName:        fmt.Sprintf("projects/%s/logs/%s", s.projectID, s.logName),
+		Resource:       s.resource,
+		Labels:         s.labels,
+		Entries:        entries,
+		PartialSuccess: true,
+	})
+	if err != nil {
+		s.errorHandler(fmt.Errorf("dropping %d entries: %v", len(entries), err))
+		return
+	}
+
+	op := s.tracer.Start("log.sink.cloudlogging.flush")
+	op.Tag("entries", strconv.Itoa(len(entries)))
+	defer func() { op.End(err) }()
+
+	backoff := s.backoff
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			op.Retry(err)
+			time.Sleep(backoff)
+			if backoff *= 2; backoff > s.maxBackoff {
+				backoff = s.maxBackoff
+			}
+		}
+
+		var retry bool
+		retry, err = s.write(body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= s.maxRetries {
+			s.errorHandler(fmt.Errorf("dropping %d entries after %d attempts: %v", len(entries), attempt+1, err))
+			return
+		}
+	}
+}
+
+// write performs a single entries:write call and reports whether a failure may be retried.
+func (s *Sink) write(body []byte) (bool, error) {
+	resp, err := s.client.Post(s.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("entries:write failed with status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+}
@@ -0,0 +1,166 @@
+package cloudlogging
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-godin/log/level"
+)
+
+type response struct {
+	status int
+	body   string
+}
+
+func newSink(t *testing.T, url string, opts ...Option) *Sink {
+	t.Helper()
+	opts = append([]Option{
+		HTTPClient(http.DefaultClient),
+		MonitoredResource(Resource{Type: "global", Labels: map[string]string{"project_id": "acme"}}),
+		FlushInterval(0),
+	}, opts...)
+	s, err := New(context.Background(), "orders", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.endpoint = url
+	return s
+}
+
+func TestSinkWrite(t *testing.T) {
+	ok := response{http.StatusOK, "{}"}
+	tests := []struct {
+		name      string
+		responses []response
+		requests  int
+		errors    int
+	}{
+		{name: "written", responses: []response{ok}, requests: 1},
+		{name: "throttled retried", responses: []response{{http.StatusTooManyRequests, ""}, ok}, requests: 2},
+		{name: "server error retried", responses: []response{{http.StatusServiceUnavailable, ""}, ok}, requests: 2},
+		{name: "retries exhausted", responses: []response{{http.StatusServiceUnavailable, ""}}, requests: 3, errors: 1},
+		{name: "bad request dropped", responses: []response{{http.StatusBadRequest, `{"error":"invalid"}`}}, requests: 1, errors: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mtx      sync.Mutex
+				requests int
+				errors   int
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mtx.Lock()
+				resp := tt.responses[min(requests, len(tt.responses)-1)]
+				requests++
+				mtx.Unlock()
+				w.WriteHeader(resp.status)
+				_, _ = w.Write([]byte(resp.body))
+			}))
+			defer srv.Close()
+
+			s := newSink(t, srv.URL,
+				Retry(2, time.Millisecond, time.Millisecond),
+				ErrorHandler(func(error) { mtx.Lock(); errors++; mtx.Unlock() }),
+			)
+			if err := s.Log("message", "m"); err != nil {
+				t.Fatal(err)
+			}
+			_ = s.Close()
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			if requests != tt.requests || errors != tt.errors {
+				t.Errorf("requests, errors = %d, %d, want %d, %d", requests, errors, tt.requests, tt.errors)
+			}
+		})
+	}
+}
+
+func TestSinkEntries(t *testing.T) {
+	requests := make(chan writeRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req writeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding the request: %v", err)
+		}
+		requests <- req
+	}))
+	defer srv.Close()
+
+	s := newSink(t, srv.URL, Labels(map[string]string{"env": "prod"}))
+	_ = s.Log(level.Key(), level.WarnValue(), "message", "slow", "ms", 250)
+	_ = s.Log(level.Key(), level.DebugValue(), "message", "cache hit")
+	_ = s.Log("message", "no level")
+	_ = s.Close()
+
+	req := <-requests
+	if req.LogName != "projects/acme/logs/orders" || req.Labels["env"] != "prod" || !req.PartialSuccess {
+		t.Errorf("logName, labels, partialSuccess = %s, %v, %v", req.LogName, req.Labels, req.PartialSuccess)
+	}
+	if len(req.Entries) != 3 {
+		t.Fatalf("wrote %d entries, want 3", len(req.Entries))
+	}
+	tests := []struct {
+		severity string
+		payload  map[string]interface{}
+	}{
+		{"WARNING", map[string]interface{}{"message": "slow", "ms": float64(250)}},
+		{"DEBUG", map[string]interface{}{"message": "cache hit"}},
+		{"DEFAULT", map[string]interface{}{"message": "no level"}},
+	}
+	for i, tt := range tests {
+		e := req.Entries[i]
+		if e.Severity != tt.severity || !reflect.DeepEqual(e.JSONPayload, tt.payload) {
+			t.Errorf("entry %d = %s %v, want %s %v", i, e.Severity, e.JSONPayload, tt.severity, tt.payload)
+		}
+		if _, err := time.Parse(time.RFC3339Nano, e.Timestamp); err != nil {
+			t.Errorf("entry %d: %v", i, err)
+		}
+	}
+}
+
+func TestNewProjectID(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []Option
+		wantErr      bool
+		wantResource map[string]string
+	}{
+		{
+			name:         "detected",
+			opts:         []Option{MonitoredResource(Resource{Type: "global", Labels: map[string]string{"project_id": "acme"}})},
+			wantResource: map[string]string{"project_id": "acme"},
+		},
+		{
+			name:         "configured",
+			opts:         []Option{ProjectID("acme"), MonitoredResource(Resource{Type: "gce_instance", Labels: map[string]string{"zone": "europe-west1-b"}})},
+			wantResource: map[string]string{"project_id": "acme", "zone": "europe-west1-b"},
+		},
+		{
+			name:    "missing",
+			opts:    []Option{MonitoredResource(Resource{Type: "global"})},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(context.Background(), "orders", append([]Option{HTTPClient(http.DefaultClient)}, tt.opts...)...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer s.Close()
+			if !reflect.DeepEqual(s.resource.Labels, tt.wantResource) {
+				t.Errorf("resource labels = %v, want %v", s.resource.Labels, tt.wantResource)
+			}
+		})
+	}
+}
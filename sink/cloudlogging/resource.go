@@ -0,0 +1,78 @@
+package cloudlogging
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"cloud.google.com/go/compute/metadata"
+)
+
+const namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// Resource is the monitored resource entries are associated with.
+type Resource struct {
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels"`
+}
+
+// DetectResource detects whether the process runs on GKE or GCE using the
+// metadata server and returns the matching monitored resource. Outside of
+// Google Cloud the "global" resource is returned.
+func DetectResource(ctx context.Context) Resource {
+	if !metadata.OnGCE() {
+		return Resource{
+			Type:   "global",
+			Labels: map[string]string{"project_id": os.Getenv("GOOGLE_CLOUD_PROJECT")},
+		}
+	}
+
+	projectID, _ := metadata.ProjectIDWithContext(ctx)
+	zone, _ := metadata.ZoneWithContext(ctx)
+
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		cluster, _ := metadata.InstanceAttributeValueWithContext(ctx, "cluster-name")
+		location, err := metadata.InstanceAttributeValueWithContext(ctx, "cluster-location")
+		if err != nil || location == "" {
+			location = zone
+		}
+		pod, _ := os.Hostname()
+		return Resource{
+			Type: "k8s_container",
+			Labels: map[string]string{
+				"project_id":     projectID,
+				"location":       strings.TrimSpace(location),
+				"cluster_name":   strings.TrimSpace(cluster),
+				"namespace_name": namespace(),
+				"pod_name":       pod,
+				"container_name": os.Getenv("CONTAINER_NAME"),
+			},
+		}
+	}
+
+	instanceID, _ := metadata.InstanceIDWithContext(ctx)
+	return Resource{
+		Type: "gce_instance",
+		Labels: map[string]string{
+			"project_id":  projectID,
+			"instance_id": instanceID,
+			"zone":        zone,
+		},
+	}
+}
+
+// namespace returns the namespace of the pod, preferring the downward API
+// environment variable over the service account mount.
+func namespace() string {
+	if ns := os.Getenv("NAMESPACE"); ns != "" {
+		return ns
+	}
+	if ns := os.Getenv("POD_NAMESPACE"); ns != "" {
+		return ns
+	}
+	data, err := os.ReadFile(namespaceFile)
+	if err != nil {
+		return "default"
+	}
+	return strings.TrimSpace(string(data))
+}
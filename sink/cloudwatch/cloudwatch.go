@@ -0,0 +1,339 @@
+// Package cloudwatch provides a sink which ships log entries to AWS CloudWatch
+// Logs using the PutLogEvents API.
+package cloudwatch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/smithy-go"
+	"github.com/go-godin/log/internal/batch"
+	"github.com/go-godin/log/selftrace"
+	"github.com/go-kit/kit/log"
+)
+
+// Limits of the PutLogEvents API.
+const (
+	maxBatchEvents = 10000
+	maxBatchBytes  = 1048576
+	maxEventBytes  = 262144
+	eventOverhead  = 26
+	maxBatchSpan   = 24 * time.Hour
+)
+
+var (
+	// ErrQueueFull is returned by Log if the sink drops entries when its queue is full.
+	ErrQueueFull = errors.New("cloudwatch: queue is full, entry dropped")
+	// ErrClosed is returned by Log after the sink has been closed.
+	ErrClosed = errors.New("cloudwatch: sink is closed")
+)
+
+// Client is the subset of the CloudWatch Logs API used by the Sink.
+// It is implemented by *cloudwatchlogs.Client.
+type Client interface {
+	PutLogEvents(ctx context.Context, params *cloudwatchlogs.PutLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutLogEventsOutput, error)
+	CreateLogGroup(ctx context.Context, params *cloudwatchlogs.CreateLogGroupInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogGroupOutput, error)
+	CreateLogStream(ctx context.Context, params *cloudwatchlogs.CreateLogStreamInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogStreamOutput, error)
+}
+
+// Sink buffers log entries and ships them to a CloudWatch Logs stream. It
+// implements the go-kit log.Logger interface and can be passed to log.WithSink.
+type Sink struct {
+	client          Client
+	group           string
+	stream          string
+	createIfMissing bool
+	timeout         time.Duration
+	batching        batch.Config
+	maxRetries      int
+	backoff         time.Duration
+	maxBackoff      time.Duration
+	errorHandler    func(error)
+	tracer          *selftrace.Tracer
+
+	sequenceToken *string
+	batcher       *batch.Batcher[types.InputLogEvent]
+}
+
+// Option sets a parameter for the Sink.
+type Option func(*Sink)
+
+// LogStream sets the log stream entries are written to. Defaults to the
+// hostname followed by the start time of the process.
+func LogStream(name string) Option {
+	return func(s *Sink) { s.stream = name }
+}
+
+// CreateIfMissing creates the log group and stream if they don't exist yet.
+func CreateIfMissing() Option {
+	return func(s *Sink) { s.createIfMissing = true }
+}
+
+// Timeout sets the timeout of a single API call. Defaults to ten seconds.
+func Timeout(timeout time.Duration) Option {
+	return func(s *Sink) { s.timeout = timeout }
+}
+
+// FlushInterval sets the maximum time an entry is buffered before it's sent.
+// Zero sends batches only once they're full, on Flush and on Close. Defaults
+// to five seconds.
+func FlushInterval(interval time.Duration) Option {
+	return batching(batch.MaxLatency(interval))
+}
+
+// QueueSize sets the amount of entries buffered in memory while a batch is
+// being sent. Once the queue is full, Log blocks unless DropWhenFull is set.
+// Defaults to 10000.
+func QueueSize(size int) Option {
+	return batching(batch.QueueSize(size))
+}
+
+// DropWhenFull instructs Log to drop entries and return ErrQueueFull instead of
+// blocking the caller if the queue is full.
+func DropWhenFull() Option {
+	return batching(batch.DropWhenFull())
+}
+
+// batching applies options of the batcher to the Sink.
+func batching(opts ...batch.Option) Option {
+	return func(s *Sink) {
+		for _, opt := range opts {
+			opt(&s.batching)
+		}
+	}
+}
+
+// Retry configures how often throttled or failed batches are retried. The
+// delay between attempts starts at backoff and doubles up to maxBackoff.
+// Defaults to 5 retries, starting at 200ms up to 10s.
+func Retry(maxRetries int, backoff, maxBackoff time.Duration) Option {
+	return func(s *Sink) {
+		s.maxRetries = maxRetries
+		s.backoff = backoff
+		s.maxBackoff = maxBackoff
+	}
+}
+
+// ErrorHandler sets the function called with errors which occur while
+// shipping entries. By default errors are written to stderr.
+func ErrorHandler(handler func(error)) Option {
+	return func(s *Sink) { s.errorHandler = handler }
+}
+
+// Tracer reports slow flushes and retries of the sink as spans.
+func Tracer(tracer *selftrace.Tracer) Option {
+	return func(s *Sink) { s.tracer = tracer }
+}
+
+// New creates a Sink writing to the given log group and starts its
+// background worker. The client is usually created with
+// cloudwatchlogs.NewFromConfig, which picks up the credentials of Lambda
+// functions and ECS tasks automatically.
+func New(client Client, group string, opts ...Option) *Sink {
+	hostname, _ := os.Hostname()
+	s := &Sink{
+		client:     client,
+		group:      group,
+		stream:     fmt.Sprintf("%s/%d", hostname, time.Now().Unix()),
+		timeout:    10 * time.Second,
+		maxRetries: 5,
+		backoff:    200 * time.Millisecond,
+		maxBackoff: 10 * time.Second,
+		errorHandler: func(err error) {
+			fmt.Fprintf(os.Stderr, "cloudwatch sink: %v\n", err)
+		},
+		batching: batch.Config{
+			MaxEntries:   maxBatchEvents,
+			MaxBytes:     maxBatchBytes,
+			MaxLatency:   5 * time.Second,
+			QueueSize:    10000,
+			ErrQueueFull: ErrQueueFull,
+			ErrClosed:    ErrClosed,
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.batcher = batch.New(s.batching, func(e types.InputLogEvent) int { return len(*e.Message) + eventOverhead }, s.send)
+
+	return s
+}
+
+// Log encodes the entry as JSON and enqueues it for the next batch.
+func (s *Sink) Log(keyvals ...interface{}) error {
+	var buf bytes.Buffer
+	if err := log.NewJSONLogger(&buf).Log(keyvals...); err != nil {
+		return err
+	}
+	message := bytes.TrimRight(buf.Bytes(), "\n")
+	if len(message)+eventOverhead > maxEventBytes {
+		message = message[:maxEventBytes-eventOverhead]
+	}
+	event := types.InputLogEvent{
+		Message:   aws.String(string(message)),
+		Timestamp: aws.Int64(time.Now().UnixNano() / int64(time.Millisecond)),
+	}
+	return s.batcher.Add(event)
+}
+
+// Dropped returns the amount of entries dropped because the queue was full.
+func (s *Sink) Dropped() uint64 {
+	return s.batcher.Dropped()
+}
+
+// Flush sends all entries enqueued so far and blocks until they're written.
+func (s *Sink) Flush() error {
+	s.batcher.Flush()
+	return nil
+}
+
+// Close stops accepting new entries, sends the remaining ones and stops the worker.
+func (s *Sink) Close() error {
+	s.batcher.Close()
+	return nil
+}
+
+// send writes the batch in chronological order, splitting it if it spans
+// more than the 24 hours allowed for a single PutLogEvents call.
+func (s *Sink) send(events []types.InputLogEvent) {
+	if len(events) == 0 {
+		return
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return *events[i].Timestamp < *events[j].Timestamp
+	})
+
+	start := 0
+	for i := range events {
+		if time.Duration(*events[i].Timestamp-*events[start].Timestamp)*time.Millisecond >= maxBatchSpan {
+			s.put(events[start:i])
+			start = i
+		}
+	}
+	s.put(events[start:])
+}
+
+// put calls PutLogEvents, retrying with backoff while the API is throttling
+// or unavailable and correcting the sequence token if it was rejected.
+func (s *Sink) put(events []types.InputLogEvent) {
+	op := s.tracer.Start("log.sink.cloudwatch.flush")
+	op.Tag("entries", strconv.Itoa(len(events)))
+	var err error
+	defer func() { op.End(err) }()
+
+	backoff := s.backoff
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			op.Retry(err)
+		}
+		err = s.putOnce(events)
+		if err == nil {
+			return
+		}
+
+		var (
+			invalidToken *types.InvalidSequenceTokenException
+			accepted     *types.DataAlreadyAcceptedException
+			notFound     *types.ResourceNotFoundException
+		)
+		switch {
+		case errors.As(err, &accepted):
+			s.sequenceToken = accepted.ExpectedSequenceToken
+			err = nil
+			return
+		case errors.As(err, &invalidToken):
+			s.sequenceToken = invalidToken.ExpectedSequenceToken
+			if attempt < s.maxRetries {
+				continue
+			}
+		case errors.As(err, &notFound) && s.createIfMissing:
+			if cerr := s.create(); cerr != nil {
+				err = cerr
+			} else if attempt < s.maxRetries {
+				continue
+			}
+		case retryable(err) && attempt < s.maxRetries:
+			time.Sleep(backoff)
+			if backoff *= 2; backoff > s.maxBackoff {
+				backoff = s.maxBackoff
+			}
+			continue
+		}
+
+		s.errorHandler(fmt.Errorf("dropping %d entries after %d attempts: %v", len(events), attempt+1, err))
+		return
+	}
+}
+
+func (s *Sink) putOnce(events []types.InputLogEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	out, err := s.client.PutLogEvents(ctx, &cloudwatchlogs.PutLogEventsInput{
+		LogGroupName:  aws.String(s.group),
+		LogStreamName: aws.String(s.stream),
+		LogEvents:     events,
+		SequenceToken: s.sequenceToken,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.sequenceToken = out.NextSequenceToken
+	if info := out.RejectedLogEventsInfo; info != nil {
+		s.errorHandler(fmt.Errorf("entries rejected by CloudWatch (too old until index %d, too new from index %d, expired until index %d)",
+			aws.ToInt32(info.TooOldLogEventEndIndex), aws.ToInt32(info.TooNewLogEventStartIndex), aws.ToInt32(info.ExpiredLogEventEndIndex)))
+	}
+	return nil
+}
+
+// create creates the log group and stream, ignoring already existing ones.
+func (s *Sink) create() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	var exists *types.ResourceAlreadyExistsException
+	_, err := s.client.CreateLogGroup(ctx, &cloudwatchlogs.CreateLogGroupInput{
+		LogGroupName: aws.String(s.group),
+	})
+	if err != nil && !errors.As(err, &exists) {
+		return err
+	}
+	_, err = s.client.CreateLogStream(ctx, &cloudwatchlogs.CreateLogStreamInput{
+		LogGroupName:  aws.String(s.group),
+		LogStreamName: aws.String(s.stream),
+	})
+	if err != nil && !errors.As(err, &exists) {
+		return err
+	}
+	s.sequenceToken = nil
+	return nil
+}
+
+// retryable reports whether err is caused by throttling or a temporary
+// outage of the service.
+func retryable(err error) bool {
+	var unavailable *types.ServiceUnavailableException
+	if errors.As(err, &unavailable) {
+		return true
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "ThrottlingException", "Throttling", "TooManyRequestsException", "RequestLimitExceeded":
+			return true
+		}
+		return apiErr.ErrorFault() == smithy.FaultServer
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,211 @@
+package cloudwatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/smithy-go"
+)
+
+// client answers the PutLogEvents calls with errs in turn and records the
+// calls it received.
+type client struct {
+	mtx    sync.Mutex
+	errs   []error
+	calls  []string
+	tokens []string
+	events [][]types.InputLogEvent
+}
+
+func (c *client) PutLogEvents(_ context.Context, in *cloudwatchlogs.PutLogEventsInput, _ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutLogEventsOutput, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.calls = append(c.calls, "put")
+	c.tokens = append(c.tokens, aws.ToString(in.SequenceToken))
+	c.events = append(c.events, in.LogEvents)
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &cloudwatchlogs.PutLogEventsOutput{NextSequenceToken: aws.String(fmt.Sprintf("next-%d", len(c.calls)))}, nil
+}
+
+func (c *client) CreateLogGroup(context.Context, *cloudwatchlogs.CreateLogGroupInput, ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogGroupOutput, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.calls = append(c.calls, "create group")
+	return nil, &types.ResourceAlreadyExistsException{}
+}
+
+func (c *client) CreateLogStream(context.Context, *cloudwatchlogs.CreateLogStreamInput, ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogStreamOutput, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.calls = append(c.calls, "create stream")
+	return &cloudwatchlogs.CreateLogStreamOutput{}, nil
+}
+
+func TestSinkPut(t *testing.T) {
+	throttled := &smithy.GenericAPIError{Code: "ThrottlingException"}
+	tests := []struct {
+		name       string
+		opts       []Option
+		errs       []error
+		wantCalls  string
+		wantTokens string
+		wantErrors int
+	}{
+		{
+			name:       "accepted",
+			wantCalls:  "put",
+			wantTokens: "",
+		},
+		{
+			name:       "throttled retried",
+			errs:       []error{throttled},
+			wantCalls:  "put,put",
+			wantTokens: ",",
+		},
+		{
+			name:       "retries exhausted",
+			errs:       []error{throttled, throttled, throttled},
+			wantCalls:  "put,put,put",
+			wantTokens: ",,",
+			wantErrors: 1,
+		},
+		{
+			name:       "invalid sequence token corrected",
+			errs:       []error{&types.InvalidSequenceTokenException{ExpectedSequenceToken: aws.String("expected")}},
+			wantCalls:  "put,put",
+			wantTokens: ",expected",
+		},
+		{
+			name:       "data already accepted",
+			errs:       []error{&types.DataAlreadyAcceptedException{ExpectedSequenceToken: aws.String("expected")}},
+			wantCalls:  "put",
+			wantTokens: "",
+		},
+		{
+			name:       "missing stream created",
+			opts:       []Option{CreateIfMissing()},
+			errs:       []error{&types.ResourceNotFoundException{}},
+			wantCalls:  "put,create group,create stream,put",
+			wantTokens: ",",
+		},
+		{
+			name:       "missing stream",
+			errs:       []error{&types.ResourceNotFoundException{}},
+			wantCalls:  "put",
+			wantTokens: "",
+			wantErrors: 1,
+		},
+		{
+			name:       "invalid request dropped",
+			errs:       []error{&types.InvalidParameterException{}},
+			wantCalls:  "put",
+			wantTokens: "",
+			wantErrors: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &client{errs: tt.errs}
+			var errs []error
+			opts := append([]Option{
+				LogStream("web-1"),
+				FlushInterval(0),
+				Retry(2, time.Millisecond, time.Millisecond),
+				ErrorHandler(func(err error) { errs = append(errs, err) }),
+			}, tt.opts...)
+			s := New(c, "orders", opts...)
+			if err := s.Log("message", "m"); err != nil {
+				t.Fatal(err)
+			}
+			_ = s.Close()
+
+			if calls := strings.Join(c.calls, ","); calls != tt.wantCalls {
+				t.Errorf("calls = %s, want %s", calls, tt.wantCalls)
+			}
+			if tokens := strings.Join(c.tokens, ","); tokens != tt.wantTokens {
+				t.Errorf("sequence tokens = %q, want %q", tokens, tt.wantTokens)
+			}
+			if len(errs) != tt.wantErrors {
+				t.Errorf("reported errors %v, want %d", errs, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestSinkSend(t *testing.T) {
+	hour := int64(time.Hour / time.Millisecond)
+	event := func(ts int64) types.InputLogEvent {
+		return types.InputLogEvent{Message: aws.String(fmt.Sprint(ts)), Timestamp: aws.Int64(ts)}
+	}
+	c := &client{}
+	s := New(c, "orders", LogStream("web-1"))
+	defer s.Close()
+	s.send([]types.InputLogEvent{event(30 * hour), event(0), event(2 * hour), event(25 * hour)})
+
+	var batches []string
+	for _, events := range c.events {
+		var messages []string
+		for _, e := range events {
+			messages = append(messages, *e.Message)
+		}
+		batches = append(batches, strings.Join(messages, ","))
+	}
+	want := []string{fmt.Sprintf("0,%d", 2*hour), fmt.Sprintf("%d,%d", 25*hour, 30*hour)}
+	if strings.Join(batches, " ") != strings.Join(want, " ") {
+		t.Errorf("sent batches %q, want %q", batches, want)
+	}
+}
+
+func TestSinkLog(t *testing.T) {
+	c := &client{}
+	s := New(c, "orders", LogStream("web-1"))
+	_ = s.Log("message", strings.Repeat("a", maxEventBytes))
+	_ = s.Log("message", "m", "n", 1)
+	_ = s.Close()
+
+	if len(c.events) != 1 || len(c.events[0]) != 2 {
+		t.Fatalf("sent %v, want a batch of 2 events", c.events)
+	}
+	if n := len(*c.events[0][0].Message); n != maxEventBytes-eventOverhead {
+		t.Errorf("oversized message truncated to %d bytes, want %d", n, maxEventBytes-eventOverhead)
+	}
+	if msg := *c.events[0][1].Message; msg != `{"message":"m","n":1}` {
+		t.Errorf("message = %s", msg)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"unavailable", &types.ServiceUnavailableException{}, true},
+		{"throttled", &smithy.GenericAPIError{Code: "Throttling"}, true},
+		{"server fault", &smithy.GenericAPIError{Code: "InternalFailure", Fault: smithy.FaultServer}, true},
+		{"client fault", &smithy.GenericAPIError{Code: "AccessDenied", Fault: smithy.FaultClient}, false},
+		{"timeout", fmt.Errorf("put: %w", context.DeadlineExceeded), true},
+		{"other", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.err); got != tt.want {
+				t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
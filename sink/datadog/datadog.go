@@ -0,0 +1,333 @@
+// Package datadog provides a sink shipping entries to Datadog, either through
+// the TCP intake of the local agent or directly to the HTTP logs intake.
+package datadog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-godin/log/internal/batch"
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/level"
+	"github.com/go-godin/log/sink/network"
+)
+
+var (
+	// ErrQueueFull is returned by Log if the sink drops entries when its queue is full.
+	ErrQueueFull = errors.New("datadog: queue is full, entry dropped")
+	// ErrClosed is returned by Log after the sink has been closed.
+	ErrClosed = errors.New("datadog: sink is closed")
+)
+
+// statuses maps the names of godin's levels to Datadog statuses.
+var statuses = map[string]string{
+	"debug":   "debug",
+	"info":    "info",
+	"warning": "warn",
+	"error":   "error",
+	"fatal":   "critical",
+	"panic":   "emergency",
+}
+
+// Sink ships entries to Datadog. It implements the go-kit log.Logger
+// interface and can be passed to log.WithSink.
+type Sink struct {
+	source       string
+	service      string
+	host         string
+	tags         string
+	apiKey       string
+	client       *http.Client
+	batching     batch.Config
+	maxRetries   int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	errorHandler func(error)
+
+	// agent is set when shipping to the agent's TCP intake
+	agent *network.Writer
+
+	// intake is set when shipping to the HTTP intake
+	intake  string
+	batcher *batch.Batcher[[]byte]
+}
+
+// Option sets a parameter for the Sink.
+type Option func(*Sink)
+
+// Source sets the ddsource attribute. Defaults to "go".
+func Source(source string) Option {
+	return func(s *Sink) { s.source = source }
+}
+
+// Service sets the service attribute of entries without a "service" field,
+// as bound with With("service", ...).
+func Service(service string) Option {
+	return func(s *Sink) { s.service = service }
+}
+
+// Host sets the hostname attribute. Defaults to the hostname.
+func Host(host string) Option {
+	return func(s *Sink) { s.host = host }
+}
+
+// Tags sets the ddtags attribute, e.g. Tags("env:prod", "team:payments").
+func Tags(tags ...string) Option {
+	return func(s *Sink) { s.tags = strings.Join(tags, ",") }
+}
+
+// APIKey sets the API key authenticating requests to the HTTP intake.
+func APIKey(key string) Option {
+	return func(s *Sink) { s.apiKey = key }
+}
+
+// HTTPClient sets the client used for the HTTP intake. Defaults to http.DefaultClient.
+func HTTPClient(client *http.Client) Option {
+	return func(s *Sink) { s.client = client }
+}
+
+// BatchSize sets the maximum amount of entries and bytes sent to the HTTP
+// intake in a single request. Defaults to (and is capped at) 1000 entries
+// and 5MB, the limits of the intake.
+func BatchSize(entries, bytes int) Option {
+	return batching(batch.MaxEntries(entries), batch.MaxBytes(bytes))
+}
+
+// FlushInterval sets the maximum time an entry is buffered before it's sent
+// to the HTTP intake. Zero sends batches only once they're full, on Flush and
+// on Close. Defaults to one second.
+func FlushInterval(interval time.Duration) Option {
+	return batching(batch.MaxLatency(interval))
+}
+
+// QueueSize sets the amount of entries buffered in memory while a request
+// is in flight. Once the queue is full, Log blocks unless DropWhenFull is
+// set. Defaults to 10000.
+func QueueSize(size int) Option {
+	return batching(batch.QueueSize(size))
+}
+
+// DropWhenFull instructs Log to drop entries and return ErrQueueFull instead of
+// blocking the caller if the queue is full.
+func DropWhenFull() Option {
+	return batching(batch.DropWhenFull())
+}
+
+// batching applies options of the batcher to the Sink.
+func batching(opts ...batch.Option) Option {
+	return func(s *Sink) {
+		for _, opt := range opts {
+			opt(&s.batching)
+		}
+	}
+}
+
+// Retry configures how often failed requests are retried. The delay between
+// attempts starts at backoff and doubles up to maxBackoff.
+// Defaults to 3 retries, starting at 100ms up to 5s.
+func Retry(maxRetries int, backoff, maxBackoff time.Duration) Option {
+	return func(s *Sink) {
+		s.maxRetries = maxRetries
+		s.backoff = backoff
+		s.maxBackoff = maxBackoff
+	}
+}
+
+// ErrorHandler sets the function called with errors which occur while
+// shipping entries. By default errors are written to stderr.
+func ErrorHandler(handler func(error)) Option {
+	return func(s *Sink) { s.errorHandler = handler }
+}
+
+// New creates a Sink for address. For the agent's TCP intake, address is
+// formatted as "tcp://localhost:10518"; the agent needs logs collection
+// enabled for that port. For the HTTP intake it's the intake URL, e.g.
+// "https://http-intake.logs.datadoghq.eu", and APIKey is required.
+func New(address string, opts ...Option) (*Sink, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	s := &Sink{
+		source:     "go",
+		host:       hostname,
+		client:     http.DefaultClient,
+		maxRetries: 3,
+		backoff:    100 * time.Millisecond,
+		maxBackoff: 5 * time.Second,
+		errorHandler: func(err error) {
+			fmt.Fprintf(os.Stderr, "datadog sink: %v\n", err)
+		},
+		batching: batch.Config{
+			MaxEntries:   1000,
+			MaxBytes:     5 << 20,
+			MaxLatency:   time.Second,
+			QueueSize:    10000,
+			ErrQueueFull: ErrQueueFull,
+			ErrClosed:    ErrClosed,
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.batching.MaxEntries > 1000 {
+		s.batching.MaxEntries = 1000
+	}
+	if s.batching.MaxBytes > 5<<20 {
+		s.batching.MaxBytes = 5 << 20
+	}
+
+	switch u.Scheme {
+	case "tcp":
+		s.agent, err = network.New(address)
+		if err != nil {
+			return nil, err
+		}
+	case "http", "https":
+		if s.apiKey == "" {
+			return nil, errors.New("datadog: the HTTP intake requires an API key")
+		}
+		s.intake = strings.TrimRight(address, "/") + "/api/v2/logs"
+		s.batcher = batch.New(s.batching, func(body []byte) int { return len(body) }, s.send)
+	default:
+		return nil, fmt.Errorf("datadog: unsupported scheme %q, use tcp, http or https", u.Scheme)
+	}
+	return s, nil
+}
+
+// Log maps the entry to Datadog's attributes and ships it.
+func (s *Sink) Log(keyvals ...interface{}) error {
+	body, err := json.Marshal(s.record(keyvals))
+	if err != nil {
+		return err
+	}
+
+	if s.agent != nil {
+		_, err = s.agent.Write(append(body, '\n'))
+		return err
+	}
+	return s.batcher.Add(body)
+}
+
+// Dropped returns the amount of entries dropped because the queue or the
+// agent connection buffer was full.
+func (s *Sink) Dropped() uint64 {
+	if s.agent != nil {
+		return s.agent.Dropped()
+	}
+	return s.batcher.Dropped()
+}
+
+// Flush sends all entries enqueued for the HTTP intake so far and blocks
+// until the request is done.
+func (s *Sink) Flush() error {
+	if s.agent != nil {
+		return nil
+	}
+	s.batcher.Flush()
+	return nil
+}
+
+// Close stops accepting new entries, sends the remaining ones and closes the
+// agent connection or stops the worker.
+func (s *Sink) Close() error {
+	if s.agent != nil {
+		return s.agent.Close()
+	}
+	s.batcher.Close()
+	return nil
+}
+
+// record converts keyvals into a Datadog log record. The level becomes the
+// status, all other fields are kept as attributes.
+func (s *Sink) record(keyvals []interface{}) map[string]interface{} {
+	record := kv.Map(keyvals)
+	if lvl, ok := level.FromKeyvals(keyvals); ok {
+		delete(record, kv.Key(level.Key()))
+		if status, ok := statuses[lvl.String()]; ok {
+			record["status"] = status
+		}
+	}
+	if _, ok := record["service"]; !ok && s.service != "" {
+		record["service"] = s.service
+	}
+	record["ddsource"] = s.source
+	if s.host != "" {
+		record["hostname"] = s.host
+	}
+	if s.tags != "" {
+		record["ddtags"] = s.tags
+	}
+	return record
+}
+
+// send posts the batch to the intake, retrying transport errors, throttling
+// and server side failures.
+func (s *Sink) send(bodies [][]byte) {
+	if len(bodies) == 0 {
+		return
+	}
+
+	var body bytes.Buffer
+	body.WriteByte('[')
+	for i, b := range bodies {
+		if i > 0 {
+			body.WriteByte(',')
+		}
+		body.Write(b)
+	}
+	body.WriteByte(']')
+
+	backoff := s.backoff
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			if backoff *= 2; backoff > s.maxBackoff {
+				backoff = s.maxBackoff
+			}
+		}
+
+		retry, err := s.post(body.Bytes())
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= s.maxRetries {
+			s.errorHandler(fmt.Errorf("dropping %d entries after %d attempts: %v", len(bodies), attempt+1, err))
+			return
+		}
+	}
+}
+
+// post sends a single request. It reports whether a failure is worth retrying.
+func (s *Sink) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, s.intake, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("DD-API-KEY", s.apiKey)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("intake request failed with status %d: %s", resp.StatusCode, msg)
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
@@ -0,0 +1,190 @@
+package datadog
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-godin/log/level"
+)
+
+type response struct {
+	status int
+	body   string
+}
+
+func TestSinkIntake(t *testing.T) {
+	ok := response{http.StatusAccepted, "{}"}
+	tests := []struct {
+		name      string
+		responses []response
+		requests  int
+		errors    int
+	}{
+		{name: "accepted", responses: []response{ok}, requests: 1},
+		{name: "throttled retried", responses: []response{{http.StatusTooManyRequests, ""}, ok}, requests: 2},
+		{name: "server error retried", responses: []response{{http.StatusBadGateway, ""}, ok}, requests: 2},
+		{name: "retries exhausted", responses: []response{{http.StatusBadGateway, ""}}, requests: 3, errors: 1},
+		{name: "forbidden dropped", responses: []response{{http.StatusForbidden, `{"errors":["invalid API key"]}`}}, requests: 1, errors: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mtx      sync.Mutex
+				requests int
+				errors   int
+				entries  int
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var records []map[string]interface{}
+				if err := json.NewDecoder(r.Body).Decode(&records); err != nil {
+					t.Errorf("decoding the request: %v", err)
+				}
+				mtx.Lock()
+				resp := tt.responses[min(requests, len(tt.responses)-1)]
+				requests++
+				entries = len(records)
+				mtx.Unlock()
+				if r.URL.Path != "/api/v2/logs" || r.Header.Get("DD-API-KEY") != "secret" {
+					t.Errorf("request to %s with API key %q", r.URL.Path, r.Header.Get("DD-API-KEY"))
+				}
+				w.WriteHeader(resp.status)
+				_, _ = w.Write([]byte(resp.body))
+			}))
+			defer srv.Close()
+
+			s, err := New(srv.URL+"/",
+				APIKey("secret"),
+				FlushInterval(0),
+				Retry(2, time.Millisecond, time.Millisecond),
+				ErrorHandler(func(error) { mtx.Lock(); errors++; mtx.Unlock() }),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = s.Log("message", "a")
+			_ = s.Log("message", "b")
+			_ = s.Close()
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			if requests != tt.requests || errors != tt.errors {
+				t.Errorf("requests, errors = %d, %d, want %d, %d", requests, errors, tt.requests, tt.errors)
+			}
+			if entries != 2 {
+				t.Errorf("sent %d entries per request, want 2", entries)
+			}
+		})
+	}
+}
+
+func TestSinkAgent(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if scanner := bufio.NewScanner(conn); scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	s, err := New("tcp://"+ln.Addr().String(), Host("web-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Log("message", "m"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case line := <-lines:
+		if line != `{"ddsource":"go","hostname":"web-1","message":"m"}` {
+			t.Errorf("agent received %s", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the agent received nothing")
+	}
+}
+
+func TestSinkRecord(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		keyvals []interface{}
+		want    map[string]interface{}
+	}{
+		{
+			name:    "defaults",
+			keyvals: []interface{}{"message", "m"},
+			want:    map[string]interface{}{"message": "m", "ddsource": "go"},
+		},
+		{
+			name:    "status",
+			keyvals: []interface{}{level.Key(), level.WarnValue(), "message", "m"},
+			want:    map[string]interface{}{"message": "m", "ddsource": "go", "status": "warn"},
+		},
+		{
+			name:    "attributes",
+			opts:    []Option{Source("billing"), Service("orders"), Host("web-1"), Tags("env:prod", "team:payments")},
+			keyvals: []interface{}{"message", "m"},
+			want:    map[string]interface{}{"message": "m", "ddsource": "billing", "service": "orders", "hostname": "web-1", "ddtags": "env:prod,team:payments"},
+		},
+		{
+			name:    "service field",
+			opts:    []Option{Service("orders")},
+			keyvals: []interface{}{"message", "m", "service", "billing"},
+			want:    map[string]interface{}{"message": "m", "ddsource": "go", "service": "billing"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Sink{source: "go"}
+			for _, opt := range tt.opts {
+				opt(s)
+			}
+			if got := s.record(tt.keyvals); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("record() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		opts    []Option
+		wantErr bool
+	}{
+		{name: "agent", address: "tcp://localhost:10518"},
+		{name: "intake", address: "https://http-intake.logs.datadoghq.eu", opts: []Option{APIKey("secret")}},
+		{name: "intake without API key", address: "https://http-intake.logs.datadoghq.eu", wantErr: true},
+		{name: "unsupported scheme", address: "udp://localhost:10518", wantErr: true},
+		{name: "invalid address", address: "tcp://[::1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(tt.address, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil {
+				_ = s.Close()
+			}
+		})
+	}
+}
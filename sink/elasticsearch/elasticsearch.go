@@ -0,0 +1,339 @@
+// Package elasticsearch provides a sink which ships log entries to
+// Elasticsearch using the _bulk API.
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-godin/log/internal/batch"
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/retention"
+	"github.com/go-godin/log/selftrace"
+)
+
+// TimestampKey is the field Elasticsearch and Kibana take the time of a
+// document from.
+const TimestampKey = "@timestamp"
+
+var (
+	// ErrQueueFull is returned by Log if the sink drops entries when its queue is full.
+	ErrQueueFull = errors.New("elasticsearch: queue is full, entry dropped")
+	// ErrClosed is returned by Log after the sink has been closed.
+	ErrClosed = errors.New("elasticsearch: sink is closed")
+)
+
+// Sink buffers log entries and ships them to Elasticsearch in batches.
+// It implements the go-kit log.Logger interface and can be passed to log.WithSink.
+type Sink struct {
+	url              string
+	client           *http.Client
+	username         string
+	password         string
+	indexTemplate    string
+	index            indexTemplate
+	defaultRetention string
+	batching         batch.Config
+	maxRetries       int
+	backoff          time.Duration
+	maxBackoff       time.Duration
+	errorHandler     func(error)
+	tracer           *selftrace.Tracer
+
+	batcher *batch.Batcher[document]
+}
+
+type document struct {
+	index string
+	body  []byte
+}
+
+// Option sets a parameter for the Sink.
+type Option func(*Sink)
+
+// Index sets the index name template. Placeholders in curly braces are either
+// date patterns ({yyyy.MM.dd}) formatted with the UTC time of the entry, or
+// field names ({service}) looked up in the entry. Field values are lowercased
+// and stripped of the characters Elasticsearch rejects in index names.
+// Defaults to "logs-{yyyy.MM.dd}".
+//
+// Use the {retention} placeholder to route entries into indices managed by
+// different lifecycle policies, e.g. "logs-{retention}-{yyyy.MM.dd}".
+func Index(template string) Option {
+	return func(s *Sink) { s.indexTemplate = template }
+}
+
+// DefaultRetention sets the retention policy of entries which don't carry
+// a retention field.
+func DefaultRetention(policy string) Option {
+	return func(s *Sink) { s.defaultRetention = policy }
+}
+
+// HTTPClient sets the client used to talk to Elasticsearch.
+func HTTPClient(client *http.Client) Option {
+	return func(s *Sink) { s.client = client }
+}
+
+// BasicAuth sets the credentials sent with every bulk request.
+func BasicAuth(username, password string) Option {
+	return func(s *Sink) {
+		s.username = username
+		s.password = password
+	}
+}
+
+// BatchSize sets the maximum amount of entries and bytes sent with a single
+// bulk request. Defaults to 500 entries and 5MB.
+func BatchSize(entries, bytes int) Option {
+	return batching(batch.MaxEntries(entries), batch.MaxBytes(bytes))
+}
+
+// FlushInterval sets the maximum time an entry is buffered before it's sent.
+// Zero sends batches only once they're full, on Flush and on Close. Defaults
+// to one second.
+func FlushInterval(interval time.Duration) Option {
+	return batching(batch.MaxLatency(interval))
+}
+
+// QueueSize sets the amount of entries buffered in memory while a batch is
+// being sent. Once the queue is full, Log blocks unless DropWhenFull is set.
+// Defaults to 10000.
+func QueueSize(size int) Option {
+	return batching(batch.QueueSize(size))
+}
+
+// DropWhenFull instructs Log to drop entries and return ErrQueueFull instead of
+// blocking the caller if the queue is full.
+func DropWhenFull() Option {
+	return batching(batch.DropWhenFull())
+}
+
+// batching applies options of the batcher to the Sink.
+func batching(opts ...batch.Option) Option {
+	return func(s *Sink) {
+		for _, opt := range opts {
+			opt(&s.batching)
+		}
+	}
+}
+
+// Retry configures how often failed bulk requests are retried. The delay
+// between attempts starts at backoff and doubles up to maxBackoff.
+// Defaults to 3 retries, starting at 100ms up to 5s.
+func Retry(maxRetries int, backoff, maxBackoff time.Duration) Option {
+	return func(s *Sink) {
+		s.maxRetries = maxRetries
+		s.backoff = backoff
+		s.maxBackoff = maxBackoff
+	}
+}
+
+// ErrorHandler sets the function called with errors which occur while
+// shipping entries. By default errors are written to stderr.
+func ErrorHandler(handler func(error)) Option {
+	return func(s *Sink) { s.errorHandler = handler }
+}
+
+// Tracer reports slow flushes and retries of the sink as spans.
+func Tracer(tracer *selftrace.Tracer) Option {
+	return func(s *Sink) { s.tracer = tracer }
+}
+
+// New creates a Sink shipping entries to the Elasticsearch cluster at url
+// (e.g. "http://localhost:9200") and starts its background worker.
+// An error is returned if the index template is invalid.
+func New(url string, opts ...Option) (*Sink, error) {
+	s := &Sink{
+		url:           strings.TrimRight(url, "/"),
+		client:        http.DefaultClient,
+		maxRetries:    3,
+		backoff:       100 * time.Millisecond,
+		maxBackoff:    5 * time.Second,
+		indexTemplate: "logs-{yyyy.MM.dd}",
+		errorHandler: func(err error) {
+			fmt.Fprintf(os.Stderr, "elasticsearch sink: %v\n", err)
+		},
+		batching: batch.Config{
+			MaxEntries:   500,
+			MaxBytes:     5 << 20,
+			MaxLatency:   time.Second,
+			QueueSize:    10000,
+			ErrQueueFull: ErrQueueFull,
+			ErrClosed:    ErrClosed,
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	index, err := parseIndexTemplate(s.indexTemplate)
+	if err != nil {
+		return nil, err
+	}
+	s.index = index
+
+	s.batcher = batch.New(s.batching, func(doc document) int { return len(doc.body) }, s.send)
+
+	return s, nil
+}
+
+// Log encodes the entry and enqueues it for the next bulk request. Entries
+// without a TimestampKey field get the current time, so time-based index
+// patterns and lifecycle policies see every document.
+func (s *Sink) Log(keyvals ...interface{}) error {
+	now := time.Now()
+	fields := kv.Map(keyvals)
+	if _, ok := fields[TimestampKey]; !ok {
+		fields[TimestampKey] = now.UTC().Format(time.RFC3339Nano)
+	}
+	if _, ok := fields[retention.Key]; !ok && s.defaultRetention != "" {
+		fields[retention.Key] = s.defaultRetention
+	}
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	doc := document{
+		index: s.index.resolve(fields, now),
+		body:  body,
+	}
+
+	return s.batcher.Add(doc)
+}
+
+// Dropped returns the amount of entries dropped because the queue was full.
+func (s *Sink) Dropped() uint64 {
+	return s.batcher.Dropped()
+}
+
+// Flush sends all entries enqueued so far and blocks until the request is done.
+func (s *Sink) Flush() error {
+	s.batcher.Flush()
+	return nil
+}
+
+// Close stops accepting new entries, sends the remaining ones and stops the worker.
+func (s *Sink) Close() error {
+	s.batcher.Close()
+	return nil
+}
+
+// send ships the batch, retrying the whole request on transport errors and
+// server side failures, and single documents which were rejected because
+// the cluster is overloaded.
+func (s *Sink) send(docs []document) {
+	if len(docs) == 0 {
+		return
+	}
+
+	op := s.tracer.Start("log.sink.elasticsearch.flush")
+	op.Tag("entries", strconv.Itoa(len(docs)))
+	var err error
+	defer func() { op.End(err) }()
+
+	backoff := s.backoff
+	for attempt := 0; len(docs) > 0; attempt++ {
+		if attempt > 0 {
+			op.Retry(err)
+			time.Sleep(backoff)
+			if backoff *= 2; backoff > s.maxBackoff {
+				backoff = s.maxBackoff
+			}
+		}
+
+		var retry []document
+		retry, err = s.bulk(docs)
+		if err != nil && retry == nil {
+			retry = docs // the request couldn't be built, retry or report all of them
+		}
+		if err != nil && attempt >= s.maxRetries {
+			s.errorHandler(fmt.Errorf("dropping %d entries after %d attempts: %v", len(retry), attempt+1, err))
+			return
+		}
+		docs = retry
+	}
+}
+
+// bulk sends a single bulk request. It returns the documents which should be
+// retried together with the reason.
+func (s *Sink) bulk(docs []document) ([]document, error) {
+	var body bytes.Buffer
+	for _, doc := range docs {
+		meta, _ := json.Marshal(map[string]interface{}{
+			"index": map[string]string{"_index": doc.index},
+		})
+		body.Write(meta)
+		body.WriteByte('\n')
+		body.Write(doc.body)
+		body.WriteByte('\n')
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url+"/_bulk", &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if s.username != "" || s.password != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return docs, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return docs, fmt.Errorf("bulk request failed with status %d", resp.StatusCode)
+	}
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		s.errorHandler(fmt.Errorf("dropping %d entries, bulk request failed with status %d: %s", len(docs), resp.StatusCode, msg))
+		return nil, nil
+	}
+
+	var result bulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, nil // the request itself succeeded, the response can't be inspected
+	}
+	if !result.Errors {
+		return nil, nil
+	}
+
+	var retry []document
+	for i, item := range result.Items {
+		if i >= len(docs) {
+			break
+		}
+		status := item.Index.Status
+		switch {
+		case status == http.StatusTooManyRequests:
+			retry = append(retry, docs[i])
+		case status >= 300:
+			s.errorHandler(fmt.Errorf("entry rejected by index %q with status %d: %s", docs[i].index, status, item.Index.Error))
+		}
+	}
+	if len(retry) > 0 {
+		return retry, fmt.Errorf("%d entries rejected with status %d", len(retry), http.StatusTooManyRequests)
+	}
+	return nil, nil
+}
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []struct {
+		Index struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"index"`
+	} `json:"items"`
+}
@@ -0,0 +1,220 @@
+package elasticsearch
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type response struct {
+	status int
+	body   string
+}
+
+func TestSinkBulk(t *testing.T) {
+	ok := response{http.StatusOK, `{"errors":false,"items":[{"index":{"status":201}}]}`}
+	tests := []struct {
+		name      string
+		responses []response
+		requests  int
+		errors    int
+	}{
+		{name: "accepted", responses: []response{ok}, requests: 1},
+		{name: "overloaded cluster retried", responses: []response{{http.StatusTooManyRequests, ""}, ok}, requests: 2},
+		{name: "server error retried", responses: []response{{http.StatusBadGateway, ""}, ok}, requests: 2},
+		{name: "retries exhausted", responses: []response{{http.StatusBadGateway, ""}, {http.StatusBadGateway, ""}, {http.StatusBadGateway, ""}}, requests: 3, errors: 1},
+		{name: "bad request dropped", responses: []response{{http.StatusBadRequest, `{"error":"invalid"}`}}, requests: 1, errors: 1},
+		{name: "rejected document retried", responses: []response{{http.StatusOK, `{"errors":true,"items":[{"index":{"status":429}}]}`}, ok}, requests: 2},
+		{name: "rejected document dropped", responses: []response{{http.StatusOK, `{"errors":true,"items":[{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}}]}`}}, requests: 1, errors: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mtx      sync.Mutex
+				requests int
+				errors   int
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mtx.Lock()
+				resp := tt.responses[min(requests, len(tt.responses)-1)]
+				requests++
+				mtx.Unlock()
+				if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+					t.Errorf("request to %s with content type %q", r.URL.Path, r.Header.Get("Content-Type"))
+				}
+				w.WriteHeader(resp.status)
+				_, _ = w.Write([]byte(resp.body))
+			}))
+			defer srv.Close()
+
+			s, err := New(srv.URL,
+				FlushInterval(0),
+				Retry(2, time.Millisecond, time.Millisecond),
+				ErrorHandler(func(error) { mtx.Lock(); errors++; mtx.Unlock() }),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Log("message", "m"); err != nil {
+				t.Fatal(err)
+			}
+			_ = s.Close()
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			if requests != tt.requests || errors != tt.errors {
+				t.Errorf("requests, errors = %d, %d, want %d, %d", requests, errors, tt.requests, tt.errors)
+			}
+		})
+	}
+}
+
+func TestSinkInvalidURL(t *testing.T) {
+	var errors int
+	s, err := New("http://[::1",
+		FlushInterval(0),
+		Retry(1, time.Millisecond, time.Millisecond),
+		ErrorHandler(func(error) { errors++ }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Log("message", "m"); err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Close()
+	if errors != 1 {
+		t.Errorf("errors = %d, want 1", errors)
+	}
+}
+
+func TestSinkIndex(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		keyvals   []interface{}
+		wantIndex string
+	}{
+		{name: "field", opts: []Option{Index("logs-{service}")}, keyvals: []interface{}{"service", "orders"}, wantIndex: "logs-orders"},
+		{name: "date", opts: []Option{Index("logs-{yyyy}")}, wantIndex: "logs-" + time.Now().UTC().Format("2006")},
+		{name: "date separators", opts: []Option{Index("logs-{yyyy.MM}")}, wantIndex: "logs-" + time.Now().UTC().Format("2006.01")},
+		{name: "field containing token", opts: []Option{Index("logs-{address}")}, keyvals: []interface{}{"address", "Main"}, wantIndex: "logs-main"},
+		{name: "field containing tokens", opts: []Option{Index("logs-{ddsource}-{hidden}")}, keyvals: []interface{}{"ddsource", "go", "hidden", "no"}, wantIndex: "logs-go-no"},
+		{name: "invalid characters stripped", opts: []Option{Index("logs-{service}")}, keyvals: []interface{}{"service", `Order Service/"EU", #1`}, wantIndex: "logs-orderserviceeu1"},
+		{name: "leading characters trimmed", opts: []Option{Index("{service}-logs")}, keyvals: []interface{}{"service", "_+internal"}, wantIndex: "internal-logs"},
+		{name: "nothing valid left", opts: []Option{Index("logs-{service}")}, keyvals: []interface{}{"service", "*?"}, wantIndex: "logs-unknown"},
+		{name: "retention", opts: []Option{Index("logs-{retention}"), DefaultRetention("short")}, wantIndex: "logs-short"},
+		{name: "retention field", opts: []Option{Index("logs-{retention}"), DefaultRetention("short")}, keyvals: []interface{}{"retention", "audit"}, wantIndex: "logs-audit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indices := make(chan string, 1)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var meta struct {
+					Index struct {
+						Index string `json:"_index"`
+					} `json:"index"`
+				}
+				scanner := bufio.NewScanner(r.Body)
+				if scanner.Scan() {
+					_ = json.Unmarshal(scanner.Bytes(), &meta)
+				}
+				indices <- meta.Index.Index
+				_, _ = w.Write([]byte(`{"errors":false}`))
+			}))
+			defer srv.Close()
+
+			s, err := New(srv.URL, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = s.Log(append(tt.keyvals, "message", "m")...)
+			_ = s.Close()
+			if index := <-indices; index != tt.wantIndex {
+				t.Errorf("index = %q, want %q", index, tt.wantIndex)
+			}
+		})
+	}
+}
+
+func TestSinkTimestamp(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyvals []interface{}
+		want    string // empty for the current time
+	}{
+		{name: "added", keyvals: []interface{}{"message", "m"}},
+		{name: "kept", keyvals: []interface{}{TimestampKey, "2024-05-01T12:00:00Z", "message", "m"}, want: "2024-05-01T12:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs := make(chan map[string]interface{}, 1)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var doc map[string]interface{}
+				scanner := bufio.NewScanner(r.Body)
+				if scanner.Scan() && scanner.Scan() {
+					_ = json.Unmarshal(scanner.Bytes(), &doc)
+				}
+				docs <- doc
+				_, _ = w.Write([]byte(`{"errors":false}`))
+			}))
+			defer srv.Close()
+
+			s, err := New(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			before := time.Now()
+			_ = s.Log(tt.keyvals...)
+			_ = s.Close()
+
+			got, _ := (<-docs)[TimestampKey].(string)
+			if tt.want != "" {
+				if got != tt.want {
+					t.Errorf("%s = %q, want %q", TimestampKey, got, tt.want)
+				}
+				return
+			}
+			ts, err := time.Parse(time.RFC3339Nano, got)
+			if err != nil || ts.Before(before) || ts.After(time.Now()) || !strings.HasSuffix(got, "Z") {
+				t.Errorf("%s = %q, want the current UTC time", TimestampKey, got)
+			}
+		})
+	}
+}
+
+func TestParseIndexTemplate(t *testing.T) {
+	for _, tmpl := range []string{"logs-{", "logs-{}"} {
+		if _, err := parseIndexTemplate(tmpl); err == nil {
+			t.Errorf("parseIndexTemplate(%q) succeeded", tmpl)
+		}
+	}
+}
+
+func TestDateLayout(t *testing.T) {
+	tests := []struct {
+		pattern string
+		layout  string
+		ok      bool
+	}{
+		{"yyyy.MM.dd", "2006.01.02", true},
+		{"yy-MM/dd_HH", "06-01/02_15", true},
+		{"address", "", false},
+		{"hidden", "", false},
+		{"ddsource", "", false},
+		{"yyyy1", "", false},
+		{"Jan-dd", "", false},
+		{".-", "", false},
+	}
+	for _, tt := range tests {
+		layout, ok := dateLayout(tt.pattern)
+		if layout != tt.layout || ok != tt.ok {
+			t.Errorf("dateLayout(%q) = %q, %v, want %q, %v", tt.pattern, layout, ok, tt.layout, tt.ok)
+		}
+	}
+}
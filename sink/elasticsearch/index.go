@@ -0,0 +1,140 @@
+package elasticsearch
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-godin/log/internal/kv"
+)
+
+// dateTokens maps the Joda-style date tokens commonly used in Elasticsearch
+// index names to Go time layouts. Longer tokens come first.
+var dateTokens = []struct {
+	token  string
+	layout string
+}{
+	{"yyyy", "2006"},
+	{"yy", "06"},
+	{"MM", "01"},
+	{"dd", "02"},
+	{"HH", "15"},
+}
+
+// indexTemplate resolves index names like "logs-{service}-{yyyy.MM.dd}".
+// Placeholders made of date tokens and separators are formatted using the
+// entry time, all others are looked up in the entry fields.
+type indexTemplate struct {
+	segments []segment
+}
+
+type segment struct {
+	literal string
+	field   string
+	layout  string
+}
+
+func parseIndexTemplate(tmpl string) (indexTemplate, error) {
+	var t indexTemplate
+	for len(tmpl) > 0 {
+		start := strings.IndexByte(tmpl, '{')
+		if start < 0 {
+			t.segments = append(t.segments, segment{literal: tmpl})
+			break
+		}
+		end := strings.IndexByte(tmpl[start:], '}')
+		if end < 0 {
+			return indexTemplate{}, fmt.Errorf("unterminated placeholder in index template %q", tmpl)
+		}
+		end += start
+
+		if start > 0 {
+			t.segments = append(t.segments, segment{literal: tmpl[:start]})
+		}
+		name := tmpl[start+1 : end]
+		if name == "" {
+			return indexTemplate{}, fmt.Errorf("empty placeholder in index template")
+		}
+		if layout, ok := dateLayout(name); ok {
+			t.segments = append(t.segments, segment{layout: layout})
+		} else {
+			t.segments = append(t.segments, segment{field: name})
+		}
+		tmpl = tmpl[end+1:]
+	}
+	return t, nil
+}
+
+// dateLayout converts a date pattern to a Go layout. It reports false unless
+// the pattern consists only of date tokens and the separators ".-_/", so
+// field names such as "address" are never taken for dates.
+func dateLayout(pattern string) (string, bool) {
+	var b strings.Builder
+	found := false
+	for len(pattern) > 0 {
+		if strings.IndexByte(".-_/", pattern[0]) >= 0 {
+			b.WriteByte(pattern[0])
+			pattern = pattern[1:]
+			continue
+		}
+		matched := false
+		for _, dt := range dateTokens {
+			if strings.HasPrefix(pattern, dt.token) {
+				b.WriteString(dt.layout)
+				pattern = pattern[len(dt.token):]
+				matched, found = true, true
+				break
+			}
+		}
+		if !matched {
+			return "", false
+		}
+	}
+	if !found {
+		return "", false
+	}
+	return b.String(), true
+}
+
+// invalidIndexChars are the characters Elasticsearch rejects in index names.
+const invalidIndexChars = `\/*?"<>|,#: `
+
+// resolve returns the index name for an entry. Missing fields, and fields
+// without characters valid in index names, resolve to "unknown" so entries
+// are never rejected because of the template.
+func (t indexTemplate) resolve(fields map[string]interface{}, ts time.Time) string {
+	var b strings.Builder
+	for _, s := range t.segments {
+		switch {
+		case s.layout != "":
+			b.WriteString(ts.UTC().Format(s.layout))
+		case s.field != "":
+			v, ok := fields[s.field]
+			if !ok || v == nil {
+				b.WriteString("unknown")
+				continue
+			}
+			b.WriteString(indexValue(kv.String(v)))
+		default:
+			b.WriteString(s.literal)
+		}
+	}
+	// index names must not start with these
+	return strings.TrimLeft(b.String(), "-_+")
+}
+
+// indexValue makes a field value usable in an index name: it's lowercased
+// and stripped of the characters Elasticsearch rejects.
+func indexValue(v string) string {
+	v = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(invalidIndexChars, r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, v)
+	if strings.Trim(v, "-_+.") == "" {
+		return "unknown"
+	}
+	return v
+}
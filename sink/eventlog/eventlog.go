@@ -0,0 +1,167 @@
+// Package eventlog provides a sink writing entries to the Windows Event Log.
+// On other platforms New returns ErrUnsupported.
+package eventlog
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/level"
+)
+
+// EventIDKey is the key of the field overriding the event ID of a single entry.
+const EventIDKey = "event_id"
+
+// ErrUnsupported is returned by New on platforms without an Event Log.
+var ErrUnsupported = errors.New("eventlog: the Windows Event Log is not available on this platform")
+
+// writer is implemented by the Event Log handle of golang.org/x/sys.
+type writer interface {
+	Info(eid uint32, msg string) error
+	Warning(eid uint32, msg string) error
+	Error(eid uint32, msg string) error
+	Close() error
+}
+
+// Sink reports entries as events of a source. Debug and Info entries become
+// Information events, Warning entries Warning events and Error (and more
+// severe) entries Error events. Levels added with level.Register are
+// reported like the built-in level below them, entries without a level as
+// Information. It implements the go-kit log.Logger interface.
+type Sink struct {
+	source   string
+	install  bool
+	eventIDs map[string]uint32
+	writer   writer
+}
+
+// Option sets a parameter for the Sink.
+type Option func(*Sink)
+
+// EventID sets the event ID reported for entries of the named level. Single
+// entries can override it with an EventIDKey field. Defaults to 1 for debug
+// and info, 2 for warning and 3 for error, fatal and panic. Levels added
+// with level.Register default to the ID of the built-in level below them.
+func EventID(levelName string, id uint32) Option {
+	return func(s *Sink) { s.eventIDs[levelName] = id }
+}
+
+// Install registers the source in the registry if it doesn't exist yet,
+// using EventCreate.exe as message file. This requires administrative
+// privileges, so services usually register their source during setup.
+func Install() Option {
+	return func(s *Sink) { s.install = true }
+}
+
+// New opens the Event Log for the given source name.
+func New(source string, opts ...Option) (*Sink, error) {
+	s := &Sink{
+		source: source,
+		eventIDs: map[string]uint32{
+			"debug":   1,
+			"info":    1,
+			"warning": 2,
+			"error":   3,
+			"fatal":   3,
+			"panic":   3,
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	w, err := open(source, s.install)
+	if err != nil {
+		return nil, err
+	}
+	s.writer = w
+	return s, nil
+}
+
+// Log reports the entry as event.
+func (s *Sink) Log(keyvals ...interface{}) error {
+	fields := kv.Map(keyvals)
+	lvl := level.InfoValue()
+	if v, ok := level.FromKeyvals(keyvals); ok {
+		lvl = v
+		delete(fields, kv.Key(level.Key()))
+	}
+	kind := kindOf(lvl)
+
+	eid, ok := s.eventIDs[lvl.String()]
+	if !ok {
+		eid = s.eventIDs[kind]
+	}
+	if v, ok := fields[EventIDKey]; ok {
+		if id, err := strconv.ParseUint(kv.String(v), 10, 32); err == nil {
+			eid = uint32(id)
+		}
+		delete(fields, EventIDKey)
+	}
+
+	msg := format(fields)
+	switch kind {
+	case "warning":
+		return s.writer.Warning(eid, msg)
+	case "error":
+		return s.writer.Error(eid, msg)
+	default:
+		return s.writer.Info(eid, msg)
+	}
+}
+
+// kindOf returns the kind of event reported for entries of the level, the
+// name of the nearest built-in level below or at its severity, so levels
+// added with level.Register are reported by their severity.
+func kindOf(lvl level.Value) string {
+	switch {
+	case lvl.Severity() >= level.ErrorValue().Severity():
+		return "error"
+	case lvl.Severity() >= level.WarnValue().Severity():
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// Close closes the Event Log handle.
+func (s *Sink) Close() error {
+	return s.writer.Close()
+}
+
+// format renders the message followed by the remaining fields in logfmt
+// style, sorted by key so events of the same kind look alike.
+func format(fields map[string]interface{}) string {
+	var b strings.Builder
+	if v, ok := fields[kv.MessageKey]; ok {
+		b.WriteString(kv.String(v))
+		delete(fields, kv.MessageKey)
+	}
+	if len(fields) == 0 {
+		return b.String()
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if b.Len() > 0 {
+		b.WriteString("\r\n\r\n")
+	}
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		v := kv.String(fields[k])
+		if strings.ContainsAny(v, " \"=\r\n") {
+			v = strconv.Quote(v)
+		}
+		fmt.Fprintf(&b, "%s=%s", k, v)
+	}
+	return b.String()
+}
@@ -0,0 +1,7 @@
+//go:build !windows
+
+package eventlog
+
+func open(source string, install bool) (writer, error) {
+	return nil, ErrUnsupported
+}
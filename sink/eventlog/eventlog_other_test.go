@@ -0,0 +1,11 @@
+//go:build !windows
+
+package eventlog
+
+import "testing"
+
+func TestNewUnsupported(t *testing.T) {
+	if _, err := New("orders"); err != ErrUnsupported {
+		t.Errorf("New() = %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,137 @@
+package eventlog
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-godin/log/level"
+)
+
+// recordingWriter records the reported events.
+type recordingWriter struct {
+	events []string
+}
+
+func (w *recordingWriter) Info(eid uint32, msg string) error    { return w.report("info", eid, msg) }
+func (w *recordingWriter) Warning(eid uint32, msg string) error { return w.report("warning", eid, msg) }
+func (w *recordingWriter) Error(eid uint32, msg string) error   { return w.report("error", eid, msg) }
+func (w *recordingWriter) Close() error                         { return nil }
+
+func (w *recordingWriter) report(kind string, eid uint32, msg string) error {
+	w.events = append(w.events, fmt.Sprintf("%s %d %q", kind, eid, msg))
+	return nil
+}
+
+// customLevels are registered once per process, between the built-in levels.
+var customLevels = func() map[string]level.Value {
+	levels := make(map[string]level.Value)
+	for name, severity := range map[string]int{
+		"eventlog-notice":   250,
+		"eventlog-alert":    350,
+		"eventlog-critical": 450,
+	} {
+		v, err := level.Register(name, severity)
+		if err != nil {
+			panic(err)
+		}
+		levels[name] = v
+	}
+	return levels
+}()
+
+func TestSink(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		keyvals []interface{}
+		want    string
+	}{
+		{
+			name:    "no level",
+			keyvals: []interface{}{"message", "started"},
+			want:    `info 1 "started"`,
+		},
+		{
+			name:    "debug",
+			keyvals: []interface{}{level.Key(), level.DebugValue(), "message", "cache hit"},
+			want:    `info 1 "cache hit"`,
+		},
+		{
+			name:    "warning",
+			keyvals: []interface{}{level.Key(), level.WarnValue(), "message", "slow"},
+			want:    `warning 2 "slow"`,
+		},
+		{
+			name:    "error",
+			keyvals: []interface{}{level.Key(), level.ErrorValue(), "message", "failed"},
+			want:    `error 3 "failed"`,
+		},
+		{
+			name:    "custom level below warning",
+			keyvals: []interface{}{level.Key(), customLevels["eventlog-notice"], "message", "rotated"},
+			want:    `info 1 "rotated"`,
+		},
+		{
+			name:    "custom level below error",
+			keyvals: []interface{}{level.Key(), customLevels["eventlog-alert"], "message", "disk full"},
+			want:    `warning 2 "disk full"`,
+		},
+		{
+			name:    "custom level above error",
+			keyvals: []interface{}{level.Key(), customLevels["eventlog-critical"], "message", "corrupted"},
+			want:    `error 3 "corrupted"`,
+		},
+		{
+			name:    "custom level event ID",
+			opts:    []Option{EventID("eventlog-alert", 7)},
+			keyvals: []interface{}{level.Key(), customLevels["eventlog-alert"], "message", "disk full"},
+			want:    `warning 7 "disk full"`,
+		},
+		{
+			name:    "forced debug",
+			keyvals: []interface{}{level.Key(), level.Force(level.DebugValue()), "message", "cache hit"},
+			want:    `info 1 "cache hit"`,
+		},
+		{
+			name:    "fields",
+			keyvals: []interface{}{"message", "failed", "user", "jane doe", "attempt", 2},
+			want:    `info 1 "failed\r\n\r\nattempt=2 user=\"jane doe\""`,
+		},
+		{
+			name:    "fields without message",
+			keyvals: []interface{}{"attempt", 2},
+			want:    `info 1 "attempt=2"`,
+		},
+		{
+			name:    "configured event ID",
+			opts:    []Option{EventID("warning", 100)},
+			keyvals: []interface{}{level.Key(), level.WarnValue(), "message", "slow"},
+			want:    `warning 100 "slow"`,
+		},
+		{
+			name:    "event ID field",
+			keyvals: []interface{}{"message", "started", EventIDKey, 42},
+			want:    `info 42 "started"`,
+		},
+		{
+			name:    "invalid event ID field",
+			keyvals: []interface{}{"message", "started", EventIDKey, "x"},
+			want:    `info 1 "started"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &recordingWriter{}
+			s := &Sink{eventIDs: map[string]uint32{"debug": 1, "info": 1, "warning": 2, "error": 3}, writer: w}
+			for _, opt := range tt.opts {
+				opt(s)
+			}
+			if err := s.Log(tt.keyvals...); err != nil {
+				t.Fatal(err)
+			}
+			if len(w.events) != 1 || w.events[0] != tt.want {
+				t.Errorf("reported %v, want %s", w.events, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package eventlog
+
+import (
+	"strings"
+
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+func open(source string, install bool) (writer, error) {
+	if install {
+		err := eventlog.InstallAsEventCreate(source, eventlog.Info|eventlog.Warning|eventlog.Error)
+		// x/sys doesn't export an error for an existing source
+		if err != nil && !strings.HasSuffix(err.Error(), "registry key already exists") {
+			return nil, err
+		}
+	}
+	return eventlog.Open(source)
+}
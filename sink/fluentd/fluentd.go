@@ -0,0 +1,336 @@
+// Package fluentd provides a sink speaking the Fluentd forward protocol, so
+// entries can be sent to fluentd or fluent-bit aggregators directly.
+package fluentd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-godin/log/internal/batch"
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/internal/msgpack"
+	"github.com/go-godin/log/selftrace"
+)
+
+var (
+	// ErrQueueFull is returned by Log if the sink drops entries when its queue is full.
+	ErrQueueFull = errors.New("fluentd: queue is full, entry dropped")
+	// ErrClosed is returned by Log after the sink has been closed.
+	ErrClosed = errors.New("fluentd: sink is closed")
+)
+
+// eventTimeExt is the msgpack extension type of the forward protocol's EventTime.
+const eventTimeExt = 0
+
+// Sink buffers log entries and forwards them to a Fluentd compatible
+// aggregator using the forward protocol. It implements the go-kit log.Logger
+// interface and can be passed to log.WithSink.
+type Sink struct {
+	network      string
+	address      string
+	tag          string
+	requireAck   bool
+	timeout      time.Duration
+	batching     batch.Config
+	maxRetries   int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	errorHandler func(error)
+	tracer       *selftrace.Tracer
+
+	conn    net.Conn
+	acks    *msgpack.Decoder
+	batcher *batch.Batcher[event]
+}
+
+type event struct {
+	time   time.Time
+	record map[string]interface{}
+}
+
+// Option sets a parameter for the Sink.
+type Option func(*Sink)
+
+// Tag sets the tag every entry is forwarded with. Defaults to "app".
+func Tag(tag string) Option {
+	return func(s *Sink) { s.tag = tag }
+}
+
+// Network sets the network used to reach the aggregator, e.g. "unix" for a
+// socket path. Defaults to "tcp".
+func Network(network string) Option {
+	return func(s *Sink) { s.network = network }
+}
+
+// RequireAck makes the sink wait for the aggregator to acknowledge every
+// chunk. Chunks which aren't acknowledged within the timeout are resent.
+func RequireAck() Option {
+	return func(s *Sink) { s.requireAck = true }
+}
+
+// Timeout sets the timeout for connecting, writing and waiting for acks.
+// Defaults to five seconds.
+func Timeout(timeout time.Duration) Option {
+	return func(s *Sink) { s.timeout = timeout }
+}
+
+// BatchSize sets the maximum amount of entries forwarded in a single chunk.
+// Defaults to 500.
+func BatchSize(entries int) Option {
+	return batching(batch.MaxEntries(entries))
+}
+
+// FlushInterval sets the maximum time an entry is buffered before it's sent.
+// Zero sends chunks only once they're full, on Flush and on Close. Defaults
+// to one second.
+func FlushInterval(interval time.Duration) Option {
+	return batching(batch.MaxLatency(interval))
+}
+
+// QueueSize sets the amount of entries buffered in memory while a chunk is
+// being sent. Once the queue is full, Log blocks unless DropWhenFull is set.
+// Defaults to 10000.
+func QueueSize(size int) Option {
+	return batching(batch.QueueSize(size))
+}
+
+// DropWhenFull instructs Log to drop entries and return ErrQueueFull instead of
+// blocking the caller if the queue is full.
+func DropWhenFull() Option {
+	return batching(batch.DropWhenFull())
+}
+
+// batching applies options of the batcher to the Sink.
+func batching(opts ...batch.Option) Option {
+	return func(s *Sink) {
+		for _, opt := range opts {
+			opt(&s.batching)
+		}
+	}
+}
+
+// Retry configures how often failed chunks are resent. The delay between
+// attempts starts at backoff and doubles up to maxBackoff.
+// Defaults to 3 retries, starting at 100ms up to 5s.
+func Retry(maxRetries int, backoff, maxBackoff time.Duration) Option {
+	return func(s *Sink) {
+		s.maxRetries = maxRetries
+		s.backoff = backoff
+		s.maxBackoff = maxBackoff
+	}
+}
+
+// ErrorHandler sets the function called with errors which occur while
+// forwarding entries. By default errors are written to stderr.
+func ErrorHandler(handler func(error)) Option {
+	return func(s *Sink) { s.errorHandler = handler }
+}
+
+// Tracer reports slow flushes and retries of the sink as spans.
+func Tracer(tracer *selftrace.Tracer) Option {
+	return func(s *Sink) { s.tracer = tracer }
+}
+
+// New creates a Sink forwarding entries to the aggregator listening on
+// address (e.g. "localhost:24224") and starts its background worker.
+// The connection is established lazily and re-established after failures.
+func New(address string, opts ...Option) *Sink {
+	s := &Sink{
+		network:    "tcp",
+		address:    address,
+		tag:        "app",
+		timeout:    5 * time.Second,
+		maxRetries: 3,
+		backoff:    100 * time.Millisecond,
+		maxBackoff: 5 * time.Second,
+		errorHandler: func(err error) {
+			fmt.Fprintf(os.Stderr, "fluentd sink: %v\n", err)
+		},
+		batching: batch.Config{
+			MaxEntries:   500,
+			MaxLatency:   time.Second,
+			QueueSize:    10000,
+			ErrQueueFull: ErrQueueFull,
+			ErrClosed:    ErrClosed,
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.batcher = batch.New(s.batching, func(event) int { return 0 }, s.send)
+
+	return s
+}
+
+// Log enqueues the entry for the next chunk.
+func (s *Sink) Log(keyvals ...interface{}) error {
+	e := event{
+		time:   time.Now(),
+		record: kv.Map(keyvals),
+	}
+	return s.batcher.Add(e)
+}
+
+// Dropped returns the amount of entries dropped because the queue was full.
+func (s *Sink) Dropped() uint64 {
+	return s.batcher.Dropped()
+}
+
+// Flush sends all entries enqueued so far and blocks until they're written
+// (and acknowledged, if RequireAck is set).
+func (s *Sink) Flush() error {
+	s.batcher.Flush()
+	return nil
+}
+
+// Close stops accepting new entries, sends the remaining ones and closes the connection.
+func (s *Sink) Close() error {
+	s.batcher.Close()
+	// the worker stopped, the connection isn't used anymore
+	s.disconnect()
+	return nil
+}
+
+// send forwards the batch as a single chunk, reconnecting and retrying with
+// backoff on failures.
+func (s *Sink) send(events []event) {
+	if len(events) == 0 {
+		return
+	}
+
+	chunk, err := s.encode(events)
+	if err != nil {
+		s.errorHandler(fmt.Errorf("dropping %d entries: %v", len(events), err))
+		return
+	}
+
+	op := s.tracer.Start("log.sink.fluentd.flush")
+	op.Tag("entries", strconv.Itoa(len(events)))
+	defer func() { op.End(err) }()
+
+	backoff := s.backoff
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			op.Retry(err)
+			time.Sleep(backoff)
+			if backoff *= 2; backoff > s.maxBackoff {
+				backoff = s.maxBackoff
+			}
+		}
+
+		err = s.write(chunk)
+		if err == nil {
+			return
+		}
+		s.disconnect()
+		if attempt >= s.maxRetries {
+			s.errorHandler(fmt.Errorf("dropping %d entries after %d attempts: %v", len(events), attempt+1, err))
+			return
+		}
+	}
+}
+
+// encode builds a forward mode message: [tag, [[time, record], ...], option].
+func (s *Sink) encode(events []event) (chunk, error) {
+	var c chunk
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+
+	enc.EncodeArrayHeader(3)
+	enc.EncodeString(s.tag)
+	enc.EncodeArrayHeader(len(events))
+	for _, e := range events {
+		enc.EncodeArrayHeader(2)
+		enc.EncodeExt(eventTimeExt, eventTime(e.time))
+		if err := enc.Encode(e.record); err != nil {
+			return c, err
+		}
+	}
+
+	option := map[string]interface{}{"size": len(events)}
+	if s.requireAck {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return c, err
+		}
+		c.id = base64.StdEncoding.EncodeToString(id)
+		option["chunk"] = c.id
+	}
+	if err := enc.Encode(option); err != nil {
+		return c, err
+	}
+
+	c.data = buf.Bytes()
+	return c, nil
+}
+
+type chunk struct {
+	id   string
+	data []byte
+}
+
+// write sends the chunk over the current connection and waits for its ack.
+func (s *Sink) write(c chunk) error {
+	if err := s.connect(); err != nil {
+		return err
+	}
+
+	if err := s.conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		return err
+	}
+	if _, err := s.conn.Write(c.data); err != nil {
+		return err
+	}
+	if c.id == "" {
+		return nil
+	}
+
+	resp, err := s.acks.Decode()
+	if err != nil {
+		return fmt.Errorf("waiting for ack: %v", err)
+	}
+	m, ok := resp.(map[string]interface{})
+	if !ok || m["ack"] != c.id {
+		return fmt.Errorf("unexpected ack %v for chunk %s", resp, c.id)
+	}
+	return nil
+}
+
+func (s *Sink) connect() error {
+	if s.conn != nil {
+		return nil
+	}
+	conn, err := net.DialTimeout(s.network, s.address, s.timeout)
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	s.acks = msgpack.NewDecoder(conn)
+	return nil
+}
+
+func (s *Sink) disconnect() {
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+		s.acks = nil
+	}
+}
+
+// eventTime encodes t as the payload of the forward protocol's EventTime
+// extension: big-endian seconds followed by nanoseconds.
+func eventTime(t time.Time) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b[:4], uint32(t.Unix()))
+	binary.BigEndian.PutUint32(b[4:], uint32(t.Nanosecond()))
+	return b
+}
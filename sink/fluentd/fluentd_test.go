@@ -0,0 +1,103 @@
+package fluentd
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-godin/log/internal/msgpack"
+)
+
+// listen accepts a single connection and reports the amount of entries of
+// every forward mode message received.
+func listen(t *testing.T) (string, <-chan int) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	chunks := make(chan int, 10)
+	go func() {
+		defer close(chunks)
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		dec := msgpack.NewDecoder(conn)
+		for {
+			msg, err := dec.Decode()
+			if err != nil {
+				return
+			}
+			if parts, ok := msg.([]interface{}); ok && len(parts) == 3 {
+				entries, _ := parts[1].([]interface{})
+				chunks <- len(entries)
+			}
+		}
+	}()
+	return l.Addr().String(), chunks
+}
+
+func TestSinkChunks(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		entries int
+		flush   bool
+		want    []int
+	}{
+		{
+			name:    "without flush interval",
+			opts:    []Option{FlushInterval(0)},
+			entries: 3,
+			flush:   true,
+			want:    []int{3},
+		},
+		{
+			name:    "batch size",
+			opts:    []Option{BatchSize(2), FlushInterval(time.Hour)},
+			entries: 5,
+			flush:   true,
+			want:    []int{2, 2, 1},
+		},
+		{
+			name:    "flush interval",
+			opts:    []Option{FlushInterval(time.Millisecond)},
+			entries: 2,
+			want:    []int{2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, chunks := listen(t)
+			s := New(addr, append([]Option{ErrorHandler(func(err error) { t.Error(err) })}, tt.opts...)...)
+			for i := 0; i < tt.entries; i++ {
+				if err := s.Log("message", "entry", "i", i); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.flush {
+				_ = s.Flush()
+			}
+
+			var got []int
+			for total := 0; total < tt.entries; {
+				select {
+				case n := <-chunks:
+					got = append(got, n)
+					total += n
+				case <-time.After(time.Second):
+					t.Fatalf("chunks = %v, want %v", got, tt.want)
+				}
+			}
+			_ = s.Close()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chunks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
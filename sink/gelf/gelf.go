@@ -0,0 +1,277 @@
+// Package gelf provides a sink shipping entries to Graylog using the GELF
+// format over UDP (chunked as per the specification) or TCP.
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/level"
+)
+
+const (
+	chunkMagic0     = 0x1e
+	chunkMagic1     = 0x0f
+	chunkHeaderSize = 12
+	maxChunks       = 128
+)
+
+// ErrTooLarge is returned if an entry needs more than 128 chunks.
+var ErrTooLarge = errors.New("gelf: message exceeds the maximum of 128 chunks")
+
+// syslogLevels maps the names of godin's levels to syslog severities.
+var syslogLevels = map[string]int{
+	"debug":   7,
+	"info":    6,
+	"warning": 4,
+	"error":   3,
+	"fatal":   2,
+	"panic":   0,
+}
+
+// Compression is the compression applied to UDP payloads.
+type Compression int
+
+const (
+	// Gzip compresses payloads with gzip.
+	Gzip Compression = iota
+	// Zlib compresses payloads with zlib.
+	Zlib
+	// None sends payloads uncompressed.
+	None
+)
+
+// Encode converts keyvals into a GELF 1.1 message. The message field becomes
+// short_message, the level is mapped to its syslog severity and all other
+// fields are added as additional fields.
+func Encode(host string, ts time.Time, keyvals []interface{}) ([]byte, error) {
+	fields := kv.Map(keyvals)
+	msg := map[string]interface{}{
+		"version":   "1.1",
+		"host":      host,
+		"timestamp": float64(ts.UnixNano()) / float64(time.Second),
+	}
+
+	if lvl, ok := level.FromKeyvals(keyvals); ok {
+		if severity, ok := syslogLevels[lvl.String()]; ok {
+			msg["level"] = severity
+		}
+		delete(fields, kv.Key(level.Key()))
+	}
+
+	short := "-" // short_message is mandatory and must not be empty
+	if v, ok := fields[kv.MessageKey]; ok {
+		if s := kv.String(v); s != "" {
+			short = s
+		}
+		delete(fields, kv.MessageKey)
+	}
+	if i := strings.IndexByte(short, '\n'); i >= 0 {
+		msg["full_message"] = short
+		short = short[:i]
+	}
+	msg["short_message"] = short
+
+	for k, v := range fields {
+		if k == "id" || k == "_id" {
+			k = "field_id" // _id is reserved
+		}
+		msg["_"+strings.TrimPrefix(k, "_")] = v
+	}
+	return json.Marshal(msg)
+}
+
+// Sink writes GELF messages to Graylog. It implements the go-kit log.Logger
+// interface and can be passed to log.WithSink.
+type Sink struct {
+	network     string
+	address     string
+	host        string
+	compression Compression
+	chunkSize   int
+	timeout     time.Duration
+
+	mtx  sync.Mutex
+	conn net.Conn
+}
+
+// Option sets a parameter for the Sink.
+type Option func(*Sink)
+
+// Host sets the host field of every message. Defaults to the hostname.
+func Host(host string) Option {
+	return func(s *Sink) { s.host = host }
+}
+
+// WithCompression sets the compression of UDP payloads. Defaults to Gzip.
+// TCP payloads are never compressed as Graylog doesn't support it.
+func WithCompression(c Compression) Option {
+	return func(s *Sink) { s.compression = c }
+}
+
+// ChunkSize sets the maximum size of a single UDP datagram. Defaults to 1420
+// bytes, which fits into the MTU of most networks.
+func ChunkSize(size int) Option {
+	return func(s *Sink) { s.chunkSize = size }
+}
+
+// Timeout sets the timeout for connecting and writing. Defaults to five seconds.
+func Timeout(timeout time.Duration) Option {
+	return func(s *Sink) { s.timeout = timeout }
+}
+
+// New creates a Sink sending to the Graylog input at address, which must
+// be formatted as "udp://host:port" or "tcp://host:port".
+func New(address string, opts ...Option) (*Sink, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "udp" && u.Scheme != "tcp" {
+		return nil, fmt.Errorf("gelf: unsupported scheme %q, use udp or tcp", u.Scheme)
+	}
+
+	hostname, _ := os.Hostname()
+	s := &Sink{
+		network:   u.Scheme,
+		address:   u.Host,
+		host:      hostname,
+		chunkSize: 1420,
+		timeout:   5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.chunkSize <= chunkHeaderSize {
+		return nil, fmt.Errorf("gelf: chunk size must exceed %d bytes", chunkHeaderSize)
+	}
+	return s, nil
+}
+
+// Log encodes the entry and sends it to Graylog.
+func (s *Sink) Log(keyvals ...interface{}) error {
+	msg, err := Encode(s.host, time.Now(), keyvals)
+	if err != nil {
+		return err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.network == "tcp" {
+		// messages are delimited by a null byte, a failed write is retried
+		// once on a fresh connection
+		msg = append(msg, 0)
+		if err := s.write(msg); err != nil {
+			s.disconnect()
+			return s.write(msg)
+		}
+		return nil
+	}
+
+	payload, err := s.compress(msg)
+	if err != nil {
+		return err
+	}
+	if len(payload) <= s.chunkSize {
+		return s.write(payload)
+	}
+	return s.writeChunked(payload)
+}
+
+// Close closes the connection to Graylog.
+func (s *Sink) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.disconnect()
+	return nil
+}
+
+func (s *Sink) compress(msg []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	switch s.compression {
+	case Gzip:
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(msg); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	case Zlib:
+		w := zlib.NewWriter(&buf)
+		if _, err := w.Write(msg); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return msg, nil
+	}
+	return buf.Bytes(), nil
+}
+
+// writeChunked splits the payload into chunks sharing a random message id.
+func (s *Sink) writeChunked(payload []byte) error {
+	size := s.chunkSize - chunkHeaderSize
+	count := (len(payload) + size - 1) / size
+	if count > maxChunks {
+		return ErrTooLarge
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+
+	chunk := make([]byte, 0, s.chunkSize)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * size
+		if end > len(payload) {
+			end = len(payload)
+		}
+		chunk = append(chunk[:0], chunkMagic0, chunkMagic1)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, payload[i*size:end]...)
+		if err := s.write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Sink) write(data []byte) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.address, s.timeout)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	if err := s.conn.SetWriteDeadline(time.Now().Add(s.timeout)); err != nil {
+		return err
+	}
+	_, err := s.conn.Write(data)
+	return err
+}
+
+func (s *Sink) disconnect() {
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+}
@@ -0,0 +1,249 @@
+package gelf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-godin/log/level"
+)
+
+func TestEncode(t *testing.T) {
+	ts := time.Unix(1700000000, 500000000)
+	tests := []struct {
+		name    string
+		keyvals []interface{}
+		want    map[string]interface{}
+	}{
+		{
+			name:    "message",
+			keyvals: []interface{}{"message", "started"},
+			want:    map[string]interface{}{"short_message": "started"},
+		},
+		{
+			name:    "level",
+			keyvals: []interface{}{level.Key(), level.WarnValue(), "message", "slow"},
+			want:    map[string]interface{}{"short_message": "slow", "level": float64(4)},
+		},
+		{
+			name:    "missing message",
+			keyvals: []interface{}{"user", "jane"},
+			want:    map[string]interface{}{"short_message": "-", "_user": "jane"},
+		},
+		{
+			name:    "empty message",
+			keyvals: []interface{}{"message", ""},
+			want:    map[string]interface{}{"short_message": "-"},
+		},
+		{
+			name:    "multiline message",
+			keyvals: []interface{}{"message", "panic\ngoroutine 1"},
+			want:    map[string]interface{}{"short_message": "panic", "full_message": "panic\ngoroutine 1"},
+		},
+		{
+			name:    "additional fields",
+			keyvals: []interface{}{"message", "m", "_user", "jane", "id", 7},
+			want:    map[string]interface{}{"short_message": "m", "_user": "jane", "_field_id": float64(7)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Encode("web-1", ts, tt.keyvals)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got map[string]interface{}
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]interface{}{"version": "1.1", "host": "web-1", "timestamp": 1700000000.5}
+			for k, v := range tt.want {
+				want[k] = v
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Encode() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestSinkUDP(t *testing.T) {
+	tests := []struct {
+		name        string
+		compression Compression
+		chunkSize   int
+		message     string
+		wantChunks  int
+		decompress  func(io.Reader) (io.Reader, error)
+	}{
+		{
+			name:        "gzip",
+			compression: Gzip,
+			message:     "m",
+			decompress:  func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		},
+		{
+			name:        "zlib",
+			compression: Zlib,
+			message:     "m",
+			decompress:  func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) },
+		},
+		{
+			name:        "uncompressed",
+			compression: None,
+			message:     "m",
+		},
+		{
+			name:        "chunked",
+			compression: None,
+			chunkSize:   100,
+			message:     strings.Repeat("a", 300), // about 380 bytes encoded
+			wantChunks:  5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			opts := []Option{Host("web-1"), WithCompression(tt.compression)}
+			if tt.chunkSize > 0 {
+				opts = append(opts, ChunkSize(tt.chunkSize))
+			}
+			s, err := New("udp://"+conn.LocalAddr().String(), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			if err := s.Log("message", tt.message); err != nil {
+				t.Fatal(err)
+			}
+
+			var payload []byte
+			buf := make([]byte, 2048)
+			for i := 0; i < max(tt.wantChunks, 1); i++ {
+				_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+				n, _, err := conn.ReadFrom(buf)
+				if err != nil {
+					t.Fatal(err)
+				}
+				datagram := buf[:n]
+				if tt.wantChunks > 0 {
+					if datagram[0] != chunkMagic0 || datagram[1] != chunkMagic1 || datagram[10] != byte(i) || datagram[11] != byte(tt.wantChunks) {
+						t.Fatalf("chunk %d has header % x", i, datagram[:chunkHeaderSize])
+					}
+					if len(datagram) > tt.chunkSize {
+						t.Errorf("chunk %d has %d bytes, want at most %d", i, len(datagram), tt.chunkSize)
+					}
+					datagram = datagram[chunkHeaderSize:]
+				}
+				payload = append(payload, datagram...)
+			}
+
+			var r io.Reader = bytes.NewReader(payload)
+			if tt.decompress != nil {
+				if r, err = tt.decompress(r); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var msg map[string]interface{}
+			if err := json.NewDecoder(r).Decode(&msg); err != nil {
+				t.Fatal(err)
+			}
+			if msg["short_message"] != tt.message {
+				t.Errorf("short_message = %v, want %s", msg["short_message"], tt.message)
+			}
+		})
+	}
+}
+
+func TestSinkTooLarge(t *testing.T) {
+	s, err := New("udp://127.0.0.1:12201", WithCompression(None), ChunkSize(chunkHeaderSize+1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Log("message", strings.Repeat("a", 200)); err != ErrTooLarge {
+		t.Errorf("Log() = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestSinkTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	messages := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			msg, err := r.ReadString(0)
+			if err != nil {
+				return
+			}
+			messages <- strings.TrimSuffix(msg, "\x00")
+		}
+	}()
+
+	s, err := New("tcp://"+ln.Addr().String(), Host("web-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for _, m := range []string{"a", "b"} {
+		if err := s.Log("message", m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, want := range []string{"a", "b"} {
+		select {
+		case data := <-messages:
+			var msg map[string]interface{}
+			if err := json.Unmarshal([]byte(data), &msg); err != nil {
+				t.Fatalf("decoding %q: %v", data, err)
+			}
+			if msg["short_message"] != want {
+				t.Errorf("short_message = %v, want %s", msg["short_message"], want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("graylog received nothing")
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		opts    []Option
+		wantErr bool
+	}{
+		{name: "udp", address: "udp://localhost:12201"},
+		{name: "tcp", address: "tcp://localhost:12201"},
+		{name: "unsupported scheme", address: "http://localhost:12201", wantErr: true},
+		{name: "chunk size too small", address: "udp://localhost:12201", opts: []Option{ChunkSize(chunkHeaderSize)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.address, tt.opts...); (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+// Package nats provides a sink publishing entries as JSON messages to NATS
+// subjects, optionally through JetStream for persistence.
+package nats
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-godin/log/internal/kv"
+	stdnats "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// ErrFlushTimeout is returned by Flush if not all messages were acknowledged in time.
+var ErrFlushTimeout = errors.New("nats: flush timed out")
+
+// Sink publishes every entry to the subject resolved from its fields. It
+// implements the go-kit log.Logger interface and can be passed to log.WithSink.
+type Sink struct {
+	conn         *stdnats.Conn
+	subject      subjectTemplate
+	jetStream    bool
+	maxPending   int
+	timeout      time.Duration
+	errorHandler func(error)
+
+	js jetstream.JetStream
+}
+
+// Option sets a parameter for the Sink.
+type Option func(*Sink)
+
+// JetStream publishes through JetStream, so entries are persisted by the
+// stream bound to the subjects. Publishing is asynchronous, failed
+// acknowledgements are reported to the error handler.
+func JetStream() Option {
+	return func(s *Sink) { s.jetStream = true }
+}
+
+// MaxPending sets the maximum amount of unacknowledged JetStream messages.
+// Once reached, Log blocks until acknowledgements arrive. Defaults to 4000.
+func MaxPending(n int) Option {
+	return func(s *Sink) { s.maxPending = n }
+}
+
+// Timeout sets how long Flush waits for outstanding messages. Defaults to five seconds.
+func Timeout(timeout time.Duration) Option {
+	return func(s *Sink) { s.timeout = timeout }
+}
+
+// ErrorHandler sets the function called with errors which occur
+// asynchronously. By default errors are written to stderr.
+func ErrorHandler(handler func(error)) Option {
+	return func(s *Sink) { s.errorHandler = handler }
+}
+
+// New creates a Sink publishing over conn. The subject may contain field
+// placeholders, e.g. "logs.{service}.{severity}". Missing fields resolve to
+// "unknown", characters which aren't allowed in subject tokens are replaced.
+// The connection is owned by the caller.
+func New(conn *stdnats.Conn, subject string, opts ...Option) (*Sink, error) {
+	tmpl, err := parseSubjectTemplate(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Sink{
+		conn:       conn,
+		subject:    tmpl,
+		maxPending: 4000,
+		timeout:    5 * time.Second,
+		errorHandler: func(err error) {
+			fmt.Fprintf(os.Stderr, "nats sink: %v\n", err)
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.jetStream {
+		s.js, err = jetstream.New(conn,
+			jetstream.WithPublishAsyncMaxPending(s.maxPending),
+			jetstream.WithPublishAsyncErrHandler(func(_ jetstream.JetStream, msg *stdnats.Msg, err error) {
+				s.errorHandler(fmt.Errorf("publishing to %s: %v", msg.Subject, err))
+			}),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Log publishes the entry.
+func (s *Sink) Log(keyvals ...interface{}) error {
+	fields := kv.Map(keyvals)
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	subject := s.subject.resolve(fields)
+	if s.js != nil {
+		_, err = s.js.PublishAsync(subject, data)
+		return err
+	}
+	return s.conn.Publish(subject, data)
+}
+
+// Flush blocks until all messages published so far were sent to the server,
+// or acknowledged by JetStream.
+func (s *Sink) Flush() error {
+	if s.js == nil {
+		return s.conn.FlushTimeout(s.timeout)
+	}
+	select {
+	case <-s.js.PublishAsyncComplete():
+		return nil
+	case <-time.After(s.timeout):
+		return ErrFlushTimeout
+	}
+}
+
+// Close flushes the sink. The connection stays open.
+func (s *Sink) Close() error {
+	return s.Flush()
+}
+
+// subjectTemplate resolves subjects like "logs.{service}.{severity}".
+type subjectTemplate struct {
+	literals []string
+	fields   []string
+}
+
+func parseSubjectTemplate(tmpl string) (subjectTemplate, error) {
+	var t subjectTemplate
+	for {
+		start := strings.IndexByte(tmpl, '{')
+		if start < 0 {
+			t.literals = append(t.literals, tmpl)
+			return t, nil
+		}
+		end := strings.IndexByte(tmpl[start:], '}')
+		if end < 0 {
+			return subjectTemplate{}, fmt.Errorf("nats: unterminated placeholder in subject %q", tmpl)
+		}
+		end += start
+		if end == start+1 {
+			return subjectTemplate{}, fmt.Errorf("nats: empty placeholder in subject")
+		}
+		t.literals = append(t.literals, tmpl[:start])
+		t.fields = append(t.fields, tmpl[start+1:end])
+		tmpl = tmpl[end+1:]
+	}
+}
+
+// resolve returns the subject of an entry, one literal more than fields.
+func (t subjectTemplate) resolve(fields map[string]interface{}) string {
+	if len(t.fields) == 0 {
+		return t.literals[0]
+	}
+	var b strings.Builder
+	for i, field := range t.fields {
+		b.WriteString(t.literals[i])
+		v, ok := fields[field]
+		if !ok || v == nil {
+			b.WriteString("unknown")
+			continue
+		}
+		b.WriteString(subjectToken(kv.String(v)))
+	}
+	b.WriteString(t.literals[len(t.literals)-1])
+	return b.String()
+}
+
+// subjectToken replaces the characters which separate tokens or act as
+// wildcards in subjects.
+func subjectToken(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t', '\r', '\n':
+			return '_'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,118 @@
+package nats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	stdnats "github.com/nats-io/nats.go"
+)
+
+func TestSubjectTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tmpl    string
+		fields  map[string]interface{}
+		want    string
+		wantErr bool
+	}{
+		{name: "literal", tmpl: "logs", want: "logs"},
+		{name: "fields", tmpl: "logs.{service}.{severity}", fields: map[string]interface{}{"service": "orders", "severity": "info"}, want: "logs.orders.info"},
+		{name: "missing field", tmpl: "logs.{service}", want: "logs.unknown"},
+		{name: "nil field", tmpl: "logs.{service}", fields: map[string]interface{}{"service": nil}, want: "logs.unknown"},
+		{name: "empty field", tmpl: "logs.{service}", fields: map[string]interface{}{"service": ""}, want: "logs.unknown"},
+		{name: "replaced characters", tmpl: "logs.{service}", fields: map[string]interface{}{"service": "a.b *>c"}, want: "logs.a_b___c"},
+		{name: "number", tmpl: "logs.{code}.errors", fields: map[string]interface{}{"code": 503}, want: "logs.503.errors"},
+		{name: "unterminated placeholder", tmpl: "logs.{service", wantErr: true},
+		{name: "empty placeholder", tmpl: "logs.{}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := parseSubjectTemplate(tt.tmpl)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSubjectTemplate() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := tmpl.resolve(tt.fields); got != tt.want {
+				t.Errorf("resolve() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// serve runs a minimal NATS server accepting a single client and sends the
+// subject and payload of every published message to msgs.
+func serve(t *testing.T, ln net.Listener, msgs chan<- string) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "INFO {\"server_id\":\"test\",\"version\":\"2.10.0\",\"proto\":1,\"headers\":true,\"max_payload\":1048576}\r\n")
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "PING":
+			fmt.Fprintf(conn, "PONG\r\n")
+		case "PUB":
+			var size int
+			fmt.Sscan(fields[len(fields)-1], &size)
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				t.Errorf("reading the payload: %v", err)
+				return
+			}
+			msgs <- fields[1] + " " + string(payload[:size])
+		}
+	}
+}
+
+func TestSink(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	msgs := make(chan string, 2)
+	go serve(t, ln, msgs)
+
+	conn, err := stdnats.Connect("nats://"+ln.Addr().String(), stdnats.NoReconnect())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	s, err := New(conn, "logs.{service}", Timeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Log("message", "a", "service", "orders")
+	_ = s.Log("message", "b")
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{`logs.orders {"message":"a","service":"orders"}`, `logs.unknown {"message":"b"}`} {
+		select {
+		case msg := <-msgs:
+			if msg != want {
+				t.Errorf("published %s, want %s", msg, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("nothing was published")
+		}
+	}
+}
@@ -0,0 +1,275 @@
+// Package network provides a resilient writer sending log output over TCP or
+// UDP. While the connection is down, output is buffered in memory and
+// drained once the connection has been re-established.
+package network
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Writer writes to a network connection, reconnecting automatically. Each
+// call to Write is sent as a single unit, so a go-kit JSON or logfmt logger
+// on top of it sends one entry per write:
+//
+//	w, _ := network.New("tcp://logs.internal:5170")
+//	logger := log.NewLogger("info", log.WithSink(kitlog.NewJSONLogger(w)))
+type Writer struct {
+	network      string
+	address      string
+	dialTimeout  time.Duration
+	writeTimeout time.Duration
+	bufferSize   int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+
+	mtx       sync.Mutex
+	conn      net.Conn
+	buffer    [][]byte
+	buffered  int
+	reconnect chan struct{}
+	done      chan struct{}
+	stopped   chan struct{}
+	closed    bool
+	dropped   atomic.Uint64
+}
+
+// Option sets a parameter for the Writer.
+type Option func(*Writer)
+
+// DialTimeout sets the timeout for establishing a connection. Defaults to five seconds.
+func DialTimeout(timeout time.Duration) Option {
+	return func(w *Writer) { w.dialTimeout = timeout }
+}
+
+// WriteTimeout sets the timeout of a single write. Defaults to five seconds.
+func WriteTimeout(timeout time.Duration) Option {
+	return func(w *Writer) { w.writeTimeout = timeout }
+}
+
+// BufferSize sets the amount of bytes buffered while the connection is down.
+// Once the buffer is full, the oldest writes are dropped. Defaults to 8MB.
+func BufferSize(bytes int) Option {
+	return func(w *Writer) { w.bufferSize = bytes }
+}
+
+// Backoff sets the delay between reconnection attempts, which starts at
+// backoff and doubles up to maxBackoff. Defaults to 100ms up to 30s.
+func Backoff(backoff, maxBackoff time.Duration) Option {
+	return func(w *Writer) {
+		w.backoff = backoff
+		w.maxBackoff = maxBackoff
+	}
+}
+
+// New creates a Writer for address, which must be formatted as
+// "tcp://host:port" or "udp://host:port". The connection is established in
+// the background, writes are buffered until it is up.
+func New(address string, opts ...Option) (*Writer, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6":
+	default:
+		return nil, fmt.Errorf("network: unsupported scheme %q, use tcp or udp", u.Scheme)
+	}
+
+	w := &Writer{
+		network:      u.Scheme,
+		address:      u.Host,
+		dialTimeout:  5 * time.Second,
+		writeTimeout: 5 * time.Second,
+		bufferSize:   8 << 20,
+		backoff:      100 * time.Millisecond,
+		maxBackoff:   30 * time.Second,
+		reconnect:    make(chan struct{}, 1),
+		done:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	go w.run()
+	w.signal()
+
+	return w, nil
+}
+
+// Write sends p over the connection. If the connection is down or older
+// writes are still buffered, p is buffered instead. Write never fails
+// because of network errors, it only drops data once the buffer is full.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if w.closed {
+		return 0, fmt.Errorf("network: writer is closed")
+	}
+
+	if w.conn != nil && len(w.buffer) == 0 {
+		if err := w.write(p); err == nil {
+			return len(p), nil
+		}
+		w.disconnect()
+	}
+
+	w.enqueue(p)
+	w.signal()
+	return len(p), nil
+}
+
+// Dropped returns the amount of writes dropped because the buffer was full.
+func (w *Writer) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Buffered returns the amount of bytes waiting for the connection.
+func (w *Writer) Buffered() int {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.buffered
+}
+
+// Close stops reconnecting, makes a last attempt to drain the buffer and
+// closes the connection.
+func (w *Writer) Close() error {
+	w.mtx.Lock()
+	if w.closed {
+		w.mtx.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mtx.Unlock()
+
+	close(w.done)
+	<-w.stopped
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.conn == nil && len(w.buffer) > 0 {
+		_ = w.connect()
+	}
+	w.drain()
+	w.disconnect()
+	return nil
+}
+
+func (w *Writer) run() {
+	defer close(w.stopped)
+
+	backoff := w.backoff
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-w.reconnect:
+		}
+
+		for {
+			// dial without holding the lock, so writers aren't blocked meanwhile
+			w.mtx.Lock()
+			connected := w.conn != nil
+			w.mtx.Unlock()
+			if !connected {
+				conn, err := net.DialTimeout(w.network, w.address, w.dialTimeout)
+				if err != nil {
+					select {
+					case <-w.done:
+						return
+					case <-time.After(backoff):
+					}
+					if backoff *= 2; backoff > w.maxBackoff {
+						backoff = w.maxBackoff
+					}
+					continue
+				}
+				w.mtx.Lock()
+				if w.conn != nil {
+					_ = w.conn.Close()
+				}
+				w.conn = conn
+				w.mtx.Unlock()
+			}
+
+			w.mtx.Lock()
+			ok := w.drain()
+			w.mtx.Unlock()
+			if ok {
+				backoff = w.backoff
+				break
+			}
+		}
+	}
+}
+
+// drain writes the buffered data. It reports false and disconnects if a write failed.
+func (w *Writer) drain() bool {
+	for len(w.buffer) > 0 {
+		if w.conn == nil {
+			return false
+		}
+		p := w.buffer[0]
+		if err := w.write(p); err != nil {
+			w.disconnect()
+			return false
+		}
+		w.buffer[0] = nil
+		w.buffer = w.buffer[1:]
+		w.buffered -= len(p)
+	}
+	return true
+}
+
+// enqueue buffers a copy of p, dropping the oldest data if it doesn't fit.
+func (w *Writer) enqueue(p []byte) {
+	if len(p) > w.bufferSize {
+		w.dropped.Add(1)
+		return
+	}
+	for w.buffered+len(p) > w.bufferSize && len(w.buffer) > 0 {
+		w.buffered -= len(w.buffer[0])
+		w.buffer[0] = nil
+		w.buffer = w.buffer[1:]
+		w.dropped.Add(1)
+	}
+	w.buffer = append(w.buffer, append([]byte(nil), p...))
+	w.buffered += len(p)
+}
+
+func (w *Writer) signal() {
+	select {
+	case w.reconnect <- struct{}{}:
+	default:
+	}
+}
+
+func (w *Writer) connect() error {
+	conn, err := net.DialTimeout(w.network, w.address, w.dialTimeout)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+func (w *Writer) write(p []byte) error {
+	if err := w.conn.SetWriteDeadline(time.Now().Add(w.writeTimeout)); err != nil {
+		return err
+	}
+	_, err := w.conn.Write(p)
+	return err
+}
+
+func (w *Writer) disconnect() {
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+}
@@ -0,0 +1,155 @@
+package network
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{"tcp://localhost:5170", false},
+		{"udp6://[::1]:5170", false},
+		{"http://localhost:5170", true},
+		{"tcp://[::1", true},
+	}
+	for _, tt := range tests {
+		w, err := New(tt.address)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("New(%s) error = %v, want error %v", tt.address, err, tt.wantErr)
+		}
+		if err == nil {
+			_ = w.Close()
+		}
+	}
+}
+
+func TestWriterEnqueue(t *testing.T) {
+	tests := []struct {
+		name         string
+		writes       []string
+		want         []string
+		wantBuffered int
+		wantDropped  uint64
+	}{
+		{name: "fits", writes: []string{"ab", "cd"}, want: []string{"ab", "cd"}, wantBuffered: 4},
+		{name: "oldest dropped", writes: []string{"ab", "cd", "efg"}, want: []string{"cd", "efg"}, wantBuffered: 5, wantDropped: 1},
+		{name: "too large", writes: []string{"ab", "abcdefg"}, want: []string{"ab"}, wantBuffered: 2, wantDropped: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &Writer{bufferSize: 6}
+			for _, p := range tt.writes {
+				w.enqueue([]byte(p))
+			}
+			var got []string
+			for _, p := range w.buffer {
+				got = append(got, string(p))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("buffered %q, want %q", got, tt.want)
+			}
+			if w.buffered != tt.wantBuffered || w.Dropped() != tt.wantDropped {
+				t.Errorf("buffered, dropped = %d, %d, want %d, %d", w.buffered, w.Dropped(), tt.wantBuffered, tt.wantDropped)
+			}
+		})
+	}
+}
+
+// readLines accepts a single connection and sends the lines read from it to lines.
+func readLines(ln net.Listener, lines chan<- string) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		lines <- scanner.Text()
+	}
+}
+
+func receive(t *testing.T, lines <-chan string, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		select {
+		case line := <-lines:
+			if line != w {
+				t.Errorf("received %q, want %q", line, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%q wasn't received", w)
+		}
+	}
+}
+
+func TestWriterTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 3)
+	go readLines(ln, lines)
+
+	w, err := New("tcp://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	for _, line := range []string{"a\n", "b\n", "c\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	receive(t, lines, "a", "b", "c")
+}
+
+func TestWriterReconnects(t *testing.T) {
+	// reserve an address nobody listens on yet
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	w, err := New("tcp://"+addr, Backoff(10*time.Millisecond, 10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	_, _ = w.Write([]byte("a\n"))
+	_, _ = w.Write([]byte("b\n"))
+	if n := w.Buffered(); n != 4 {
+		t.Errorf("buffered %d bytes while the connection is down, want 4", n)
+	}
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("the address was taken meanwhile: %v", err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 2)
+	go readLines(ln, lines)
+	receive(t, lines, "a", "b")
+}
+
+func TestWriterClosed(t *testing.T) {
+	w, err := New("udp://127.0.0.1:5170")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = w.Close()
+	if _, err := w.Write([]byte("a\n")); err == nil {
+		t.Error("Write() after Close succeeded")
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("closing twice: %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+// Package postgres provides a sink storing selected entries, e.g. audit
+// entries or errors, in a PostgreSQL table for compliance purposes. Entries
+// are written in batches using COPY.
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-godin/log/internal/batch"
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/level"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	// ErrQueueFull is returned by Log if the sink drops entries when its queue is full.
+	ErrQueueFull = errors.New("postgres: queue is full, entry dropped")
+	// ErrClosed is returned by Log after the sink has been closed.
+	ErrClosed = errors.New("postgres: sink is closed")
+)
+
+var columns = []string{"ts", "level", "message", "fields"}
+
+// Conn is the subset of the pgx API used by the Sink. It is implemented by
+// *pgx.Conn and *pgxpool.Pool.
+type Conn interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+// Sink copies the selected entries into a table with the columns ts
+// (timestamptz), level (text), message (text) and fields (jsonb, all other
+// fields). It implements the go-kit log.Logger interface and can be passed
+// to log.WithSink, or registered with route.Sink to only receive entries
+// directed to it with log.To.
+type Sink struct {
+	conn         Conn
+	table        pgx.Identifier
+	createTable  bool
+	levels       map[string]bool
+	filter       func(keyvals []interface{}) bool
+	timeout      time.Duration
+	batching     batch.Config
+	maxRetries   int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	errorHandler func(error)
+
+	batcher *batch.Batcher[[]any]
+}
+
+// Option sets a parameter for the Sink.
+type Option func(*Sink)
+
+// Table sets the table, optionally qualified by its schema, e.g. "audit.logs".
+// Defaults to "logs".
+func Table(name string) Option {
+	return func(s *Sink) { s.table = pgx.Identifier(strings.Split(name, ".")) }
+}
+
+// CreateTable creates the table when the sink is created, unless it exists.
+func CreateTable() Option {
+	return func(s *Sink) { s.createTable = true }
+}
+
+// Levels restricts the sink to entries of the named levels, e.g. "error".
+// By default entries of all levels are stored.
+func Levels(names ...string) Option {
+	return func(s *Sink) {
+		s.levels = make(map[string]bool, len(names))
+		for _, name := range names {
+			s.levels[name] = true
+		}
+	}
+}
+
+// Filter restricts the sink to entries for which filter reports true.
+func Filter(filter func(keyvals []interface{}) bool) Option {
+	return func(s *Sink) { s.filter = filter }
+}
+
+// Timeout sets the timeout of a single COPY. Defaults to ten seconds.
+func Timeout(timeout time.Duration) Option {
+	return func(s *Sink) { s.timeout = timeout }
+}
+
+// BatchSize sets the maximum amount of entries copied at once. Defaults to 1000.
+func BatchSize(entries int) Option {
+	return batching(batch.MaxEntries(entries))
+}
+
+// FlushInterval sets the maximum time an entry is buffered before it's
+// written. Zero writes batches only once they're full, on Flush and on
+// Close. Defaults to one second.
+func FlushInterval(interval time.Duration) Option {
+	return batching(batch.MaxLatency(interval))
+}
+
+// QueueSize sets the amount of entries buffered in memory while a batch is
+// being written. Once the queue is full, Log blocks unless DropWhenFull is
+// set. Defaults to 10000.
+func QueueSize(size int) Option {
+	return batching(batch.QueueSize(size))
+}
+
+// DropWhenFull instructs Log to drop entries and return ErrQueueFull instead of
+// blocking the caller if the queue is full.
+func DropWhenFull() Option {
+	return batching(batch.DropWhenFull())
+}
+
+// batching applies options of the batcher to the Sink.
+func batching(opts ...batch.Option) Option {
+	return func(s *Sink) {
+		for _, opt := range opts {
+			opt(&s.batching)
+		}
+	}
+}
+
+// Retry configures how often failed batches are retried. The delay between
+// attempts starts at backoff and doubles up to maxBackoff.
+// Defaults to 5 retries, starting at 100ms up to 10s.
+func Retry(maxRetries int, backoff, maxBackoff time.Duration) Option {
+	return func(s *Sink) {
+		s.maxRetries = maxRetries
+		s.backoff = backoff
+		s.maxBackoff = maxBackoff
+	}
+}
+
+// ErrorHandler sets the function called with errors which occur while
+// writing entries. By default errors are written to stderr.
+func ErrorHandler(handler func(error)) Option {
+	return func(s *Sink) { s.errorHandler = handler }
+}
+
+// New creates a Sink writing over conn and starts its background worker.
+func New(ctx context.Context, conn Conn, opts ...Option) (*Sink, error) {
+	s := &Sink{
+		conn:       conn,
+		table:      pgx.Identifier{"logs"},
+		timeout:    10 * time.Second,
+		maxRetries: 5,
+		backoff:    100 * time.Millisecond,
+		maxBackoff: 10 * time.Second,
+		errorHandler: func(err error) {
+			fmt.Fprintf(os.Stderr, "postgres sink: %v\n", err)
+		},
+		batching: batch.Config{
+			MaxEntries:   1000,
+			MaxLatency:   time.Second,
+			QueueSize:    10000,
+			ErrQueueFull: ErrQueueFull,
+			ErrClosed:    ErrClosed,
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.createTable {
+		_, err := conn.Exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			ts timestamptz NOT NULL,
+			level text NOT NULL,
+			message text NOT NULL,
+			fields jsonb NOT NULL
+		)`, s.table.Sanitize()))
+		if err != nil {
+			return nil, fmt.Errorf("postgres: creating table: %v", err)
+		}
+	}
+
+	s.batcher = batch.New(s.batching, func([]any) int { return 0 }, s.copy)
+
+	return s, nil
+}
+
+// Log enqueues the entry for the next batch, unless it's not selected.
+func (s *Sink) Log(keyvals ...interface{}) error {
+	lvl, hasLevel := level.FromKeyvals(keyvals)
+	if s.levels != nil && (!hasLevel || !s.levels[lvl.String()]) {
+		return nil
+	}
+	if s.filter != nil && !s.filter(keyvals) {
+		return nil
+	}
+
+	fields := kv.Map(keyvals)
+	name := ""
+	if hasLevel {
+		name = lvl.String()
+		delete(fields, kv.Key(level.Key()))
+	}
+	message := ""
+	if v, ok := fields[kv.MessageKey]; ok {
+		message = kv.String(v)
+		delete(fields, kv.MessageKey)
+	}
+	return s.batcher.Add([]any{time.Now(), name, message, fields})
+}
+
+// Dropped returns the amount of entries dropped because the queue was full.
+func (s *Sink) Dropped() uint64 {
+	return s.batcher.Dropped()
+}
+
+// Flush writes all entries enqueued so far and blocks until they're committed.
+func (s *Sink) Flush() error {
+	s.batcher.Flush()
+	return nil
+}
+
+// Close stops accepting new entries, writes the remaining ones and stops the
+// worker. The connection stays open.
+func (s *Sink) Close() error {
+	s.batcher.Close()
+	return nil
+}
+
+// copy writes the batch with COPY, retrying failed attempts. COPY is atomic,
+// so a failed attempt never leaves a partial batch behind.
+func (s *Sink) copy(rows [][]any) {
+	if len(rows) == 0 {
+		return
+	}
+
+	var err error
+	backoff := s.backoff
+	for attempt := 0; attempt <= s.maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			if backoff *= 2; backoff > s.maxBackoff {
+				backoff = s.maxBackoff
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		_, err = s.conn.CopyFrom(ctx, s.table, columns, pgx.CopyFromRows(rows))
+		cancel()
+		if err == nil {
+			return
+		}
+	}
+	s.errorHandler(fmt.Errorf("dropping %d entries after %d attempts: %v", len(rows), s.maxRetries+1, err))
+}
@@ -0,0 +1,178 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-godin/log/level"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// conn records the statements and copied rows, failing the first failures
+// copies.
+type conn struct {
+	mtx      sync.Mutex
+	failures int
+	copies   int
+	execs    []string
+	tables   []string
+	rows     [][]any
+}
+
+func (c *conn) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.execs = append(c.execs, sql)
+	return pgconn.CommandTag{}, nil
+}
+
+func (c *conn) CopyFrom(_ context.Context, table pgx.Identifier, columnNames []string, src pgx.CopyFromSource) (int64, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.copies++
+	if c.failures > 0 {
+		c.failures--
+		return 0, errors.New("connection reset")
+	}
+	if !reflect.DeepEqual(columnNames, columns) {
+		return 0, errors.New("unexpected columns")
+	}
+	c.tables = append(c.tables, table.Sanitize())
+	for src.Next() {
+		row, err := src.Values()
+		if err != nil {
+			return 0, err
+		}
+		c.rows = append(c.rows, row)
+	}
+	return int64(len(c.rows)), nil
+}
+
+func TestSinkSelection(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		keyvals []interface{}
+		want    []any // level, message and fields, nil if not stored
+	}{
+		{
+			name:    "all levels",
+			keyvals: []interface{}{level.Key(), level.InfoValue(), "message", "login", "user", "jane"},
+			want:    []any{"info", "login", map[string]interface{}{"user": "jane"}},
+		},
+		{
+			name:    "no level",
+			keyvals: []interface{}{"message", "login"},
+			want:    []any{"", "login", map[string]interface{}{}},
+		},
+		{
+			name:    "selected level",
+			opts:    []Option{Levels("error")},
+			keyvals: []interface{}{level.Key(), level.ErrorValue(), "message", "failed"},
+			want:    []any{"error", "failed", map[string]interface{}{}},
+		},
+		{
+			name:    "other level",
+			opts:    []Option{Levels("error")},
+			keyvals: []interface{}{level.Key(), level.InfoValue(), "message", "login"},
+		},
+		{
+			name:    "no level with levels",
+			opts:    []Option{Levels("error")},
+			keyvals: []interface{}{"message", "login"},
+		},
+		{
+			name: "filtered",
+			opts: []Option{Filter(func(keyvals []interface{}) bool {
+				return len(keyvals) > 2
+			})},
+			keyvals: []interface{}{"message", "login"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &conn{}
+			s, err := New(context.Background(), c, append([]Option{FlushInterval(0)}, tt.opts...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Log(tt.keyvals...); err != nil {
+				t.Fatal(err)
+			}
+			_ = s.Close()
+
+			if tt.want == nil {
+				if len(c.rows) != 0 {
+					t.Errorf("stored %v, want nothing", c.rows)
+				}
+				return
+			}
+			if len(c.rows) != 1 {
+				t.Fatalf("stored %d rows, want 1", len(c.rows))
+			}
+			row := c.rows[0]
+			if _, ok := row[0].(time.Time); !ok {
+				t.Errorf("ts = %#v", row[0])
+			}
+			if !reflect.DeepEqual(row[1:], tt.want) {
+				t.Errorf("stored %#v, want %#v", row[1:], tt.want)
+			}
+		})
+	}
+}
+
+func TestSinkCopy(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int
+		wantCopies int
+		wantErrors int
+	}{
+		{name: "copied", wantCopies: 1},
+		{name: "retried", failures: 2, wantCopies: 3},
+		{name: "retries exhausted", failures: 3, wantCopies: 3, wantErrors: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &conn{failures: tt.failures}
+			var errs []error
+			s, err := New(context.Background(), c,
+				Table("audit.logs"),
+				FlushInterval(0),
+				Retry(2, time.Millisecond, time.Millisecond),
+				ErrorHandler(func(err error) { errs = append(errs, err) }),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = s.Log("message", "a")
+			_ = s.Log("message", "b")
+			_ = s.Close()
+
+			if c.copies != tt.wantCopies || len(errs) != tt.wantErrors {
+				t.Errorf("copies, errors = %d, %v, want %d, %d", c.copies, errs, tt.wantCopies, tt.wantErrors)
+			}
+			if tt.wantErrors == 0 && (len(c.rows) != 2 || c.tables[0] != `"audit"."logs"`) {
+				t.Errorf("copied %d rows into %v, want 2 into audit.logs", len(c.rows), c.tables)
+			}
+		})
+	}
+}
+
+func TestNewCreateTable(t *testing.T) {
+	c := &conn{}
+	s, err := New(context.Background(), c, Table("audit.logs"), CreateTable())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if len(c.execs) != 1 || !strings.HasPrefix(c.execs[0], `CREATE TABLE IF NOT EXISTS "audit"."logs" (`) {
+		t.Errorf("executed %q", c.execs)
+	}
+}
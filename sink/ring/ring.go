@@ -0,0 +1,130 @@
+// Package ring provides an in-memory sink retaining the most recent entries
+// and an http.Handler dumping them, so recent debug output can be inspected
+// on demand without raising the level of the logger.
+package ring
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/level"
+)
+
+// Entry is a retained entry.
+type Entry struct {
+	Time   time.Time              `json:"time"`
+	Fields map[string]interface{} `json:"fields"`
+	level  string
+}
+
+// Buffer retains the last entries handed to it, overwriting the oldest once
+// it's full. It implements the go-kit log.Logger interface, combine it with
+// log.WithRecorder to retain entries of all levels:
+//
+//	buf := ring.New(1000)
+//	logger := log.NewLogger("info", log.WithRecorder(buf))
+//	http.Handle("/debug/logs", buf.Handler())
+type Buffer struct {
+	mtx     sync.Mutex
+	entries []Entry
+	next    int
+	full    bool
+}
+
+// New creates a Buffer retaining up to size entries.
+func New(size int) *Buffer {
+	if size < 1 {
+		size = 1
+	}
+	return &Buffer{entries: make([]Entry, size)}
+}
+
+// Log retains the entry.
+func (b *Buffer) Log(keyvals ...interface{}) error {
+	e := Entry{
+		Time:   time.Now(),
+		Fields: kv.Map(keyvals),
+	}
+	if lvl, ok := level.FromKeyvals(keyvals); ok {
+		e.level = lvl.String()
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.entries[b.next] = e
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+	return nil
+}
+
+// Entries returns the retained entries, oldest first.
+func (b *Buffer) Entries() []Entry {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if !b.full {
+		return append([]Entry(nil), b.entries[:b.next]...)
+	}
+	entries := make([]Entry, 0, len(b.entries))
+	entries = append(entries, b.entries[b.next:]...)
+	return append(entries, b.entries[:b.next]...)
+}
+
+// Reset drops all retained entries.
+func (b *Buffer) Reset() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	for i := range b.entries {
+		b.entries[i] = Entry{}
+	}
+	b.next = 0
+	b.full = false
+}
+
+// Handler returns an http.Handler responding with the retained entries as
+// JSON array, oldest first. The query parameter "level" restricts the dump
+// to entries of the given levels (e.g. ?level=debug&level=info), "limit"
+// to the most recent n entries.
+func (b *Buffer) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		entries := b.Entries()
+		if levels := r.URL.Query()["level"]; len(levels) > 0 {
+			allowed := make(map[string]bool, len(levels))
+			for _, l := range levels {
+				allowed[l] = true
+			}
+			filtered := entries[:0]
+			for _, e := range entries {
+				if allowed[e.level] {
+					filtered = append(filtered, e)
+				}
+			}
+			entries = filtered
+		}
+		if s := r.URL.Query().Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				http.Error(w, "invalid limit", http.StatusBadRequest)
+				return
+			}
+			if n < len(entries) {
+				entries = entries[len(entries)-n:]
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(entries)
+	})
+}
@@ -0,0 +1,93 @@
+package ring
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-godin/log/level"
+)
+
+func messages(entries []Entry) string {
+	var ms []string
+	for _, e := range entries {
+		ms = append(ms, fmt.Sprint(e.Fields["message"]))
+	}
+	return strings.Join(ms, ",")
+}
+
+func TestBuffer(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		entries int
+		want    string
+	}{
+		{name: "empty", size: 3, want: ""},
+		{name: "partially filled", size: 3, entries: 2, want: "0,1"},
+		{name: "full", size: 3, entries: 3, want: "0,1,2"},
+		{name: "wrapped", size: 3, entries: 5, want: "2,3,4"},
+		{name: "minimum size", size: 0, entries: 2, want: "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New(tt.size)
+			for i := 0; i < tt.entries; i++ {
+				_ = b.Log("message", i)
+			}
+			if got := messages(b.Entries()); got != tt.want {
+				t.Errorf("Entries() = %s, want %s", got, tt.want)
+			}
+			b.Reset()
+			if n := len(b.Entries()); n != 0 {
+				t.Errorf("%d entries retained after Reset", n)
+			}
+		})
+	}
+}
+
+func TestBufferHandler(t *testing.T) {
+	b := New(10)
+	_ = b.Log(level.Key(), level.DebugValue(), "message", "a")
+	_ = b.Log(level.Key(), level.InfoValue(), "message", "b")
+	_ = b.Log(level.Key(), level.ErrorValue(), "message", "c")
+	_ = b.Log("message", "d")
+
+	tests := []struct {
+		name       string
+		method     string
+		query      string
+		wantStatus int
+		want       string
+	}{
+		{name: "all", method: http.MethodGet, wantStatus: http.StatusOK, want: "a,b,c,d"},
+		{name: "level", method: http.MethodGet, query: "level=debug&level=error", wantStatus: http.StatusOK, want: "a,c"},
+		{name: "limit", method: http.MethodGet, query: "limit=2", wantStatus: http.StatusOK, want: "c,d"},
+		{name: "level and limit", method: http.MethodGet, query: "level=debug&level=info&limit=1", wantStatus: http.StatusOK, want: "b"},
+		{name: "limit beyond the entries", method: http.MethodGet, query: "limit=100", wantStatus: http.StatusOK, want: "a,b,c,d"},
+		{name: "invalid limit", method: http.MethodGet, query: "limit=-1", wantStatus: http.StatusBadRequest},
+		{name: "method not allowed", method: http.MethodPost, wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			b.Handler().ServeHTTP(rec, httptest.NewRequest(tt.method, "/debug/logs?"+tt.query, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var entries []Entry
+			if err := json.NewDecoder(rec.Body).Decode(&entries); err != nil {
+				t.Fatal(err)
+			}
+			if got := messages(entries); got != tt.want {
+				t.Errorf("dumped %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
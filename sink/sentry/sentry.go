@@ -0,0 +1,212 @@
+// Package sentry forwards Error (and Fatal) entries to Sentry as events.
+package sentry
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"time"
+
+	stdsentry "github.com/getsentry/sentry-go"
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/level"
+	"github.com/go-kit/kit/log"
+)
+
+// ErrFlushTimeout is returned by Flush if not all events could be delivered in time.
+var ErrFlushTimeout = errors.New("sentry: flush timed out")
+
+// sentryLevels maps the names of godin's levels to Sentry levels.
+var sentryLevels = map[string]stdsentry.Level{
+	"debug":   stdsentry.LevelDebug,
+	"info":    stdsentry.LevelInfo,
+	"warning": stdsentry.LevelWarning,
+	"error":   stdsentry.LevelError,
+	"fatal":   stdsentry.LevelFatal,
+	"panic":   stdsentry.LevelFatal,
+}
+
+// Sink captures entries of the configured levels as Sentry events. All other
+// entries are ignored. The remaining keyvals are attached as "fields"
+// context. It implements the go-kit log.Logger interface.
+type Sink struct {
+	hub          *stdsentry.Hub
+	levels       map[string]bool
+	sampleRate   float64
+	tagKeys      []string
+	errorKeys    []string
+	flushTimeout time.Duration
+	random       func() float64
+}
+
+// Option sets a parameter for the Sink.
+type Option func(*Sink)
+
+// Hub sets the hub events are captured with. Defaults to sentry.CurrentHub().
+func Hub(hub *stdsentry.Hub) Option {
+	return func(s *Sink) { s.hub = hub }
+}
+
+// Levels sets the names of the levels forwarded to Sentry.
+// Defaults to "error", "fatal" and "panic".
+func Levels(names ...string) Option {
+	return func(s *Sink) {
+		s.levels = make(map[string]bool, len(names))
+		for _, name := range names {
+			s.levels[name] = true
+		}
+	}
+}
+
+// SampleRate sets the share (0..1) of entries which are forwarded. Defaults to 1.
+func SampleRate(rate float64) Option {
+	return func(s *Sink) { s.sampleRate = rate }
+}
+
+// TagKeys sets the entry fields attached as tags instead of extra context.
+// Defaults to "trace_id" and "span_id".
+func TagKeys(keys ...string) Option {
+	return func(s *Sink) { s.tagKeys = keys }
+}
+
+// ErrorKeys sets the entry fields whose error values become the exception
+// of the event. Defaults to "err" and "error".
+func ErrorKeys(keys ...string) Option {
+	return func(s *Sink) { s.errorKeys = keys }
+}
+
+// FlushTimeout sets how long Flush waits for buffered events to be
+// delivered. Defaults to two seconds.
+func FlushTimeout(timeout time.Duration) Option {
+	return func(s *Sink) { s.flushTimeout = timeout }
+}
+
+// New creates a Sink capturing entries with the Sentry client of the hub,
+// which must be initialized using sentry.Init or sentry.NewClient.
+func New(opts ...Option) *Sink {
+	s := &Sink{
+		hub:          stdsentry.CurrentHub(),
+		sampleRate:   1,
+		tagKeys:      []string{"trace_id", "span_id"},
+		errorKeys:    []string{"err", "error"},
+		flushTimeout: 2 * time.Second,
+		random:       rand.Float64,
+	}
+	Levels("error", "fatal", "panic")(s)
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Hook returns a logger which captures entries with s and passes every entry
+// on to next, so Sentry can be added next to any other sink.
+func (s *Sink) Hook(next log.Logger) log.Logger {
+	return log.LoggerFunc(func(keyvals ...interface{}) error {
+		_ = s.Log(keyvals...)
+		return next.Log(keyvals...)
+	})
+}
+
+// Log captures the entry as Sentry event if its level is forwarded.
+func (s *Sink) Log(keyvals ...interface{}) error {
+	lvl, ok := level.FromKeyvals(keyvals)
+	if !ok || !s.levels[lvl.String()] {
+		return nil
+	}
+	if s.sampleRate < 1 && s.random() >= s.sampleRate {
+		return nil
+	}
+
+	s.hub.CaptureEvent(s.event(lvl, keyvals))
+	return nil
+}
+
+// Flush waits until all captured events are delivered or the flush timeout
+// passes. Call it before the process exits.
+func (s *Sink) Flush() error {
+	if !s.hub.Flush(s.flushTimeout) {
+		return ErrFlushTimeout
+	}
+	return nil
+}
+
+// Close flushes the sink.
+func (s *Sink) Close() error {
+	return s.Flush()
+}
+
+func (s *Sink) event(lvl level.Value, keyvals []interface{}) *stdsentry.Event {
+	event := stdsentry.NewEvent()
+	event.Level = sentryLevels[lvl.String()]
+	event.Logger = "godin"
+	event.Timestamp = time.Now()
+
+	fields := kv.Map(keyvals)
+	delete(fields, kv.Key(level.Key()))
+	if msg, ok := fields[kv.MessageKey]; ok {
+		event.Message = kv.String(msg)
+		delete(fields, kv.MessageKey)
+	}
+
+	for _, key := range s.tagKeys {
+		if v, ok := fields[key]; ok {
+			event.Tags[key] = kv.String(v)
+			delete(fields, key)
+		}
+	}
+
+	for _, key := range s.errorKeys {
+		err := errorValue(keyvals, key)
+		if err == nil {
+			continue
+		}
+		event.Exception = append(event.Exception, exceptions(err)...)
+		delete(fields, key)
+	}
+	if event.Message == "" && len(event.Exception) > 0 {
+		event.Message = event.Exception[0].Value
+	}
+
+	if len(fields) > 0 {
+		event.Contexts["fields"] = fields
+	}
+	return event
+}
+
+// errorValue returns the error stored under key. It uses the raw keyvals as
+// the fields only contain the error message.
+func errorValue(keyvals []interface{}, key string) error {
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if kv.Key(keyvals[i]) != key {
+			continue
+		}
+		if err, ok := keyvals[i+1].(error); ok && !isNil(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// exceptions returns an exception per constituent of multi-errors as created
+// by errors.Join, and a single exception for all other errors.
+func exceptions(err error) []stdsentry.Exception {
+	if u, ok := err.(interface{ Unwrap() []error }); ok {
+		var list []stdsentry.Exception
+		for _, e := range u.Unwrap() {
+			if e != nil {
+				list = append(list, exceptions(e)...)
+			}
+		}
+		return list
+	}
+	return []stdsentry.Exception{{
+		Type:  reflect.TypeOf(err).String(),
+		Value: err.Error(),
+	}}
+}
+
+func isNil(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
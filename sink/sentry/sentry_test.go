@@ -0,0 +1,185 @@
+package sentry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	stdsentry "github.com/getsentry/sentry-go"
+	"github.com/go-godin/log/level"
+	kitlog "github.com/go-kit/kit/log"
+)
+
+// transport records the events sent to Sentry.
+type transport struct {
+	mtx    sync.Mutex
+	events []*stdsentry.Event
+}
+
+func (t *transport) Flush(time.Duration) bool              { return true }
+func (t *transport) FlushWithContext(context.Context) bool { return true }
+func (t *transport) Configure(stdsentry.ClientOptions)     {}
+func (t *transport) Close()                                {}
+
+func (t *transport) SendEvent(event *stdsentry.Event) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.events = append(t.events, event)
+}
+
+func newHub(t *testing.T) (*stdsentry.Hub, *transport) {
+	t.Helper()
+	tr := &transport{}
+	client, err := stdsentry.NewClient(stdsentry.ClientOptions{Transport: tr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stdsentry.NewHub(client, stdsentry.NewScope()), tr
+}
+
+type customError struct{ msg string }
+
+func (e *customError) Error() string { return e.msg }
+
+func TestSink(t *testing.T) {
+	var nilErr *customError
+	tests := []struct {
+		name          string
+		opts          []Option
+		keyvals       []interface{}
+		wantEvent     bool
+		wantLevel     stdsentry.Level
+		wantMessage   string
+		wantTags      map[string]string
+		wantFields    map[string]interface{}
+		wantException []string // type: value
+	}{
+		{
+			name:    "info ignored",
+			keyvals: []interface{}{level.Key(), level.InfoValue(), "message", "started"},
+		},
+		{
+			name:    "no level ignored",
+			keyvals: []interface{}{"message", "started"},
+		},
+		{
+			name:        "error",
+			keyvals:     []interface{}{level.Key(), level.ErrorValue(), "message", "failed", "order", 7},
+			wantEvent:   true,
+			wantLevel:   stdsentry.LevelError,
+			wantMessage: "failed",
+			wantFields:  map[string]interface{}{"order": 7},
+		},
+		{
+			name:        "fatal",
+			keyvals:     []interface{}{level.Key(), level.FatalValue(), "message", "exiting"},
+			wantEvent:   true,
+			wantLevel:   stdsentry.LevelFatal,
+			wantMessage: "exiting",
+		},
+		{
+			name:        "configured levels",
+			opts:        []Option{Levels("warning")},
+			keyvals:     []interface{}{level.Key(), level.WarnValue(), "message", "slow"},
+			wantEvent:   true,
+			wantLevel:   stdsentry.LevelWarning,
+			wantMessage: "slow",
+		},
+		{
+			name:        "tags",
+			keyvals:     []interface{}{level.Key(), level.ErrorValue(), "message", "failed", "trace_id", "abc"},
+			wantEvent:   true,
+			wantLevel:   stdsentry.LevelError,
+			wantMessage: "failed",
+			wantTags:    map[string]string{"trace_id": "abc"},
+		},
+		{
+			name:          "exception",
+			keyvals:       []interface{}{level.Key(), level.ErrorValue(), "err", errors.New("timeout")},
+			wantEvent:     true,
+			wantLevel:     stdsentry.LevelError,
+			wantMessage:   "timeout",
+			wantException: []string{"*errors.errorString: timeout"},
+		},
+		{
+			name:          "joined errors",
+			keyvals:       []interface{}{level.Key(), level.ErrorValue(), "message", "failed", "error", errors.Join(errors.New("a"), fmt.Errorf("b: %w", &customError{"custom"}))},
+			wantEvent:     true,
+			wantLevel:     stdsentry.LevelError,
+			wantMessage:   "failed",
+			wantException: []string{"*errors.errorString: a", "*fmt.wrapError: b: custom"},
+		},
+		{
+			name:        "typed nil error",
+			keyvals:     []interface{}{level.Key(), level.ErrorValue(), "message", "failed", "err", nilErr},
+			wantEvent:   true,
+			wantLevel:   stdsentry.LevelError,
+			wantMessage: "failed",
+			wantFields:  map[string]interface{}{"err": nil},
+		},
+		{
+			name:    "sampled out",
+			opts:    []Option{SampleRate(0.5)},
+			keyvals: []interface{}{level.Key(), level.ErrorValue(), "message", "failed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub, tr := newHub(t)
+			s := New(append([]Option{Hub(hub)}, tt.opts...)...)
+			s.random = func() float64 { return 0.75 }
+			if err := s.Log(tt.keyvals...); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			if !tt.wantEvent {
+				if len(tr.events) != 0 {
+					t.Errorf("captured %d events, want none", len(tr.events))
+				}
+				return
+			}
+			if len(tr.events) != 1 {
+				t.Fatalf("captured %d events, want 1", len(tr.events))
+			}
+			event := tr.events[0]
+			if event.Level != tt.wantLevel || event.Message != tt.wantMessage || event.Logger != "godin" {
+				t.Errorf("level, message, logger = %s, %q, %s, want %s, %q, godin", event.Level, event.Message, event.Logger, tt.wantLevel, tt.wantMessage)
+			}
+			for k, v := range tt.wantTags {
+				if event.Tags[k] != v {
+					t.Errorf("tag %s = %q, want %q", k, event.Tags[k], v)
+				}
+			}
+			if fields := event.Contexts["fields"]; len(tt.wantFields) > 0 && !reflect.DeepEqual(map[string]interface{}(fields), tt.wantFields) {
+				t.Errorf("fields = %v, want %v", fields, tt.wantFields)
+			}
+			var exceptions []string
+			for _, e := range event.Exception {
+				exceptions = append(exceptions, e.Type+": "+e.Value)
+			}
+			if !reflect.DeepEqual(exceptions, tt.wantException) {
+				t.Errorf("exceptions = %q, want %q", exceptions, tt.wantException)
+			}
+		})
+	}
+}
+
+func TestSinkHook(t *testing.T) {
+	hub, tr := newHub(t)
+	var passed int
+	next := kitlog.LoggerFunc(func(...interface{}) error { passed++; return nil })
+	logger := New(Hub(hub)).Hook(next)
+	_ = logger.Log(level.Key(), level.InfoValue(), "message", "started")
+	_ = logger.Log(level.Key(), level.ErrorValue(), "message", "failed")
+	hub.Flush(time.Second)
+	if passed != 2 || len(tr.events) != 1 {
+		t.Errorf("passed %d entries and captured %d events, want 2 and 1", passed, len(tr.events))
+	}
+}
@@ -0,0 +1,277 @@
+// Package splunk provides a sink shipping entries to the Splunk HTTP Event
+// Collector (HEC) in batches.
+package splunk
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-godin/log/internal/batch"
+	"github.com/go-godin/log/internal/kv"
+)
+
+var (
+	// ErrQueueFull is returned by Log if the sink drops entries when its queue is full.
+	ErrQueueFull = errors.New("splunk: queue is full, entry dropped")
+	// ErrClosed is returned by Log after the sink has been closed.
+	ErrClosed = errors.New("splunk: sink is closed")
+)
+
+// Sink buffers entries and sends them to the event endpoint of the HEC.
+// It implements the go-kit log.Logger interface and can be passed to log.WithSink.
+type Sink struct {
+	url          string
+	token        string
+	index        string
+	source       string
+	sourceType   string
+	host         string
+	gzip         bool
+	client       *http.Client
+	batching     batch.Config
+	maxRetries   int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	errorHandler func(error)
+
+	batcher *batch.Batcher[[]byte]
+}
+
+// event is the HEC event envelope.
+type event struct {
+	Time       float64                `json:"time"`
+	Host       string                 `json:"host,omitempty"`
+	Source     string                 `json:"source,omitempty"`
+	SourceType string                 `json:"sourcetype,omitempty"`
+	Index      string                 `json:"index,omitempty"`
+	Event      map[string]interface{} `json:"event"`
+}
+
+// Option sets a parameter for the Sink.
+type Option func(*Sink)
+
+// Index sets the index events are stored in. Defaults to the default index of the token.
+func Index(index string) Option {
+	return func(s *Sink) { s.index = index }
+}
+
+// Source sets the source of the events.
+func Source(source string) Option {
+	return func(s *Sink) { s.source = source }
+}
+
+// SourceType sets the sourcetype of the events. Defaults to "_json".
+func SourceType(sourceType string) Option {
+	return func(s *Sink) { s.sourceType = sourceType }
+}
+
+// Host sets the host of the events. Defaults to the hostname.
+func Host(host string) Option {
+	return func(s *Sink) { s.host = host }
+}
+
+// Gzip compresses the request bodies.
+func Gzip() Option {
+	return func(s *Sink) { s.gzip = true }
+}
+
+// HTTPClient sets the client used for the requests. Defaults to http.DefaultClient.
+func HTTPClient(client *http.Client) Option {
+	return func(s *Sink) { s.client = client }
+}
+
+// BatchSize sets the maximum amount of entries and bytes sent in a single
+// request. Defaults to 500 entries and 1MB, the default limit of the HEC.
+func BatchSize(entries, bytes int) Option {
+	return batching(batch.MaxEntries(entries), batch.MaxBytes(bytes))
+}
+
+// FlushInterval sets the maximum time an entry is buffered before it's sent.
+// Zero sends batches only once they're full, on Flush and on Close. Defaults
+// to one second.
+func FlushInterval(interval time.Duration) Option {
+	return batching(batch.MaxLatency(interval))
+}
+
+// QueueSize sets the amount of entries buffered in memory while a request
+// is in flight. Once the queue is full, Log blocks unless DropWhenFull is
+// set. Defaults to 10000.
+func QueueSize(size int) Option {
+	return batching(batch.QueueSize(size))
+}
+
+// DropWhenFull instructs Log to drop entries and return ErrQueueFull instead of
+// blocking the caller if the queue is full.
+func DropWhenFull() Option {
+	return batching(batch.DropWhenFull())
+}
+
+// batching applies options of the batcher to the Sink.
+func batching(opts ...batch.Option) Option {
+	return func(s *Sink) {
+		for _, opt := range opts {
+			opt(&s.batching)
+		}
+	}
+}
+
+// Retry configures how often failed requests are retried. The delay between
+// attempts starts at backoff and doubles up to maxBackoff.
+// Defaults to 3 retries, starting at 100ms up to 5s.
+func Retry(maxRetries int, backoff, maxBackoff time.Duration) Option {
+	return func(s *Sink) {
+		s.maxRetries = maxRetries
+		s.backoff = backoff
+		s.maxBackoff = maxBackoff
+	}
+}
+
+// ErrorHandler sets the function called with errors which occur while
+// shipping entries. By default errors are written to stderr.
+func ErrorHandler(handler func(error)) Option {
+	return func(s *Sink) { s.errorHandler = handler }
+}
+
+// New creates a Sink sending to the HEC at url (e.g. "https://splunk:8088")
+// authenticated with the given token, and starts its background worker.
+func New(url, token string, opts ...Option) *Sink {
+	hostname, _ := os.Hostname()
+	s := &Sink{
+		url:        strings.TrimRight(url, "/") + "/services/collector/event",
+		token:      token,
+		sourceType: "_json",
+		host:       hostname,
+		client:     http.DefaultClient,
+		maxRetries: 3,
+		backoff:    100 * time.Millisecond,
+		maxBackoff: 5 * time.Second,
+		errorHandler: func(err error) {
+			fmt.Fprintf(os.Stderr, "splunk sink: %v\n", err)
+		},
+		batching: batch.Config{
+			MaxEntries:   500,
+			MaxBytes:     1 << 20,
+			MaxLatency:   time.Second,
+			QueueSize:    10000,
+			ErrQueueFull: ErrQueueFull,
+			ErrClosed:    ErrClosed,
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.batcher = batch.New(s.batching, func(body []byte) int { return len(body) }, s.send)
+
+	return s
+}
+
+// Log wraps the entry in a HEC event and enqueues it for the next request.
+func (s *Sink) Log(keyvals ...interface{}) error {
+	body, err := json.Marshal(event{
+		Time:       float64(time.Now().UnixNano()) / float64(time.Second),
+		Host:       s.host,
+		Source:     s.source,
+		SourceType: s.sourceType,
+		Index:      s.index,
+		Event:      kv.Map(keyvals),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.batcher.Add(body)
+}
+
+// Dropped returns the amount of entries dropped because the queue was full.
+func (s *Sink) Dropped() uint64 {
+	return s.batcher.Dropped()
+}
+
+// Flush sends all entries enqueued so far and blocks until the request is done.
+func (s *Sink) Flush() error {
+	s.batcher.Flush()
+	return nil
+}
+
+// Close stops accepting new entries, sends the remaining ones and stops the worker.
+func (s *Sink) Close() error {
+	s.batcher.Close()
+	return nil
+}
+
+// send posts the batch, retrying transport errors, throttling and server
+// side failures.
+func (s *Sink) send(events [][]byte) {
+	if len(events) == 0 {
+		return
+	}
+
+	// the HEC accepts concatenated events in a single request
+	var body bytes.Buffer
+	if s.gzip {
+		w := gzip.NewWriter(&body)
+		for _, b := range events {
+			_, _ = w.Write(b)
+		}
+		_ = w.Close()
+	} else {
+		for _, b := range events {
+			body.Write(b)
+		}
+	}
+
+	backoff := s.backoff
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			if backoff *= 2; backoff > s.maxBackoff {
+				backoff = s.maxBackoff
+			}
+		}
+
+		retry, err := s.post(body.Bytes())
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= s.maxRetries {
+			s.errorHandler(fmt.Errorf("dropping %d entries after %d attempts: %v", len(events), attempt+1, err))
+			return
+		}
+	}
+}
+
+// post sends a single request. It reports whether a failure is worth retrying.
+func (s *Sink) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Splunk "+s.token)
+	if s.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("request failed with status %d: %s", resp.StatusCode, msg)
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
@@ -0,0 +1,149 @@
+package splunk
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type response struct {
+	status int
+	body   string
+}
+
+func TestSinkSend(t *testing.T) {
+	ok := response{http.StatusOK, `{"text":"Success","code":0}`}
+	tests := []struct {
+		name      string
+		responses []response
+		requests  int
+		errors    int
+	}{
+		{name: "accepted", responses: []response{ok}, requests: 1},
+		{name: "busy retried", responses: []response{{http.StatusServiceUnavailable, `{"text":"Server is busy","code":9}`}, ok}, requests: 2},
+		{name: "throttled retried", responses: []response{{http.StatusTooManyRequests, ""}, ok}, requests: 2},
+		{name: "retries exhausted", responses: []response{{http.StatusServiceUnavailable, ""}}, requests: 3, errors: 1},
+		{name: "invalid token dropped", responses: []response{{http.StatusForbidden, `{"text":"Invalid token","code":4}`}}, requests: 1, errors: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mtx      sync.Mutex
+				requests int
+				errors   int
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mtx.Lock()
+				resp := tt.responses[min(requests, len(tt.responses)-1)]
+				requests++
+				mtx.Unlock()
+				if r.URL.Path != "/services/collector/event" || r.Header.Get("Authorization") != "Splunk secret" {
+					t.Errorf("request to %s authorized with %q", r.URL.Path, r.Header.Get("Authorization"))
+				}
+				w.WriteHeader(resp.status)
+				_, _ = w.Write([]byte(resp.body))
+			}))
+			defer srv.Close()
+
+			s := New(srv.URL+"/", "secret",
+				FlushInterval(0),
+				Retry(2, time.Millisecond, time.Millisecond),
+				ErrorHandler(func(error) { mtx.Lock(); errors++; mtx.Unlock() }),
+			)
+			if err := s.Log("message", "m"); err != nil {
+				t.Fatal(err)
+			}
+			_ = s.Close()
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			if requests != tt.requests || errors != tt.errors {
+				t.Errorf("requests, errors = %d, %d, want %d, %d", requests, errors, tt.requests, tt.errors)
+			}
+		})
+	}
+}
+
+func TestSinkEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		wantGzip bool
+		want     event
+	}{
+		{
+			name: "defaults",
+			opts: []Option{Host("web-1")},
+			want: event{Host: "web-1", SourceType: "_json"},
+		},
+		{
+			name: "metadata",
+			opts: []Option{Host("web-1"), Index("main"), Source("orders"), SourceType("godin")},
+			want: event{Host: "web-1", Source: "orders", SourceType: "godin", Index: "main"},
+		},
+		{
+			name:     "compressed",
+			opts:     []Option{Host("web-1"), Gzip()},
+			wantGzip: true,
+			want:     event{Host: "web-1", SourceType: "_json"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bodies := make(chan []event, 1)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body io.Reader = r.Body
+				if gzipped := r.Header.Get("Content-Encoding") == "gzip"; gzipped != tt.wantGzip {
+					t.Errorf("gzip compressed = %v, want %v", gzipped, tt.wantGzip)
+				} else if gzipped {
+					gz, err := gzip.NewReader(r.Body)
+					if err != nil {
+						t.Errorf("decompressing: %v", err)
+						return
+					}
+					body = gz
+				}
+				var events []event
+				dec := json.NewDecoder(body)
+				for dec.More() {
+					var e event
+					if err := dec.Decode(&e); err != nil {
+						t.Errorf("decoding: %v", err)
+						break
+					}
+					events = append(events, e)
+				}
+				bodies <- events
+			}))
+			defer srv.Close()
+
+			s := New(srv.URL, "secret", append([]Option{FlushInterval(0)}, tt.opts...)...)
+			begin := time.Now()
+			_ = s.Log("message", "a")
+			_ = s.Log("message", "b", "n", 1)
+			_ = s.Close()
+
+			events := <-bodies
+			if len(events) != 2 {
+				t.Fatalf("sent %d events in a request, want 2", len(events))
+			}
+			for i, e := range events {
+				if e.Time < float64(begin.Unix()) {
+					t.Errorf("event %d: time = %f, want after %d", i, e.Time, begin.Unix())
+				}
+				if e.Host != tt.want.Host || e.Source != tt.want.Source || e.SourceType != tt.want.SourceType || e.Index != tt.want.Index {
+					t.Errorf("event %d: host, source, sourcetype, index = %q, %q, %q, %q, want %q, %q, %q, %q", i,
+						e.Host, e.Source, e.SourceType, e.Index, tt.want.Host, tt.want.Source, tt.want.SourceType, tt.want.Index)
+				}
+			}
+			if events[1].Event["message"] != "b" || events[1].Event["n"] != float64(1) {
+				t.Errorf("event = %v", events[1].Event)
+			}
+		})
+	}
+}
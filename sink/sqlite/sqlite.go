@@ -0,0 +1,353 @@
+// Package sqlite provides a sink persisting entries into a local SQLite
+// database, e.g. for desktop or edge applications whose users export the
+// diagnostics on demand. The database is opened by the caller with the
+// driver of their choice (e.g. modernc.org/sqlite or mattn/go-sqlite3).
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/go-godin/log/internal/batch"
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/level"
+	"github.com/go-godin/log/retention"
+)
+
+var (
+	// ErrQueueFull is returned by Log if the sink drops entries when its queue is full.
+	ErrQueueFull = errors.New("sqlite: queue is full, entry dropped")
+	// ErrClosed is returned by Log after the sink has been closed.
+	ErrClosed = errors.New("sqlite: sink is closed")
+)
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Sink writes entries in batches, each in a single transaction, and prunes
+// expired rows periodically. It implements the go-kit log.Logger interface
+// and can be passed to log.WithSink.
+//
+// Entries are stored in a table with the columns ts (unix nanoseconds),
+// level, message, fields (all other fields as JSON object) and expires_at
+// (unix nanoseconds, NULL if the entry never expires).
+type Sink struct {
+	db            *sql.DB
+	table         string
+	maxAge        time.Duration
+	maxRows       int64
+	pruneInterval time.Duration
+	batching      batch.Config
+	errorHandler  func(error)
+
+	batcher *batch.Batcher[row]
+	// writes serializes the inserts of the worker and Prune
+	writes    sync.Mutex
+	closeOnce sync.Once
+	stop      chan struct{}
+	pruned    chan struct{}
+}
+
+type row struct {
+	ts      int64
+	level   string
+	message string
+	fields  []byte
+	expires sql.NullInt64
+}
+
+// Option sets a parameter for the Sink.
+type Option func(*Sink)
+
+// Table sets the name of the table. Defaults to "logs".
+func Table(name string) Option {
+	return func(s *Sink) { s.table = name }
+}
+
+// MaxAge sets how long entries without a retention field are kept. Entries
+// carrying a retention field (see package retention) expire accordingly.
+// Defaults to 30 days, zero keeps them forever.
+func MaxAge(age time.Duration) Option {
+	return func(s *Sink) { s.maxAge = age }
+}
+
+// MaxRows bounds the amount of stored entries, the oldest are pruned first.
+// Defaults to zero, which disables the bound.
+func MaxRows(n int64) Option {
+	return func(s *Sink) { s.maxRows = n }
+}
+
+// PruneInterval sets how often expired entries are pruned. Zero disables
+// pruning, except by calling Prune. Defaults to one hour.
+func PruneInterval(interval time.Duration) Option {
+	return func(s *Sink) { s.pruneInterval = interval }
+}
+
+// BatchSize sets the maximum amount of entries written in a single
+// transaction. Defaults to 500.
+func BatchSize(entries int) Option {
+	return batching(batch.MaxEntries(entries))
+}
+
+// FlushInterval sets the maximum time an entry is buffered before it's
+// written. Zero writes batches only once they're full, on Flush and on
+// Close. Defaults to one second.
+func FlushInterval(interval time.Duration) Option {
+	return batching(batch.MaxLatency(interval))
+}
+
+// QueueSize sets the amount of entries buffered in memory while a batch is
+// being written. Once the queue is full, Log blocks unless DropWhenFull is
+// set. Defaults to 10000.
+func QueueSize(size int) Option {
+	return batching(batch.QueueSize(size))
+}
+
+// DropWhenFull instructs Log to drop entries and return ErrQueueFull instead of
+// blocking the caller if the queue is full.
+func DropWhenFull() Option {
+	return batching(batch.DropWhenFull())
+}
+
+// batching applies options of the batcher to the Sink.
+func batching(opts ...batch.Option) Option {
+	return func(s *Sink) {
+		for _, opt := range opts {
+			opt(&s.batching)
+		}
+	}
+}
+
+// ErrorHandler sets the function called with errors which occur while
+// writing or pruning entries. By default errors are written to stderr.
+func ErrorHandler(handler func(error)) Option {
+	return func(s *Sink) { s.errorHandler = handler }
+}
+
+// New creates the table unless it exists and starts the background worker.
+func New(db *sql.DB, opts ...Option) (*Sink, error) {
+	s := &Sink{
+		db:            db,
+		table:         "logs",
+		maxAge:        30 * 24 * time.Hour,
+		pruneInterval: time.Hour,
+		errorHandler: func(err error) {
+			fmt.Fprintf(os.Stderr, "sqlite sink: %v\n", err)
+		},
+		batching: batch.Config{
+			MaxEntries:   500,
+			MaxLatency:   time.Second,
+			QueueSize:    10000,
+			ErrQueueFull: ErrQueueFull,
+			ErrClosed:    ErrClosed,
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if !identifier.MatchString(s.table) {
+		return nil, fmt.Errorf("sqlite: invalid table name %q", s.table)
+	}
+
+	schema := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			ts INTEGER NOT NULL,
+			level TEXT NOT NULL,
+			message TEXT NOT NULL,
+			fields TEXT NOT NULL,
+			expires_at INTEGER
+		)`, s.table),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %[1]s_ts ON %[1]s (ts)`, s.table),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %[1]s_expires_at ON %[1]s (expires_at)`, s.table),
+	}
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, fmt.Errorf("sqlite: creating schema: %v", err)
+		}
+	}
+
+	s.batcher = batch.New(s.batching, func(row) int { return 0 }, s.write)
+	s.stop = make(chan struct{})
+	s.pruned = make(chan struct{})
+	go s.prune()
+
+	return s, nil
+}
+
+// Log encodes the entry and enqueues it for the next batch.
+func (s *Sink) Log(keyvals ...interface{}) error {
+	now := time.Now()
+	fields := kv.Map(keyvals)
+	r := row{ts: now.UnixNano()}
+	if lvl, ok := level.FromKeyvals(keyvals); ok {
+		r.level = lvl.String()
+		delete(fields, kv.Key(level.Key()))
+	}
+	if v, ok := fields[kv.MessageKey]; ok {
+		r.message = kv.String(v)
+		delete(fields, kv.MessageKey)
+	}
+	if age := s.retention(keyvals); age > 0 {
+		r.expires = sql.NullInt64{Int64: now.Add(age).UnixNano(), Valid: true}
+	}
+	var err error
+	if r.fields, err = json.Marshal(fields); err != nil {
+		return err
+	}
+	return s.batcher.Add(r)
+}
+
+// Dropped returns the amount of entries dropped because the queue was full.
+func (s *Sink) Dropped() uint64 {
+	return s.batcher.Dropped()
+}
+
+// Flush writes all entries enqueued so far and blocks until they're committed.
+func (s *Sink) Flush() error {
+	s.batcher.Flush()
+	return nil
+}
+
+// Close stops accepting new entries, writes the remaining ones and stops the
+// worker and the pruning. The database stays open.
+func (s *Sink) Close() error {
+	s.batcher.Close()
+	s.closeOnce.Do(func() { close(s.stop) })
+	<-s.pruned
+	return nil
+}
+
+// Export writes the entries stored since the given time as JSON lines to w,
+// oldest first, e.g. to attach them to a support request.
+func (s *Sink) Export(ctx context.Context, w io.Writer, since time.Time) error {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(
+		`SELECT ts, level, message, fields FROM %s WHERE ts >= ? ORDER BY ts, id`, s.table),
+		since.UnixNano())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	enc := json.NewEncoder(w)
+	for rows.Next() {
+		var (
+			ts             int64
+			lvl, msg, data string
+		)
+		if err := rows.Scan(&ts, &lvl, &msg, &data); err != nil {
+			return err
+		}
+		entry := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			return err
+		}
+		entry["time"] = time.Unix(0, ts).UTC()
+		if lvl != "" {
+			entry[kv.Key(level.Key())] = lvl
+		}
+		if msg != "" {
+			entry[kv.MessageKey] = msg
+		}
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Prune deletes expired entries, and the oldest entries exceeding MaxRows.
+func (s *Sink) Prune(ctx context.Context) error {
+	s.writes.Lock()
+	defer s.writes.Unlock()
+
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(
+		`DELETE FROM %s WHERE expires_at IS NOT NULL AND expires_at < ?`, s.table),
+		time.Now().UnixNano()); err != nil {
+		return err
+	}
+	if s.maxRows > 0 {
+		if _, err := s.db.ExecContext(ctx, fmt.Sprintf(
+			`DELETE FROM %[1]s WHERE id <= (SELECT id FROM %[1]s ORDER BY id DESC LIMIT 1 OFFSET ?)`, s.table),
+			s.maxRows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retention returns how long the entry is kept, zero meaning forever.
+func (s *Sink) retention(keyvals []interface{}) time.Duration {
+	policy, ok := retention.FromKeyvals(keyvals)
+	if !ok {
+		return s.maxAge
+	}
+	age, err := retention.Parse(policy)
+	if err != nil {
+		return s.maxAge
+	}
+	return age
+}
+
+// prune calls Prune every PruneInterval until the sink is closed.
+func (s *Sink) prune() {
+	defer close(s.pruned)
+	if s.pruneInterval <= 0 {
+		<-s.stop
+		return
+	}
+
+	ticker := time.NewTicker(s.pruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Prune(context.Background()); err != nil {
+				s.errorHandler(fmt.Errorf("pruning: %v", err))
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// write inserts the batch in a single transaction.
+func (s *Sink) write(rows []row) {
+	if len(rows) == 0 {
+		return
+	}
+	s.writes.Lock()
+	defer s.writes.Unlock()
+	if err := s.insert(rows); err != nil {
+		s.errorHandler(fmt.Errorf("dropping %d entries: %v", len(rows), err))
+	}
+}
+
+func (s *Sink) insert(rows []row) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(fmt.Sprintf(
+		`INSERT INTO %s (ts, level, message, fields, expires_at) VALUES (?, ?, ?, ?, ?)`, s.table))
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, r := range rows {
+		if _, err := stmt.Exec(r.ts, r.level, r.message, string(r.fields), r.expires); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,179 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-godin/log/level"
+	"github.com/go-godin/log/retention"
+	_ "modernc.org/sqlite"
+)
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection opens a database of its own
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newSink(t *testing.T, db *sql.DB, opts ...Option) *Sink {
+	t.Helper()
+	s, err := New(db, append([]Option{FlushInterval(0), PruneInterval(0)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func export(t *testing.T, s *Sink, since time.Time) []map[string]interface{} {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := s.Export(context.Background(), &buf, since); err != nil {
+		t.Fatal(err)
+	}
+	var entries []map[string]interface{}
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var entry map[string]interface{}
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestSinkExport(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyvals []interface{}
+		want    map[string]interface{}
+	}{
+		{
+			name:    "level and message",
+			keyvals: []interface{}{level.Key(), level.WarnValue(), "message", "slow", "ms", 250},
+			want:    map[string]interface{}{"severity": "warning", "message": "slow", "ms": float64(250)},
+		},
+		{
+			name:    "fields only",
+			keyvals: []interface{}{"user", "jane"},
+			want:    map[string]interface{}{"user": "jane"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSink(t, openDB(t))
+			begin := time.Now()
+			if err := s.Log(tt.keyvals...); err != nil {
+				t.Fatal(err)
+			}
+			s.Flush()
+
+			entries := export(t, s, begin)
+			if len(entries) != 1 {
+				t.Fatalf("exported %d entries, want 1", len(entries))
+			}
+			entry := entries[0]
+			ts, err := time.Parse(time.RFC3339Nano, entry["time"].(string))
+			if err != nil || ts.Before(begin) {
+				t.Errorf("time = %v, want after %v", entry["time"], begin)
+			}
+			delete(entry, "time")
+			if len(entry) != len(tt.want) {
+				t.Errorf("exported %v, want %v", entry, tt.want)
+			}
+			for k, v := range tt.want {
+				if entry[k] != v {
+					t.Errorf("%s = %v, want %v", k, entry[k], v)
+				}
+			}
+			if later := export(t, s, time.Now()); len(later) != 0 {
+				t.Errorf("exported %v since now", later)
+			}
+		})
+	}
+}
+
+func TestSinkPrune(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		entries [][]interface{}
+		want    []string
+	}{
+		{
+			name:    "kept",
+			entries: [][]interface{}{{"message", "a"}, {"message", "b"}},
+			want:    []string{"a", "b"},
+		},
+		{
+			name:    "expired by max age",
+			opts:    []Option{MaxAge(time.Nanosecond)},
+			entries: [][]interface{}{{"message", "a"}, {"message", "b", retention.Key, "30d"}},
+			want:    []string{"b"},
+		},
+		{
+			name:    "kept forever",
+			opts:    []Option{MaxAge(0)},
+			entries: [][]interface{}{{"message", "a"}},
+			want:    []string{"a"},
+		},
+		{
+			name:    "invalid retention",
+			opts:    []Option{MaxAge(time.Nanosecond)},
+			entries: [][]interface{}{{"message", "a", retention.Key, "forever"}},
+		},
+		{
+			name:    "max rows",
+			opts:    []Option{MaxRows(2)},
+			entries: [][]interface{}{{"message", "a"}, {"message", "b"}, {"message", "c"}},
+			want:    []string{"b", "c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSink(t, openDB(t), tt.opts...)
+			for _, keyvals := range tt.entries {
+				_ = s.Log(keyvals...)
+			}
+			s.Flush()
+			time.Sleep(time.Millisecond)
+			if err := s.Prune(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, entry := range export(t, s, time.Unix(0, 0)) {
+				got = append(got, entry["message"].(string))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("kept %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTable(t *testing.T) {
+	db := openDB(t)
+	if _, err := New(db, Table("logs; DROP TABLE users")); err == nil {
+		t.Error("New accepted an invalid table name")
+	}
+	s := newSink(t, db, Table("diagnostics"))
+	_ = s.Log("message", "a")
+	s.Flush()
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM diagnostics`).Scan(&n); err != nil || n != 1 {
+		t.Errorf("diagnostics has %d rows (%v), want 1", n, err)
+	}
+}
@@ -0,0 +1,87 @@
+// Package zerolog provides a sink encoding entries with zerolog's
+// zero-allocation JSON encoder, as a faster replacement of the go-kit JSON
+// logger used by default. The output keeps the format of the godin logger.
+package zerolog
+
+import (
+	"io"
+
+	"github.com/go-godin/log/internal/kv"
+	stdzerolog "github.com/rs/zerolog"
+)
+
+// Sink writes entries as JSON lines through a zerolog.Logger. It implements
+// the go-kit log.Logger interface:
+//
+//	logger := log.NewLogger("info", log.WithSink(zerolog.New(os.Stdout)))
+type Sink struct {
+	logger stdzerolog.Logger
+}
+
+// New creates a Sink writing to w. Writes are serialized.
+func New(w io.Writer) *Sink {
+	return &Sink{logger: stdzerolog.New(stdzerolog.SyncWriter(w))}
+}
+
+// NewWithLogger creates a Sink writing through logger, e.g. one configured
+// with timestamps or hooks. The level of logger is ignored, the godin level
+// filter applies.
+func NewWithLogger(logger stdzerolog.Logger) *Sink {
+	return &Sink{logger: logger}
+}
+
+// Log encodes the entry. The level is written as severity, keyvals of common
+// types are encoded without reflection.
+func (s *Sink) Log(keyvals ...interface{}) error {
+	e := s.logger.Log()
+	for i := 0; i < len(keyvals); i += 2 {
+		var value interface{} = "(MISSING)"
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+		encode(e, kv.Key(keyvals[i]), value)
+	}
+	e.Send()
+	return nil
+}
+
+// encode writes the value as the JSON sink of the godin logger does: values
+// implementing json.Marshaler or encoding.TextMarshaler, e.g. time.Time and
+// the multi-errors, encode themselves, other errors and fmt.Stringer are
+// written as strings, see kv.Value.
+func encode(e *stdzerolog.Event, key string, value interface{}) {
+	switch v := kv.Value(value).(type) {
+	case nil:
+		e.Interface(key, nil)
+	case string:
+		e.Str(key, v)
+	case bool:
+		e.Bool(key, v)
+	case int:
+		e.Int(key, v)
+	case int8:
+		e.Int8(key, v)
+	case int16:
+		e.Int16(key, v)
+	case int32:
+		e.Int32(key, v)
+	case int64:
+		e.Int64(key, v)
+	case uint:
+		e.Uint(key, v)
+	case uint8:
+		e.Uint8(key, v)
+	case uint16:
+		e.Uint16(key, v)
+	case uint32:
+		e.Uint32(key, v)
+	case uint64:
+		e.Uint64(key, v)
+	case float32:
+		e.Float32(key, v)
+	case float64:
+		e.Float64(key, v)
+	default:
+		e.Interface(key, v)
+	}
+}
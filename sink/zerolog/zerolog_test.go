@@ -0,0 +1,74 @@
+package zerolog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-godin/log"
+)
+
+// stringer is a fmt.Stringer, logged as its string.
+type stringer struct{}
+
+func (stringer) String() string { return "stringer" }
+
+// marshaler encodes itself, although it's an error.
+type marshaler struct{}
+
+func (marshaler) Error() string                { return "marshaler" }
+func (marshaler) MarshalJSON() ([]byte, error) { return []byte(`{"code":42}`), nil }
+
+// TestGolden compares the lines of the sink with the ones of the JSON sink of
+// the godin logger.
+func TestGolden(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"string", "value"},
+		{"escaped string", "<a & \"b\">\n"},
+		{"bool", true},
+		{"int", -42},
+		{"int8", int8(-8)},
+		{"uint8", uint8(8)},
+		{"uint64", uint64(1 << 63)},
+		{"float", 0.1},
+		{"large float", 1e21},
+		{"nil", nil},
+		{"time", time.Date(2024, 5, 1, 12, 30, 15, 123456789, time.UTC)},
+		{"duration", 1500 * time.Millisecond},
+		{"error", errors.New("failed")},
+		{"multi-error", errors.Join(errors.New("first"), errors.New("second"))},
+		{"marshaler error", marshaler{}},
+		{"stringer", stringer{}},
+		{"text marshaler", net.ParseIP("10.0.0.1")},
+		{"bytes", []byte("raw")},
+		{"slice", []string{"a", "b"}},
+		{"map", map[string]int{"a": 1}},
+		{"struct", struct{ A int }{A: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want, got bytes.Buffer
+			log.NewLogger(log.LevelInfo, log.WithOutput(&want, log.LevelInfo)).Info("test", "value", tt.value)
+			log.NewLogger(log.LevelInfo, log.WithSink(New(&got))).Info("test", "value", tt.value)
+
+			// the order of the fields and the escaping of strings may differ
+			var gotEntry, wantEntry map[string]interface{}
+			if err := json.Unmarshal(got.Bytes(), &gotEntry); err != nil {
+				t.Fatalf("decoding %s: %v", got.String(), err)
+			}
+			if err := json.Unmarshal(want.Bytes(), &wantEntry); err != nil {
+				t.Fatalf("decoding %s: %v", want.String(), err)
+			}
+			if !reflect.DeepEqual(gotEntry, wantEntry) {
+				t.Errorf("got %s, want %s", got.String(), want.String())
+			}
+		})
+	}
+}
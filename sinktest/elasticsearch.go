@@ -0,0 +1,103 @@
+//go:build integration
+
+package sinktest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	godin "github.com/go-godin/log"
+	"github.com/go-godin/log/sink/elasticsearch"
+	"github.com/ory/dockertest/v3"
+)
+
+// Elasticsearch validates that the Elasticsearch sink delivers all entries
+// into the templated indices, including entries logged while the cluster
+// was unresponsive.
+func Elasticsearch(t *testing.T) {
+	h := New(t)
+	resource := h.Run(&dockertest.RunOptions{
+		Repository: "elasticsearch",
+		Tag:        "7.17.22",
+		Env: []string{
+			"discovery.type=single-node",
+			"xpack.security.enabled=false",
+			"ES_JAVA_OPTS=-Xms512m -Xmx512m",
+		},
+	})
+	url := "http://" + resource.GetHostPort("9200/tcp")
+	h.WaitHTTP(url + "/_cluster/health?wait_for_status=yellow")
+
+	t.Run("delivery", func(t *testing.T) {
+		var errs Errors
+		sink, err := elasticsearch.New(url,
+			elasticsearch.Index("sinktest-{service}-{yyyy.MM.dd}"),
+			elasticsearch.FlushInterval(100*time.Millisecond),
+			elasticsearch.ErrorHandler(errs.Record),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		logger := godin.NewLogger("debug", godin.WithSink(sink)).With("service", "Delivery")
+		for i := 0; i < 50; i++ {
+			logger.Info("entry", "i", i)
+		}
+		sink.Close()
+
+		errs.Check(t)
+		if n := countDocuments(t, url, "sinktest-delivery-*"); n != 50 {
+			t.Errorf("expected 50 documents, got %d", n)
+		}
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		var errs Errors
+		sink, err := elasticsearch.New(url,
+			elasticsearch.Index("sinktest-retry"),
+			elasticsearch.HTTPClient(&http.Client{Timeout: time.Second}),
+			elasticsearch.Retry(10, 200*time.Millisecond, 2*time.Second),
+			elasticsearch.ErrorHandler(errs.Record),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		logger := godin.NewLogger("debug", godin.WithSink(sink))
+
+		outage := h.Outage(resource, 3*time.Second)
+		for i := 0; i < 20; i++ {
+			logger.Info("entry", "i", i)
+		}
+		_ = sink.Flush()
+		<-outage
+		sink.Close()
+
+		if n := countDocuments(t, url, "sinktest-retry"); n != 20 {
+			t.Errorf("expected 20 documents after the outage, got %d (errors: %v)", n, errs.All())
+		}
+	})
+}
+
+func countDocuments(t *testing.T, url, index string) int {
+	t.Helper()
+	resp, err := http.Post(fmt.Sprintf("%s/%s/_refresh", url, index), "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	resp, err = http.Get(fmt.Sprintf("%s/%s/_count", url, index))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Count int `json:"count"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	return result.Count
+}
@@ -0,0 +1,107 @@
+//go:build integration
+
+package sinktest
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	godin "github.com/go-godin/log"
+	"github.com/go-godin/log/sink/fluentd"
+	"github.com/ory/dockertest/v3"
+)
+
+// fluentdConfig accepts forward mode input and prints every event to stdout,
+// where the suite picks them up from the container logs.
+const fluentdConfig = `<source>
+  @type forward
+  port 24224
+  bind 0.0.0.0
+</source>
+<match **>
+  @type stdout
+</match>
+`
+
+// Fluentd validates that the fluentd sink delivers acknowledged chunks in
+// forward mode, including entries logged while the aggregator was
+// unresponsive.
+func Fluentd(t *testing.T) {
+	h := New(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "fluent.conf"), []byte(fluentdConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	resource := h.Run(&dockertest.RunOptions{
+		Repository: "fluent/fluentd",
+		Tag:        "v1.16-1",
+		Env:        []string{"FLUENTD_CONF=fluent.conf"},
+		Mounts:     []string{dir + ":/fluentd/etc"},
+	})
+	address := resource.GetHostPort("24224/tcp")
+	h.Wait("fluentd", func() error {
+		conn, err := net.DialTimeout("tcp", address, time.Second)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	})
+
+	t.Run("delivery", func(t *testing.T) {
+		var errs Errors
+		sink := fluentd.New(address,
+			fluentd.Tag("sinktest.delivery"),
+			fluentd.RequireAck(),
+			fluentd.FlushInterval(100*time.Millisecond),
+			fluentd.ErrorHandler(errs.Record),
+		)
+		logger := godin.NewLogger("debug", godin.WithSink(sink))
+		for i := 0; i < 50; i++ {
+			logger.Info("entry", "i", i)
+		}
+		sink.Close()
+
+		errs.Check(t)
+		waitForLines(t, h, resource, "sinktest.delivery", 50)
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		var errs Errors
+		sink := fluentd.New(address,
+			fluentd.Tag("sinktest.retry"),
+			fluentd.RequireAck(),
+			fluentd.Timeout(time.Second),
+			fluentd.Retry(10, 200*time.Millisecond, 2*time.Second),
+			fluentd.ErrorHandler(errs.Record),
+		)
+		logger := godin.NewLogger("debug", godin.WithSink(sink))
+
+		outage := h.Outage(resource, 3*time.Second)
+		for i := 0; i < 20; i++ {
+			logger.Info("entry", "i", i)
+		}
+		_ = sink.Flush()
+		<-outage
+		sink.Close()
+
+		// a chunk which was received but not acknowledged during the outage
+		// is resent, so at least all entries must be there
+		waitForLines(t, h, resource, "sinktest.retry", 20)
+	})
+}
+
+// waitForLines waits until the container printed at least n events with tag.
+func waitForLines(t *testing.T, h *Harness, resource *dockertest.Resource, tag string, n int) {
+	t.Helper()
+	h.Wait(tag, func() error {
+		if got := strings.Count(h.Logs(resource), " "+tag+": "); got < n {
+			return fmt.Errorf("got %d of %d events", got, n)
+		}
+		return nil
+	})
+}
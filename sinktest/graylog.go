@@ -0,0 +1,179 @@
+//go:build integration
+
+package sinktest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	godin "github.com/go-godin/log"
+	"github.com/go-godin/log/sink/gelf"
+	"github.com/ory/dockertest/v3"
+)
+
+const graylogPassword = "sinktest"
+
+// GELF validates that the GELF sink delivers chunked, compressed UDP
+// messages as well as null byte delimited TCP messages to Graylog.
+func GELF(t *testing.T) {
+	h := New(t)
+	network := h.Network("sinktest-graylog")
+	suffix := randomHex(t, 4)
+
+	mongo := "mongo-" + suffix
+	h.Run(&dockertest.RunOptions{
+		Name:       mongo,
+		Repository: "mongo",
+		Tag:        "6.0",
+		Networks:   []*dockertest.Network{network},
+	})
+	opensearch := "opensearch-" + suffix
+	h.Run(&dockertest.RunOptions{
+		Name:       opensearch,
+		Repository: "opensearchproject/opensearch",
+		Tag:        "2.11.1",
+		Networks:   []*dockertest.Network{network},
+		Env: []string{
+			"discovery.type=single-node",
+			"plugins.security.disabled=true",
+			"OPENSEARCH_JAVA_OPTS=-Xms512m -Xmx512m",
+		},
+	})
+
+	sum := sha256.Sum256([]byte(graylogPassword))
+	graylog := h.Run(&dockertest.RunOptions{
+		Repository:   "graylog/graylog",
+		Tag:          "5.2",
+		Networks:     []*dockertest.Network{network},
+		ExposedPorts: []string{"9000/tcp", "12201/tcp", "12201/udp"},
+		Env: []string{
+			"GRAYLOG_PASSWORD_SECRET=sinktest-password-secret",
+			"GRAYLOG_ROOT_PASSWORD_SHA2=" + hex.EncodeToString(sum[:]),
+			"GRAYLOG_HTTP_EXTERNAL_URI=http://127.0.0.1:9000/",
+			"GRAYLOG_ELASTICSEARCH_HOSTS=http://" + opensearch + ":9200",
+			"GRAYLOG_MONGODB_URI=mongodb://" + mongo + ":27017/graylog",
+		},
+	})
+	api := "http://" + graylog.GetHostPort("9000/tcp") + "/api"
+	h.WaitHTTP(api + "/system/lbstatus")
+
+	createInput(t, api, "org.graylog2.inputs.gelf.udp.GELFUDPInput", map[string]interface{}{
+		"bind_address":          "0.0.0.0",
+		"port":                  12201,
+		"recv_buffer_size":      262144,
+		"decompress_size_limit": 8388608,
+	})
+	createInput(t, api, "org.graylog2.inputs.gelf.tcp.GELFTCPInput", map[string]interface{}{
+		"bind_address":          "0.0.0.0",
+		"port":                  12201,
+		"recv_buffer_size":      1048576,
+		"use_null_delimiter":    true,
+		"max_message_size":      2097152,
+		"decompress_size_limit": 8388608,
+	})
+
+	t.Run("udp", func(t *testing.T) {
+		// a small chunk size and a large incompressible field force
+		// every message to be split into several chunks
+		sink, err := gelf.New("udp://"+graylog.GetHostPort("12201/udp"), gelf.ChunkSize(512))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sink.Close()
+
+		run := randomHex(t, 8)
+		logger := godin.NewLogger("debug", godin.WithSink(sink)).With("run", run)
+		h.Wait("gelf udp input", func() error {
+			// UDP input may not be bound yet, so keep sending until it shows up
+			logger.Info("chunked", "payload", randomHex(t, 2048))
+			return expectMessages(api, run, 1)
+		})
+	})
+
+	t.Run("tcp", func(t *testing.T) {
+		sink, err := gelf.New("tcp://" + graylog.GetHostPort("12201/tcp"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sink.Close()
+
+		run := randomHex(t, 8)
+		logger := godin.NewLogger("debug", godin.WithSink(sink)).With("run", run)
+		h.Wait("gelf tcp input", func() error {
+			return sink.Log("message", "probe")
+		})
+		for i := 0; i < 10; i++ {
+			logger.Info("framed", "i", i)
+		}
+		h.Wait("gelf tcp messages", func() error {
+			return expectMessages(api, run, 10)
+		})
+	})
+}
+
+func createInput(t *testing.T, api, kind string, configuration map[string]interface{}) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]interface{}{
+		"title":         "sinktest " + kind,
+		"type":          kind,
+		"global":        true,
+		"configuration": configuration,
+	})
+	req, _ := http.NewRequest(http.MethodPost, api+"/system/inputs", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := graylogDo(req)
+	if err != nil {
+		t.Fatalf("creating input %s: %v", kind, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		t.Fatalf("creating input %s: status %d", kind, resp.StatusCode)
+	}
+}
+
+// expectMessages fails unless Graylog indexed at least n messages of run.
+func expectMessages(api, run string, n int) error {
+	query := url.Values{"query": {"run:" + run}, "range": {"600"}, "limit": {"0"}}
+	req, _ := http.NewRequest(http.MethodGet, api+"/search/universal/relative?"+query.Encode(), nil)
+	resp, err := graylogDo(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("search: status %d", resp.StatusCode)
+	}
+	var result struct {
+		TotalResults int `json:"total_results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if result.TotalResults < n {
+		return fmt.Errorf("found %d of %d messages", result.TotalResults, n)
+	}
+	return nil
+}
+
+func graylogDo(req *http.Request) (*http.Response, error) {
+	req.SetBasicAuth("admin", graylogPassword)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Requested-By", "sinktest")
+	return http.DefaultClient.Do(req)
+}
+
+func randomHex(t *testing.T, n int) string {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(b)
+}
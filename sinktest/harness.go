@@ -0,0 +1,163 @@
+//go:build integration
+
+// Package sinktest is an integration test harness for the network sinks. It
+// spins up the real backends in Docker containers using dockertest and
+// provides suites validating end-to-end delivery, framing and retry
+// behaviour of each sink, e.g.:
+//
+//	//go:build integration
+//
+//	func TestElasticsearch(t *testing.T) { sinktest.Elasticsearch(t) }
+//
+// The package is a module of its own, so importers of the logger don't
+// depend on Docker. It's only compiled with the integration build tag and
+// requires a reachable Docker daemon:
+//
+//	cd sinktest && go test -tags integration ./...
+package sinktest
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	dc "github.com/ory/dockertest/v3/docker"
+)
+
+// Harness manages the containers started for a single test.
+type Harness struct {
+	t    testing.TB
+	pool *dockertest.Pool
+}
+
+// New connects to the Docker daemon, skipping the test if it's unavailable.
+func New(t testing.TB) *Harness {
+	t.Helper()
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Skipf("docker is not available: %v", err)
+	}
+	if err := pool.Client.Ping(); err != nil {
+		t.Skipf("docker is not available: %v", err)
+	}
+	pool.MaxWait = 3 * time.Minute
+	return &Harness{t: t, pool: pool}
+}
+
+// Run starts a container which is purged once the test finished.
+func (h *Harness) Run(opts *dockertest.RunOptions) *dockertest.Resource {
+	h.t.Helper()
+	resource, err := h.pool.RunWithOptions(opts, func(hc *dc.HostConfig) {
+		hc.AutoRemove = true
+		hc.RestartPolicy = dc.RestartPolicy{Name: "no"}
+	})
+	if err != nil {
+		h.t.Fatalf("starting %s:%s: %v", opts.Repository, opts.Tag, err)
+	}
+	h.t.Cleanup(func() { _ = h.pool.Purge(resource) })
+	return resource
+}
+
+// Network creates a network shared by containers which need to talk to each other.
+func (h *Harness) Network(name string) *dockertest.Network {
+	h.t.Helper()
+	network, err := h.pool.CreateNetwork(fmt.Sprintf("%s-%d", name, time.Now().UnixNano()))
+	if err != nil {
+		h.t.Fatalf("creating network: %v", err)
+	}
+	h.t.Cleanup(func() { _ = network.Close() })
+	return network
+}
+
+// Wait retries op with exponential backoff until it succeeds or the pool's
+// maximum wait time passes.
+func (h *Harness) Wait(what string, op func() error) {
+	h.t.Helper()
+	if err := h.pool.Retry(op); err != nil {
+		h.t.Fatalf("waiting for %s: %v", what, err)
+	}
+}
+
+// WaitHTTP waits until url responds with a status below 300.
+func (h *Harness) WaitHTTP(url string) {
+	h.t.Helper()
+	h.Wait(url, func() error {
+		resp, err := http.Get(url)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("status %d", resp.StatusCode)
+		}
+		return nil
+	})
+}
+
+// Outage pauses the container for the given duration in the background,
+// simulating a backend which stopped responding. The returned channel is
+// closed once the container is running again.
+func (h *Harness) Outage(resource *dockertest.Resource, d time.Duration) <-chan struct{} {
+	h.t.Helper()
+	if err := h.pool.Client.PauseContainer(resource.Container.ID); err != nil {
+		h.t.Fatalf("pausing container: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		time.Sleep(d)
+		if err := h.pool.Client.UnpauseContainer(resource.Container.ID); err != nil {
+			h.t.Errorf("unpausing container: %v", err)
+		}
+	}()
+	return done
+}
+
+// Logs returns the stdout and stderr output of the container so far.
+func (h *Harness) Logs(resource *dockertest.Resource) string {
+	h.t.Helper()
+	var buf bytes.Buffer
+	err := h.pool.Client.Logs(dc.LogsOptions{
+		Container:    resource.Container.ID,
+		OutputStream: &buf,
+		ErrorStream:  &buf,
+		Stdout:       true,
+		Stderr:       true,
+	})
+	if err != nil {
+		h.t.Fatalf("reading container logs: %v", err)
+	}
+	return buf.String()
+}
+
+// Errors collects the errors reported to a sink's ErrorHandler.
+type Errors struct {
+	mtx  sync.Mutex
+	errs []error
+}
+
+// Record stores err, it can be passed as ErrorHandler option.
+func (e *Errors) Record(err error) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	e.errs = append(e.errs, err)
+}
+
+// All returns the errors recorded so far.
+func (e *Errors) All() []error {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	return append([]error(nil), e.errs...)
+}
+
+// Check fails the test if any error was recorded.
+func (e *Errors) Check(t testing.TB) {
+	t.Helper()
+	for _, err := range e.All() {
+		t.Errorf("sink reported: %v", err)
+	}
+}
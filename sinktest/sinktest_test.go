@@ -0,0 +1,11 @@
+//go:build integration
+
+package sinktest
+
+import "testing"
+
+func TestElasticsearch(t *testing.T) { Elasticsearch(t) }
+
+func TestFluentd(t *testing.T) { Fluentd(t) }
+
+func TestGELF(t *testing.T) { GELF(t) }
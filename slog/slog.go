@@ -0,0 +1,171 @@
+// Package slog connects the godin logger with the structured logger of the
+// standard library: Handler writes slog records through a Log, NewSink
+// writes the entries of a Log to a slog.Handler.
+package slog
+
+import (
+	"context"
+	stdslog "log/slog"
+	"time"
+
+	"github.com/go-godin/log"
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/level"
+	kitlog "github.com/go-kit/kit/log"
+)
+
+// Handler is a slog.Handler writing records through a Log, so they pass the
+// same level filter and sinks as the entries of the Log. Records are bound
+// to the span and fields of their context, see log.WithTrace. Attributes of
+// groups are flattened into keys joined by dots, e.g. "request.method".
+type Handler struct {
+	logger log.Log
+	attrs  []interface{}
+	prefix string
+}
+
+// NewHandler creates a Handler writing through logger:
+//
+//	slog.SetDefault(stdslog.New(slog.NewHandler(logger)))
+func NewHandler(logger log.Log) *Handler {
+	return &Handler{logger: logger}
+}
+
+// Enabled reports whether the Log accepts records of the level.
+func (h *Handler) Enabled(_ context.Context, lvl stdslog.Level) bool {
+	return h.logger.IsLevelEnabled(log.Level(levelFor(lvl).String()))
+}
+
+// Handle writes the record.
+func (h *Handler) Handle(ctx context.Context, r stdslog.Record) error {
+	keyvals := make([]interface{}, 0, len(h.attrs)+2*r.NumAttrs())
+	keyvals = append(keyvals, h.attrs...)
+	r.Attrs(func(a stdslog.Attr) bool {
+		keyvals = appendAttr(keyvals, h.prefix, a)
+		return true
+	})
+
+	l := h.logger
+	if ctx != nil {
+		l = l.WithTrace(ctx)
+	}
+	l.At(levelFor(r.Level), r.Message, append(keyvals, log.CallerPC(r.PC))...)
+	return nil
+}
+
+// WithAttrs returns a Handler adding attrs to all records.
+func (h *Handler) WithAttrs(attrs []stdslog.Attr) stdslog.Handler {
+	c := *h
+	c.attrs = append([]interface{}(nil), h.attrs...)
+	for _, a := range attrs {
+		c.attrs = appendAttr(c.attrs, h.prefix, a)
+	}
+	return &c
+}
+
+// WithGroup returns a Handler prefixing the keys of subsequent attributes
+// with name.
+func (h *Handler) WithGroup(name string) stdslog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.prefix = h.prefix + name + "."
+	return &c
+}
+
+// appendAttr appends the flattened attribute, skipping empty ones as slog
+// requires.
+func appendAttr(keyvals []interface{}, prefix string, a stdslog.Attr) []interface{} {
+	a.Value = a.Value.Resolve()
+	if a.Equal(stdslog.Attr{}) {
+		return keyvals
+	}
+	if a.Value.Kind() == stdslog.KindGroup {
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			keyvals = appendAttr(keyvals, groupPrefix, ga)
+		}
+		return keyvals
+	}
+	return append(keyvals, prefix+a.Key, a.Value.Any())
+}
+
+// levelFor maps a slog level to the nearest less severe godin level.
+func levelFor(lvl stdslog.Level) level.Value {
+	switch {
+	case lvl < stdslog.LevelInfo:
+		return level.DebugValue()
+	case lvl < stdslog.LevelWarn:
+		return level.InfoValue()
+	case lvl < stdslog.LevelError:
+		return level.WarnValue()
+	default:
+		return level.ErrorValue()
+	}
+}
+
+// slogLevel maps a godin level to a slog level. The severities of both are
+// linear, debug (100) is slog.LevelDebug (-4), error (400) slog.LevelError
+// (8), so fatal and panic are above slog.LevelError.
+func slogLevel(v level.Value) stdslog.Level {
+	return stdslog.Level((v.Severity() - level.InfoValue().Severity()) * 4 / 100)
+}
+
+// sink writes entries to a slog.Handler.
+type sink struct {
+	handler stdslog.Handler
+}
+
+// NewSink returns a sink writing the entries of a Log to handler, to be
+// passed to log.WithSink:
+//
+//	logger := log.NewLogger("info", log.WithSink(slog.NewSink(stdslog.NewJSONHandler(os.Stdout, nil))))
+//
+// The level filter of the Log applies, the handler's Enabled is respected
+// too. The message field becomes the message of the record.
+func NewSink(handler stdslog.Handler) kitlog.Logger {
+	return sink{handler: handler}
+}
+
+func (s sink) Log(keyvals ...interface{}) error {
+	lvl := stdslog.LevelInfo
+	if v, ok := level.FromKeyvals(keyvals); ok {
+		lvl = slogLevel(v)
+	}
+	ctx := context.Background()
+	if !s.handler.Enabled(ctx, lvl) {
+		return nil
+	}
+
+	var (
+		message string
+		attrs   []stdslog.Attr
+	)
+	for i := 0; i < len(keyvals); i += 2 {
+		key := kv.Key(keyvals[i])
+		var value interface{} = kitlog.ErrMissingValue
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+		switch {
+		case keyvals[i] == level.Key():
+		case key == log.MessageKey:
+			message = kv.String(value)
+		default:
+			attrs = append(attrs, stdslog.Any(key, value))
+		}
+	}
+
+	r := stdslog.NewRecord(time.Now(), lvl, message, 0)
+	r.AddAttrs(attrs...)
+	return s.handler.Handle(ctx, r)
+}
+
+// NewLogger creates a Log writing to handler, see NewSink.
+func NewLogger(handler stdslog.Handler, logLevel string, opts ...log.Option) log.Log {
+	return log.NewLogger(logLevel, append(opts, log.WithSink(NewSink(handler)))...)
+}
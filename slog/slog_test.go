@@ -0,0 +1,64 @@
+package slog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stdslog "log/slog"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-godin/log"
+	kitlog "github.com/go-kit/kit/log"
+)
+
+// here returns the call site of its caller as reported by log.WithCaller.
+func here() string {
+	_, file, line, _ := runtime.Caller(1)
+	return filepath.Join(filepath.Base(filepath.Dir(file)), filepath.Base(file)) + ":" + strconv.Itoa(line)
+}
+
+func TestHandlerCaller(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(l *stdslog.Logger)
+	}{
+		{
+			name: "logger method",
+			log:  func(l *stdslog.Logger) { l.Info("test", "want", here()) },
+		},
+		{
+			name: "context method",
+			log:  func(l *stdslog.Logger) { l.WarnContext(context.Background(), "test", "want", here()) },
+		},
+		{
+			name: "record without pc",
+			log: func(l *stdslog.Logger) {
+				_ = l.Handler().Handle(context.Background(), stdslog.NewRecord(time.Now(), stdslog.LevelInfo, "test", 0))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := log.NewLogger(log.LevelDebug, log.WithSink(kitlog.NewJSONLogger(&buf)), log.WithCaller(0))
+			tt.log(stdslog.New(NewHandler(logger)))
+
+			var entry map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatal(err)
+			}
+			caller, ok := entry[log.CallerKey]
+			if want, wanted := entry["want"]; !wanted {
+				if ok {
+					t.Errorf("caller = %v, want none", caller)
+				}
+			} else if caller != want {
+				t.Errorf("caller = %v, want %v", caller, want)
+			}
+		})
+	}
+}
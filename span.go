@@ -0,0 +1,75 @@
+package log
+
+import (
+	"context"
+	"sync"
+)
+
+// SpanRecorder records the entries of a Log bound to a span by WithTrace or
+// WithSpan. Implementations for zipkin, OpenTelemetry and Jaeger are provided
+// by the modules below tracing, each with a go.mod of its own, so only
+// services which trace depend on a tracer.
+//
+// Recorders which also implement
+//
+//	TraceContext() Traceparent
+//
+// add the trace_id, span_id and sampled fields to entries. They return the
+// zero Traceparent for spans they can't tell the trace context of, e.g.
+// spans of another tracer, which are then logged without trace fields and
+// aren't subject to WithTraceSampledDebug.
+type SpanRecorder interface {
+	// Annotate records a timed event.
+	Annotate(message string)
+	// Tag sets an attribute.
+	Tag(key, value string)
+	// SetError marks the span as failed.
+	SetError(message string)
+}
+
+// SpanSource returns the span recorder of the span carried by ctx, or nil.
+type SpanSource func(ctx context.Context) SpanRecorder
+
+var (
+	spanSourcesMtx sync.RWMutex
+	spanSources    []SpanSource
+)
+
+// RegisterSpanSource makes the spans of source available to WithTrace.
+// Sources are asked in the order they were registered. The packages below
+// tracing register their source when they're imported:
+//
+//	import _ "github.com/go-godin/log/tracing/zipkin"
+func RegisterSpanSource(source SpanSource) {
+	spanSourcesMtx.Lock()
+	defer spanSourcesMtx.Unlock()
+	spanSources = append(spanSources, source)
+}
+
+// spanFromContext returns the span recorder of the first registered source
+// knowing the span of ctx, falling back to a trace context stored with
+// ContextWithTraceparent, or nil.
+func spanFromContext(ctx context.Context) SpanRecorder {
+	spanSourcesMtx.RLock()
+	defer spanSourcesMtx.RUnlock()
+	for _, source := range spanSources {
+		if span := source(ctx); span != nil {
+			return span
+		}
+	}
+	if tp, ok := TraceparentFromContext(ctx); ok {
+		return tp
+	}
+	return nil
+}
+
+// traceContext returns the trace context of the span of the Log, if it
+// provides one.
+func (l Log) traceContext() (Traceparent, bool) {
+	if tc, ok := l.span.(interface{ TraceContext() Traceparent }); ok {
+		if tp := tc.TraceContext(); tp != (Traceparent{}) {
+			return tp, true
+		}
+	}
+	return Traceparent{}, false
+}
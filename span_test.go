@@ -0,0 +1,289 @@
+package log
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-godin/log/correlation"
+)
+
+func TestWithSpanTraceFields(t *testing.T) {
+	tests := []struct {
+		name string
+		span SpanRecorder
+		want map[string]interface{}
+	}{
+		{
+			name: "trace context",
+			span: Traceparent{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true},
+			want: map[string]interface{}{TraceIDKey: "4bf92f3577b34da6a3ce929d0e0e4736", SpanIDKey: "00f067aa0ba902b7", SampledKey: true},
+		},
+		{
+			name: "zero trace context",
+			span: Traceparent{},
+		},
+		{
+			name: "no span",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, out := newBufferLogger(LevelInfo)
+			logger.WithSpan(tt.span).Info("test")
+
+			entry := out.entries(t)[0]
+			for _, key := range []string{TraceIDKey, SpanIDKey, SampledKey} {
+				got, ok := entry[key]
+				want, wanted := tt.want[key]
+				if ok != wanted || got != want {
+					t.Errorf("%s = %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWithSpanRebinding(t *testing.T) {
+	first := Traceparent{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true}
+	second := Traceparent{TraceID: "0af7651916cd43dd8448eb211c80319c", SpanID: "b7ad6b7169203331"}
+	tests := []struct {
+		name    string
+		bind    func(Log) Log
+		traceID string
+		spanID  string
+	}{
+		{name: "rebound", bind: func(l Log) Log { return l.WithSpan(first).WithSpan(second) }, traceID: second.TraceID, spanID: second.SpanID},
+		{name: "rebound after With", bind: func(l Log) Log { return l.WithSpan(first).With("k", "v").WithSpan(second) }, traceID: second.TraceID, spanID: second.SpanID},
+		{name: "unbound", bind: func(l Log) Log { return l.WithSpan(first).WithSpan(nil) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, out := newBufferLogger(LevelInfo)
+			tt.bind(logger).Info("test")
+
+			want := 0
+			if tt.traceID != "" {
+				want = 1
+			}
+			if n := strings.Count(out.String(), `"`+TraceIDKey+`"`); n != want {
+				t.Errorf("%s appears %d times in %s, want %d", TraceIDKey, n, out, want)
+			}
+			entry := out.entries(t)[0]
+			if tt.traceID == "" {
+				return
+			}
+			if entry[TraceIDKey] != tt.traceID || entry[SpanIDKey] != tt.spanID {
+				t.Errorf("trace, span = %v, %v, want %s, %s", entry[TraceIDKey], entry[SpanIDKey], tt.traceID, tt.spanID)
+			}
+		})
+	}
+}
+
+func TestWithTraceRebinding(t *testing.T) {
+	ctx, err := ContextWithTraceparent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := correlation.NewContext(ctx, "first")
+	second := correlation.NewContext(context.Background(), "second")
+
+	logger, out := newBufferLogger(LevelInfo)
+	logger.WithTrace(first).With("k", "v").WithTrace(second).Info("test")
+
+	for _, key := range []string{TraceIDKey, correlation.Key} {
+		if n := strings.Count(out.String(), `"`+key+`"`); n > 1 {
+			t.Errorf("%s appears %d times in %s", key, n, out)
+		}
+	}
+	entry := out.entries(t)[0]
+	if _, ok := entry[TraceIDKey]; ok {
+		t.Errorf("%s of the previous trace kept", TraceIDKey)
+	}
+	if entry[correlation.Key] != "second" || entry["k"] != "v" {
+		t.Errorf("entry = %v, want %s second and k v", entry, correlation.Key)
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+	for _, tt := range []struct {
+		name    string
+		header  string
+		want    Traceparent
+		wantErr bool
+	}{
+		{name: "sampled", header: "00-" + traceID + "-" + spanID + "-01", want: Traceparent{TraceID: traceID, SpanID: spanID, Sampled: true}},
+		{name: "not sampled", header: "00-" + traceID + "-" + spanID + "-00", want: Traceparent{TraceID: traceID, SpanID: spanID}},
+		{name: "other flags", header: "00-" + traceID + "-" + spanID + "-03", want: Traceparent{TraceID: traceID, SpanID: spanID, Sampled: true}},
+		{name: "surrounding space", header: " 00-" + traceID + "-" + spanID + "-01 ", want: Traceparent{TraceID: traceID, SpanID: spanID, Sampled: true}},
+		{name: "future version with more fields", header: "01-" + traceID + "-" + spanID + "-01-extra", want: Traceparent{TraceID: traceID, SpanID: spanID, Sampled: true}},
+		{name: "version 00 with more fields", header: "00-" + traceID + "-" + spanID + "-01-extra", wantErr: true},
+		{name: "invalid version", header: "ff-" + traceID + "-" + spanID + "-01", wantErr: true},
+		{name: "upper case", header: "00-" + strings.ToUpper(traceID) + "-" + spanID + "-01", wantErr: true},
+		{name: "short trace ID", header: "00-" + traceID[:16] + "-" + spanID + "-01", wantErr: true},
+		{name: "zero trace ID", header: "00-" + strings.Repeat("0", 32) + "-" + spanID + "-01", wantErr: true},
+		{name: "zero span ID", header: "00-" + traceID + "-" + strings.Repeat("0", 16) + "-01", wantErr: true},
+		{name: "missing flags", header: "00-" + traceID + "-" + spanID, wantErr: true},
+		{name: "empty", header: "", wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTraceparent(tt.header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTraceparent(%q) = %v, want error %v", tt.header, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTraceparent(%q) = %+v, want %+v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithTraceparent(t *testing.T) {
+	logger, out := newBufferLogger(LevelInfo)
+	logger.WithTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01").Info("traced")
+	logger.WithTraceparent("invalid").Info("untraced")
+
+	entries := out.entries(t)
+	if entries[0][TraceIDKey] != "4bf92f3577b34da6a3ce929d0e0e4736" || entries[0][SampledKey] != true {
+		t.Errorf("traced entry = %v", entries[0])
+	}
+	if _, ok := entries[1][TraceIDKey]; ok {
+		t.Errorf("invalid header bound: %v", entries[1])
+	}
+}
+
+func TestTraceFromHeader(t *testing.T) {
+	const (
+		traceID   = "4bf92f3577b34da6a3ce929d0e0e4736"
+		traceID64 = "a3ce929d0e0e4736"
+		spanID    = "00f067aa0ba902b7"
+	)
+	for _, tt := range []struct {
+		name   string
+		header http.Header
+		want   Traceparent
+		ok     bool
+	}{
+		{name: "multi", header: http.Header{"X-B3-Traceid": {traceID}, "X-B3-Spanid": {spanID}, "X-B3-Sampled": {"1"}}, want: Traceparent{TraceID: traceID, SpanID: spanID, Sampled: true}, ok: true},
+		{name: "multi 64 bit", header: http.Header{"X-B3-Traceid": {traceID64}, "X-B3-Spanid": {spanID}, "X-B3-Sampled": {"0"}}, want: Traceparent{TraceID: traceID64, SpanID: spanID}, ok: true},
+		{name: "multi upper case", header: http.Header{"X-B3-Traceid": {strings.ToUpper(traceID)}, "X-B3-Spanid": {spanID}, "X-B3-Sampled": {"true"}}, want: Traceparent{TraceID: traceID, SpanID: spanID, Sampled: true}, ok: true},
+		{name: "multi debug", header: http.Header{"X-B3-Traceid": {traceID}, "X-B3-Spanid": {spanID}, "X-B3-Flags": {"1"}}, want: Traceparent{TraceID: traceID, SpanID: spanID, Sampled: true}, ok: true},
+		{name: "single", header: http.Header{"B3": {traceID + "-" + spanID + "-1-" + spanID}}, want: Traceparent{TraceID: traceID, SpanID: spanID, Sampled: true}, ok: true},
+		{name: "single debug", header: http.Header{"B3": {traceID + "-" + spanID + "-d"}}, want: Traceparent{TraceID: traceID, SpanID: spanID, Sampled: true}, ok: true},
+		{name: "single without sampling", header: http.Header{"B3": {traceID + "-" + spanID}}, want: Traceparent{TraceID: traceID, SpanID: spanID}, ok: true},
+		{name: "single preferred", header: http.Header{"B3": {traceID + "-" + spanID + "-0"}, "X-B3-Traceid": {traceID64}, "X-B3-Spanid": {spanID}, "X-B3-Sampled": {"1"}}, want: Traceparent{TraceID: traceID, SpanID: spanID}, ok: true},
+		{name: "single sampling only", header: http.Header{"B3": {"0"}}},
+		{name: "invalid span ID", header: http.Header{"X-B3-Traceid": {traceID}, "X-B3-Spanid": {"xyz"}}},
+		{name: "traceparent fallback", header: http.Header{"Traceparent": {"00-" + traceID + "-" + spanID + "-01"}}, want: Traceparent{TraceID: traceID, SpanID: spanID, Sampled: true}, ok: true},
+		{name: "none", header: http.Header{}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := TraceFromHeader(tt.header)
+			if ok != tt.ok || ok && got != tt.want {
+				t.Errorf("TraceFromHeader() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestTraceparentStringPadding(t *testing.T) {
+	tp := Traceparent{TraceID: "a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true}
+	if got, want := tp.String(), "00-0000000000000000a3ce929d0e0e4736-00f067aa0ba902b7-01"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestWithRequest(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("b3", "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1")
+	r.Header.Set("X-Request-ID", "req-1")
+
+	logger, out := newBufferLogger(LevelInfo)
+	logger.WithRequest(r).Info("m")
+
+	entry := out.entries(t)[0]
+	if entry[TraceIDKey] != "4bf92f3577b34da6a3ce929d0e0e4736" || entry[SpanIDKey] != "00f067aa0ba902b7" || entry[RequestIDKey] != "req-1" {
+		t.Errorf("entry = %v", entry)
+	}
+}
+
+func TestWithDatadogTraceFields(t *testing.T) {
+	span := Traceparent{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true}
+	for _, tt := range []struct {
+		name string
+		opts []Option
+		want map[string]interface{}
+	}{
+		{name: "enabled", opts: []Option{WithDatadogTraceFields()}, want: map[string]interface{}{
+			DatadogTraceIDKey: "11803532876627986230",
+			DatadogSpanIDKey:  "67667974448284343",
+		}},
+		{name: "disabled"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, out := newBufferLogger(LevelInfo, tt.opts...)
+			logger.WithSpan(span).Info("m")
+
+			entry := out.entries(t)[0]
+			for _, key := range []string{DatadogTraceIDKey, DatadogSpanIDKey} {
+				got, ok := entry[key]
+				want, wanted := tt.want[key]
+				if ok != wanted || got != want {
+					t.Errorf("%s = %v, want %v", key, got, want)
+				}
+			}
+			if entry[TraceIDKey] != span.TraceID {
+				t.Errorf("%s = %v, want %s", TraceIDKey, entry[TraceIDKey], span.TraceID)
+			}
+		})
+	}
+}
+
+// recordingSpan records what a Log hands to its span.
+type recordingSpan struct {
+	annotations []string
+	tags        map[string]string
+}
+
+func (s *recordingSpan) Annotate(message string) { s.annotations = append(s.annotations, message) }
+func (s *recordingSpan) SetError(string)         {}
+func (s *recordingSpan) Tag(key, value string) {
+	if s.tags == nil {
+		s.tags = make(map[string]string)
+	}
+	s.tags[key] = value
+}
+
+func TestSpanTagPolicies(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts []Option
+		want map[string]string
+	}{
+		{name: "all", want: map[string]string{"a": "1", "b": "2", "c": "3"}},
+		{name: "disabled", opts: []Option{WithoutSpanTags()}},
+		{name: "max", opts: []Option{WithMaxSpanTags(2)}, want: map[string]string{"a": "1", "b": "2"}},
+		{name: "keys", opts: []Option{WithSpanTagKeys("c", "a")}, want: map[string]string{"a": "1", "c": "3"}},
+		{name: "keys and max", opts: []Option{WithSpanTagKeys("b", "c"), WithMaxSpanTags(1)}, want: map[string]string{"b": "2"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			span := &recordingSpan{}
+			logger, _ := newBufferLogger(LevelInfo, tt.opts...)
+			logger.WithSpan(span).Info("m", "a", 1, "b", 2, "c", 3)
+
+			if !reflect.DeepEqual(span.tags, tt.want) {
+				t.Errorf("tags = %v, want %v", span.tags, tt.want)
+			}
+			if len(span.annotations) != 1 || span.annotations[0] != "m" {
+				t.Errorf("annotations = %v, want the message", span.annotations)
+			}
+		})
+	}
+}
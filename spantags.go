@@ -0,0 +1,33 @@
+package log
+
+// spanTagPolicy restricts the keyvals WithTrace tags onto spans.
+type spanTagPolicy struct {
+	disabled bool
+	max      int
+	keys     map[string]bool
+}
+
+// WithoutSpanTags stops Logs bound to a span by WithTrace from tagging the
+// keyvals of entries onto the span. Messages are still recorded as
+// annotations.
+func WithoutSpanTags() Option {
+	return func(o *options) { o.spanTags.disabled = true }
+}
+
+// WithMaxSpanTags limits the amount of keyvals of a single entry tagged onto
+// the span, to keep spans small when entries carry large payloads.
+func WithMaxSpanTags(n int) Option {
+	return func(o *options) { o.spanTags.max = n }
+}
+
+// WithSpanTagKeys only tags keyvals with the given keys onto the span.
+func WithSpanTagKeys(keys ...string) Option {
+	return func(o *options) {
+		if o.spanTags.keys == nil {
+			o.spanTags.keys = make(map[string]bool, len(keys))
+		}
+		for _, key := range keys {
+			o.spanTags.keys[key] = true
+		}
+	}
+}
@@ -0,0 +1,306 @@
+// Package sqllog wraps database/sql drivers to log queries with the godin
+// logger:
+//
+//	connector, err := pq.NewConnector(dsn)
+//	db := sql.OpenDB(sqllog.WrapConnector(connector, logger, sqllog.SlowThreshold(time.Second)))
+package sqllog
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	"github.com/go-godin/log"
+	"github.com/go-godin/log/level"
+)
+
+type options struct {
+	queryLevel    level.Value
+	errorLevel    level.Value
+	slowThreshold time.Duration
+	redact        func(arg driver.NamedValue) interface{}
+}
+
+// Option sets a parameter for the wrapped driver.
+type Option func(*options)
+
+// QueryLevel sets the level of queries. Defaults to debug.
+func QueryLevel(lvl string) Option {
+	return func(o *options) { o.queryLevel = parseLevel(lvl, o.queryLevel) }
+}
+
+// ErrorLevel sets the level of failed queries. Defaults to error.
+func ErrorLevel(lvl string) Option {
+	return func(o *options) { o.errorLevel = parseLevel(lvl, o.errorLevel) }
+}
+
+// SlowThreshold logs queries taking longer than threshold as warning,
+// regardless of QueryLevel. Defaults to zero, which disables it.
+func SlowThreshold(threshold time.Duration) Option {
+	return func(o *options) { o.slowThreshold = threshold }
+}
+
+// Redact sets the function converting query arguments to logged values,
+// e.g. to mask credentials. Use RedactAll to omit all values. By default the
+// values are logged as they are.
+func Redact(redact func(arg driver.NamedValue) interface{}) Option {
+	return func(o *options) { o.redact = redact }
+}
+
+// RedactAll replaces every argument by "[REDACTED]", see Redact.
+func RedactAll(driver.NamedValue) interface{} {
+	return "[REDACTED]"
+}
+
+// parseLevel returns the named level, accepting aliases as log.ParseLevel,
+// or fallback.
+func parseLevel(name string, fallback level.Value) level.Value {
+	lvl, err := log.ParseLevel(name)
+	if err != nil {
+		return fallback
+	}
+	if v, ok := level.Parse(string(lvl)); ok {
+		return v
+	}
+	return fallback
+}
+
+type queryLogger struct {
+	logger log.Log
+	options
+}
+
+func newQueryLogger(logger log.Log, opts []Option) *queryLogger {
+	o := options{
+		queryLevel: level.DebugValue(),
+		errorLevel: level.ErrorValue(),
+		redact:     func(arg driver.NamedValue) interface{} { return arg.Value },
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &queryLogger{logger: logger, options: o}
+}
+
+// callerHint reports the code calling database/sql as call site of the
+// entries.
+var callerHint = log.CallerOutside("database/sql.")
+
+// log logs a query started at begin, bound to the trace of ctx. Queries the
+// driver skipped are logged when they're retried with a prepared statement.
+func (q *queryLogger) log(ctx context.Context, query string, args []driver.NamedValue, begin time.Time, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+	elapsed := time.Since(begin)
+	keyvals := []interface{}{"query", query, "duration", elapsed.Seconds()}
+	if len(args) > 0 {
+		values := make([]interface{}, len(args))
+		for i, arg := range args {
+			values[i] = q.redact(arg)
+		}
+		keyvals = append(keyvals, "args", values)
+	}
+
+	l := q.logger.WithTrace(ctx)
+	switch {
+	case err != nil:
+		l.At(q.errorLevel, "query failed", append(keyvals, "err", err, callerHint)...)
+	case q.slowThreshold > 0 && elapsed > q.slowThreshold:
+		l.Warning("slow query", append(keyvals, "threshold", q.slowThreshold.Seconds(), callerHint)...)
+	default:
+		l.At(q.queryLevel, "query", append(keyvals, callerHint)...)
+	}
+}
+
+// WrapDriver returns a driver logging the queries of the connections opened
+// by d, e.g. to be registered with sql.Register.
+func WrapDriver(d driver.Driver, logger log.Log, opts ...Option) driver.Driver {
+	return &wrappedDriver{Driver: d, logger: newQueryLogger(logger, opts)}
+}
+
+type wrappedDriver struct {
+	driver.Driver
+	logger *queryLogger
+}
+
+func (d *wrappedDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: c, logger: d.logger}, nil
+}
+
+// WrapConnector returns a connector logging the queries of the connections
+// created by c, to be passed to sql.OpenDB.
+func WrapConnector(c driver.Connector, logger log.Log, opts ...Option) driver.Connector {
+	return &connector{Connector: c, logger: newQueryLogger(logger, opts)}
+}
+
+type connector struct {
+	driver.Connector
+	logger *queryLogger
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	dc, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: dc, logger: c.logger}, nil
+}
+
+// conn logs the queries executed on a connection. It implements the
+// optional interfaces database/sql checks for, delegating to the wrapped
+// connection where it implements them.
+type conn struct {
+	driver.Conn
+	logger *queryLogger
+}
+
+var (
+	_ driver.ConnPrepareContext = (*conn)(nil)
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.Pinger             = (*conn)(nil)
+	_ driver.SessionResetter    = (*conn)(nil)
+	_ driver.Validator          = (*conn)(nil)
+	_ driver.NamedValueChecker  = (*conn)(nil)
+)
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	s, err := c.Conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{Stmt: s, query: query, logger: c.logger}, nil
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	pc, ok := c.Conn.(driver.ConnPrepareContext)
+	if !ok {
+		return c.Prepare(query)
+	}
+	s, err := pc.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{Stmt: s, query: query, logger: c.logger}, nil
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if bc, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return bc.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ec, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	begin := time.Now()
+	res, err := ec.ExecContext(ctx, query, args)
+	c.logger.log(ctx, query, args, begin, err)
+	return res, err
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	qc, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	begin := time.Now()
+	rows, err := qc.QueryContext(ctx, query, args)
+	c.logger.log(ctx, query, args, begin, err)
+	return rows, err
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// stmt logs the executions of a prepared statement.
+type stmt struct {
+	driver.Stmt
+	query  string
+	logger *queryLogger
+}
+
+var (
+	_ driver.StmtExecContext  = (*stmt)(nil)
+	_ driver.StmtQueryContext = (*stmt)(nil)
+)
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	begin := time.Now()
+	var (
+		res driver.Result
+		err error
+	)
+	if ec, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = ec.ExecContext(ctx, args)
+	} else {
+		res, err = s.Stmt.Exec(values(args))
+	}
+	s.logger.log(ctx, s.query, args, begin, err)
+	return res, err
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	begin := time.Now()
+	var (
+		rows driver.Rows
+		err  error
+	)
+	if qc, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = qc.QueryContext(ctx, args)
+	} else {
+		rows, err = s.Stmt.Query(values(args))
+	}
+	s.logger.log(ctx, s.query, args, begin, err)
+	return rows, err
+}
+
+func (s *stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	v := make([]driver.Value, len(args))
+	for i, arg := range args {
+		v[i] = arg.Value
+	}
+	return v
+}
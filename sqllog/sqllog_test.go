@@ -0,0 +1,219 @@
+package sqllog
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-godin/log"
+	kitlog "github.com/go-kit/kit/log"
+	"modernc.org/sqlite"
+)
+
+func openDB(t *testing.T, lvl string, opts ...Option) (*sql.DB, func() []map[string]interface{}) {
+	t.Helper()
+	var buf bytes.Buffer
+	logger := log.NewLogger(lvl, log.WithSink(kitlog.NewJSONLogger(&buf)))
+	db := sql.OpenDB(&dsnConnector{name: ":memory:", driver: WrapDriver(&sqlite.Driver{}, logger, opts...)})
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(`CREATE TABLE users (id INTEGER, name TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	return db, func() []map[string]interface{} {
+		var entries []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var entry map[string]interface{}
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("decoding %q: %v", line, err)
+			}
+			entries = append(entries, entry)
+		}
+		buf.Reset()
+		return entries
+	}
+}
+
+func TestWrapDriver(t *testing.T) {
+	const insert = `INSERT INTO users (id, name) VALUES (?, ?)`
+	tests := []struct {
+		name        string
+		level       string
+		opts        []Option
+		query       func(db *sql.DB) error
+		wantMessage string // empty if nothing is logged
+		wantLevel   string
+		wantQuery   string
+		wantArgs    []interface{}
+	}{
+		{
+			name:        "exec",
+			level:       log.LevelDebug,
+			query:       func(db *sql.DB) error { _, err := db.Exec(insert, 1, "jane"); return err },
+			wantMessage: "query",
+			wantLevel:   "debug",
+			wantQuery:   insert,
+			wantArgs:    []interface{}{float64(1), "jane"},
+		},
+		{
+			name:  "query",
+			level: log.LevelDebug,
+			query: func(db *sql.DB) error {
+				rows, err := db.Query(`SELECT name FROM users`)
+				if err == nil {
+					rows.Close()
+				}
+				return err
+			},
+			wantMessage: "query",
+			wantLevel:   "debug",
+			wantQuery:   `SELECT name FROM users`,
+		},
+		{
+			name:  "prepared statement",
+			level: log.LevelDebug,
+			query: func(db *sql.DB) error {
+				stmt, err := db.Prepare(insert)
+				if err != nil {
+					return err
+				}
+				defer stmt.Close()
+				_, err = stmt.Exec(2, "john")
+				return err
+			},
+			wantMessage: "query",
+			wantLevel:   "debug",
+			wantQuery:   insert,
+			wantArgs:    []interface{}{float64(2), "john"},
+		},
+		{
+			name:  "below the level",
+			level: log.LevelInfo,
+			query: func(db *sql.DB) error { _, err := db.Exec(insert, 1, "jane"); return err },
+		},
+		{
+			name:        "query level",
+			level:       log.LevelInfo,
+			opts:        []Option{QueryLevel("info")},
+			query:       func(db *sql.DB) error { _, err := db.Exec(insert, 1, "jane"); return err },
+			wantMessage: "query",
+			wantLevel:   "info",
+			wantQuery:   insert,
+			wantArgs:    []interface{}{float64(1), "jane"},
+		},
+		{
+			name:        "failed",
+			level:       log.LevelInfo,
+			query:       func(db *sql.DB) error { _, _ = db.Exec(`INSERT INTO missing VALUES (1)`); return nil },
+			wantMessage: "query failed",
+			wantLevel:   "error",
+			wantQuery:   `INSERT INTO missing VALUES (1)`,
+		},
+		{
+			name:        "error level",
+			level:       log.LevelInfo,
+			opts:        []Option{ErrorLevel("warn")},
+			query:       func(db *sql.DB) error { _, _ = db.Exec(`INSERT INTO missing VALUES (1)`); return nil },
+			wantMessage: "query failed",
+			wantLevel:   "warning",
+			wantQuery:   `INSERT INTO missing VALUES (1)`,
+		},
+		{
+			name:        "slow",
+			level:       log.LevelInfo,
+			opts:        []Option{SlowThreshold(time.Nanosecond)},
+			query:       func(db *sql.DB) error { _, err := db.Exec(insert, 1, "jane"); return err },
+			wantMessage: "slow query",
+			wantLevel:   "warning",
+			wantQuery:   insert,
+			wantArgs:    []interface{}{float64(1), "jane"},
+		},
+		{
+			name:        "redacted",
+			level:       log.LevelDebug,
+			opts:        []Option{Redact(RedactAll)},
+			query:       func(db *sql.DB) error { _, err := db.Exec(insert, 1, "jane"); return err },
+			wantMessage: "query",
+			wantLevel:   "debug",
+			wantQuery:   insert,
+			wantArgs:    []interface{}{"[REDACTED]", "[REDACTED]"},
+		},
+		{
+			name:  "redacted by position",
+			level: log.LevelDebug,
+			opts: []Option{Redact(func(arg driver.NamedValue) interface{} {
+				if arg.Ordinal == 2 {
+					return "***"
+				}
+				return arg.Value
+			})},
+			query:       func(db *sql.DB) error { _, err := db.Exec(insert, 1, "jane"); return err },
+			wantMessage: "query",
+			wantLevel:   "debug",
+			wantQuery:   insert,
+			wantArgs:    []interface{}{float64(1), "***"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, entries := openDB(t, tt.level, tt.opts...)
+			if err := tt.query(db); err != nil {
+				t.Fatal(err)
+			}
+
+			logged := entries()
+			if tt.wantMessage == "" {
+				if len(logged) != 0 {
+					t.Errorf("logged %v, want nothing", logged)
+				}
+				return
+			}
+			if len(logged) != 1 {
+				t.Fatalf("logged %d entries, want 1: %v", len(logged), logged)
+			}
+			entry := logged[0]
+			if entry[log.MessageKey] != tt.wantMessage || entry["severity"] != tt.wantLevel || entry["query"] != tt.wantQuery {
+				t.Errorf("message, severity, query = %v, %v, %v, want %s, %s, %s", entry[log.MessageKey], entry["severity"], entry["query"], tt.wantMessage, tt.wantLevel, tt.wantQuery)
+			}
+			if args, _ := entry["args"].([]interface{}); !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", entry["args"], tt.wantArgs)
+			}
+			if _, ok := entry["duration"].(float64); !ok {
+				t.Errorf("duration = %v", entry["duration"])
+			}
+		})
+	}
+}
+
+func TestWrapConnector(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewLogger(log.LevelDebug, log.WithSink(kitlog.NewJSONLogger(&buf)))
+	connector := &dsnConnector{name: ":memory:", driver: &sqlite.Driver{}}
+	db := sql.OpenDB(WrapConnector(connector, logger))
+	defer db.Close()
+	if _, err := db.ExecContext(context.Background(), `SELECT 1`); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"query":"SELECT 1"`) {
+		t.Errorf("logged %s, want the query", buf.String())
+	}
+}
+
+// dsnConnector is a driver.Connector opening name with driver.
+type dsnConnector struct {
+	name   string
+	driver driver.Driver
+}
+
+func (c *dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open(c.name) }
+func (c *dsnConnector) Driver() driver.Driver                        { return c.driver }
@@ -0,0 +1,175 @@
+// Package stats counts entries per fingerprint, i.e. per level and message,
+// giving a "top talkers" view when diagnosing the log volume of a service.
+package stats
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/level"
+	"github.com/go-kit/kit/log"
+)
+
+// Other is the message of the fingerprint collecting entries once the
+// maximum amount of fingerprints is tracked.
+const Other = "(other)"
+
+// Stat holds the statistics of a fingerprint.
+type Stat struct {
+	Level     string    `json:"level"`
+	Message   string    `json:"message"`
+	Count     uint64    `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+type fingerprint struct {
+	level   string
+	message string
+}
+
+// Collector tracks the statistics. It implements the go-kit log.Logger
+// interface, combine it with log.WithRecorder to count entries of all levels
+// or with Hook to count the entries reaching a sink:
+//
+//	c := stats.New()
+//	logger := log.NewLogger("info", log.WithRecorder(c))
+//	http.Handle("/debug/log-stats", c.Handler())
+type Collector struct {
+	maxFingerprints int
+	now             func() time.Time
+
+	mtx   sync.Mutex
+	stats map[fingerprint]*Stat
+}
+
+// Option sets a parameter for the Collector.
+type Option func(*Collector)
+
+// MaxFingerprints bounds the amount of tracked fingerprints. Entries with
+// further fingerprints are counted as Other. Defaults to 10000.
+func MaxFingerprints(n int) Option {
+	return func(c *Collector) { c.maxFingerprints = n }
+}
+
+// New creates a Collector.
+func New(options ...Option) *Collector {
+	c := &Collector{
+		maxFingerprints: 10000,
+		now:             time.Now,
+		stats:           make(map[fingerprint]*Stat),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// Log counts the entry.
+func (c *Collector) Log(keyvals ...interface{}) error {
+	var fp fingerprint
+	if lvl, ok := level.FromKeyvals(keyvals); ok {
+		fp.level = lvl.String()
+	}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if kv.Key(keyvals[i]) == kv.MessageKey {
+			fp.message = kv.String(keyvals[i+1])
+			break
+		}
+	}
+
+	now := c.now()
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	s, ok := c.stats[fp]
+	if !ok && len(c.stats) >= c.maxFingerprints {
+		fp = fingerprint{level: fp.level, message: Other}
+		s, ok = c.stats[fp]
+	}
+	if !ok {
+		s = &Stat{Level: fp.level, Message: fp.message, FirstSeen: now}
+		c.stats[fp] = s
+	}
+	s.Count++
+	s.LastSeen = now
+	return nil
+}
+
+// Hook returns a logger which counts entries with c and passes them on to next.
+func (c *Collector) Hook(next log.Logger) log.Logger {
+	return log.LoggerFunc(func(keyvals ...interface{}) error {
+		_ = c.Log(keyvals...)
+		return next.Log(keyvals...)
+	})
+}
+
+// Stats returns the statistics of all fingerprints, most frequent first.
+func (c *Collector) Stats() []Stat {
+	c.mtx.Lock()
+	stats := make([]Stat, 0, len(c.stats))
+	for _, s := range c.stats {
+		stats = append(stats, *s)
+	}
+	c.mtx.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].LastSeen.After(stats[j].LastSeen)
+	})
+	return stats
+}
+
+// Reset drops all statistics.
+func (c *Collector) Reset() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.stats = make(map[fingerprint]*Stat)
+}
+
+// Handler returns an http.Handler responding with the statistics as JSON
+// array, most frequent first. The query parameter "limit" restricts the
+// response to the top n fingerprints, "level" to the given levels.
+func (c *Collector) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		stats := c.Stats()
+		if levels := r.URL.Query()["level"]; len(levels) > 0 {
+			allowed := make(map[string]bool, len(levels))
+			for _, l := range levels {
+				allowed[l] = true
+			}
+			filtered := stats[:0]
+			for _, s := range stats {
+				if allowed[s.Level] {
+					filtered = append(filtered, s)
+				}
+			}
+			stats = filtered
+		}
+		if s := r.URL.Query().Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				http.Error(w, "invalid limit", http.StatusBadRequest)
+				return
+			}
+			if n < len(stats) {
+				stats = stats[:n]
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(stats)
+	})
+}
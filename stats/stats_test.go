@@ -0,0 +1,163 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-godin/log/level"
+	kitlog "github.com/go-kit/kit/log"
+)
+
+// summary formats stats as level/message=count.
+func summary(stats []Stat) string {
+	var s []string
+	for _, st := range stats {
+		s = append(s, fmt.Sprintf("%s/%s=%d", st.Level, st.Message, st.Count))
+	}
+	return strings.Join(s, ",")
+}
+
+// newCollector returns a Collector whose clock advances a second per entry.
+func newCollector(opts ...Option) *Collector {
+	c := New(opts...)
+	now := time.Unix(0, 0)
+	c.now = func() time.Time { now = now.Add(time.Second); return now }
+	return c
+}
+
+func TestCollector(t *testing.T) {
+	var (
+		info = []interface{}{level.Key(), level.InfoValue(), "message", "request"}
+		warn = []interface{}{level.Key(), level.WarnValue(), "message", "slow"}
+		fail = []interface{}{level.Key(), level.ErrorValue(), "message", "failed"}
+	)
+	tests := []struct {
+		name    string
+		opts    []Option
+		entries [][]interface{}
+		want    string
+	}{
+		{name: "empty"},
+		{
+			name:    "most frequent first",
+			entries: [][]interface{}{warn, info, info, fail, info, warn},
+			want:    "info/request=3,warning/slow=2,error/failed=1",
+		},
+		{
+			name:    "ties most recent first",
+			entries: [][]interface{}{info, warn, fail},
+			want:    "error/failed=1,warning/slow=1,info/request=1",
+		},
+		{
+			name: "fingerprint per level",
+			entries: [][]interface{}{
+				{level.Key(), level.InfoValue(), "message", "retry"},
+				{level.Key(), level.WarnValue(), "message", "retry"},
+				{level.Key(), level.WarnValue(), "message", "retry"},
+			},
+			want: "warning/retry=2,info/retry=1",
+		},
+		{
+			name:    "no level or message",
+			entries: [][]interface{}{{"user", "jane"}},
+			want:    "/=1",
+		},
+		{
+			name:    "other beyond max fingerprints",
+			opts:    []Option{MaxFingerprints(2)},
+			entries: [][]interface{}{info, warn, fail, {level.Key(), level.ErrorValue(), "message", "timeout"}, info},
+			want:    "info/request=2,error/" + Other + "=2,warning/slow=1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCollector(tt.opts...)
+			for _, keyvals := range tt.entries {
+				_ = c.Log(keyvals...)
+			}
+			if got := summary(c.Stats()); got != tt.want {
+				t.Errorf("Stats() = %s, want %s", got, tt.want)
+			}
+			c.Reset()
+			if n := len(c.Stats()); n != 0 {
+				t.Errorf("%d fingerprints retained after Reset", n)
+			}
+		})
+	}
+}
+
+func TestCollectorSeen(t *testing.T) {
+	c := newCollector()
+	for i := 0; i < 3; i++ {
+		_ = c.Log("message", "a")
+	}
+	stats := c.Stats()
+	if len(stats) != 1 || stats[0].FirstSeen != time.Unix(1, 0) || stats[0].LastSeen != time.Unix(3, 0) {
+		t.Errorf("Stats() = %+v, want first seen at 1s and last seen at 3s", stats)
+	}
+}
+
+func TestCollectorHook(t *testing.T) {
+	c := New()
+	var passed int
+	logger := c.Hook(kitlog.LoggerFunc(func(...interface{}) error { passed++; return nil }))
+	_ = logger.Log("message", "a")
+	_ = logger.Log("message", "a")
+	if stats := c.Stats(); passed != 2 || len(stats) != 1 || stats[0].Count != 2 {
+		t.Errorf("passed %d entries and counted %+v, want 2 entries of a fingerprint", passed, stats)
+	}
+}
+
+func TestCollectorHandler(t *testing.T) {
+	c := newCollector()
+	for _, keyvals := range [][]interface{}{
+		{level.Key(), level.DebugValue(), "message", "a"},
+		{level.Key(), level.DebugValue(), "message", "a"},
+		{level.Key(), level.DebugValue(), "message", "a"},
+		{level.Key(), level.InfoValue(), "message", "b"},
+		{level.Key(), level.InfoValue(), "message", "b"},
+		{level.Key(), level.ErrorValue(), "message", "c"},
+	} {
+		_ = c.Log(keyvals...)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		query      string
+		wantStatus int
+		want       string
+	}{
+		{name: "all", method: http.MethodGet, wantStatus: http.StatusOK, want: "debug/a=3,info/b=2,error/c=1"},
+		{name: "level", method: http.MethodGet, query: "level=debug&level=error", wantStatus: http.StatusOK, want: "debug/a=3,error/c=1"},
+		{name: "limit", method: http.MethodGet, query: "limit=2", wantStatus: http.StatusOK, want: "debug/a=3,info/b=2"},
+		{name: "level and limit", method: http.MethodGet, query: "level=info&level=error&limit=1", wantStatus: http.StatusOK, want: "info/b=2"},
+		{name: "limit beyond the fingerprints", method: http.MethodGet, query: "limit=100", wantStatus: http.StatusOK, want: "debug/a=3,info/b=2,error/c=1"},
+		{name: "invalid limit", method: http.MethodGet, query: "limit=x", wantStatus: http.StatusBadRequest},
+		{name: "method not allowed", method: http.MethodPost, wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			c.Handler().ServeHTTP(rec, httptest.NewRequest(tt.method, "/debug/log-stats?"+tt.query, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var stats []Stat
+			if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+				t.Fatal(err)
+			}
+			if got := summary(stats); got != tt.want {
+				t.Errorf("responded %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
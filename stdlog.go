@@ -0,0 +1,46 @@
+package log
+
+import (
+	"io"
+	stdlog "log"
+	"strings"
+
+	"github.com/go-godin/log/level"
+)
+
+// StdWriter returns a writer logging everything written to it as the
+// message of an entry of the given level, e.g. for libraries which only
+// accept an io.Writer. Trailing newlines are removed. Unknown levels fall
+// back to info.
+func (l Log) StdWriter(lvl Level) io.Writer {
+	return stdWriter{logger: l, level: levelValue(lvl)}
+}
+
+// levelValue returns the value of the level, accepting aliases as
+// ParseLevel, or info.
+func levelValue(lvl Level) level.Value {
+	if parsed, err := ParseLevel(string(lvl)); err == nil {
+		if v, ok := level.Parse(string(parsed)); ok {
+			return v
+		}
+	}
+	return level.InfoValue()
+}
+
+// StdLogger returns a standard library logger writing through StdWriter,
+// e.g. for http.Server.ErrorLog:
+//
+//	server := &http.Server{ErrorLog: logger.StdLogger(log.LevelError)}
+func (l Log) StdLogger(lvl Level) *stdlog.Logger {
+	return stdlog.New(l.StdWriter(lvl), "", 0)
+}
+
+type stdWriter struct {
+	logger Log
+	level  level.Value
+}
+
+func (w stdWriter) Write(p []byte) (int, error) {
+	w.logger.At(w.level, strings.TrimRight(string(p), "\r\n"))
+	return len(p), nil
+}
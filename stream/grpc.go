@@ -0,0 +1,69 @@
+package stream
+
+//go:generate sh -c "cd streampb && buf generate"
+
+import (
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/stream/streampb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type grpcService struct {
+	streampb.UnimplementedLogStreamServer
+	hub     *Hub
+	options handlerOptions
+}
+
+// GRPCService returns an implementation of the LogStream gRPC service
+// streaming the entries of the hub. Requests with unknown levels fail with
+// InvalidArgument. Register it with
+//
+//	streampb.RegisterLogStreamServer(server, hub.GRPCService())
+func (h *Hub) GRPCService(opts ...HandlerOption) streampb.LogStreamServer {
+	o := defaultHandlerOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &grpcService{hub: h, options: o}
+}
+
+func (s *grpcService) StreamLogs(req *streampb.StreamLogsRequest, stream streampb.LogStream_StreamLogsServer) error {
+	sub, err := s.hub.Subscribe(Filter{Level: req.GetLevel(), Fields: req.GetFields()}, s.options.buffer)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	defer sub.Close()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case e, ok := <-sub.C():
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(protoEntry(e)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func protoEntry(e Entry) *streampb.LogEntry {
+	entry := &streampb.LogEntry{
+		Time:   timestamppb.New(e.Time),
+		Level:  e.Level,
+		Fields: &structpb.Struct{},
+	}
+	if msg, ok := e.Fields[kv.MessageKey]; ok {
+		entry.Message = kv.String(msg)
+	}
+	// the JSON encoding already normalized the values to JSON types
+	if err := entry.Fields.UnmarshalJSON(e.JSON); err != nil {
+		entry.Fields = nil
+	}
+	return entry
+}
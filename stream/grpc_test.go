@@ -0,0 +1,72 @@
+package stream
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-godin/log/level"
+	"github.com/go-godin/log/stream/streampb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// serverStream records the entries sent by StreamLogs.
+type serverStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	entries chan *streampb.LogEntry
+}
+
+func (s *serverStream) Context() context.Context { return s.ctx }
+
+func (s *serverStream) Send(entry *streampb.LogEntry) error {
+	s.entries <- entry
+	return nil
+}
+
+func TestGRPCService(t *testing.T) {
+	h := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &serverStream{ctx: ctx, entries: make(chan *streampb.LogEntry, 10)}
+	done := make(chan error, 1)
+	go func() {
+		done <- h.GRPCService().StreamLogs(&streampb.StreamLogsRequest{
+			Level:  "warning",
+			Fields: map[string]string{"service": "billing"},
+		}, stream)
+	}()
+	waitSubscribers(t, h, 1)
+
+	_ = h.Log(level.Key(), level.InfoValue(), "message", "ignored", "service", "billing")
+	_ = h.Log(level.Key(), level.WarnValue(), "message", "slow", "service", "billing", "ms", 250)
+
+	entry := <-stream.entries
+	if entry.GetLevel() != "warning" || entry.GetMessage() != "slow" || !entry.GetTime().IsValid() {
+		t.Errorf("level, message, time = %s, %q, %v, want warning, slow and a time", entry.GetLevel(), entry.GetMessage(), entry.GetTime())
+	}
+	fields := entry.GetFields().AsMap()
+	if fields["service"] != "billing" || fields["ms"] != float64(250) || fields["severity"] != "warning" {
+		t.Errorf("fields = %v", fields)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("StreamLogs = %v, want nil after the client went away", err)
+	}
+	if n := h.Subscribers(); n != 0 {
+		t.Errorf("Subscribers() = %d after StreamLogs returned, want 0", n)
+	}
+	if len(stream.entries) != 0 {
+		t.Errorf("sent %d further entries", len(stream.entries))
+	}
+}
+
+func TestGRPCServiceUnknownLevel(t *testing.T) {
+	h := NewHub()
+	stream := &serverStream{ctx: context.Background(), entries: make(chan *streampb.LogEntry, 1)}
+	err := h.GRPCService().StreamLogs(&streampb.StreamLogsRequest{Level: "verbose"}, stream)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("StreamLogs() = %v, want InvalidArgument", err)
+	}
+}
@@ -0,0 +1,204 @@
+// Package stream broadcasts entries to live subscribers, e.g. a debug
+// console tailing a running service over WebSocket.
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-godin/log"
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/level"
+)
+
+// Entry is a broadcast entry.
+type Entry struct {
+	Time   time.Time
+	Level  string
+	Fields map[string]interface{}
+	// JSON is the encoded entry including its time, shared by all subscribers.
+	JSON []byte
+}
+
+// Filter selects the entries a subscriber receives.
+type Filter struct {
+	// Level is the name of the least severe level received, e.g. "warning",
+	// and accepts the aliases of log.ParseLevel. Empty receives all levels.
+	// Entries without level are always received.
+	Level string `json:"level,omitempty"`
+	// Fields restricts the entries to those whose fields hold the given values.
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// FilterFromQuery creates a Filter from URL query parameters: "level" sets
+// the least severe level, all other parameters are field matches, e.g.
+// ?level=warning&service=billing.
+func FilterFromQuery(query url.Values) Filter {
+	f := Filter{Level: query.Get("level")}
+	for key, values := range query {
+		if key == "level" || len(values) == 0 {
+			continue
+		}
+		if f.Fields == nil {
+			f.Fields = make(map[string]string)
+		}
+		f.Fields[key] = values[0]
+	}
+	return f
+}
+
+// minLevel returns the least severe level received, nil if the filter
+// receives all levels.
+func (f Filter) minLevel() (level.Value, error) {
+	if f.Level == "" {
+		return nil, nil
+	}
+	lvl, err := log.ParseLevel(f.Level)
+	if err != nil {
+		return nil, fmt.Errorf("stream: %w", err)
+	}
+	v, _ := level.Parse(string(lvl))
+	return v, nil
+}
+
+// Match reports whether the entry passes the filter. A filter with an
+// unknown level, which Subscribe rejects, receives all levels.
+func (f Filter) Match(e Entry) bool {
+	if e.Level != "" {
+		min, _ := f.minLevel()
+		if lvl, known := level.Parse(e.Level); min != nil && known && lvl.Severity() < min.Severity() {
+			return false
+		}
+	}
+	for key, value := range f.Fields {
+		v, ok := e.Fields[key]
+		if !ok || kv.String(v) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Hub hands every entry to its subscribers. Subscribers which can't keep up
+// lose entries instead of slowing down the logger. It implements the go-kit
+// log.Logger interface; combine it with log.WithRecorder to stream entries
+// of all levels regardless of the level of the logger.
+type Hub struct {
+	mtx  sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+// NewHub creates a Hub without subscribers.
+func NewHub() *Hub {
+	return &Hub{subs: make(map[*Subscription]struct{})}
+}
+
+// Log broadcasts the entry. Entries are only encoded if there are subscribers.
+func (h *Hub) Log(keyvals ...interface{}) error {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+	if len(h.subs) == 0 {
+		return nil
+	}
+
+	e := Entry{
+		Time:   time.Now(),
+		Fields: kv.Map(keyvals),
+	}
+	if lvl, ok := level.FromKeyvals(keyvals); ok {
+		e.Level = lvl.String()
+	}
+	doc := make(map[string]interface{}, len(e.Fields)+1)
+	for k, v := range e.Fields {
+		doc[k] = v
+	}
+	if _, ok := doc["time"]; !ok {
+		doc["time"] = e.Time
+	}
+	var err error
+	if e.JSON, err = json.Marshal(doc); err != nil {
+		return err
+	}
+
+	for sub := range h.subs {
+		if !sub.filter().Match(e) {
+			continue
+		}
+		select {
+		case sub.c <- e:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+	return nil
+}
+
+// Subscribe registers a subscriber receiving the entries passing filter.
+// Up to buffer entries are buffered for it. It returns an error if the
+// filter names an unknown level.
+func (h *Hub) Subscribe(filter Filter, buffer int) (*Subscription, error) {
+	sub := &Subscription{hub: h, c: make(chan Entry, buffer)}
+	if err := sub.SetFilter(filter); err != nil {
+		return nil, err
+	}
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.subs[sub] = struct{}{}
+	return sub, nil
+}
+
+// Subscribers returns the amount of current subscribers.
+func (h *Hub) Subscribers() int {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+	return len(h.subs)
+}
+
+// Subscription receives entries from a Hub until it's closed.
+type Subscription struct {
+	hub     *Hub
+	c       chan Entry
+	f       atomic.Value
+	dropped atomic.Uint64
+	once    sync.Once
+}
+
+// C returns the channel the entries are delivered on. It's closed by Close.
+func (s *Subscription) C() <-chan Entry {
+	return s.c
+}
+
+// SetFilter replaces the filter of the subscription. It returns an error,
+// and keeps the current filter, if filter names an unknown level.
+func (s *Subscription) SetFilter(filter Filter) error {
+	if _, err := filter.minLevel(); err != nil {
+		return err
+	}
+	s.f.Store(filter)
+	return nil
+}
+
+// Dropped returns the amount of entries lost because the subscriber didn't
+// keep up.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close unsubscribes and closes the channel.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.mtx.Lock()
+		delete(s.hub.subs, s)
+		close(s.c)
+		s.hub.mtx.Unlock()
+	})
+}
+
+func (s *Subscription) filter() Filter {
+	return s.f.Load().(Filter)
+}
@@ -0,0 +1,173 @@
+package stream
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-godin/log/level"
+)
+
+func TestFilterFromQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  Filter
+	}{
+		{query: "", want: Filter{}},
+		{query: "level=warning", want: Filter{Level: "warning"}},
+		{query: "service=billing&region=eu&region=us", want: Filter{Fields: map[string]string{"service": "billing", "region": "eu"}}},
+		{query: "level=debug&service=billing", want: Filter{Level: "debug", Fields: map[string]string{"service": "billing"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := FilterFromQuery(query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterFromQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		entry  Entry
+		want   bool
+	}{
+		{name: "empty filter", entry: Entry{Level: "debug"}, want: true},
+		{name: "more severe level", filter: Filter{Level: "warning"}, entry: Entry{Level: "error"}, want: true},
+		{name: "same level", filter: Filter{Level: "warning"}, entry: Entry{Level: "warning"}, want: true},
+		{name: "less severe level", filter: Filter{Level: "warning"}, entry: Entry{Level: "info"}},
+		{name: "entry without level", filter: Filter{Level: "error"}, entry: Entry{}, want: true},
+		{name: "alias", filter: Filter{Level: "warn"}, entry: Entry{Level: "info"}},
+		{name: "upper case", filter: Filter{Level: "WARNING"}, entry: Entry{Level: "info"}},
+		{name: "syslog severity", filter: Filter{Level: "4"}, entry: Entry{Level: "warning"}, want: true},
+		{name: "unknown filter level", filter: Filter{Level: "verbose"}, entry: Entry{Level: "debug"}, want: true},
+		{
+			name:   "fields",
+			filter: Filter{Fields: map[string]string{"service": "billing", "shard": "3"}},
+			entry:  Entry{Fields: map[string]interface{}{"service": "billing", "shard": 3, "user": "jane"}},
+			want:   true,
+		},
+		{
+			name:   "field mismatch",
+			filter: Filter{Fields: map[string]string{"service": "billing"}},
+			entry:  Entry{Fields: map[string]interface{}{"service": "orders"}},
+		},
+		{
+			name:   "field missing",
+			filter: Filter{Fields: map[string]string{"service": "billing"}},
+			entry:  Entry{Fields: map[string]interface{}{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(tt.entry); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// subscribe subscribes to h, failing the test on errors.
+func subscribe(t *testing.T, h *Hub, filter Filter, buffer int) *Subscription {
+	t.Helper()
+	sub, err := h.Subscribe(filter, buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sub
+}
+
+func TestHub(t *testing.T) {
+	h := NewHub()
+	if err := h.Log("message", "nobody listening"); err != nil {
+		t.Fatal(err)
+	}
+
+	all := subscribe(t, h, Filter{}, 10)
+	errors := subscribe(t, h, Filter{Level: "err"}, 10)
+	if n := h.Subscribers(); n != 2 {
+		t.Errorf("Subscribers() = %d, want 2", n)
+	}
+	begin := time.Now()
+	_ = h.Log(level.Key(), level.InfoValue(), "message", "started")
+	_ = h.Log(level.Key(), level.ErrorValue(), "message", "failed", "time", "yesterday")
+
+	e := <-all.C()
+	if e.Level != "info" || e.Fields["message"] != "started" || e.Time.Before(begin) {
+		t.Errorf("received %+v, want the info entry", e)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(e.JSON, &doc); err != nil || doc["message"] != "started" || doc["time"] == nil {
+		t.Errorf("JSON = %s (%v), want message and time", e.JSON, err)
+	}
+	if e = <-all.C(); e.Level != "error" {
+		t.Errorf("received %+v, want the error entry", e)
+	}
+	e = <-errors.C()
+	if e.Level != "error" {
+		t.Errorf("received %+v, want the error entry", e)
+	}
+	if err := json.Unmarshal(e.JSON, &doc); err != nil || doc["time"] != "yesterday" {
+		t.Errorf("JSON = %s (%v), want the logged time", e.JSON, err)
+	}
+
+	if err := errors.SetFilter(Filter{}); err != nil {
+		t.Fatal(err)
+	}
+	_ = h.Log(level.Key(), level.DebugValue(), "message", "details")
+	if e = <-errors.C(); e.Level != "debug" {
+		t.Errorf("received %+v after replacing the filter, want the debug entry", e)
+	}
+
+	all.Close()
+	all.Close()
+	for range all.C() {
+		// drain the entries buffered before Close
+	}
+	if n := h.Subscribers(); n != 1 {
+		t.Errorf("Subscribers() = %d after Close, want 1", n)
+	}
+}
+
+func TestHubDropped(t *testing.T) {
+	h := NewHub()
+	sub := subscribe(t, h, Filter{}, 2)
+	defer sub.Close()
+	for i := 0; i < 5; i++ {
+		_ = h.Log("message", i)
+	}
+	if n := sub.Dropped(); n != 3 {
+		t.Errorf("Dropped() = %d, want 3", n)
+	}
+	if e := <-sub.C(); e.Fields["message"] != 0 {
+		t.Errorf("received %v, want the oldest entry", e.Fields)
+	}
+}
+
+func TestSubscribeUnknownLevel(t *testing.T) {
+	h := NewHub()
+	if sub, err := h.Subscribe(Filter{Level: "verbose"}, 1); err == nil {
+		t.Errorf("Subscribe() = %v, want an error for the unknown level", sub)
+	}
+	if n := h.Subscribers(); n != 0 {
+		t.Errorf("Subscribers() = %d, want 0", n)
+	}
+
+	sub := subscribe(t, h, Filter{Level: "error"}, 1)
+	defer sub.Close()
+	if err := sub.SetFilter(Filter{Level: "verbose"}); err == nil {
+		t.Error("SetFilter() succeeded, want an error for the unknown level")
+	}
+	_ = h.Log(level.Key(), level.InfoValue(), "message", "ignored")
+	if len(sub.C()) != 0 {
+		t.Error("received an info entry, want the error filter kept")
+	}
+}
@@ -0,0 +1,70 @@
+package stream
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SSEHandler returns an http.Handler streaming the entries of the hub as
+// Server-Sent Events, e.g. for tailing with the browser's EventSource. The
+// filter is taken from the query parameters (see FilterFromQuery), unknown
+// levels are rejected with 400 Bad Request. Every entry is sent as a "log"
+// event carrying the JSON encoded entry.
+func (h *Hub) SSEHandler(opts ...HandlerOption) http.Handler {
+	o := defaultHandlerOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		sub, err := h.Subscribe(FilterFromQuery(r.URL.Query()), o.buffer)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer sub.Close()
+		rc := http.NewResponseController(w)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no") // disable proxy buffering of nginx
+		w.WriteHeader(http.StatusOK)
+		if err := rc.Flush(); err != nil {
+			return // streaming isn't supported by the writer
+		}
+
+		// comments keep idle connections from being closed by proxies
+		keepAlive := time.NewTicker(o.keepAlive)
+		defer keepAlive.Stop()
+
+		for {
+			var msg []byte
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepAlive.C:
+				msg = []byte(": keep-alive\n\n")
+			case e, ok := <-sub.C():
+				if !ok {
+					return
+				}
+				msg = fmt.Appendf(nil, "event: log\ndata: %s\n\n", e.JSON)
+			}
+			// the deadline covers the write only, not waiting for entries
+			_ = rc.SetWriteDeadline(time.Now().Add(o.writeTimeout))
+			_, err := w.Write(msg)
+			if err == nil {
+				err = rc.Flush()
+			}
+			if err != nil {
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-godin/log/level"
+)
+
+// waitSubscribers waits until the hub has n subscribers.
+func waitSubscribers(t *testing.T, h *Hub, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for h.Subscribers() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("hub has %d subscribers, want %d", h.Subscribers(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSSEHandler(t *testing.T) {
+	h := NewHub()
+	srv := httptest.NewServer(h.SSEHandler())
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?level=warning&service=billing", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status, content type = %d, %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	waitSubscribers(t, h, 1)
+
+	_ = h.Log(level.Key(), level.InfoValue(), "message", "ignored", "service", "billing")
+	_ = h.Log(level.Key(), level.ErrorValue(), "message", "ignored", "service", "orders")
+	_ = h.Log(level.Key(), level.ErrorValue(), "message", "failed", "service", "billing")
+
+	r := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 2 {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if lines[0] != "event: log" || !strings.HasPrefix(lines[1], "data: {") || !strings.Contains(lines[1], `"message":"failed"`) {
+		t.Errorf("received %q, want the failed entry", lines)
+	}
+
+	cancel()
+	waitSubscribers(t, h, 0)
+}
+
+func TestSSEHandlerIdle(t *testing.T) {
+	h := NewHub()
+	// keep-alives are due after the write timeout passed
+	srv := httptest.NewServer(h.SSEHandler(WriteTimeout(50*time.Millisecond), func(o *handlerOptions) {
+		o.keepAlive = 100 * time.Millisecond
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	waitSubscribers(t, h, 1)
+
+	r := bufio.NewReader(resp.Body)
+	for keepAlives := 0; keepAlives < 2; {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("idle stream closed: %v", err)
+		}
+		if strings.TrimSpace(line) == ": keep-alive" {
+			keepAlives++
+		}
+	}
+
+	_ = h.Log("message", "after idling")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream closed after keep-alives: %v", err)
+		}
+		if strings.Contains(line, `"message":"after idling"`) {
+			break
+		}
+	}
+}
+
+func TestSSEHandlerMethod(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewHub().SSEHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != http.MethodGet {
+		t.Errorf("status, Allow = %d, %s, want %d, GET", rec.Code, rec.Header().Get("Allow"), http.StatusMethodNotAllowed)
+	}
+}
+
+func TestSSEHandlerUnknownLevel(t *testing.T) {
+	h := NewHub()
+	rec := httptest.NewRecorder()
+	h.SSEHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?level=verbose", nil))
+	if rec.Code != http.StatusBadRequest || h.Subscribers() != 0 {
+		t.Errorf("status, subscribers = %d, %d, want %d, 0", rec.Code, h.Subscribers(), http.StatusBadRequest)
+	}
+}
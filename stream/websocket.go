@@ -0,0 +1,117 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// WebSocketHandler returns an http.Handler streaming the entries of the hub
+// as JSON text messages to WebSocket clients. The initial filter is taken
+// from the query parameters (see FilterFromQuery); clients can replace it
+// at any time by sending a Filter as JSON message, e.g. {"level":"debug"}.
+// Filters with unknown levels are rejected with 400 Bad Request, or close
+// the connection if sent as message.
+func (h *Hub) WebSocketHandler(opts ...HandlerOption) http.Handler {
+	o := defaultHandlerOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sub, err := h.Subscribe(FilterFromQuery(r.URL.Query()), o.buffer)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer sub.Close()
+
+		conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+			OriginPatterns: o.originPatterns,
+		})
+		if err != nil {
+			return // Accept already responded
+		}
+		defer conn.CloseNow()
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		go func() {
+			// reading is required to process control frames, text messages
+			// replace the filter
+			defer cancel()
+			for {
+				typ, data, err := conn.Read(ctx)
+				if err != nil {
+					return
+				}
+				var filter Filter
+				if typ != websocket.MessageText || json.Unmarshal(data, &filter) != nil {
+					continue
+				}
+				if err := sub.SetFilter(filter); err != nil {
+					conn.Close(websocket.StatusPolicyViolation, err.Error())
+					return
+				}
+			}
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				conn.Close(websocket.StatusNormalClosure, "")
+				return
+			case e, ok := <-sub.C():
+				if !ok {
+					return
+				}
+				wctx, wcancel := context.WithTimeout(ctx, o.writeTimeout)
+				err := conn.Write(wctx, websocket.MessageText, e.JSON)
+				wcancel()
+				if err != nil {
+					return
+				}
+			}
+		}
+	})
+}
+
+// HandlerOption sets a parameter of the streaming handlers.
+type HandlerOption func(*handlerOptions)
+
+type handlerOptions struct {
+	buffer         int
+	writeTimeout   time.Duration
+	originPatterns []string
+	// keepAlive is the interval of the comments sent to idle SSE clients
+	keepAlive time.Duration
+}
+
+func defaultHandlerOptions() handlerOptions {
+	return handlerOptions{
+		buffer:       256,
+		writeTimeout: 10 * time.Second,
+		keepAlive:    15 * time.Second,
+	}
+}
+
+// Buffer sets the amount of entries buffered per client. Once it's full,
+// the client loses entries. Defaults to 256.
+func Buffer(entries int) HandlerOption {
+	return func(o *handlerOptions) { o.buffer = entries }
+}
+
+// WriteTimeout sets the time after which a client which doesn't take any
+// entries is disconnected. Defaults to ten seconds.
+func WriteTimeout(timeout time.Duration) HandlerOption {
+	return func(o *handlerOptions) { o.writeTimeout = timeout }
+}
+
+// OriginPatterns sets the host patterns of cross origin WebSocket clients
+// which are accepted. By default only same origin clients are.
+func OriginPatterns(patterns ...string) HandlerOption {
+	return func(o *handlerOptions) { o.originPatterns = patterns }
+}
@@ -0,0 +1,113 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/go-godin/log/level"
+)
+
+func TestWebSocketHandler(t *testing.T) {
+	h := NewHub()
+	srv := httptest.NewServer(h.WebSocketHandler())
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http")+"?level=error", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseNow()
+	waitSubscribers(t, h, 1)
+
+	read := func() map[string]interface{} {
+		t.Helper()
+		typ, data, err := conn.Read(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var entry map[string]interface{}
+		if typ != websocket.MessageText || json.Unmarshal(data, &entry) != nil {
+			t.Fatalf("received %s message %s", typ, data)
+		}
+		return entry
+	}
+
+	_ = h.Log(level.Key(), level.InfoValue(), "message", "ignored")
+	_ = h.Log(level.Key(), level.ErrorValue(), "message", "failed")
+	if entry := read(); entry["message"] != "failed" {
+		t.Errorf("received %v, want the failed entry", entry)
+	}
+
+	// replace the filter, the next entry is logged once it's in place
+	if err := conn.Write(ctx, websocket.MessageText, []byte(`{"level":"debug"}`)); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var sub *Subscription
+		h.mtx.RLock()
+		for s := range h.subs {
+			sub = s
+		}
+		h.mtx.RUnlock()
+		if sub.filter().Level == "debug" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("filter wasn't replaced")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	_ = h.Log(level.Key(), level.DebugValue(), "message", "details")
+	if entry := read(); entry["message"] != "details" {
+		t.Errorf("received %v, want the debug entry", entry)
+	}
+
+	// a filter with an unknown level closes the connection
+	if err := conn.Write(ctx, websocket.MessageText, []byte(`{"level":"verbose"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := conn.Read(ctx); websocket.CloseStatus(err) != websocket.StatusPolicyViolation {
+		t.Errorf("Read() = %v, want the connection closed as policy violation", err)
+	}
+	waitSubscribers(t, h, 0)
+}
+
+func TestWebSocketHandlerOrigin(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []HandlerOption
+		query      string
+		wantStatus int
+	}{
+		{name: "cross origin rejected", wantStatus: http.StatusForbidden},
+		{name: "unknown level", opts: []HandlerOption{OriginPatterns("console.example.com")}, query: "?level=verbose", wantStatus: http.StatusBadRequest},
+		{name: "cross origin allowed", opts: []HandlerOption{OriginPatterns("console.example.com")}, wantStatus: http.StatusSwitchingProtocols},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(NewHub().WebSocketHandler(tt.opts...))
+			defer srv.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			conn, resp, err := websocket.Dial(ctx, srv.URL+tt.query, &websocket.DialOptions{
+				HTTPHeader: http.Header{"Origin": []string{"https://console.example.com"}},
+			})
+			if err == nil {
+				conn.CloseNow()
+			}
+			if resp == nil || resp.StatusCode != tt.wantStatus {
+				t.Errorf("response %v (%v), want status %d", resp, err, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+// Package tee fans every entry out to multiple sinks, e.g. JSON on stdout and
+// Elasticsearch at the same time. Every sink is fed by its own queue and
+// worker, so a slow or failing sink doesn't hold up the others.
+package tee
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-kit/kit/log"
+)
+
+// ErrClosed is returned by Log after the Tee has been closed.
+var ErrClosed = errors.New("tee: closed")
+
+// Tee hands a copy of every entry to each of its sinks. It implements the
+// go-kit log.Logger interface and can be passed to log.WithSink:
+//
+//	t := tee.New(tee.Sink("stdout", kitlog.NewJSONLogger(os.Stdout)), tee.Sink("es", es))
+//	logger := log.NewLogger("info", log.WithSink(t))
+type Tee struct {
+	branches     []*branch
+	queueSize    int
+	errorHandler func(error)
+
+	mtx    sync.RWMutex
+	closed bool
+}
+
+type branch struct {
+	name    string
+	sink    log.Logger
+	queue   chan entry
+	done    chan struct{}
+	dropped atomic.Uint64
+}
+
+type entry struct {
+	keyvals []interface{}
+	ack     chan struct{}
+}
+
+// Option sets a parameter for the Tee.
+type Option func(*Tee)
+
+// Sink adds a sink under the given name, which identifies it in errors.
+func Sink(name string, sink log.Logger) Option {
+	return func(t *Tee) {
+		t.branches = append(t.branches, &branch{name: name, sink: sink})
+	}
+}
+
+// QueueSize sets the amount of entries buffered for each sink. Once the
+// queue of a sink is full, further entries are dropped for that sink only.
+// Defaults to 1000.
+func QueueSize(size int) Option {
+	return func(t *Tee) { t.queueSize = size }
+}
+
+// ErrorHandler sets the function called with errors returned by the sinks.
+// By default errors are written to stderr.
+func ErrorHandler(handler func(error)) Option {
+	return func(t *Tee) { t.errorHandler = handler }
+}
+
+// New creates a Tee writing to the given sinks and starts a worker per sink.
+func New(opts ...Option) *Tee {
+	t := &Tee{
+		queueSize: 1000,
+		errorHandler: func(err error) {
+			fmt.Fprintf(os.Stderr, "tee: %v\n", err)
+		},
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	for _, b := range t.branches {
+		b.queue = make(chan entry, t.queueSize)
+		b.done = make(chan struct{})
+		go t.run(b)
+	}
+	return t
+}
+
+// Log enqueues the entry for every sink. It never blocks: sinks whose queue
+// is full lose the entry, which is counted by Dropped.
+func (t *Tee) Log(keyvals ...interface{}) error {
+	// the sinks may keep the slice around, so each entry gets its own copy
+	e := entry{keyvals: append([]interface{}(nil), keyvals...)}
+
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+	if t.closed {
+		return ErrClosed
+	}
+	for _, b := range t.branches {
+		select {
+		case b.queue <- e:
+		default:
+			b.dropped.Add(1)
+		}
+	}
+	return nil
+}
+
+// Dropped returns the amount of entries dropped per sink name because the
+// queue of the sink was full.
+func (t *Tee) Dropped() map[string]uint64 {
+	dropped := make(map[string]uint64, len(t.branches))
+	for _, b := range t.branches {
+		dropped[b.name] += b.dropped.Load()
+	}
+	return dropped
+}
+
+// Flush blocks until every sink processed the entries enqueued so far and
+// flushes the sinks which provide a Flush method themselves.
+func (t *Tee) Flush() error {
+	t.mtx.RLock()
+	if t.closed {
+		t.mtx.RUnlock()
+		return nil
+	}
+	acks := make([]chan struct{}, len(t.branches))
+	for i, b := range t.branches {
+		acks[i] = make(chan struct{})
+		b.queue <- entry{ack: acks[i]}
+	}
+	t.mtx.RUnlock()
+
+	for _, ack := range acks {
+		<-ack
+	}
+	return nil
+}
+
+// Close stops accepting new entries, waits until every sink processed its
+// queue and closes the sinks which provide a Close method.
+func (t *Tee) Close() error {
+	t.mtx.Lock()
+	if t.closed {
+		t.mtx.Unlock()
+		return nil
+	}
+	t.closed = true
+	for _, b := range t.branches {
+		close(b.queue)
+	}
+	t.mtx.Unlock()
+
+	for _, b := range t.branches {
+		<-b.done
+		if c, ok := b.sink.(interface{ Close() error }); ok {
+			t.handle(b, c.Close())
+		}
+	}
+	return nil
+}
+
+func (t *Tee) run(b *branch) {
+	defer close(b.done)
+	for e := range b.queue {
+		if e.ack != nil {
+			if f, ok := b.sink.(interface{ Flush() error }); ok {
+				t.handle(b, f.Flush())
+			}
+			close(e.ack)
+			continue
+		}
+		t.handle(b, b.sink.Log(e.keyvals...))
+	}
+}
+
+func (t *Tee) handle(b *branch, err error) {
+	if err != nil {
+		t.errorHandler(fmt.Errorf("sink %q: %v", b.name, err))
+	}
+}
@@ -0,0 +1,154 @@
+package tee
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingSink records the messages logged and counts calls to Flush and
+// Close.
+type recordingSink struct {
+	err error
+
+	mtx      sync.Mutex
+	messages []string
+	flushed  int
+	closed   int
+}
+
+func (s *recordingSink) Log(keyvals ...interface{}) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.messages = append(s.messages, fmt.Sprint(keyvals[1]))
+	return s.err
+}
+
+func (s *recordingSink) Flush() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.flushed++
+	return nil
+}
+
+func (s *recordingSink) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.closed++
+	return s.err
+}
+
+func (s *recordingSink) String() string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return strings.Join(s.messages, ",")
+}
+
+func TestTee(t *testing.T) {
+	tests := []struct {
+		name       string
+		errs       []error // per sink
+		wantErrors []string
+	}{
+		{name: "single sink", errs: []error{nil}},
+		{name: "multiple sinks", errs: []error{nil, nil, nil}},
+		{
+			name:       "failing sink",
+			errs:       []error{nil, errors.New("unavailable")},
+			wantErrors: []string{`sink "1": unavailable`, `sink "1": unavailable`, `sink "1": unavailable`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mtx    sync.Mutex
+				errors []string
+				sinks  []*recordingSink
+				opts   = []Option{ErrorHandler(func(err error) { mtx.Lock(); errors = append(errors, err.Error()); mtx.Unlock() })}
+			)
+			for i, err := range tt.errs {
+				s := &recordingSink{err: err}
+				sinks = append(sinks, s)
+				opts = append(opts, Sink(fmt.Sprint(i), s))
+			}
+			tee := New(opts...)
+			_ = tee.Log("message", "a")
+			_ = tee.Log("message", "b")
+			if err := tee.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			for i, s := range sinks {
+				if got := s.String(); got != "a,b" || s.flushed != 1 {
+					t.Errorf("sink %d: logged %s and flushed %d times, want a,b and once", i, got, s.flushed)
+				}
+			}
+			if err := tee.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := tee.Log("message", "c"); err != ErrClosed {
+				t.Errorf("Log after Close = %v, want ErrClosed", err)
+			}
+			for i, s := range sinks {
+				if s.closed != 1 {
+					t.Errorf("sink %d: closed %d times, want once", i, s.closed)
+				}
+			}
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			if strings.Join(errors, "|") != strings.Join(tt.wantErrors, "|") {
+				t.Errorf("errors = %q, want %q", errors, tt.wantErrors)
+			}
+		})
+	}
+}
+
+// blockingSink blocks every Log until release is closed.
+type blockingSink struct {
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (s *blockingSink) Log(...interface{}) error {
+	s.once.Do(func() { close(s.started) })
+	<-s.release
+	return nil
+}
+
+func TestTeeDropped(t *testing.T) {
+	slow := &blockingSink{started: make(chan struct{}), release: make(chan struct{})}
+	fast := &recordingSink{}
+	tee := New(QueueSize(1), Sink("slow", slow), Sink("fast", fast))
+
+	_ = tee.Log("message", "a")
+	<-slow.started
+	// the slow sink blocks on a, b fills its queue and c is dropped
+	_ = tee.Log("message", "b")
+	_ = tee.Log("message", "c")
+
+	dropped := tee.Dropped()
+	if dropped["slow"] != 1 {
+		t.Errorf("dropped %d entries for the slow sink, want 1", dropped["slow"])
+	}
+	close(slow.release)
+	_ = tee.Close()
+	// the fast sink has a queue of one entry as well, but isn't held up
+	if logged := uint64(len(fast.messages)); logged+dropped["fast"] != 3 {
+		t.Errorf("fast sink logged %d and dropped %d entries, want 3 in total", logged, dropped["fast"])
+	}
+}
+
+func TestTeeCopiesKeyvals(t *testing.T) {
+	s := &recordingSink{}
+	tee := New(Sink("s", s))
+	keyvals := []interface{}{"message", "a"}
+	_ = tee.Log(keyvals...)
+	keyvals[1] = "changed"
+	_ = tee.Close()
+	if got := s.String(); got != "a" {
+		t.Errorf("logged %s, want a", got)
+	}
+}
@@ -0,0 +1,58 @@
+package log
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// TimeKey is the key of the timestamp added by WithTimestamp.
+const TimeKey = "time"
+
+// WithTimestamp adds the time of every entry as TimeKey field, formatted in
+// UTC as RFC 3339 with milliseconds, e.g. "2024-05-01T12:00:00.123Z". The
+// formatted second is cached, so only the milliseconds are formatted per
+// entry.
+func WithTimestamp() Option {
+	return func(o *options) { o.timestamp = cachedTimestamp(time.Now) }
+}
+
+// WithEpochMillisTimestamp adds the time of every entry as TimeKey field in
+// milliseconds since 1970, which is cheaper than formatting it.
+func WithEpochMillisTimestamp() Option {
+	return func(o *options) {
+		o.timestamp = func() interface{} { return time.Now().UnixMilli() }
+	}
+}
+
+// formattedSecond is a second formatted up to the fractional part.
+type formattedSecond struct {
+	unix   int64
+	prefix string
+}
+
+// cachedTimestamp returns a valuer formatting the times of now, which only
+// formats the date and time of day once per second.
+func cachedTimestamp(now func() time.Time) log.Valuer {
+	var cache atomic.Pointer[formattedSecond]
+	return func() interface{} {
+		t := now().UTC()
+		sec := t.Unix()
+		cached := cache.Load()
+		if cached == nil || cached.unix != sec {
+			cached = &formattedSecond{unix: sec, prefix: t.Format("2006-01-02T15:04:05")}
+			cache.Store(cached)
+		}
+
+		var buf [len("2006-01-02T15:04:05.000Z")]byte
+		n := copy(buf[:], cached.prefix)
+		ms := t.Nanosecond() / int(time.Millisecond)
+		buf[n] = '.'
+		buf[n+1] = byte('0' + ms/100)
+		buf[n+2] = byte('0' + ms/10%10)
+		buf[n+3] = byte('0' + ms%10)
+		buf[n+4] = 'Z'
+		return string(buf[:n+5])
+	}
+}
@@ -0,0 +1,114 @@
+package log
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// TraceparentHeader is the W3C Trace Context header carrying the trace.
+const TraceparentHeader = "traceparent"
+
+// Traceparent is the trace context of a W3C traceparent header, e.g.
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+type Traceparent struct {
+	TraceID string
+	SpanID  string
+	Sampled bool
+}
+
+// ParseTraceparent parses the value of a traceparent header.
+func ParseTraceparent(header string) (Traceparent, error) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	// later versions may append fields
+	if len(parts) < 4 || parts[0] == "00" && len(parts) != 4 {
+		return Traceparent{}, fmt.Errorf("invalid traceparent %q", header)
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isHex(version, 2) || version == "ff" || !isHex(traceID, 32) || !isHex(spanID, 16) || !isHex(flags, 2) {
+		return Traceparent{}, fmt.Errorf("invalid traceparent %q", header)
+	}
+	if strings.Trim(traceID, "0") == "" || strings.Trim(spanID, "0") == "" {
+		return Traceparent{}, fmt.Errorf("invalid traceparent %q: all-zero ID", header)
+	}
+
+	b, _ := hex.DecodeString(flags)
+	return Traceparent{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: b[0]&1 == 1,
+	}, nil
+}
+
+// String formats the trace context as the value of a traceparent header.
+// 64 bit trace IDs, e.g. of B3 headers, are padded with zeros.
+func (t Traceparent) String() string {
+	flags := "00"
+	if t.Sampled {
+		flags = "01"
+	}
+	traceID := t.TraceID
+	if len(traceID) < 32 {
+		traceID = strings.Repeat("0", 32-len(traceID)) + traceID
+	}
+	return "00-" + traceID + "-" + t.SpanID + "-" + flags
+}
+
+// isHex reports whether s consists of n lower case hex digits.
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if (s[i] < '0' || s[i] > '9') && (s[i] < 'a' || s[i] > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+type traceparentKey struct{}
+
+// ContextWithTraceparent returns a copy of ctx carrying the trace context of
+// the traceparent header, which WithTrace picks up if ctx carries no other
+// span. Invalid headers are reported and ctx is returned.
+func ContextWithTraceparent(ctx context.Context, header string) (context.Context, error) {
+	tp, err := ParseTraceparent(header)
+	if err != nil {
+		return ctx, err
+	}
+	return ContextWithTrace(ctx, tp), nil
+}
+
+// ContextWithTrace returns a copy of ctx carrying the trace context, e.g.
+// one returned by TraceFromHeader, which WithTrace picks up if ctx carries
+// no other span.
+func ContextWithTrace(ctx context.Context, tp Traceparent) context.Context {
+	return context.WithValue(ctx, traceparentKey{}, tp)
+}
+
+// TraceparentFromContext returns the trace context stored with
+// ContextWithTraceparent.
+func TraceparentFromContext(ctx context.Context) (Traceparent, bool) {
+	tp, ok := ctx.Value(traceparentKey{}).(Traceparent)
+	return tp, ok
+}
+
+// WithTraceparent adds the trace_id, span_id and sampled fields of the
+// traceparent header to all entries of the returned Log, e.g. for services
+// behind proxies which only forward W3C headers. Invalid headers are ignored.
+func (l Log) WithTraceparent(header string) Log {
+	tp, err := ParseTraceparent(header)
+	if err != nil {
+		return l
+	}
+	return l.WithSpan(tp)
+}
+
+// A Traceparent is a SpanRecorder without a span: the IDs are logged, but
+// annotations, tags and errors are discarded.
+func (Traceparent) Annotate(string)             {}
+func (Traceparent) Tag(string, string)          {}
+func (Traceparent) SetError(string)             {}
+func (t Traceparent) TraceContext() Traceparent { return t }
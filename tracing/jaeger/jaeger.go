@@ -0,0 +1,70 @@
+// Package jaeger records the entries of godin Logs bound to OpenTracing
+// spans created by jaeger-client-go with WithTrace. Importing it registers
+// its span source:
+//
+//	import _ "github.com/go-godin/log/tracing/jaeger"
+package jaeger
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-godin/log"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	otlog "github.com/opentracing/opentracing-go/log"
+)
+
+func init() {
+	log.RegisterSpanSource(FromContext)
+}
+
+// spanContext is implemented by the span context of jaeger-client-go, whose
+// String method returns "trace-id:span-id:parent-id:flags". It's matched
+// structurally, to not depend on the client.
+type spanContext interface {
+	IsSampled() bool
+	String() string
+}
+
+// FromContext returns the recorder of the Jaeger span of ctx, or nil.
+func FromContext(ctx context.Context) log.SpanRecorder {
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		if _, ok := span.Context().(spanContext); ok {
+			return New(span)
+		}
+	}
+	return nil
+}
+
+// New returns a recorder of span, to be passed to WithSpan. The trace fields
+// are only added for spans created by jaeger-client-go.
+func New(span opentracing.Span) log.SpanRecorder {
+	return recorder{span}
+}
+
+type recorder struct {
+	span opentracing.Span
+}
+
+func (r recorder) Annotate(message string) { r.span.LogFields(otlog.String("event", message)) }
+func (r recorder) Tag(key, value string)   { r.span.SetTag(key, value) }
+func (r recorder) SetError(string)         { ext.Error.Set(r.span, true) }
+
+// TraceContext returns the zero Traceparent for spans of other tracers, so
+// they're logged without trace fields.
+func (r recorder) TraceContext() log.Traceparent {
+	sc, ok := r.span.Context().(spanContext)
+	if !ok {
+		return log.Traceparent{}
+	}
+	parts := strings.SplitN(sc.String(), ":", 3)
+	if len(parts) < 2 {
+		return log.Traceparent{}
+	}
+	return log.Traceparent{
+		TraceID: parts[0],
+		SpanID:  parts[1],
+		Sampled: sc.IsSampled(),
+	}
+}
@@ -0,0 +1,109 @@
+package jaeger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-godin/log"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// jaegerContext is a span context formatted like the one of
+// jaeger-client-go.
+type jaegerContext struct {
+	mocktracer.MockSpanContext
+	id string
+}
+
+func (c jaegerContext) IsSampled() bool { return c.Sampled }
+func (c jaegerContext) String() string  { return c.id }
+
+// jaegerSpan is a span of the mock tracer with a jaeger span context.
+type jaegerSpan struct {
+	*mocktracer.MockSpan
+	id string
+}
+
+func (s jaegerSpan) Context() opentracing.SpanContext {
+	return jaegerContext{MockSpanContext: s.SpanContext, id: s.id}
+}
+
+func TestTraceContext(t *testing.T) {
+	tracer := mocktracer.New()
+	tests := []struct {
+		name string
+		span opentracing.Span
+		want log.Traceparent
+	}{
+		{
+			name: "jaeger span",
+			span: jaegerSpan{MockSpan: tracer.StartSpan("op").(*mocktracer.MockSpan), id: "4bf92f3577b34da6:00f067aa0ba902b7:0:1"},
+			want: log.Traceparent{TraceID: "4bf92f3577b34da6", SpanID: "00f067aa0ba902b7", Sampled: true},
+		},
+		{
+			name: "malformed id",
+			span: jaegerSpan{MockSpan: tracer.StartSpan("op").(*mocktracer.MockSpan), id: "4bf92f3577b34da6"},
+		},
+		{
+			name: "span of another tracer",
+			span: tracer.StartSpan("op"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := New(tt.span).(interface{ TraceContext() log.Traceparent })
+			if got := rec.TraceContext(); got != tt.want {
+				t.Errorf("TraceContext() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+type nopSink struct{}
+
+func (nopSink) Log(...interface{}) error { return nil }
+
+func TestFromContext(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("op").(*mocktracer.MockSpan)
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want bool
+	}{
+		{"jaeger span", opentracing.ContextWithSpan(context.Background(), jaegerSpan{MockSpan: span, id: "1:2:0:1"}), true},
+		{"span of another tracer", opentracing.ContextWithSpan(context.Background(), span), false},
+		{"no span", context.Background(), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FromContext(tt.ctx) != nil; got != tt.want {
+				t.Errorf("FromContext() found a span: %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordsEntries(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("op").(*mocktracer.MockSpan)
+	logger := log.NewLogger("info", log.WithSink(nopSink{})).WithSpan(New(span))
+	logger.Info("handled")
+	logger.Error("failed")
+
+	if failed, _ := span.Tag("error").(bool); !failed {
+		t.Errorf("error tag = %v, want true", span.Tag("error"))
+	}
+	var events []interface{}
+	for _, record := range span.Logs() {
+		for _, field := range record.Fields {
+			if field.Key == "event" {
+				events = append(events, field.ValueString)
+			}
+		}
+	}
+	if len(events) != 2 || events[0] != "handled" || events[1] != "failed" {
+		t.Errorf("events = %v, want handled and failed", events)
+	}
+}
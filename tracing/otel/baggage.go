@@ -0,0 +1,33 @@
+package otel
+
+import (
+	"context"
+
+	"github.com/go-godin/log"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// BaggageFields returns a context extractor copying the given members of the
+// OpenTelemetry baggage of the context into fields of the same name, so
+// attributes propagated across services, e.g. an experiment id, show up in
+// the logs of all of them:
+//
+//	log.RegisterContextExtractor(otel.BaggageFields("experiment_id", "tenant"))
+//
+// Members missing from the baggage are skipped. The zipkin client in use
+// doesn't propagate baggage.
+func BaggageFields(keys ...string) log.ContextExtractor {
+	return func(ctx context.Context) []interface{} {
+		b := baggage.FromContext(ctx)
+		if b.Len() == 0 {
+			return nil
+		}
+		var keyvals []interface{}
+		for _, key := range keys {
+			if m := b.Member(key); m.Key() != "" {
+				keyvals = append(keyvals, key, m.Value())
+			}
+		}
+		return keyvals
+	}
+}
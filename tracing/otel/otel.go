@@ -0,0 +1,53 @@
+// Package otel records the entries of godin Logs bound to OpenTelemetry
+// spans with WithTrace. Importing it registers its span source:
+//
+//	import _ "github.com/go-godin/log/tracing/otel"
+package otel
+
+import (
+	"context"
+
+	"github.com/go-godin/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func init() {
+	log.RegisterSpanSource(FromContext)
+}
+
+// FromContext returns the recorder of the OpenTelemetry span of ctx, or nil.
+func FromContext(ctx context.Context) log.SpanRecorder {
+	if span := trace.SpanFromContext(ctx); span.SpanContext().IsValid() {
+		return New(span)
+	}
+	return nil
+}
+
+// New returns a recorder of span, to be passed to WithSpan.
+func New(span trace.Span) log.SpanRecorder {
+	return recorder{span}
+}
+
+type recorder struct {
+	span trace.Span
+}
+
+func (r recorder) Annotate(message string) { r.span.AddEvent(message) }
+func (r recorder) Tag(key, value string)   { r.span.SetAttributes(attribute.String(key, value)) }
+func (r recorder) SetError(message string) { r.span.SetStatus(codes.Error, message) }
+
+// TraceContext returns the zero Traceparent for spans without a valid span
+// context, e.g. the ones of a no-op tracer.
+func (r recorder) TraceContext() log.Traceparent {
+	sc := r.span.SpanContext()
+	if !sc.IsValid() {
+		return log.Traceparent{}
+	}
+	return log.Traceparent{
+		TraceID: sc.TraceID().String(),
+		SpanID:  sc.SpanID().String(),
+		Sampled: sc.IsSampled(),
+	}
+}
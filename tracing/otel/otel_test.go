@@ -0,0 +1,43 @@
+package otel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-godin/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceContext(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	sampled := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled})
+	tests := []struct {
+		name string
+		span trace.Span
+		want log.Traceparent
+	}{
+		{
+			name: "sampled span",
+			span: trace.SpanFromContext(trace.ContextWithSpanContext(context.Background(), sampled)),
+			want: log.Traceparent{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true},
+		},
+		{
+			name: "unsampled span",
+			span: trace.SpanFromContext(trace.ContextWithSpanContext(context.Background(), sampled.WithTraceFlags(0))),
+			want: log.Traceparent{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"},
+		},
+		{
+			name: "invalid span",
+			span: trace.SpanFromContext(context.Background()),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := New(tt.span).(interface{ TraceContext() log.Traceparent })
+			if got := rec.TraceContext(); got != tt.want {
+				t.Errorf("TraceContext() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
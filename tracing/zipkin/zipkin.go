@@ -0,0 +1,62 @@
+// Package zipkin records the entries of godin Logs bound to zipkin spans
+// with WithTrace. Importing it registers its span source:
+//
+//	import _ "github.com/go-godin/log/tracing/zipkin"
+package zipkin
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-godin/log"
+	"github.com/go-godin/log/selftrace"
+	stdzipkin "github.com/openzipkin/zipkin-go"
+)
+
+func init() {
+	log.RegisterSpanSource(FromContext)
+}
+
+// FromContext returns the recorder of the zipkin span of ctx, or nil.
+func FromContext(ctx context.Context) log.SpanRecorder {
+	if span := stdzipkin.SpanFromContext(ctx); span != nil {
+		return New(span)
+	}
+	return nil
+}
+
+// New returns a recorder of span, to be passed to WithSpan.
+func New(span stdzipkin.Span) log.SpanRecorder {
+	return recorder{span}
+}
+
+type recorder struct {
+	span stdzipkin.Span
+}
+
+func (r recorder) Annotate(message string) { r.span.Annotate(time.Now(), message) }
+func (r recorder) Tag(key, value string)   { r.span.Tag(key, value) }
+func (r recorder) SetError(message string) { r.span.Tag(string(stdzipkin.TagError), message) }
+
+func (r recorder) TraceContext() log.Traceparent {
+	sc := r.span.Context()
+	return log.Traceparent{
+		TraceID: sc.TraceID.String(),
+		SpanID:  sc.ID.String(),
+		Sampled: sc.Debug || sc.Sampled != nil && *sc.Sampled,
+	}
+}
+
+// SelfTrace returns the Starter reporting the slow operations of the
+// logging pipeline as spans of tracer, to be passed to selftrace.New.
+func SelfTrace(tracer *stdzipkin.Tracer) selftrace.Starter {
+	return func(name string, start time.Time) selftrace.Span {
+		return finishing{recorder{tracer.StartSpan(name, stdzipkin.StartTime(start))}}
+	}
+}
+
+type finishing struct {
+	recorder
+}
+
+func (f finishing) Finish() { f.span.Finish() }
@@ -0,0 +1,73 @@
+package zipkin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-godin/log"
+	"github.com/go-godin/log/selftrace"
+	stdzipkin "github.com/openzipkin/zipkin-go"
+	zipkinrecorder "github.com/openzipkin/zipkin-go/reporter/recorder"
+)
+
+func newTracer(t *testing.T) (*stdzipkin.Tracer, *zipkinrecorder.ReporterRecorder) {
+	t.Helper()
+	rec := zipkinrecorder.NewReporter()
+	tracer, err := stdzipkin.NewTracer(rec, stdzipkin.WithSampler(stdzipkin.AlwaysSample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tracer, rec
+}
+
+type nopSink struct{}
+
+func (nopSink) Log(...interface{}) error { return nil }
+
+func TestWithTrace(t *testing.T) {
+	tracer, rec := newTracer(t)
+	span := tracer.StartSpan("request")
+	ctx := stdzipkin.NewContext(context.Background(), span)
+
+	logger := log.NewLogger("info", log.WithSink(nopSink{})).WithTrace(ctx)
+	logger.Info("handled", "user", "u1")
+	logger.Error("failed")
+	span.Finish()
+
+	spans := rec.Flush()
+	if len(spans) != 1 {
+		t.Fatalf("%d spans, want 1", len(spans))
+	}
+	got := spans[0]
+	if len(got.Annotations) != 2 || got.Annotations[0].Value != "handled" || got.Annotations[1].Value != "failed" {
+		t.Errorf("annotations = %v", got.Annotations)
+	}
+	if got.Tags["user"] != "u1" || got.Tags["error"] != "failed" {
+		t.Errorf("tags = %v", got.Tags)
+	}
+}
+
+func TestFromContextWithoutSpan(t *testing.T) {
+	if r := FromContext(context.Background()); r != nil {
+		t.Fatalf("FromContext() = %v, want nil", r)
+	}
+}
+
+func TestSelfTrace(t *testing.T) {
+	tracer, rec := newTracer(t)
+	start := time.Now()
+	op := selftrace.New(SelfTrace(tracer), time.Hour).Start("log.sink.flush")
+	op.Retry(errors.New("refused"))
+	op.End(nil)
+
+	spans := rec.Flush()
+	if len(spans) != 1 {
+		t.Fatalf("%d spans, want 1", len(spans))
+	}
+	got := spans[0]
+	if got.Name != "log.sink.flush" || got.Timestamp.Before(start) || got.Tags["retries"] != "1" || len(got.Annotations) != 1 {
+		t.Errorf("span = %+v", got)
+	}
+}
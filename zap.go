@@ -1,6 +1,11 @@
 package log
 
 import (
+	"time"
+
+	"github.com/go-godin/log/internal/kv"
+	"github.com/go-godin/log/level"
+	"github.com/go-kit/kit/log"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -52,3 +57,85 @@ func NewZapLogger(logLevel string) (*zap.Logger, error) {
 
 	return zapConfig.Build()
 }
+
+// zapLevels maps the names of the levels to zap levels.
+var zapLevels = map[string]zapcore.Level{
+	LevelDebug:   zapcore.DebugLevel,
+	LevelInfo:    zapcore.InfoLevel,
+	LevelWarning: zapcore.WarnLevel,
+	LevelError:   zapcore.ErrorLevel,
+	LevelFatal:   zapcore.FatalLevel,
+	LevelPanic:   zapcore.PanicLevel,
+}
+
+// zapLevel returns the zap level of v. Custom levels map to the zap level of
+// the built-in level below them, e.g. a level between warning and error to
+// warn, and those below debug to debug.
+func zapLevel(v level.Value) zapcore.Level {
+	if lvl, ok := zapLevels[v.String()]; ok {
+		return lvl
+	}
+	switch severity := v.Severity(); {
+	case severity < level.InfoValue().Severity():
+		return zapcore.DebugLevel
+	case severity < level.WarnValue().Severity():
+		return zapcore.InfoLevel
+	case severity < level.ErrorValue().Severity():
+		return zapcore.WarnLevel
+	default:
+		return zapcore.ErrorLevel
+	}
+}
+
+// WithZapCore writes entries to core instead of a go-kit sink, e.g. to reuse
+// the encoders and outputs of an existing zap setup. The message, level and
+// logger name are mapped to the zap entry, all other keyvals become fields.
+// Entries pass the level filter of the Log and the one of core.
+func WithZapCore(core zapcore.Core) Option {
+	return WithSink(zapSink{core: core})
+}
+
+// zapSink writes entries to a zapcore.Core.
+type zapSink struct {
+	core zapcore.Core
+}
+
+func (s zapSink) Log(keyvals ...interface{}) error {
+	ent := zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now()}
+	fields := make([]zapcore.Field, 0, len(keyvals)/2)
+	for i := 0; i < len(keyvals); i += 2 {
+		var value interface{} = log.ErrMissingValue
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+		if keyvals[i] == level.Key() {
+			if v, ok := value.(level.Value); ok {
+				ent.Level = zapLevel(v)
+			}
+			continue
+		}
+		key := kv.Key(keyvals[i])
+		switch key {
+		case MessageKey:
+			ent.Message = kv.String(value)
+		case LoggerKey:
+			ent.LoggerName = kv.String(value)
+		case StacktraceKey:
+			ent.Stack = kv.String(value)
+		default:
+			fields = append(fields, zap.Any(key, value))
+		}
+	}
+
+	// the entry is written by the core directly, so fatal and panic entries
+	// don't exit or panic here
+	if ce := s.core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+	return nil
+}
+
+// Flush syncs the core, e.g. before Fatal exits.
+func (s zapSink) Flush() error {
+	return s.core.Sync()
+}
@@ -0,0 +1,61 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/go-godin/log/level"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// zapTestLevels are custom levels between the built-in ones, registered
+// once per process.
+var zapTestLevels = func() map[string]level.Value {
+	levels := make(map[string]level.Value)
+	for name, severity := range map[string]int{
+		"zap-trace":    50,
+		"zap-verbose":  150,
+		"zap-notice":   250,
+		"zap-alert":    350,
+		"zap-critical": 450,
+	} {
+		v, err := level.Register(name, severity)
+		if err != nil {
+			panic(err)
+		}
+		levels[name] = v
+	}
+	return levels
+}()
+
+func TestZapSinkLevels(t *testing.T) {
+	tests := []struct {
+		name  string
+		level level.Value
+		want  zapcore.Level
+	}{
+		{"debug", level.DebugValue(), zapcore.DebugLevel},
+		{"forced debug", level.Force(level.DebugValue()), zapcore.DebugLevel},
+		{"warning", level.WarnValue(), zapcore.WarnLevel},
+		{"fatal", level.FatalValue(), zapcore.FatalLevel},
+		{"below debug", zapTestLevels["zap-trace"], zapcore.DebugLevel},
+		{"between debug and info", zapTestLevels["zap-verbose"], zapcore.DebugLevel},
+		{"between info and warning", zapTestLevels["zap-notice"], zapcore.InfoLevel},
+		{"between warning and error", zapTestLevels["zap-alert"], zapcore.WarnLevel},
+		{"between error and fatal", zapTestLevels["zap-critical"], zapcore.ErrorLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			_ = zapSink{core: core}.Log(level.Key(), tt.level, MessageKey, "m")
+
+			entries := logs.All()
+			if len(entries) != 1 {
+				t.Fatalf("%d entries written, want 1", len(entries))
+			}
+			if got := entries[0].Level; got != tt.want {
+				t.Errorf("level = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}